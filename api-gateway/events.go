@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// 与 credit-activity-service/realtime 保持一致的键约定：
+// 每个接收方有一个 Redis Stream（用于按 Last-Event-ID 补发）和一个同名 pub/sub 频道（用于实时推送）
+const (
+	eventUserKeyPrefix = "events:user:"
+	eventReplayLimit   = 500
+	eventHeartbeat     = 25 * time.Second
+)
+
+// realtimeMessage 服务端通过 pub/sub 发布的消息
+type realtimeMessage struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// EventStreamHandler 将 Redis 中按用户划分的业务事件以 SSE 方式推送给前端
+type EventStreamHandler struct {
+	redis *redis.Client
+}
+
+func NewEventStreamHandler(client *redis.Client) *EventStreamHandler {
+	return &EventStreamHandler{redis: client}
+}
+
+// eventKeys 返回当前用户需要订阅的键。待审核提醒也按审核人的数据范围逐个推送，不再按角色广播
+func eventKeys(userID string) []string {
+	return []string{eventUserKeyPrefix + userID}
+}
+
+// Stream GET /api/events/stream
+// 浏览器 EventSource 断线重连时会自动携带 Last-Event-ID 请求头；首次连接可用 ?last_event_id= 指定。
+func (h *EventStreamHandler) Stream(c *gin.Context) {
+	userID := c.GetString("uuid")
+	keys := eventKeys(userID)
+	ctx := c.Request.Context()
+
+	// 先订阅再补发，保证补发与实时推送之间不会漏掉事件
+	pubsub := h.redis.Subscribe(ctx, keys...)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Printf("event stream subscribe failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "实时事件服务暂不可用",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	// 每个键单独记录已发送位置，个人与角色两个 Stream 的 ID 互不干扰
+	cursors := make(map[string]string, len(keys))
+	for _, key := range keys {
+		cursors[key] = lastEventID
+	}
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	if _, ok := parseStreamID(lastEventID); ok {
+		if err := h.replay(ctx, w, keys, cursors); err != nil {
+			log.Printf("event stream replay failed for user %s: %v", userID, err)
+		}
+	}
+	w.Flush()
+
+	messages := pubsub.Channel()
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var m realtimeMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+				continue
+			}
+			if compareStreamIDs(m.ID, cursors[msg.Channel]) <= 0 {
+				continue
+			}
+			writeEvent(w, m)
+			cursors[msg.Channel] = m.ID
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": ping\n\n")
+		}
+		w.Flush()
+	}
+}
+
+// replay 补发 Last-Event-ID 之后的事件，多个 Stream 的结果按 ID 合并排序
+func (h *EventStreamHandler) replay(ctx context.Context, w io.Writer, keys []string, cursors map[string]string) error {
+	type entry struct {
+		key     string
+		message realtimeMessage
+	}
+	var entries []entry
+	for _, key := range keys {
+		items, err := h.redis.XRangeN(ctx, key, "("+cursors[key], "+", eventReplayLimit).Result()
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			eventType, _ := item.Values["type"].(string)
+			data, _ := item.Values["data"].(string)
+			entries = append(entries, entry{key: key, message: realtimeMessage{ID: item.ID, Type: eventType, Data: json.RawMessage(data)}})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareStreamIDs(entries[i].message.ID, entries[j].message.ID) < 0
+	})
+	for _, e := range entries {
+		writeEvent(w, e.message)
+		cursors[e.key] = e.message.ID
+	}
+	return nil
+}
+
+func writeEvent(w io.Writer, m realtimeMessage) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", m.ID, m.Type, m.Data)
+}
+
+// parseStreamID 解析 Redis Stream ID（<毫秒>-<序号>）
+func parseStreamID(id string) ([2]uint64, bool) {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 {
+		return [2]uint64{}, false
+	}
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return [2]uint64{}, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return [2]uint64{}, false
+	}
+	return [2]uint64{ms, seq}, true
+}
+
+// compareStreamIDs 比较两个 Stream ID，无法解析的 ID 视为最小值
+func compareStreamIDs(a, b string) int {
+	pa, okA := parseStreamID(a)
+	pb, okB := parseStreamID(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := 0; i < 2; i++ {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -16,9 +16,12 @@ import (
 	"strings"
 	"time"
 
+	"credit-management/shared/servicetoken"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 )
 
 // 辅助函数
@@ -206,12 +209,29 @@ func main() {
 		JWTSecret:                getEnv("JWT_SECRET", "your-secret-key"),
 	}
 
+	// 转发请求时携带的网关服务令牌（由认证服务签发）
+	serviceTokens = servicetoken.NewSource(config.AuthServiceURL, getEnv("INTERNAL_SERVICE_NAME", servicetoken.GatewayService), getEnv("SERVICE_CLIENT_SECRET", ""))
+	// 令牌按目标服务签发（aud），发给一个服务的令牌不能拿去调用另一个服务
+	serviceAudiences = map[string]string{
+		config.UserServiceURL:           servicetoken.UserService,
+		config.AuthServiceURL:           servicetoken.AuthService,
+		config.CreditActivityServiceURL: servicetoken.CreditActivityService,
+	}
+
 	// 创建中间件
 	authMiddleware := NewAuthMiddleware(config.JWTSecret)
 	permissionMiddleware := NewPermissionMiddleware()
+	eventStreamHandler := NewEventStreamHandler(redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", getEnv("REDIS_HOST", "localhost"), getEnv("REDIS_PORT", "6379")),
+		Password: getEnv("REDIS_PASSWORD", ""),
+	}))
 
 	// 设置Gin路由
 	r := gin.Default()
+	// 只采信入口代理（前端 nginx）转发的客户端地址，并以此写入转发给后端服务的 X-Forwarded-For
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置错误: ", err)
+	}
 
 	// 获取允许的前端域名
 	corsAllowedOrigins := getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173")
@@ -230,7 +250,8 @@ func main() {
 			}
 		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, If-Match, Last-Event-ID")
+		c.Header("Access-Control-Expose-Headers", "ETag, X-Request-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -241,6 +262,9 @@ func main() {
 		c.Next()
 	})
 
+	// 请求 ID：透传给下游服务，用于审计日志关联同一请求
+	r.Use(requestIDMiddleware())
+
 	// 添加日志中间件
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
@@ -341,6 +365,14 @@ func main() {
 				admin.GET("/csv-template", createProxyHandler(config.UserServiceURL))
 				admin.POST("/import", createProxyHandler(config.UserServiceURL))
 				admin.GET("/excel-template", createProxyHandler(config.UserServiceURL))
+				admin.GET("/import-jobs", createProxyHandler(config.UserServiceURL))
+				admin.GET("/import-jobs/:id", createProxyHandler(config.UserServiceURL))
+				admin.GET("/import-jobs/:id/errors", createProxyHandler(config.UserServiceURL))
+				admin.POST("/import-jobs/:id/cancel", createProxyHandler(config.UserServiceURL))
+				admin.POST("/import-jobs/:id/resume", createProxyHandler(config.UserServiceURL))
+				admin.POST("/rollover", createProxyHandler(config.UserServiceURL))
+				admin.GET("/:id/department-scopes", createProxyHandler(config.UserServiceURL))
+				admin.PUT("/:id/department-scopes", createProxyHandler(config.UserServiceURL))
 			}
 
 			// 教师或管理员路由
@@ -356,8 +388,11 @@ func main() {
 		users.PUT("/profile", createProxyHandler(config.UserServiceURL))
 		users.GET("/:id", createProxyHandler(config.UserServiceURL))
 		users.POST("/change_password", createProxyHandler(config.UserServiceURL))
+		users.GET("/notification-channels", createProxyHandler(config.UserServiceURL))
+		users.PUT("/notification-channels", createProxyHandler(config.UserServiceURL))
 		users.GET("/activity", createProxyHandler(config.UserServiceURL))
 		users.GET("/:id/activity", createProxyHandler(config.UserServiceURL))
+		users.GET("/data-scope", createProxyHandler(config.UserServiceURL))
 
 		// 头像管理
 		users.POST("/avatar", createProxyHandler(config.UserServiceURL))
@@ -405,6 +440,8 @@ func main() {
 			activities.POST("/:id/submit", createProxyHandler(config.CreditActivityServiceURL))
 			activities.POST("/:id/withdraw", createProxyHandler(config.CreditActivityServiceURL))
 			activities.POST("/:id/copy", createProxyHandler(config.CreditActivityServiceURL))
+			activities.GET("/trash", createProxyHandler(config.CreditActivityServiceURL))
+			activities.POST("/:id/restore", createProxyHandler(config.CreditActivityServiceURL))
 			activities.POST("", createProxyHandler(config.CreditActivityServiceURL))
 			activities.PUT("/:id", createProxyHandler(config.CreditActivityServiceURL))
 
@@ -419,6 +456,11 @@ func main() {
 				teacherOrAdmin.GET("/deletable", createProxyHandler(config.CreditActivityServiceURL))
 				teacherOrAdmin.POST("/batch-delete", createProxyHandler(config.CreditActivityServiceURL))
 				teacherOrAdmin.POST("/import", createProxyHandler(config.CreditActivityServiceURL))
+				teacherOrAdmin.GET("/import-jobs", createProxyHandler(config.CreditActivityServiceURL))
+				teacherOrAdmin.GET("/import-jobs/:id", createProxyHandler(config.CreditActivityServiceURL))
+				teacherOrAdmin.GET("/import-jobs/:id/errors", createProxyHandler(config.CreditActivityServiceURL))
+				teacherOrAdmin.POST("/import-jobs/:id/cancel", createProxyHandler(config.CreditActivityServiceURL))
+				teacherOrAdmin.POST("/import-jobs/:id/resume", createProxyHandler(config.CreditActivityServiceURL))
 				teacherOrAdmin.GET("/csv-template", createProxyHandler(config.CreditActivityServiceURL))
 				teacherOrAdmin.GET("/excel-template", createProxyHandler(config.CreditActivityServiceURL))
 				teacherOrAdmin.GET("/export", createProxyHandler(config.CreditActivityServiceURL))
@@ -456,6 +498,16 @@ func main() {
 				}
 			}
 
+			// 协作者管理路由（角色检查在 credit-activity-service 内部完成）
+			collaborators := activities.Group("/:id")
+			{
+				collaborators.GET("/collaborators", createProxyHandler(config.CreditActivityServiceURL))
+				collaborators.POST("/collaborators", createProxyHandler(config.CreditActivityServiceURL))
+				collaborators.PUT("/collaborators/:user_id", createProxyHandler(config.CreditActivityServiceURL))
+				collaborators.DELETE("/collaborators/:user_id", createProxyHandler(config.CreditActivityServiceURL))
+				collaborators.POST("/transfer-ownership", createProxyHandler(config.CreditActivityServiceURL))
+			}
+
 			// 附件管理路由
 			attachments := activities.Group("/:id/attachments")
 			attachments.Use(authMiddleware.AuthRequired())
@@ -463,6 +515,7 @@ func main() {
 				attachments.GET("", createProxyHandler(config.CreditActivityServiceURL))
 				attachments.GET("/:attachment_id/download", createProxyHandler(config.CreditActivityServiceURL))
 				attachments.GET("/:attachment_id/preview", createProxyHandler(config.CreditActivityServiceURL))
+				attachments.GET("/:attachment_id/download-url", createProxyHandler(config.CreditActivityServiceURL))
 
 				// 活动所有者或教师或管理员路由
 				ownerOrTeacherOrAdminAttachments := attachments.Group("")
@@ -492,6 +545,67 @@ func main() {
 			}
 		}
 
+		// 实时事件推送（SSE，需要认证，EventSource 通过 ?token= 传递令牌）
+		events := api.Group("/events")
+		events.Use(authMiddleware.AuthRequired())
+		{
+			events.GET("/stream", eventStreamHandler.Stream)
+		}
+
+		// 站内通知路由（需要认证）
+		notifications := api.Group("/notifications")
+		notifications.Use(authMiddleware.AuthRequired())
+		{
+			notifications.GET("", createProxyHandler(config.CreditActivityServiceURL))
+			notifications.GET("/unread-count", createProxyHandler(config.CreditActivityServiceURL))
+			notifications.POST("/read-all", createProxyHandler(config.CreditActivityServiceURL))
+			notifications.POST("/:id/read", createProxyHandler(config.CreditActivityServiceURL))
+			notifications.GET("/preferences", createProxyHandler(config.CreditActivityServiceURL))
+			notifications.PUT("/preferences", createProxyHandler(config.CreditActivityServiceURL))
+		}
+
+		// 出站 webhook 订阅管理（仅管理员）
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(authMiddleware.AuthRequired(), permissionMiddleware.RequireRoles("admin"))
+		{
+			webhooks.GET("", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.POST("", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.GET("/event-types", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.GET("/:id", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.PUT("/:id", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.DELETE("/:id", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.POST("/:id/rotate-secret", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.POST("/:id/ping", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.GET("/:id/deliveries", createProxyHandler(config.CreditActivityServiceURL))
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", createProxyHandler(config.CreditActivityServiceURL))
+		}
+
+		// 部门管理（读取对所有认证用户开放，变更仅管理员）
+		departments := api.Group("/departments")
+		departments.Use(authMiddleware.AuthRequired())
+		{
+			departments.GET("", createProxyHandler(config.UserServiceURL))
+			departments.GET("/tree", createProxyHandler(config.UserServiceURL))
+			departments.GET("/:id", createProxyHandler(config.UserServiceURL))
+
+			departmentAdmin := departments.Group("")
+			departmentAdmin.Use(permissionMiddleware.RequireRoles("admin"))
+			{
+				departmentAdmin.POST("", createProxyHandler(config.UserServiceURL))
+				departmentAdmin.PUT("/:id", createProxyHandler(config.UserServiceURL))
+				departmentAdmin.DELETE("/:id", createProxyHandler(config.UserServiceURL))
+				departmentAdmin.POST("/:id/move", createProxyHandler(config.UserServiceURL))
+				departmentAdmin.POST("/:id/merge", createProxyHandler(config.UserServiceURL))
+			}
+		}
+
+		// 审计日志查询（仅管理员）
+		auditLogs := api.Group("/audit-logs")
+		auditLogs.Use(authMiddleware.AuthRequired(), permissionMiddleware.RequireRoles("admin"))
+		{
+			auditLogs.GET("", createProxyHandler(config.UserServiceURL))
+		}
+
 		// 统一检索API路由组（需要认证）
 		searchActivities := api.Group("/search")
 		searchActivities.Use(authMiddleware.AuthRequired())
@@ -500,6 +614,14 @@ func main() {
 			searchActivities.GET("/applications", createProxyHandler(config.CreditActivityServiceURL))
 			searchActivities.GET("/participants", createProxyHandler(config.CreditActivityServiceURL))
 			searchActivities.GET("/attachments", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.GET("/saved", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.POST("/saved", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.GET("/saved/:id", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.PUT("/saved/:id", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.DELETE("/saved/:id", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.GET("/saved/:id/run", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.POST("/saved/:id/subscribe", createProxyHandler(config.CreditActivityServiceURL))
+			searchActivities.DELETE("/saved/:id/subscribe", createProxyHandler(config.CreditActivityServiceURL))
 		}
 	}
 
@@ -514,14 +636,19 @@ func main() {
 				"credit_activity_service": config.CreditActivityServiceURL,
 			},
 			"endpoints": gin.H{
-				"auth":        "/api/auth",
-				"permissions": "/api/permissions",
-				"users":       "/api/users",
-				"students":    "/api/students",
-				"teachers":    "/api/teachers",
-				"search":      "/api/search",
-				"activities":  "/api/activities",
-				"health":      "/health",
+				"auth":          "/api/auth",
+				"permissions":   "/api/permissions",
+				"users":         "/api/users",
+				"students":      "/api/students",
+				"teachers":      "/api/teachers",
+				"search":        "/api/search",
+				"activities":    "/api/activities",
+				"notifications": "/api/notifications",
+				"events":        "/api/events/stream",
+				"webhooks":      "/api/webhooks",
+				"audit_logs":    "/api/audit-logs",
+				"departments":   "/api/departments",
+				"health":        "/health",
 			},
 		})
 	})
@@ -547,6 +674,30 @@ func main() {
 	}
 }
 
+// requestIDMiddleware 为请求分配 X-Request-ID（客户端已提供时沿用），并回写到响应头
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err == nil {
+				requestID = fmt.Sprintf("%x", buf)
+			}
+		}
+		if requestID != "" {
+			c.Request.Header.Set("X-Request-ID", requestID)
+			c.Header("X-Request-ID", requestID)
+		}
+		c.Next()
+	}
+}
+
+// serviceTokens 网关自身的服务令牌来源，下游服务只采信带有效网关令牌的用户头
+var serviceTokens *servicetoken.Source
+
+// serviceAudiences 目标地址到服务名的映射，用作服务令牌的 aud
+var serviceAudiences map[string]string
+
 // 创建代理处理器
 func createProxyHandler(targetURL string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -591,6 +742,17 @@ func createProxyHandler(targetURL string) gin.HandlerFunc {
 			}
 		}
 
+		// 清除客户端自带的身份头，只传递网关校验过的用户信息
+		for _, header := range []string{"X-User-ID", "X-Username", "X-User-Type", servicetoken.Header} {
+			c.Request.Header.Del(header)
+		}
+		// 下游服务只采信网关的 X-Real-IP，其值为按受信任代理解析出的客户端地址
+		c.Request.Header.Set("X-Real-IP", c.ClientIP())
+		// 拿不到令牌时仍然转发：公开接口（登录、注册等）不受影响，需要认证的接口会被下游拒绝
+		if err := serviceTokens.Apply(c.Request, serviceAudiences[targetURL]); err != nil {
+			log.Printf("获取网关服务令牌失败: %v", err)
+		}
+
 		// 将用户信息传递给下游服务
 		if userID, exists := c.Get("uuid"); exists {
 			c.Request.Header.Set("X-User-ID", userID.(string))
@@ -619,6 +781,18 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// trustedProxies 受信任的反向代理（TRUSTED_PROXIES，逗号分隔的 IP 或 CIDR）。
+// 只有来自这些地址的请求才采信 X-Forwarded-For / X-Real-IP，未配置时不采信任何代理
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 // Docker 服务配置
 var dockerServices = []struct {
 	ID   string `json:"id"`
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"credit-management/auth-service/models"
+)
+
+// 登录相关审计动作
+const (
+	auditActionLogin       = "auth.login"
+	auditActionLoginFailed = "auth.login_failed"
+	auditActionLogout      = "auth.logout"
+)
+
+// recordAudit 记录登录 / 登出审计日志，写入失败只记录日志，不影响认证流程
+func (h *AuthHandler) recordAudit(c *gin.Context, action string, statusCode int, user *models.UserIdentity, details map[string]interface{}) {
+	entry := models.AuditLog{
+		Service:      "auth-service",
+		Action:       action,
+		ResourceType: "users",
+		Method:       c.Request.Method,
+		Path:         c.Request.URL.Path,
+		StatusCode:   statusCode,
+		After:        details,
+		IP:           c.ClientIP(),
+		RequestID:    c.GetHeader("X-Request-ID"),
+	}
+	if user != nil {
+		entry.ActorID = user.UUID
+		entry.ActorType = user.UserType
+		entry.ResourceID = user.UUID
+	}
+	if err := h.db.Create(&entry).Error; err != nil {
+		log.Printf("[audit] failed to write %s: %v", action, err)
+	}
+}
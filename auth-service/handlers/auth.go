@@ -2,10 +2,8 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
+	"errors"
 	"net/http"
-	"os"
 	"time"
 
 	"log"
@@ -13,24 +11,32 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"credit-management/auth-service/models"
 	"credit-management/auth-service/utils"
 )
 
+// UserDirectory 用户信息来源（用户服务）。用户不存在时返回 utils.ErrUserNotFound
+type UserDirectory interface {
+	VerifyCredentials(ctx context.Context, req models.UserLoginRequest) (*models.UserIdentity, bool, error)
+	GetUser(ctx context.Context, userID string) (*models.UserIdentity, error)
+	RecordLogin(ctx context.Context, userID string) error
+}
+
 type AuthHandler struct {
 	db        *gorm.DB
 	jwtSecret string
 	redis     *utils.RedisClient
+	users     UserDirectory
 }
 
-func NewAuthHandler(db *gorm.DB, jwtSecret string, redis *utils.RedisClient) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, jwtSecret string, redis *utils.RedisClient, users UserDirectory) *AuthHandler {
 	return &AuthHandler{
 		db:        db,
 		jwtSecret: jwtSecret,
 		redis:     redis,
+		users:     users,
 	}
 }
 
@@ -48,71 +54,52 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	query := h.db
-	switch {
-	case req.Username != "":
-		query = query.Where("username = ?", req.Username)
-	case req.StudentID != "":
-		query = query.Where("student_id = ?", req.StudentID)
-	case req.TeacherID != "":
-		query = query.Where("teacher_id = ?", req.TeacherID)
+	loginID := map[string]interface{}{"username": req.Username, "student_id": req.StudentID, "teacher_id": req.TeacherID}
+	user, valid, err := h.users.VerifyCredentials(c.Request.Context(), req)
+	if err != nil {
+		log.Printf("校验登录凭据失败: %v", err)
+		userServiceUnavailable(c)
+		return
 	}
-
-	if err := query.First(&user).Error; err != nil {
+	if user == nil {
+		h.recordAudit(c, auditActionLoginFailed, http.StatusUnauthorized, nil, loginID)
 		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户名或密码错误", "data": nil})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	if !valid {
+		h.recordAudit(c, auditActionLoginFailed, http.StatusUnauthorized, user, loginID)
 		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户名或密码错误", "data": nil})
 		return
 	}
 
-	if user.Status != "active" {
+	if !canSignIn(user.Status) {
+		h.recordAudit(c, auditActionLoginFailed, http.StatusForbidden, user, map[string]interface{}{"status": user.Status})
 		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "账户未激活", "data": nil})
 		return
 	}
 
-	now := time.Now()
-	h.db.Model(&user).Update("last_login_at", &now)
+	if err := h.users.RecordLogin(c.Request.Context(), user.UUID); err != nil {
+		log.Printf("记录最后登录时间失败: %v", err)
+	}
 
 	// 生成JWT token
-	token, err := h.generateToken(user)
+	token, err := h.generateToken(*user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成token失败", "data": nil})
 		return
 	}
 
 	// 生成refresh token
-	refreshToken, err := h.generateRefreshToken(user)
+	refreshToken, err := h.generateRefreshToken(*user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成refresh token失败", "data": nil})
 		return
 	}
 
-	userResponse := models.UserResponse{
-		UUID:         user.UUID,
-		StudentID:    user.StudentID,
-		TeacherID:    user.TeacherID,
-		Username:     user.Username,
-		Email:        user.Email,
-		Phone:        "",
-		RealName:     user.RealName,
-		UserType:     user.UserType,
-		Status:       user.Status,
-		LastLoginAt:  nil,
-		RegisterTime: user.RegisterTime,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-	}
+	userResponse := models.NewUserResponse(*user)
 
-	if user.Phone != nil {
-		userResponse.Phone = *user.Phone
-	}
-	if user.LastLoginAt != nil {
-		userResponse.LastLoginAt = user.LastLoginAt
-	}
+	h.recordAudit(c, auditActionLogin, http.StatusOK, user, map[string]interface{}{"user_agent": c.Request.UserAgent()})
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -195,8 +182,13 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	}
 
 	// 查找用户
-	var user models.User
-	if err := h.db.Where("uuid = ?", userID).First(&user).Error; err != nil {
+	user, err := h.users.GetUser(c.Request.Context(), userID)
+	if err != nil && !errors.Is(err, utils.ErrUserNotFound) {
+		log.Printf("查询用户失败: %v", err)
+		userServiceUnavailable(c)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
 			"message": "success",
@@ -209,7 +201,7 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	}
 
 	// 检查用户状态
-	if user.Status != "active" {
+	if !canSignIn(user.Status) {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
 			"message": "success",
@@ -222,29 +214,7 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	}
 
 	// 构建用户响应
-	userResponse := models.UserResponse{
-		UUID:         user.UUID,
-		StudentID:    user.StudentID,
-		TeacherID:    user.TeacherID,
-		Username:     user.Username,
-		Email:        user.Email,
-		Phone:        "",
-		RealName:     user.RealName,
-		UserType:     user.UserType,
-		Status:       user.Status,
-		LastLoginAt:  nil,
-		RegisterTime: user.RegisterTime,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-	}
-
-	// 安全地设置指针字段
-	if user.Phone != nil {
-		userResponse.Phone = *user.Phone
-	}
-	if user.LastLoginAt != nil {
-		userResponse.LastLoginAt = user.LastLoginAt
-	}
+	userResponse := models.NewUserResponse(*user)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -289,27 +259,32 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	// 查找用户
-	var user models.User
-	if err := h.db.Where("uuid = ?", userID).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在", "data": nil})
+	user, err := h.users.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在", "data": nil})
+		} else {
+			log.Printf("查询用户失败: %v", err)
+			userServiceUnavailable(c)
+		}
 		return
 	}
 
 	// 检查用户状态
-	if user.Status != "active" {
+	if !canSignIn(user.Status) {
 		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "账号未激活", "data": nil})
 		return
 	}
 
 	// 生成新的token
-	newToken, err := h.generateToken(user)
+	newToken, err := h.generateToken(*user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成新的token失败", "data": nil})
 		return
 	}
 
 	// 生成新的refresh token
-	newRefreshToken, err := h.generateRefreshToken(user)
+	newRefreshToken, err := h.generateRefreshToken(*user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成新的refresh token失败", "data": nil})
 		return
@@ -380,6 +355,10 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		}
 	}
 
+	userID, _ := claims["uuid"].(string)
+	userType, _ := claims["user_type"].(string)
+	h.recordAudit(c, auditActionLogout, http.StatusOK, &models.UserIdentity{UUID: userID, UserType: userType}, nil)
+
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"message": "登出成功"}})
 }
 
@@ -424,14 +403,19 @@ func (h *AuthHandler) ValidatePermission(c *gin.Context) {
 	}
 
 	// 查找用户
-	var user models.User
-	if err := h.db.Where("uuid = ?", userID).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在", "data": nil})
+	user, err := h.users.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在", "data": nil})
+		} else {
+			log.Printf("查询用户失败: %v", err)
+			userServiceUnavailable(c)
+		}
 		return
 	}
 
 	// 检查用户状态
-	if user.Status != "active" {
+	if !canSignIn(user.Status) {
 		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "账户未激活", "data": nil})
 		return
 	}
@@ -492,14 +476,19 @@ func (h *AuthHandler) ValidateTokenWithClaims(c *gin.Context) {
 	}
 
 	// 查找用户
-	var user models.User
-	if err := h.db.Where("uuid = ?", userID).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在", "data": nil})
+	user, err := h.users.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在", "data": nil})
+		} else {
+			log.Printf("查询用户失败: %v", err)
+			userServiceUnavailable(c)
+		}
 		return
 	}
 
 	// 检查用户状态
-	if user.Status != "active" {
+	if !canSignIn(user.Status) {
 		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "账户未激活", "data": nil})
 		return
 	}
@@ -517,7 +506,7 @@ func (h *AuthHandler) ValidateTokenWithClaims(c *gin.Context) {
 }
 
 // generateToken 生成JWT token
-func (h *AuthHandler) generateToken(user models.User) (string, error) {
+func (h *AuthHandler) generateToken(user models.UserIdentity) (string, error) {
 	claims := jwt.MapClaims{
 		"uuid":      user.UUID,
 		"username":  user.Username,
@@ -530,7 +519,7 @@ func (h *AuthHandler) generateToken(user models.User) (string, error) {
 	return token.SignedString([]byte(h.jwtSecret))
 }
 
-func (h *AuthHandler) generateRefreshToken(user models.User) (string, error) {
+func (h *AuthHandler) generateRefreshToken(user models.UserIdentity) (string, error) {
 	claims := jwt.MapClaims{
 		"uuid": user.UUID,
 		"type": "refresh",
@@ -542,43 +531,12 @@ func (h *AuthHandler) generateRefreshToken(user models.User) (string, error) {
 	return token.SignedString([]byte(h.jwtSecret))
 }
 
-func InitializeAdminUser(db *gorm.DB) error {
-	var userCount int64
-	db.Model(&models.User{}).Where("username = ?", "admin").Count(&userCount)
-	if userCount == 0 {
-		// 从环境变量读取管理员密码，如果未设置则生成随机密码
-		adminPassword := os.Getenv("ADMIN_DEFAULT_PASSWORD")
-		if adminPassword == "" {
-			// 生成16字节随机密码
-			randomBytes := make([]byte, 16)
-			if _, err := rand.Read(randomBytes); err != nil {
-				return err
-			}
-			adminPassword = base64.URLEncoding.EncodeToString(randomBytes)
-			log.Printf("⚠️  IMPORTANT: Generated random admin password: %s", adminPassword)
-			log.Printf("⚠️  Please save this password and change it after first login!")
-		}
-
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-
-		adminUser := models.User{
-			Username: "admin",
-			Password: string(hashedPassword),
-			Email:    "admin@example.com",
-			RealName: "Administrator",
-			UserType: "admin",
-			Status:   "active",
-		}
-		if err := db.Create(&adminUser).Error; err != nil {
-			return err
-		}
-		log.Println("Admin user created successfully")
-	} else {
-		log.Println("Admin user already exists")
-	}
+// userServiceUnavailable 用户服务不可用时返回 503，避免被当作凭据错误
+func userServiceUnavailable(c *gin.Context) {
+	c.JSON(http.StatusServiceUnavailable, gin.H{"code": 503, "message": "用户服务暂不可用", "data": nil})
+}
 
-	return nil
+// canSignIn 账号状态是否允许登录；已毕业学生保留登录权限，用于查看历史学分记录
+func canSignIn(status string) bool {
+	return status == "active" || status == "graduated"
 }
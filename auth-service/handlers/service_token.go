@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"net/http"
+
+	"credit-management/shared/servicetoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceTokenHandler 为内部服务签发短期令牌。令牌使用 Ed25519 签名，
+// 下游服务只持有公钥，能校验但不能伪造；调用方用各自的客户端凭据换取令牌
+type ServiceTokenHandler struct {
+	signer  *servicetoken.Signer
+	clients map[string]string // 服务名 -> 客户端凭据
+}
+
+func NewServiceTokenHandler(privateKey ed25519.PrivateKey, clients map[string]string) *ServiceTokenHandler {
+	return &ServiceTokenHandler{
+		signer:  servicetoken.NewSigner(privateKey),
+		clients: clients,
+	}
+}
+
+type serviceTokenRequest struct {
+	Service  string `json:"service" binding:"required"`
+	Secret   string `json:"secret" binding:"required"`
+	Audience string `json:"audience" binding:"required"`
+}
+
+// IssueToken 校验服务的客户端凭据并签发服务令牌（sub 为服务名，aud 为被调用的服务）
+func (h *ServiceTokenHandler) IssueToken(c *gin.Context) {
+	var req serviceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请求参数错误", "data": nil})
+		return
+	}
+
+	secret, ok := h.clients[req.Service]
+	if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(req.Secret)) != 1 {
+		log.Printf("服务令牌申请被拒绝: service=%s ip=%s", req.Service, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "服务凭据无效", "data": nil})
+		return
+	}
+	if !h.knownService(req.Audience) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "未知的目标服务", "data": nil})
+		return
+	}
+
+	signed, expiresAt, err := h.signer.Mint(req.Service, req.Audience)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "签发服务令牌失败", "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"token":      signed,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// knownService 目标服务必须是已登记的服务或认证服务本身
+func (h *ServiceTokenHandler) knownService(name string) bool {
+	if name == servicetoken.AuthService {
+		return true
+	}
+	_, ok := h.clients[name]
+	return ok
+}
+
+// ServiceAuth 认证服务自身调用其他服务的内部接口时，直接用签名密钥签发发往 audience 的令牌
+func (h *ServiceTokenHandler) ServiceAuth(audience string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		token, _, err := h.signer.Mint(servicetoken.AuthService, audience)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(servicetoken.Header, token)
+		return nil
+	}
+}
+
+// PublicKeys 返回用于校验服务令牌的公钥（base64 编码的 Ed25519 公钥）
+func (h *ServiceTokenHandler) PublicKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"keys": []gin.H{{
+				"kid":        h.signer.KeyID(),
+				"alg":        "EdDSA",
+				"public_key": base64.StdEncoding.EncodeToString(h.signer.PublicKey()),
+			}},
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"credit-management/shared/servicetoken"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func issue(h *ServiceTokenHandler, body map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/internal/service-token", h.IssueToken)
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/internal/service-token", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIssueTokenBindsAudience(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	h := NewServiceTokenHandler(privateKey, map[string]string{
+		servicetoken.GatewayService: "gateway-secret",
+		servicetoken.UserService:    "user-secret",
+	})
+
+	w := issue(h, map[string]string{"service": servicetoken.GatewayService, "secret": "gateway-secret", "audience": servicetoken.UserService})
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	publicKey := base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	users, err := servicetoken.NewVerifier("", publicKey, servicetoken.UserService)
+	require.NoError(t, err)
+	service, err := users.Verify(context.Background(), resp.Data.Token)
+	require.NoError(t, err)
+	assert.Equal(t, servicetoken.GatewayService, service)
+
+	credits, err := servicetoken.NewVerifier("", publicKey, servicetoken.CreditActivityService)
+	require.NoError(t, err)
+	_, err = credits.Verify(context.Background(), resp.Data.Token)
+	assert.Error(t, err, "令牌只对申请时指定的服务有效")
+}
+
+func TestIssueTokenRejectsBadRequests(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	h := NewServiceTokenHandler(privateKey, map[string]string{servicetoken.GatewayService: "gateway-secret"})
+
+	w := issue(h, map[string]string{"service": servicetoken.GatewayService, "secret": "wrong", "audience": servicetoken.AuthService})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = issue(h, map[string]string{"service": servicetoken.GatewayService, "secret": "gateway-secret"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "缺少 audience")
+
+	w = issue(h, map[string]string{"service": servicetoken.GatewayService, "secret": "gateway-secret", "audience": "unknown-service"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -8,6 +12,7 @@ import (
 
 	"credit-management/auth-service/handlers"
 	"credit-management/auth-service/utils"
+	"credit-management/shared/servicetoken"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -68,17 +73,6 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	// 初始化管理员用户
-	err = db.Transaction(func(tx *gorm.DB) error {
-		if err := handlers.InitializeAdminUser(tx); err != nil {
-			return err
-		}
-		return nil
-	})
-	if err != nil {
-		log.Fatal("Failed to run initializations:", err)
-	}
-
 	// Redis连接配置
 	redisHost := getEnv("REDIS_HOST", "localhost")
 	redisPort := getEnv("REDIS_PORT", "6379")
@@ -96,14 +90,31 @@ func main() {
 	// JWT密钥
 	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
 
+	// 服务间调用令牌：SERVICE_JWT_PRIVATE_KEY 为 base64 编码的 Ed25519 私钥种子（32 字节），
+	// 生产模式（GIN_MODE=release）下必须配置，否则多副本之间、重启前后签发的令牌互不认可
+	signingKey, err := loadServiceSigningKey(getEnv("SERVICE_JWT_PRIVATE_KEY", ""), gin.Mode() != gin.ReleaseMode)
+	if err != nil {
+		log.Fatal("Invalid SERVICE_JWT_PRIVATE_KEY:", err)
+	}
+	serviceTokenHandler := handlers.NewServiceTokenHandler(signingKey, parseServiceClients(getEnv("SERVICE_CLIENTS", "")))
+
+	// 用户信息由用户服务负责（users 表只由用户服务读写，包括初始化管理员），认证服务通过其内部接口校验凭据
+	userClient := utils.NewUserServiceClient(getEnv("USER_SERVICE_URL", "http://user-service:8084"), serviceTokenHandler.ServiceAuth(servicetoken.UserService))
+
 	// 创建处理器
-	authHandler := handlers.NewAuthHandler(db, jwtSecret, redisClient)
+	authHandler := handlers.NewAuthHandler(db, jwtSecret, redisClient, userClient)
 
 	// 创建速率限制中间件（5次尝试/分钟）
 	rateLimiter := utils.NewRateLimitMiddleware(redisClient, 5, time.Minute)
 
 	// 设置Gin路由
 	r := gin.Default()
+	// 只采信网关在 X-Real-IP 中转发的客户端地址（TRUSTED_PROXIES，逗号分隔的 IP 或 CIDR，未配置时不采信任何代理），
+	// 审计日志和登录限流使用真实的客户端 IP
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := r.SetTrustedProxies(splitAndTrim(getEnv("TRUSTED_PROXIES", ""), ",")); err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置错误: ", err)
+	}
 
 	// 获取允许的前端域名
 	corsAllowedOrigins := getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173")
@@ -146,6 +157,13 @@ func main() {
 			auth.POST("/refresh-token", authHandler.RefreshToken)
 			auth.POST("/logout", authHandler.Logout)
 		}
+
+		// 内部接口（网关不转发）：服务令牌签发与公钥
+		internal := api.Group("/internal")
+		{
+			internal.POST("/service-token", serviceTokenHandler.IssueToken)
+			internal.GET("/service-keys", serviceTokenHandler.PublicKeys)
+		}
 	}
 
 	// 健康检查
@@ -161,6 +179,47 @@ func main() {
 	}
 }
 
+// loadServiceSigningKey 解析服务令牌签名密钥。未配置时只有开发模式（allowEphemeral）会生成临时密钥：
+// 下游服务会在遇到未知 kid 时重新拉取公钥，但重启后已签发的令牌全部失效，多副本之间也互不认可
+func loadServiceSigningKey(encoded string, allowEphemeral bool) (ed25519.PrivateKey, error) {
+	if encoded == "" {
+		if !allowEphemeral {
+			return nil, errors.New("未设置，生产模式下必须配置固定的签名密钥")
+		}
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("SERVICE_JWT_PRIVATE_KEY 未设置，已生成临时服务令牌签名密钥")
+		return privateKey, nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("expected %d-byte seed, got %d bytes", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// parseServiceClients 解析 SERVICE_CLIENTS（格式：服务名:凭据,服务名:凭据）
+func parseServiceClients(value string) map[string]string {
+	clients := make(map[string]string)
+	for _, entry := range splitAndTrim(value, ",") {
+		parts := splitString(entry, ":")
+		if len(parts) < 2 || trimSpace(parts[0]) == "" || trimSpace(parts[1]) == "" {
+			log.Printf("忽略格式错误的 SERVICE_CLIENTS 条目: %s", entry)
+			continue
+		}
+		clients[trimSpace(parts[0])] = trimSpace(entry[len(parts[0])+1:])
+	}
+	if len(clients) == 0 {
+		log.Println("SERVICE_CLIENTS 未配置，任何服务都无法申请服务令牌")
+	}
+	return clients
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadServiceSigningKey(t *testing.T) {
+	_, err := loadServiceSigningKey("", false)
+	assert.Error(t, err, "生产模式下必须配置签名密钥")
+
+	key, err := loadServiceSigningKey("", true)
+	require.NoError(t, err)
+	assert.NotEmpty(t, key, "开发模式下生成临时密钥")
+
+	seed := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	first, err := loadServiceSigningKey(seed, false)
+	require.NoError(t, err)
+	second, err := loadServiceSigningKey(seed, false)
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "同一种子在重启和多副本间得到同一密钥")
+
+	_, err = loadServiceSigningKey(base64.StdEncoding.EncodeToString([]byte("short")), false)
+	assert.Error(t, err)
+}
@@ -3,36 +3,23 @@ package models
 import (
 	"time"
 
-	"github.com/google/uuid"
-	"gorm.io/gorm"
+	"credit-management/shared/audit"
 )
 
-// User 用户模型（认证服务专用）
-type User struct {
-	UUID         string         `json:"uuid" gorm:"column:uuid;type:uuid;primaryKey;default:gen_random_uuid()"`
-	StudentID    *string        `json:"student_id,omitempty" gorm:"column:student_id;uniqueIndex"`
-	TeacherID    *string        `json:"teacher_id,omitempty" gorm:"column:teacher_id;uniqueIndex"`
-	Username     string         `json:"username" gorm:"uniqueIndex;not null"`
-	Password     string         `json:"-" gorm:"not null"` // 不在JSON中显示密码
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	Phone        *string        `json:"phone" gorm:"uniqueIndex"` // 可为空
-	RealName     string         `json:"real_name" gorm:"not null"`
-	UserType     string         `json:"user_type" gorm:"not null"`      // student, teacher, admin
-	Status       string         `json:"status" gorm:"default:'active'"` // active, inactive, suspended
-	Avatar       *string        `json:"avatar"`                         // 头像文件路径，可为空
-	LastLoginAt  *time.Time     `json:"last_login_at"`
-	RegisterTime time.Time      `json:"register_time" gorm:"autoCreateTime"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
-}
-
-// BeforeCreate 在创建前自动生成UUID
-func (u *User) BeforeCreate(tx *gorm.DB) error {
-	if u.UUID == "" {
-		u.UUID = uuid.New().String()
-	}
-	return nil
+// UserIdentity 用户服务返回的用户信息。users 表由用户服务负责，认证服务通过其内部接口校验凭据和查询用户
+type UserIdentity struct {
+	UUID        string     `json:"uuid"`
+	StudentID   *string    `json:"student_id,omitempty"`
+	TeacherID   *string    `json:"teacher_id,omitempty"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	Phone       *string    `json:"phone"`
+	RealName    string     `json:"real_name"`
+	UserType    string     `json:"user_type"` // student, teacher, admin
+	Status      string     `json:"status"`    // active, inactive, suspended, graduated
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 // UserLoginRequest 用户登录请求
@@ -60,6 +47,28 @@ type UserResponse struct {
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
+// NewUserResponse 构建用户响应；register_time 即注册（创建）时间
+func NewUserResponse(user UserIdentity) UserResponse {
+	response := UserResponse{
+		UUID:         user.UUID,
+		StudentID:    user.StudentID,
+		TeacherID:    user.TeacherID,
+		Username:     user.Username,
+		Email:        user.Email,
+		RealName:     user.RealName,
+		UserType:     user.UserType,
+		Status:       user.Status,
+		LastLoginAt:  user.LastLoginAt,
+		RegisterTime: user.CreatedAt,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	}
+	if user.Phone != nil {
+		response.Phone = *user.Phone
+	}
+	return response
+}
+
 // LoginResponse 登录响应
 type LoginResponse struct {
 	Token   string       `json:"token"`
@@ -97,3 +106,6 @@ type TokenValidationWithClaimsResponse struct {
 	Claims  map[string]interface{} `json:"claims,omitempty"`
 	Message string                 `json:"message"`
 }
+
+// AuditLog 审计日志（与其他服务共用 audit_log 表，只追加），定义在共享模块中
+type AuditLog = audit.Log
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"credit-management/auth-service/handlers"
 	"credit-management/auth-service/models"
@@ -39,7 +40,7 @@ func TestMain(m *testing.M) {
 	}
 
 	// Auto-migrate models
-	err = testDB.DB.AutoMigrate(&models.User{})
+	err = testDB.DB.AutoMigrate(&testUser{})
 	if err != nil {
 		panic("Failed to migrate models: " + err.Error())
 	}
@@ -54,7 +55,7 @@ func TestMain(m *testing.M) {
 
 	// Initialize auth handler
 	jwtSecret := "test-secret-key"
-	authHandler = handlers.NewAuthHandler(testDB.DB, jwtSecret, redisClient)
+	authHandler = handlers.NewAuthHandler(testDB.DB, jwtSecret, redisClient, &dbUserDirectory{db: testDB.DB})
 
 	// Set up Gin router
 	gin.SetMode(gin.TestMode)
@@ -79,13 +80,90 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// testUser users 表中测试需要的列（users 表由用户服务负责，认证服务不再有对应的模型）
+type testUser struct {
+	UUID        string  `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	StudentID   *string `gorm:"unique"`
+	TeacherID   *string `gorm:"unique"`
+	Username    string  `gorm:"unique;not null"`
+	Password    string  `gorm:"not null"`
+	Email       string  `gorm:"unique;not null"`
+	RealName    string
+	UserType    string
+	Status      string
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (testUser) TableName() string {
+	return "users"
+}
+
+func (u testUser) identity() *models.UserIdentity {
+	return &models.UserIdentity{
+		UUID:        u.UUID,
+		StudentID:   u.StudentID,
+		TeacherID:   u.TeacherID,
+		Username:    u.Username,
+		Email:       u.Email,
+		RealName:    u.RealName,
+		UserType:    u.UserType,
+		Status:      u.Status,
+		LastLoginAt: u.LastLoginAt,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
+
+// dbUserDirectory 直接读写测试库的 UserDirectory，代替用户服务的内部接口
+type dbUserDirectory struct {
+	db *gorm.DB
+}
+
+func (d *dbUserDirectory) VerifyCredentials(ctx context.Context, req models.UserLoginRequest) (*models.UserIdentity, bool, error) {
+	query := d.db.WithContext(ctx)
+	switch {
+	case req.Username != "":
+		query = query.Where("username = ?", req.Username)
+	case req.StudentID != "":
+		query = query.Where("student_id = ?", req.StudentID)
+	default:
+		query = query.Where("teacher_id = ?", req.TeacherID)
+	}
+	var user testUser
+	if err := query.First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	valid := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) == nil
+	return user.identity(), valid, nil
+}
+
+func (d *dbUserDirectory) GetUser(ctx context.Context, userID string) (*models.UserIdentity, error) {
+	var user testUser
+	if err := d.db.WithContext(ctx).Where("uuid = ?", userID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user.identity(), nil
+}
+
+func (d *dbUserDirectory) RecordLogin(ctx context.Context, userID string) error {
+	return d.db.WithContext(ctx).Model(&testUser{}).Where("uuid = ?", userID).Update("last_login_at", time.Now()).Error
+}
+
 // Helper function to create test user
-func createTestUser(t *testing.T, overrides map[string]interface{}) *models.User {
+func createTestUser(t *testing.T, overrides map[string]interface{}) *testUser {
 	password := "Password123!"
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	require.NoError(t, err)
 
-	user := &models.User{
+	user := &testUser{
 		Username:  testutils.RandomUsername(),
 		Password:  string(hashedPassword),
 		Email:     testutils.RandomEmail(),
@@ -166,7 +244,7 @@ func TestLoginWithUsername(t *testing.T) {
 	assert.Equal(t, user.Email, userData["email"])
 
 	// Verify last login was updated
-	var updatedUser models.User
+	var updatedUser testUser
 	testDB.DB.First(&updatedUser, "uuid = ?", user.UUID)
 	assert.NotNil(t, updatedUser.LastLoginAt)
 }
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"credit-management/auth-service/models"
+)
+
+// ErrUserNotFound 用户服务中不存在该用户（或已删除）
+var ErrUserNotFound = errors.New("用户不存在")
+
+// UserServiceClient 调用用户服务的内部接口校验凭据、查询用户。users 表由用户服务负责，认证服务不直接读写
+type UserServiceClient struct {
+	baseURL   string
+	client    *http.Client
+	authorize func(*http.Request) error
+}
+
+// NewUserServiceClient authorize 为请求带上服务令牌
+func NewUserServiceClient(baseURL string, authorize func(*http.Request) error) *UserServiceClient {
+	return &UserServiceClient{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		client:    &http.Client{Timeout: 5 * time.Second},
+		authorize: authorize,
+	}
+}
+
+// VerifyCredentials 校验登录凭据。用户不存在时返回 nil；密码错误时 valid 为 false，仍返回用户供记录审计
+func (u *UserServiceClient) VerifyCredentials(ctx context.Context, req models.UserLoginRequest) (*models.UserIdentity, bool, error) {
+	var result struct {
+		Valid bool                 `json:"valid"`
+		User  *models.UserIdentity `json:"user"`
+	}
+	if err := u.do(ctx, http.MethodPost, "/api/internal/credentials/verify", req, &result); err != nil {
+		return nil, false, err
+	}
+	return result.User, result.Valid, nil
+}
+
+// GetUser 查询用户，不存在时返回 ErrUserNotFound
+func (u *UserServiceClient) GetUser(ctx context.Context, userID string) (*models.UserIdentity, error) {
+	var user models.UserIdentity
+	if err := u.do(ctx, http.MethodGet, "/api/internal/users/"+url.PathEscape(userID)+"/identity", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RecordLogin 记录用户最后登录时间
+func (u *UserServiceClient) RecordLogin(ctx context.Context, userID string) error {
+	return u.do(ctx, http.MethodPost, "/api/internal/users/"+url.PathEscape(userID)+"/login", nil, nil)
+}
+
+func (u *UserServiceClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if u.authorize != nil {
+		if err := u.authorize(req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("调用用户服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrUserNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("调用用户服务失败: user-service responded with status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("解析用户服务响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+)
+
+// 渠道名称，与 user-service 中的用户渠道设置保持一致
+const (
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+	ChannelWeCom    = "wecom"
+	ChannelDingTalk = "dingtalk"
+)
+
+// Message 渲染后的通知内容，Body 为 Markdown 文本（邮件渠道按纯文本发送）
+type Message struct {
+	Kind    string `json:"kind"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Recipient 一个用户在某个渠道上的投递目标
+type Recipient struct {
+	UserID   string
+	RealName string
+	Target   string // 邮箱地址或 webhook 地址
+	Secret   string // 钉钉加签密钥等
+}
+
+// Channel 外部通知渠道
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Registry 按名称查找已启用的渠道
+type Registry struct {
+	channels map[string]Channel
+}
+
+func NewRegistry(channels ...Channel) *Registry {
+	r := &Registry{channels: make(map[string]Channel, len(channels))}
+	for _, ch := range channels {
+		r.channels[ch.Name()] = ch
+	}
+	return r
+}
+
+// Send 通过指定渠道发送，渠道未启用时返回错误
+func (r *Registry) Send(ctx context.Context, channel string, to Recipient, msg Message) error {
+	ch, ok := r.channels[channel]
+	if !ok {
+		return fmt.Errorf("notification channel %q is not enabled", channel)
+	}
+	return ch.Send(ctx, to, msg)
+}
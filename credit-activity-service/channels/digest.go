@@ -0,0 +1,139 @@
+package channels
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	digestMaxItems = 10
+	// dailyDigestRun channel_digest_runs 中每日待审核汇总的记录名
+	dailyDigestRun = "daily-review"
+)
+
+// Digest 每日向选择了汇总模式的审核人发送其数据范围内的待审核活动汇总
+type Digest struct {
+	db        *gorm.DB
+	registry  *Registry
+	directory *Directory
+	hour      int
+	now       func() time.Time
+}
+
+// NewDigest hour 为每天发送汇总的整点（本地时间）
+func NewDigest(db *gorm.DB, registry *Registry, directory *Directory, hour int) *Digest {
+	return &Digest{db: db, registry: registry, directory: directory, hour: hour, now: time.Now}
+}
+
+// Run 作为定时任务按小时调用，到达发送时间且当天尚未发送时执行
+func (d *Digest) Run(ctx context.Context) error {
+	now := d.now()
+	if now.Hour() < d.hour {
+		return nil
+	}
+	claimed, err := d.claim(ctx, now)
+	if err != nil || !claimed {
+		return err
+	}
+
+	reviewers, err := d.directory.ByUserTypes(ctx, "teacher", "admin")
+	if err != nil {
+		return err
+	}
+
+	sent, recipients := 0, 0
+	for _, reviewer := range reviewers {
+		if !hasDigestChannel(reviewer) {
+			continue
+		}
+		// 每位审核人只看到自己数据范围内的待审核活动
+		scope, err := utils.NewDataScope(d.db.WithContext(ctx), reviewer.UserID, reviewer.UserType)
+		if err != nil {
+			return err
+		}
+		pendingCount, items, err := d.pending(ctx, scope, now)
+		if err != nil {
+			return err
+		}
+		if pendingCount == 0 {
+			continue
+		}
+		recipients++
+
+		msg, err := Render(KindDailyDigest, DigestData{RecipientName: reviewer.RealName, PendingCount: pendingCount, Items: items})
+		if err != nil {
+			return err
+		}
+		for _, ch := range reviewer.Channels {
+			if !ch.Digest {
+				continue
+			}
+			to := Recipient{UserID: reviewer.UserID, RealName: reviewer.RealName, Target: ch.Target, Secret: ch.Secret}
+			if err := d.registry.Send(ctx, ch.Channel, to, msg); err != nil {
+				log.Printf("[channels] failed to send digest to user %s via %s: %v", reviewer.UserID, ch.Channel, err)
+				continue
+			}
+			sent++
+		}
+	}
+
+	log.Printf("[channels] daily digest sent %d messages to %d reviewers", sent, recipients)
+	return nil
+}
+
+// pending 数据范围内待审核活动的数量和等待最久的若干条
+func (d *Digest) pending(ctx context.Context, scope *utils.DataScope, now time.Time) (int64, []DigestItem, error) {
+	query := func() *gorm.DB {
+		return scope.Activities(d.db.WithContext(ctx).Model(&models.CreditActivity{}).Where("status = ?", models.StatusPendingReview))
+	}
+
+	var count int64
+	if err := query().Count(&count).Error; err != nil || count == 0 {
+		return count, nil, err
+	}
+	var pending []models.CreditActivity
+	if err := query().Order("updated_at ASC").Limit(digestMaxItems).Find(&pending).Error; err != nil {
+		return 0, nil, err
+	}
+	items := make([]DigestItem, 0, len(pending))
+	for _, activity := range pending {
+		items = append(items, DigestItem{
+			Title:       activity.Title,
+			Category:    activity.Category,
+			WaitingDays: int(now.Sub(activity.UpdatedAt).Hours() / 24),
+		})
+	}
+	return count, items, nil
+}
+
+func hasDigestChannel(user UserChannels) bool {
+	for _, ch := range user.Channels {
+		if ch.Digest {
+			return true
+		}
+	}
+	return false
+}
+
+// claim 领取当天的汇总：把发送日期更新为今天，已是今天时不更新并返回 false。
+// 判断和更新在同一条语句中完成，重启或多个副本同时执行时只有一次能领取成功；
+// 领取后才发送，发送中途失败当天不再重发
+func (d *Digest) claim(ctx context.Context, now time.Time) (bool, error) {
+	run := models.ChannelDigestRun{Name: dailyDigestRun, LastSentOn: now.Format("2006-01-02"), SentAt: now}
+	result := d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_sent_on", "sent_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "channel_digest_runs.last_sent_on < EXCLUDED.last_sent_on"}}},
+	}).Create(&run)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
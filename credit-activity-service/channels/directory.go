@@ -0,0 +1,85 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ChannelTarget 用户在某个渠道上的投递配置
+type ChannelTarget struct {
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+	Secret  string `json:"secret"`
+	Digest  bool   `json:"digest"`
+}
+
+// UserChannels 用户及其已启用的渠道
+type UserChannels struct {
+	UserID   string          `json:"user_id"`
+	RealName string          `json:"real_name"`
+	UserType string          `json:"user_type"`
+	Channels []ChannelTarget `json:"channels"`
+}
+
+// Directory 从 user-service 查询用户的渠道偏好
+type Directory struct {
+	baseURL string
+	auth    func(*http.Request) error // 为内部请求设置服务凭证
+	client  *http.Client
+}
+
+func NewDirectory(baseURL string, auth func(*http.Request) error) *Directory {
+	return &Directory{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		auth:    auth,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ByUserIDs 查询指定用户的渠道
+func (d *Directory) ByUserIDs(ctx context.Context, userIDs ...string) ([]UserChannels, error) {
+	return d.fetch(ctx, url.Values{"user_ids": {strings.Join(userIDs, ",")}})
+}
+
+// ByUserTypes 查询某类用户（如 teacher、admin）的渠道
+func (d *Directory) ByUserTypes(ctx context.Context, userTypes ...string) ([]UserChannels, error) {
+	return d.fetch(ctx, url.Values{"user_types": {strings.Join(userTypes, ",")}})
+}
+
+func (d *Directory) fetch(ctx context.Context, query url.Values) ([]UserChannels, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+"/api/internal/notification-channels?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := d.auth(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user-service responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Code    int            `json:"code"`
+		Message string         `json:"message"`
+		Data    []UserChannels `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Code != 0 {
+		return nil, fmt.Errorf("user-service error: %s", body.Message)
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,144 @@
+package channels
+
+import (
+	"context"
+	"log"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"gorm.io/gorm"
+)
+
+// Sink 把领域事件按用户的渠道偏好推送到邮件、webhook、企业微信、钉钉。
+// 外部渠道发送失败只记录日志，不让 outbox 重投，避免其他渠道收到重复消息。
+type Sink struct {
+	db        *gorm.DB
+	registry  *Registry
+	directory *Directory
+}
+
+func NewSink(db *gorm.DB, registry *Registry, directory *Directory) *Sink {
+	return &Sink{db: db, registry: registry, directory: directory}
+}
+
+func (s *Sink) Name() string { return "channels" }
+
+func (s *Sink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	title := s.activityTitle(ctx, event)
+	category, _ := event.Payload["category"].(string)
+
+	switch event.EventType {
+	case models.EventActivitySubmitted:
+		reviewers, err := s.reviewers(ctx, event.AggregateID)
+		if err != nil {
+			log.Printf("[channels] failed to load reviewer channels: %v", err)
+			return nil
+		}
+		// 选择每日汇总的审核人不逐条提醒
+		s.deliver(ctx, reviewers, KindReviewRequested, true, func(u UserChannels) interface{} {
+			return ActivityData{RecipientName: u.RealName, Title: title, Category: category}
+		})
+
+	case models.EventActivityApproved, models.EventActivityRejected:
+		ownerID, _ := event.Payload["owner_id"].(string)
+		kind := KindActivityApproved
+		if event.EventType == models.EventActivityRejected {
+			kind = KindActivityRejected
+		}
+		comments, _ := event.Payload["review_comments"].(string)
+		s.deliverToUsers(ctx, []string{ownerID}, kind, func(u UserChannels) interface{} {
+			return ActivityData{RecipientName: u.RealName, Title: title, Category: category, Comments: comments}
+		})
+
+	case models.EventCreditsChanged:
+		userID, _ := event.Payload["user_id"].(string)
+		s.deliverToUsers(ctx, []string{userID}, KindCreditsUpdated, func(u UserChannels) interface{} {
+			return ActivityData{
+				RecipientName:   u.RealName,
+				Title:           title,
+				Credits:         event.Payload["credits"],
+				PreviousCredits: event.Payload["previous_credits"],
+				HasPrevious:     true,
+			}
+		})
+
+	case models.EventApplicationsGranted:
+		items, _ := event.Payload["applications"].([]interface{})
+		credits := make(map[string]interface{}, len(items))
+		userIDs := make([]string, 0, len(items))
+		for _, item := range items {
+			app, _ := item.(map[string]interface{})
+			if userID, _ := app["user_id"].(string); userID != "" {
+				credits[userID] = app["credits"]
+				userIDs = append(userIDs, userID)
+			}
+		}
+		s.deliverToUsers(ctx, userIDs, KindCreditsUpdated, func(u UserChannels) interface{} {
+			return ActivityData{RecipientName: u.RealName, Title: title, Credits: credits[u.UserID]}
+		})
+	}
+	return nil
+}
+
+// reviewers 数据范围包含该活动的审核人的渠道，范围外的审核人不会收到范围外学生的活动
+func (s *Sink) reviewers(ctx context.Context, activityID string) ([]UserChannels, error) {
+	allowed, err := utils.ActivityReviewers(s.db.WithContext(ctx), []string{activityID})
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed[activityID]) == 0 {
+		return nil, nil
+	}
+	return s.directory.ByUserIDs(ctx, allowed[activityID]...)
+}
+
+func (s *Sink) deliverToUsers(ctx context.Context, userIDs []string, kind string, data func(UserChannels) interface{}) {
+	var ids []string
+	for _, id := range userIDs {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	users, err := s.directory.ByUserIDs(ctx, ids...)
+	if err != nil {
+		log.Printf("[channels] failed to load user channels: %v", err)
+		return
+	}
+	s.deliver(ctx, users, kind, false, data)
+}
+
+func (s *Sink) deliver(ctx context.Context, users []UserChannels, kind string, skipDigest bool, data func(UserChannels) interface{}) {
+	for _, user := range users {
+		msg, err := Render(kind, data(user))
+		if err != nil {
+			log.Printf("[channels] failed to render %s: %v", kind, err)
+			return
+		}
+		for _, ch := range user.Channels {
+			if skipDigest && ch.Digest {
+				continue
+			}
+			to := Recipient{UserID: user.UserID, RealName: user.RealName, Target: ch.Target, Secret: ch.Secret}
+			if err := s.registry.Send(ctx, ch.Channel, to, msg); err != nil {
+				log.Printf("[channels] failed to send %s to user %s via %s: %v", kind, user.UserID, ch.Channel, err)
+			}
+		}
+	}
+}
+
+func (s *Sink) activityTitle(ctx context.Context, event models.OutboxEvent) string {
+	if title, ok := event.Payload["title"].(string); ok && title != "" {
+		return title
+	}
+	var titles []string
+	s.db.WithContext(ctx).Unscoped().Model(&models.CreditActivity{}).
+		Where("id = ?", event.AggregateID).Limit(1).Pluck("title", &titles)
+	if len(titles) > 0 {
+		return titles[0]
+	}
+	return ""
+}
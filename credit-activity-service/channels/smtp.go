@@ -0,0 +1,58 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig 邮件服务器配置
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPChannel 通过 SMTP 发送邮件
+type SMTPChannel struct {
+	config SMTPConfig
+	send   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPChannel(config SMTPConfig) *SMTPChannel {
+	return &SMTPChannel{config: config, send: smtp.SendMail}
+}
+
+func (s *SMTPChannel) Name() string { return ChannelEmail }
+
+func (s *SMTPChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Target == "" {
+		return fmt.Errorf("recipient %s has no email address", to.UserID)
+	}
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + s.config.From + "\r\n")
+	b.WriteString("To: " + to.Target + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	// net/smtp 不支持 context，这里只在发送前检查是否已取消
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.send(s.config.Host+":"+s.config.Port, auth, s.config.From, []string{to.Target}, []byte(b.String()))
+}
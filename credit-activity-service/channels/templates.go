@@ -0,0 +1,91 @@
+package channels
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// 消息模板类型
+const (
+	KindReviewRequested  = "review_requested"
+	KindActivityApproved = "activity_approved"
+	KindActivityRejected = "activity_rejected"
+	KindCreditsUpdated   = "credits_updated"
+	KindDailyDigest      = "daily_digest"
+)
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func mustTemplate(kind, subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(kind + ".subject").Parse(subject)),
+		body:    template.Must(template.New(kind + ".body").Parse(body)),
+	}
+}
+
+var templates = map[string]messageTemplate{
+	KindReviewRequested: mustTemplate(KindReviewRequested,
+		`待审核：{{.Title}}`,
+		`{{.RecipientName}}您好，活动「{{.Title}}」（{{.Category}}）已提交审核，请及时处理。`),
+	KindActivityApproved: mustTemplate(KindActivityApproved,
+		`活动审核通过：{{.Title}}`,
+		`{{.RecipientName}}您好，您的活动「{{.Title}}」已审核通过。{{if .Comments}}
+审核意见：{{.Comments}}{{end}}`),
+	KindActivityRejected: mustTemplate(KindActivityRejected,
+		`活动审核未通过：{{.Title}}`,
+		`{{.RecipientName}}您好，您的活动「{{.Title}}」未通过审核。{{if .Comments}}
+审核意见：{{.Comments}}{{end}}`),
+	KindCreditsUpdated: mustTemplate(KindCreditsUpdated,
+		`学分变动：{{.Title}}`,
+		`{{.RecipientName}}您好，您在活动「{{.Title}}」中的学分{{if .HasPrevious}}由 {{.PreviousCredits}} {{end}}调整为 {{.Credits}}。`),
+	KindDailyDigest: mustTemplate(KindDailyDigest,
+		`每日待审核汇总：{{.PendingCount}} 个活动待审核`,
+		`{{.RecipientName}}您好，当前共有 {{.PendingCount}} 个活动等待审核{{if .Items}}，最早提交的如下：{{range .Items}}
+- {{.Title}}（{{.Category}}，已等待 {{.WaitingDays}} 天）{{end}}{{end}}`),
+}
+
+// Render 渲染指定类型的消息
+func Render(kind string, data interface{}) (Message, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown message kind %q", kind)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	return Message{Kind: kind, Subject: subject.String(), Body: body.String()}, nil
+}
+
+// ActivityData 活动相关消息的模板数据
+type ActivityData struct {
+	RecipientName   string
+	Title           string
+	Category        string
+	Comments        string
+	Credits         interface{}
+	PreviousCredits interface{}
+	HasPrevious     bool
+}
+
+// DigestItem 每日汇总中的单个待审核活动
+type DigestItem struct {
+	Title       string
+	Category    string
+	WaitingDays int
+}
+
+// DigestData 每日汇总的模板数据
+type DigestData struct {
+	RecipientName string
+	PendingCount  int64
+	Items         []DigestItem
+}
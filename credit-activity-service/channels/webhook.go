@@ -0,0 +1,153 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"credit-management/shared/netguard"
+)
+
+func postJSON(ctx context.Context, client *http.Client, target string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded with status %d", target, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// robotResult 企业微信 / 钉钉机器人接口的返回结构，HTTP 200 时仍需检查 errcode
+type robotResult struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func postRobot(ctx context.Context, client *http.Client, target string, body interface{}) error {
+	resp, err := postJSON(ctx, client, target, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result robotResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode robot response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("robot error %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// WebhookChannel 向用户配置的地址 POST JSON 消息。地址由用户填写，
+// 本文件中的渠道都使用 netguard 客户端，只能连接公网地址
+type WebhookChannel struct {
+	client *http.Client
+}
+
+func NewWebhookChannel() *WebhookChannel {
+	return &WebhookChannel{client: netguard.NewClient(10 * time.Second)}
+}
+
+func (w *WebhookChannel) Name() string { return ChannelWebhook }
+
+func (w *WebhookChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	resp, err := postJSON(ctx, w.client, to.Target, map[string]interface{}{
+		"user_id": to.UserID,
+		"kind":    msg.Kind,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// WeComChannel 企业微信群机器人（markdown 消息）
+type WeComChannel struct {
+	client *http.Client
+}
+
+func NewWeComChannel() *WeComChannel {
+	return &WeComChannel{client: netguard.NewClient(10 * time.Second)}
+}
+
+func (w *WeComChannel) Name() string { return ChannelWeCom }
+
+func (w *WeComChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	return postRobot(ctx, w.client, to.Target, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": "**" + msg.Subject + "**\n" + msg.Body,
+		},
+	})
+}
+
+// DingTalkChannel 钉钉群机器人（markdown 消息），配置了加签密钥时自动签名
+type DingTalkChannel struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewDingTalkChannel() *DingTalkChannel {
+	return &DingTalkChannel{client: netguard.NewClient(10 * time.Second), now: time.Now}
+}
+
+func (d *DingTalkChannel) Name() string { return ChannelDingTalk }
+
+func (d *DingTalkChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	target := to.Target
+	if to.Secret != "" {
+		signed, err := signDingTalkURL(target, to.Secret, d.now())
+		if err != nil {
+			return err
+		}
+		target = signed
+	}
+	return postRobot(ctx, d.client, target, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": msg.Subject,
+			"text":  "#### " + msg.Subject + "\n" + msg.Body,
+		},
+	})
+}
+
+// signDingTalkURL 按钉钉加签规则追加 timestamp 与 sign 参数
+func signDingTalkURL(target, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,106 @@
+package fulltext
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokensHanAndWords(t *testing.T) {
+	tokens := Tokens("志愿服务 Volunteer-2024！")
+
+	for _, want := range []string{
+		"志", "志愿", "愿服", "服务", // 单字和相邻两字
+		"volunteer", "2024", // 小写英文单词和数字
+		"zhiyuanfuwu", "zyfw", // 汉字段的全拼和首字母
+		"zhiyuan", "fuwu", // 相邻两字的全拼
+	} {
+		assert.Contains(t, tokens, want)
+	}
+	assert.NotContains(t, tokens, "Volunteer")
+	assert.Len(t, tokens, len(uniq(tokens)), "索引词不重复")
+}
+
+func TestTokensIndexInitialsFromEveryRune(t *testing.T) {
+	tokens := Tokens("中华人民共和国")
+	assert.Contains(t, tokens, "zhrmghg")
+	// 从词中间开始的首字母缩写（3 个及以上）也有以它开头的索引词
+	for _, query := range []string{"hrm", "rmghg", "mgh"} {
+		assert.True(t, hasPrefix(tokens, query), query)
+	}
+
+	// 首字母窗口之外的部分只能从整段开头匹配
+	long := Tokens(strings.Repeat("中", 12) + "国")
+	assert.Contains(t, long, strings.Repeat("z", 12)+"g")
+	assert.True(t, hasPrefix(long, strings.Repeat("z", initialsWindow-1)+"g"))
+	assert.False(t, hasPrefix(long, strings.Repeat("z", initialsWindow)+"g"))
+}
+
+func TestQueryTerms(t *testing.T) {
+	assert.Equal(t, []string{"'志愿'", "'愿服'", "'服务'"}, queryTerms("志愿服务"))
+	assert.Equal(t, []string{"'志'"}, queryTerms("志"))
+	assert.Equal(t, []string{"'zyfw':*", "'2024':*"}, queryTerms("ZYFW 2024"))
+	assert.Empty(t, queryTerms("！？ ,"))
+
+	q := ParseQuery("  志愿 abc ")
+	require.NotNil(t, q)
+	assert.Equal(t, "'志愿' & 'abc':*", q.tsquery)
+	assert.Equal(t, "志愿 abc", q.raw)
+	assert.Nil(t, ParseQuery("。。"))
+}
+
+func TestQuoteLexeme(t *testing.T) {
+	assert.Equal(t, `'it''s'`, quoteLexeme("it's"))
+	assert.Equal(t, `'a\\b'`, quoteLexeme(`a\b`))
+	assert.Equal(t, `'abc' 'it' 's'`, vectorLiteral("ABC it's"))
+}
+
+func TestHighlight(t *testing.T) {
+	q := ParseQuery("志愿")
+	require.NotNil(t, q)
+	assert.Equal(t, "参加<mark>志愿</mark>活动 &lt;b&gt;", q.Highlight("参加志愿活动 <b>", 0))
+
+	// 整段未出现时退回标出相邻两字
+	q = ParseQuery("志愿服务")
+	assert.Equal(t, "<mark>志愿</mark>者和<mark>服务</mark>", q.Highlight("志愿者和服务", 0))
+
+	snippet := ParseQuery("目标").Highlight(strings.Repeat("前", 50)+"目标"+strings.Repeat("后", 50), 20)
+	assert.True(t, strings.HasPrefix(snippet, "…") && strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, "<mark>目标</mark>")
+}
+
+func TestPinyinDataHasOneReadingPerRune(t *testing.T) {
+	seen := map[rune]string{}
+	for _, line := range strings.Split(pinyinData, "\n") {
+		syllable := strings.TrimRightFunc(line, func(r rune) bool { return r > unicode.MaxASCII })
+		for _, r := range line[len(syllable):] {
+			if prev, ok := seen[r]; ok {
+				t.Errorf("%c 同时出现在 %s 和 %s 中", r, prev, syllable)
+			}
+			seen[r] = syllable
+		}
+	}
+	assert.Equal(t, "zhong", Pinyin('中'))
+	assert.Equal(t, " zhangsan zs", NamePinyin("张三"))
+	assert.Equal(t, "", NamePinyin("Alice"))
+}
+
+func uniq(tokens []string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range tokens {
+		set[token] = true
+	}
+	return set
+}
+
+func hasPrefix(tokens []string, prefix string) bool {
+	for _, token := range tokens {
+		if strings.HasPrefix(token, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package fulltext
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tsConfig 数据库中可用的中文分词配置（如 zhparser 创建的 chinese），为空时只使用 Go 二元切分
+var tsConfig string
+
+// Configure 检查分词配置是否存在于数据库中，存在则在索引和查询中叠加使用；不存在时退回纯 Go 实现。
+// 更换配置后需将 credit_activities.search_indexed_at 置空，由后台任务重建索引
+func Configure(db *gorm.DB, config string) {
+	tsConfig = ""
+	if config == "" {
+		return
+	}
+	var exists bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = ?)", config).Scan(&exists).Error; err != nil {
+		log.Printf("[fulltext] failed to check text search config %q: %v", config, err)
+		return
+	}
+	if !exists {
+		log.Printf("[fulltext] text search config %q not found, using built-in tokenizer", config)
+		return
+	}
+	tsConfig = config
+	log.Printf("[fulltext] using text search config %q", config)
+}
+
+// activityDocument 参与索引的活动字段
+type activityDocument struct {
+	ID          string
+	Title       string
+	Description string
+	Details     []byte
+	UpdatedAt   time.Time
+}
+
+// IndexPending 为新建或修改后尚未索引的活动（search_indexed_at 与 updated_at 不一致）重建索引，
+// 每批 batchSize 条，直到没有待处理的活动
+func IndexPending(ctx context.Context, db *gorm.DB, batchSize int) (int, error) {
+	indexed := 0
+	for {
+		var docs []activityDocument
+		if err := db.WithContext(ctx).Raw(`
+			SELECT id, title, description, details, updated_at FROM credit_activities
+			WHERE search_indexed_at IS DISTINCT FROM updated_at
+			ORDER BY updated_at
+			LIMIT ?`, batchSize).Scan(&docs).Error; err != nil {
+			return indexed, err
+		}
+		if len(docs) == 0 {
+			return indexed, nil
+		}
+
+		progressed := false
+		for _, doc := range docs {
+			updated, err := indexDocument(db.WithContext(ctx), doc)
+			if err != nil {
+				return indexed, err
+			}
+			if updated {
+				indexed++
+				progressed = true
+			}
+		}
+		// 整批都在索引期间被再次修改，留到下一轮处理
+		if !progressed || len(docs) < batchSize {
+			return indexed, nil
+		}
+	}
+}
+
+// indexDocument 写入活动的 tsvector；活动在读取后又被修改时不写入，返回 false
+func indexDocument(db *gorm.DB, doc activityDocument) (bool, error) {
+	details := detailsText(doc.Details)
+
+	vector := "setweight(?::tsvector, 'A') || setweight(?::tsvector, 'B') || setweight(?::tsvector, 'C')"
+	args := []interface{}{vectorLiteral(doc.Title), vectorLiteral(doc.Description), vectorLiteral(details)}
+	if tsConfig != "" {
+		vector += " || setweight(to_tsvector(?::regconfig, ?), 'A')" +
+			" || setweight(to_tsvector(?::regconfig, ?), 'B')" +
+			" || setweight(to_tsvector(?::regconfig, ?), 'C')"
+		args = append(args, tsConfig, doc.Title, tsConfig, doc.Description, tsConfig, details)
+	}
+	args = append(args, doc.ID, doc.UpdatedAt)
+
+	result := db.Exec(fmt.Sprintf(`
+		UPDATE credit_activities SET search_vector = %s, search_indexed_at = updated_at
+		WHERE id = ? AND updated_at = ?`, vector), args...)
+	return result.RowsAffected > 0, result.Error
+}
+
+// detailsText 取出活动详情中的所有文本和数字，按键名排序保证结果稳定
+func detailsText(raw []byte) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var details interface{}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return ""
+	}
+	var parts []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			parts = append(parts, v)
+		case float64:
+			parts = append(parts, fmt.Sprint(v))
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(v[key])
+			}
+		}
+	}
+	walk(details)
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,53 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// pinyinTable 汉字到不带声调拼音的映射，由 pinyinData 解析得到
+var pinyinTable = parsePinyinData(pinyinData)
+
+func parsePinyinData(data string) map[rune]string {
+	table := make(map[rune]string, 7000)
+	for _, line := range strings.Split(data, "\n") {
+		var syllable strings.Builder
+		for _, r := range line {
+			if r < unicode.MaxASCII {
+				syllable.WriteRune(r)
+				continue
+			}
+			table[r] = syllable.String()
+		}
+	}
+	return table
+}
+
+// Pinyin 返回汉字的拼音（不带声调），不在常用字表中的字返回空串
+func Pinyin(r rune) string {
+	return pinyinTable[r]
+}
+
+// NamePinyin 返回姓名的全拼和首字母，形如 " zhangsan zs"。
+// 每项以空格开头，查询时用 LIKE '% xxx%' 即可按前缀匹配；姓名中没有可转换的汉字时返回空串
+func NamePinyin(name string) string {
+	full, initials := runPinyin([]rune(name))
+	if full == "" {
+		return ""
+	}
+	return " " + full + " " + initials
+}
+
+// runPinyin 返回一段文字的全拼和首字母，跳过没有拼音的字符
+func runPinyin(run []rune) (string, string) {
+	var full, initials strings.Builder
+	for _, r := range run {
+		py := Pinyin(r)
+		if py == "" {
+			continue
+		}
+		full.WriteString(py)
+		initials.WriteByte(py[0])
+	}
+	return full.String(), initials.String()
+}
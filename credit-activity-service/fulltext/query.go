@@ -0,0 +1,153 @@
+package fulltext
+
+import (
+	"html"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// SnippetRunes 描述摘要的默认长度
+const SnippetRunes = 120
+
+// Query 解析后的搜索关键词
+type Query struct {
+	raw     string
+	tsquery string
+}
+
+// ParseQuery 解析用户输入的关键词；没有可检索的内容（如只有标点）时返回 nil
+func ParseQuery(raw string) *Query {
+	raw = strings.TrimSpace(raw)
+	terms := queryTerms(raw)
+	if len(terms) == 0 {
+		return nil
+	}
+	return &Query{raw: raw, tsquery: strings.Join(terms, " & ")}
+}
+
+// match 返回 tsquery 表达式及参数；配置了中文分词时，分词结果作为另一种匹配方式
+func (q *Query) match() (string, []interface{}) {
+	if tsConfig == "" {
+		return "?::tsquery", []interface{}{q.tsquery}
+	}
+	return "(?::tsquery || plainto_tsquery(?::regconfig, ?))", []interface{}{q.tsquery, tsConfig, q.raw}
+}
+
+// Condition 返回活动的匹配条件。尚未被后台任务索引（新建或刚修改）的活动退回到标题和描述的模糊匹配，
+// 保证修改后立即可以搜到
+func (q *Query) Condition() clause.Expr {
+	match, args := q.match()
+	like := "%" + q.raw + "%"
+	return clause.Expr{
+		SQL:  "(search_vector @@ " + match + " OR (search_indexed_at IS DISTINCT FROM updated_at AND (title ILIKE ? OR description ILIKE ?)))",
+		Vars: append(args, like, like),
+	}
+}
+
+// OrderByRank 按相关度排序，标题命中的权重高于描述和详情
+func (q *Query) OrderByRank() clause.OrderBy {
+	match, args := q.match()
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  "ts_rank(search_vector, " + match + ") DESC, updated_at DESC, id DESC",
+		Vars: args,
+	}}
+}
+
+// Highlight 返回用 <mark> 标出命中位置的文本（已做 HTML 转义）。
+// maxRunes 大于 0 时截取第一个命中位置附近的片段，首尾被截断处以省略号表示
+func (q *Query) Highlight(text string, maxRunes int) string {
+	runes := []rune(text)
+	ranges := q.matchRanges(runes)
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		if len(ranges) > 0 {
+			start = ranges[0][0] - maxRunes/4
+		}
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxRunes
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, r := range ranges {
+		from, to := r[0], r[1]
+		if to <= pos || from >= end {
+			continue
+		}
+		if from < pos {
+			from = pos
+		}
+		if to > end {
+			to = end
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:from])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[from:to])))
+		b.WriteString("</mark>")
+		pos = to
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// matchRanges 找出文本中与关键词字面匹配的位置（按字符下标），重叠的区间会合并。
+// 汉字段整段未出现时，退回标出其中出现的相邻两字
+func (q *Query) matchRanges(text []rune) [][2]int {
+	lower := []rune(strings.ToLower(string(text)))
+	if len(lower) != len(text) {
+		// 极少数字符转小写后长度变化，此时不做标注
+		return nil
+	}
+
+	var ranges [][2]int
+	find := func(needle []rune) bool {
+		found := false
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				ranges = append(ranges, [2]int{i, i + len(needle)})
+				found = true
+			}
+		}
+		return found
+	}
+	for _, seg := range segments(q.raw) {
+		if find(seg.runes) || !seg.han {
+			continue
+		}
+		for i := 0; i+1 < len(seg.runes); i++ {
+			find(seg.runes[i : i+2])
+		}
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
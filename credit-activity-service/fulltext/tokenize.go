@@ -0,0 +1,138 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxTokenRunes 过长的单词（如链接、编码）截断后再入索引
+	maxTokenRunes = 64
+	// maxFieldRunes 每个字段参与索引的最大字符数，避免超长详情撑大 tsvector
+	maxFieldRunes = 20000
+	// initialsWindow 从每个汉字开始索引的拼音首字母个数。查询按前缀匹配，
+	// 因此从词中间开始、不超过该长度的首字母缩写也能命中；更长的缩写只能从汉字段开头匹配
+	initialsWindow = 8
+)
+
+// segment 文本中连续的汉字或连续的字母数字
+type segment struct {
+	runes []rune
+	han   bool
+}
+
+// segments 把文本切分为汉字段和字母数字段，其余字符（标点、空白）作为分隔
+func segments(text string) []segment {
+	var result []segment
+	var current []rune
+	currentHan := false
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, segment{runes: current, han: currentHan})
+			current = nil
+		}
+	}
+
+	count := 0
+	for _, r := range text {
+		if count++; count > maxFieldRunes {
+			break
+		}
+		han := unicode.Is(unicode.Han, r)
+		word := !han && (unicode.IsLetter(r) || unicode.IsDigit(r))
+		if !han && !word {
+			flush()
+			continue
+		}
+		if len(current) > 0 && han != currentHan {
+			flush()
+		}
+		currentHan = han
+		current = append(current, unicode.ToLower(r))
+	}
+	flush()
+	return result
+}
+
+// Tokens 返回文本的索引词：汉字的单字和相邻两字（二元切分，不依赖词典也能匹配任意词语）、
+// 小写的英文单词和数字，以及汉字段的全拼和首字母、相邻两字的全拼、从每个字开始的首字母（见 initialsWindow），
+// 用于拼音输入匹配
+func Tokens(text string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	add := func(token string) {
+		if token == "" || seen[token] {
+			return
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	for _, seg := range segments(text) {
+		if !seg.han {
+			add(truncate(seg.runes))
+			continue
+		}
+		for i := range seg.runes {
+			add(string(seg.runes[i]))
+			if i+1 < len(seg.runes) {
+				add(string(seg.runes[i : i+2]))
+				full, _ := runPinyin(seg.runes[i : i+2])
+				add(full)
+			}
+			end := i + initialsWindow
+			if end > len(seg.runes) {
+				end = len(seg.runes)
+			}
+			_, initials := runPinyin(seg.runes[i:end])
+			add(initials)
+		}
+		full, initials := runPinyin(seg.runes)
+		add(truncate([]rune(full)))
+		add(truncate([]rune(initials)))
+	}
+	return tokens
+}
+
+func truncate(runes []rune) string {
+	if len(runes) > maxTokenRunes {
+		runes = runes[:maxTokenRunes]
+	}
+	return string(runes)
+}
+
+// queryTerms 把查询拆成必须全部匹配的检索项：每段汉字取相邻两字（单字段取单字），
+// 英文、数字和拼音按前缀匹配
+func queryTerms(query string) []string {
+	var terms []string
+	for _, seg := range segments(query) {
+		if !seg.han {
+			terms = append(terms, quoteLexeme(truncate(seg.runes))+":*")
+			continue
+		}
+		if len(seg.runes) == 1 {
+			terms = append(terms, quoteLexeme(string(seg.runes)))
+			continue
+		}
+		for i := 0; i+1 < len(seg.runes); i++ {
+			terms = append(terms, quoteLexeme(string(seg.runes[i:i+2])))
+		}
+	}
+	return terms
+}
+
+// quoteLexeme 按 tsvector / tsquery 的输入语法给词加引号
+func quoteLexeme(lexeme string) string {
+	lexeme = strings.ReplaceAll(lexeme, `\`, `\\`)
+	return "'" + strings.ReplaceAll(lexeme, "'", "''") + "'"
+}
+
+// vectorLiteral 返回可直接转换为 tsvector 的字符串
+func vectorLiteral(text string) string {
+	tokens := Tokens(text)
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		quoted[i] = quoteLexeme(token)
+	}
+	return strings.Join(quoted, " ")
+}
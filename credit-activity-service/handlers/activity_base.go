@@ -5,6 +5,7 @@ import (
 
 	"credit-management/credit-activity-service/models"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/shared/storage"
 
 	"gorm.io/gorm"
 )
@@ -13,51 +14,67 @@ type ActivityHandler struct {
 	db        *gorm.DB
 	validator *utils.Validator
 	base      *utils.BaseHandler
+	files     storage.Storage // 附件文件，彻底删除活动时清理
 }
 
-func NewActivityHandler(db *gorm.DB) *ActivityHandler {
+func NewActivityHandler(db *gorm.DB, files storage.Storage) *ActivityHandler {
 	return &ActivityHandler{
 		db:        db,
+		files:     files,
 		validator: utils.NewValidator(),
 		base:      utils.NewBaseHandler(db),
 	}
 }
 
-func (h *ActivityHandler) enrichActivityResponse(activity models.CreditActivity, authToken string) models.ActivityResponse {
+func (h *ActivityHandler) enrichActivityResponse(activity models.CreditActivity) models.ActivityResponse {
 	response := models.ActivityResponse{
-		ID:             activity.ID,
-		Title:          activity.Title,
-		Description:    activity.Description,
-		StartDate:      activity.StartDate,
-		EndDate:        activity.EndDate,
-		Status:         activity.Status,
-		Category:       activity.Category,
-		OwnerID:        activity.OwnerID,
-		ReviewerID:     activity.ReviewerID,
-		ReviewComments: activity.ReviewComments,
-		ReviewedAt:     activity.ReviewedAt,
-		CreatedAt:      activity.CreatedAt,
-		UpdatedAt:      activity.UpdatedAt,
-		Details:        activity.Details,
-	}
-
-	if ownerInfo, err := h.getUserInfo(activity.OwnerID, authToken); err == nil {
-		response.OwnerInfo = ownerInfo
+		ID:                 activity.ID,
+		Title:              activity.Title,
+		Description:        activity.Description,
+		StartDate:          activity.StartDate,
+		EndDate:            activity.EndDate,
+		Status:             activity.Status,
+		Category:           activity.Category,
+		OwnerID:            activity.OwnerID,
+		ReviewerID:         activity.ReviewerID,
+		ReviewComments:     activity.ReviewComments,
+		ReviewedAt:         activity.ReviewedAt,
+		Version:            activity.Version,
+		EnrollmentClosedAt: activity.EnrollmentClosedAt,
+		OverdueFlaggedAt:   activity.OverdueFlaggedAt,
+		CreatedAt:          activity.CreatedAt,
+		UpdatedAt:          activity.UpdatedAt,
+		Details:            activity.Details,
 	}
 
 	var participants []models.ActivityParticipant
 	h.db.Where("activity_id = ? AND deleted_at IS NULL", activity.ID).Find(&participants)
 
+	var applications []models.Application
+	h.db.Where("activity_id = ? AND deleted_at IS NULL", activity.ID).Find(&applications)
+
+	// 活动负责人、参与者和申请人的信息一次批量查询
+	userIDs := []string{activity.OwnerID}
+	for _, participant := range participants {
+		userIDs = append(userIDs, participant.UUID)
+	}
+	for _, application := range applications {
+		userIDs = append(userIDs, application.UUID)
+	}
+	users := lookupUsers(h.db, userIDs)
+	response.OwnerInfo = users[activity.OwnerID]
+
 	var participantResponses []models.ParticipantResponse
 	for _, participant := range participants {
-		userInfo, err := h.getUserInfo(participant.UUID, authToken)
-		if err != nil {
+		userInfo, ok := users[participant.UUID]
+		if !ok {
 			continue
 		}
 
 		response := models.ParticipantResponse{
 			UUID:     participant.UUID,
 			Credits:  participant.Credits,
+			Version:  participant.Version,
 			JoinedAt: participant.JoinedAt,
 			UserInfo: userInfo,
 		}
@@ -66,13 +83,10 @@ func (h *ActivityHandler) enrichActivityResponse(activity models.CreditActivity,
 	}
 	response.Participants = participantResponses
 
-	var applications []models.Application
-	h.db.Where("activity_id = ? AND deleted_at IS NULL", activity.ID).Find(&applications)
-
 	var applicationResponses []models.ApplicationResponse
 	for _, application := range applications {
-		userInfo, err := h.getUserInfo(application.UUID, authToken)
-		if err != nil {
+		userInfo, ok := users[application.UUID]
+		if !ok {
 			continue
 		}
 
@@ -107,10 +121,6 @@ func (h *ActivityHandler) enrichActivityResponse(activity models.CreditActivity,
 	return response
 }
 
-func (h *ActivityHandler) getUserInfo(userID string, authToken string) (*models.UserInfo, error) {
-	return utils.GetUserInfo(userID, authToken)
-}
-
 func (h *ActivityHandler) validateActivityRequest(req models.ActivityRequest) error {
 	return h.validator.ValidateActivityRequest(req)
 }
@@ -8,6 +8,7 @@ import (
 	"credit-management/credit-activity-service/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func (h *ActivityHandler) BatchDeleteActivities(c *gin.Context) {
@@ -40,7 +41,7 @@ func (h *ActivityHandler) BatchDeleteActivities(c *gin.Context) {
 	}()
 
 	var deletedCount int
-	var attachmentsToCleanup []models.Attachment
+	deletedAt := time.Now().Truncate(time.Microsecond)
 
 	for _, activityID := range req.ActivityIDs {
 		if err := h.validator.ValidateUUID(activityID); err != nil {
@@ -52,20 +53,12 @@ func (h *ActivityHandler) BatchDeleteActivities(c *gin.Context) {
 			continue
 		}
 
-		attachments, err := h.softDeleteActivityRelations(tx, activity.ID)
-		if err != nil {
-			tx.Rollback()
-			utils.SendInternalServerError(c, err)
-			return
-		}
-
-		if err := tx.Delete(&models.CreditActivity{ID: activity.ID}).Error; err != nil {
+		if err := h.softDeleteActivity(tx, activity.ID, deletedAt); err != nil {
 			tx.Rollback()
 			utils.SendInternalServerError(c, err)
 			return
 		}
 
-		attachmentsToCleanup = append(attachmentsToCleanup, attachments...)
 		deletedCount++
 	}
 
@@ -74,13 +67,12 @@ func (h *ActivityHandler) BatchDeleteActivities(c *gin.Context) {
 		return
 	}
 
-	h.cleanupAttachmentFiles(attachmentsToCleanup)
-
 	utils.SendSuccessResponse(c, gin.H{
 		"message":       "批量删除活动成功",
 		"deleted_count": deletedCount,
 		"total_count":   len(req.ActivityIDs),
-		"deleted_at":    time.Now(),
+		"deleted_at":    deletedAt,
+		"purge_at":      deletedAt.Add(utils.TrashRetention()),
 	})
 }
 
@@ -200,7 +192,7 @@ func (h *ActivityHandler) BatchUpdateActivities(c *gin.Context) {
 			continue
 		}
 
-		if activity.OwnerID != userID && userType != "admin" {
+		if userType != "admin" && !utils.CanEditActivity(utils.GetActivityRole(tx, &activity, userID)) {
 			errors = append(errors, fmt.Sprintf("第%d个活动无权限更新", i+1))
 			continue
 		}
@@ -210,6 +202,11 @@ func (h *ActivityHandler) BatchUpdateActivities(c *gin.Context) {
 			continue
 		}
 
+		if upd.Main.Version != nil && *upd.Main.Version != activity.Version {
+			errors = append(errors, fmt.Sprintf("第%d个活动已被他人修改（当前版本 %d）", i+1, activity.Version))
+			continue
+		}
+
 		if upd.Main.Title != nil {
 			activity.Title = *upd.Main.Title
 		}
@@ -270,15 +267,32 @@ func (h *ActivityHandler) BatchUpdateActivities(c *gin.Context) {
 			activity.Details = upd.Main.Details
 		}
 
-		if err := tx.Save(&activity).Error; err != nil {
+		// 以读取时的版本号作为更新条件，防止覆盖并发修改
+		result := tx.Model(&models.CreditActivity{}).
+			Where("id = ? AND version = ?", activity.ID, activity.Version).
+			Updates(map[string]interface{}{
+				"title":       activity.Title,
+				"description": activity.Description,
+				"start_date":  activity.StartDate,
+				"end_date":    activity.EndDate,
+				"category":    activity.Category,
+				"details":     activity.Details,
+				"version":     gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
 			errors = append(errors, fmt.Sprintf("第%d个活动主表更新失败", i+1))
 			continue
 		}
+		if result.RowsAffected == 0 {
+			errors = append(errors, fmt.Sprintf("第%d个活动已被他人修改", i+1))
+			continue
+		}
 
 		updatedActivities = append(updatedActivities, models.ActivityCreateResponse{
 			ID:        activity.ID,
 			Title:     activity.Title,
 			Status:    activity.Status,
+			Version:   activity.Version + 1,
 			CreatedAt: activity.CreatedAt,
 		})
 	}
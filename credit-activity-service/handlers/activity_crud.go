@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"time"
+
 	"credit-management/credit-activity-service/models"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/shared/audit"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/datatypes"
@@ -51,7 +55,7 @@ func (h *ActivityHandler) CreateActivity(c *gin.Context) {
 
 	// 详情改为写入 JSONB
 
-	response := h.enrichActivityResponse(activity, "")
+	response := h.enrichActivityResponse(activity)
 	utils.SendCreatedResponse(c, "活动创建成功", response)
 }
 
@@ -77,7 +81,23 @@ func (h *ActivityHandler) GetActivities(c *gin.Context) {
 		c.DefaultQuery("page_size", c.DefaultQuery("limit", "10")),
 	)
 
-	activities, total, err := h.base.SearchActivities(query, status, category, ownerID, userID, userType, page, limit)
+	pager, err := utils.NewPager(c, page, limit)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	activities, total, err := h.base.SearchActivities(query, status, category, ownerID, userID, userType, scope, pager)
+	if errors.Is(err, utils.ErrCursorMismatch) {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
@@ -85,7 +105,7 @@ func (h *ActivityHandler) GetActivities(c *gin.Context) {
 
 	// 为列表构建轻量级响应，避免为每个活动加载全部参与者和申请详情
 	if len(activities) == 0 {
-		utils.SendPaginatedResponse(c, []models.ActivityResponse{}, total, page, limit)
+		pager.Send(c, []models.ActivityResponse{}, total)
 		return
 	}
 
@@ -137,27 +157,30 @@ func (h *ActivityHandler) GetActivities(c *gin.Context) {
 	responses := make([]models.ActivityResponse, 0, len(activities))
 	for _, a := range activities {
 		resp := models.ActivityResponse{
-			ID:                a.ID,
-			Title:             a.Title,
-			Description:       a.Description,
-			StartDate:         a.StartDate,
-			EndDate:           a.EndDate,
-			Status:            a.Status,
-			Category:          a.Category,
-			OwnerID:           a.OwnerID,
-			ReviewerID:        a.ReviewerID,
-			ReviewComments:    a.ReviewComments,
-			ReviewedAt:        a.ReviewedAt,
-			CreatedAt:         a.CreatedAt,
-			UpdatedAt:         a.UpdatedAt,
-			ParticipantsCount: participantMap[a.ID],
-			ApplicationsCount: applicationMap[a.ID],
+			ID:                 a.ID,
+			Title:              a.Title,
+			Description:        a.Description,
+			StartDate:          a.StartDate,
+			EndDate:            a.EndDate,
+			Status:             a.Status,
+			Category:           a.Category,
+			OwnerID:            a.OwnerID,
+			ReviewerID:         a.ReviewerID,
+			ReviewComments:     a.ReviewComments,
+			ReviewedAt:         a.ReviewedAt,
+			Version:            a.Version,
+			EnrollmentClosedAt: a.EnrollmentClosedAt,
+			OverdueFlaggedAt:   a.OverdueFlaggedAt,
+			CreatedAt:          a.CreatedAt,
+			UpdatedAt:          a.UpdatedAt,
+			ParticipantsCount:  participantMap[a.ID],
+			ApplicationsCount:  applicationMap[a.ID],
 			// 列表页暂不返回 OwnerInfo / Participants / Applications / Details，减少数据量和外部调用
 		}
 		responses = append(responses, resp)
 	}
 
-	utils.SendPaginatedResponse(c, responses, total, page, limit)
+	pager.Send(c, responses, total)
 }
 
 func (h *ActivityHandler) GetActivity(c *gin.Context) {
@@ -188,16 +211,23 @@ func (h *ActivityHandler) GetActivity(c *gin.Context) {
 		}
 		return
 	}
+	if !utils.RequireActivityInScope(c, h.db, id) {
+		return
+	}
 
-	// 权限检查
-	if userType == "student" && activity.OwnerID != userID {
+	// 权限检查：学生可查看自己创建、协作或参与的活动
+	if userType == "student" && utils.GetActivityRole(h.db, activity, userID) == "" {
 		if err := h.base.CheckUserParticipant(id, userID); err != nil {
 			utils.SendForbidden(c, "无权限查看此活动")
 			return
 		}
 	}
 
-	response := h.enrichActivityResponse(*activity, c.GetHeader("Authorization"))
+	// ETag 只用于修改时的 If-Match 校验：响应中的参与者、附件和用户信息变化时活动版本号不变，
+	// 因此不按 If-None-Match 返回 304
+	utils.SetETag(c, activity.Version)
+
+	response := h.enrichActivityResponse(*activity)
 	utils.SendSuccessResponse(c, response)
 }
 
@@ -208,13 +238,13 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("id")
-	if !exists {
+	userID := c.GetString("id")
+	if userID == "" {
 		utils.SendUnauthorized(c)
 		return
 	}
 
-	userType, _ := c.Get("user_type")
+	userType := c.GetString("user_type")
 
 	activity, err := h.base.GetActivityByID(id)
 	if err != nil {
@@ -226,7 +256,7 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 		return
 	}
 
-	if userType == "student" && activity.OwnerID != userID {
+	if userType == "student" && !canEditActivity(h.db, activity, userID, userType) {
 		utils.SendForbidden(c, "无权限修改此活动")
 		return
 	}
@@ -242,9 +272,26 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 		return
 	}
 
+	expectedVersion, checkVersion, err := utils.ExpectedVersion(c, req.Version)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+	if checkVersion && activity.Version != expectedVersion {
+		h.sendActivityConflict(c, *activity)
+		return
+	}
+
 	updates := h.buildUpdateMap(req)
-	if err := h.db.Model(&models.CreditActivity{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		utils.SendInternalServerError(c, err)
+	updates["version"] = gorm.Expr("version + 1")
+
+	query := h.db.Model(&models.CreditActivity{}).Where("id = ?", id)
+	if checkVersion {
+		query = query.Where("version = ?", expectedVersion)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		utils.SendInternalServerError(c, result.Error)
 		return
 	}
 
@@ -254,10 +301,32 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 		return
 	}
 
-	response := h.enrichActivityResponse(*updatedActivity, "")
+	// 读取与写入之间被其他请求抢先修改
+	if checkVersion && result.RowsAffected == 0 {
+		h.sendActivityConflict(c, *updatedActivity)
+		return
+	}
+
+	audit.Record(c, audit.Entry{
+		Action:       "activities.update",
+		ResourceType: "activities",
+		ResourceID:   id,
+		Before:       activity,
+		After:        updatedActivity,
+	})
+
+	utils.SetETag(c, updatedActivity.Version)
+	response := h.enrichActivityResponse(*updatedActivity)
 	utils.SendSuccessResponse(c, response)
 }
 
+// sendActivityConflict 返回 409 以及活动的最新表示，客户端可据此合并后重试
+func (h *ActivityHandler) sendActivityConflict(c *gin.Context, current models.CreditActivity) {
+	utils.SetETag(c, current.Version)
+	response := h.enrichActivityResponse(current)
+	utils.SendConflict(c, "活动已被他人修改，请刷新后重试", response)
+}
+
 func (h *ActivityHandler) buildUpdateMap(req models.ActivityUpdateRequest) map[string]interface{} {
 	updates := make(map[string]interface{})
 
@@ -290,6 +359,11 @@ func (h *ActivityHandler) buildUpdateMap(req models.ActivityUpdateRequest) map[s
 			}
 			if !endDate.IsZero() {
 				updates["end_date"] = endDate
+				// 结束日期延后时重新开放报名，并清除超期标记，由定时任务重新判定
+				if endDate.After(time.Now()) {
+					updates["enrollment_closed_at"] = nil
+					updates["overdue_flagged_at"] = nil
+				}
 			}
 		}
 	}
@@ -325,8 +399,8 @@ func (h *ActivityHandler) DeleteActivity(c *gin.Context) {
 		return
 	}
 
-	// 只有活动创建者、教师或管理员可以删除活动
-	if userType != "admin" && userType != "teacher" && activity.OwnerID != userID {
+	// 只有活动所有者（含共同所有者）、教师或管理员可以删除活动
+	if userType != "admin" && userType != "teacher" && utils.GetActivityRole(h.db, activity, userID) != models.CollaboratorRoleOwner {
 		utils.SendForbidden(c, "无权限删除该活动")
 		return
 	}
@@ -338,14 +412,9 @@ func (h *ActivityHandler) DeleteActivity(c *gin.Context) {
 		}
 	}()
 
-	attachments, err := h.softDeleteActivityRelations(tx, activity.ID)
-	if err != nil {
-		tx.Rollback()
-		utils.SendInternalServerError(c, err)
-		return
-	}
-
-	if err := tx.Delete(&models.CreditActivity{ID: activity.ID}).Error; err != nil {
+	// 软删除进入回收站，物理文件在保留期结束后由清理任务删除
+	deletedAt := time.Now().Truncate(time.Microsecond)
+	if err := h.softDeleteActivity(tx, activity.ID, deletedAt); err != nil {
 		tx.Rollback()
 		utils.SendInternalServerError(c, err)
 		return
@@ -356,7 +425,14 @@ func (h *ActivityHandler) DeleteActivity(c *gin.Context) {
 		return
 	}
 
-	h.cleanupAttachmentFiles(attachments)
-
-	utils.SendSuccessResponse(c, gin.H{"message": "活动删除成功"})
+	audit.Record(c, audit.Entry{
+		Action:       "activities.delete",
+		ResourceType: "activities",
+		ResourceID:   activity.ID,
+		Before:       activity,
+	})
+	utils.SendSuccessResponse(c, gin.H{
+		"message":  "活动已移入回收站",
+		"purge_at": deletedAt.Add(utils.TrashRetention()),
+	})
 }
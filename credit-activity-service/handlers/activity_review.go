@@ -4,7 +4,9 @@ import (
 	"time"
 
 	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/outbox"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/shared/audit"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -34,7 +36,7 @@ func (h *ActivityHandler) SubmitActivity(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID {
+	if !utils.CanEditActivity(utils.GetActivityRole(h.db, activity, userID)) {
 		utils.SendForbidden(c, "无权限提交此活动")
 		return
 	}
@@ -44,7 +46,15 @@ func (h *ActivityHandler) SubmitActivity(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Model(&activity).Update("status", models.StatusPendingReview).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&activity).Updates(map[string]interface{}{
+			"status":  models.StatusPendingReview,
+			"version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return recordStatusEvent(tx, models.EventActivitySubmitted, activity, models.StatusPendingReview, userID, "")
+	}); err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
@@ -84,6 +94,9 @@ func (h *ActivityHandler) ReviewActivity(c *gin.Context) {
 		}
 		return
 	}
+	if !utils.RequireActivityInScope(c, h.db, id) {
+		return
+	}
 
 	if activity.Status != models.StatusPendingReview &&
 		activity.Status != models.StatusApproved &&
@@ -92,12 +105,24 @@ func (h *ActivityHandler) ReviewActivity(c *gin.Context) {
 		return
 	}
 
+	// 审核期间活动被学生修改时，拒绝基于旧内容的审核结论
+	expectedVersion, checkVersion, err := utils.ExpectedVersion(c, nil)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+	if checkVersion && activity.Version != expectedVersion {
+		h.sendActivityConflict(c, *activity)
+		return
+	}
+
 	now := time.Now()
 	updates := map[string]interface{}{
 		"status":          req.Status,
 		"reviewer_id":     userID,
 		"review_comments": req.ReviewComments,
 		"reviewed_at":     &now,
+		"version":         gorm.Expr("version + 1"),
 	}
 
 	tx := h.db.Begin()
@@ -107,9 +132,19 @@ func (h *ActivityHandler) ReviewActivity(c *gin.Context) {
 		}
 	}()
 
-	if err := tx.Model(&models.CreditActivity{}).Where("id = ?", activity.ID).Updates(updates).Error; err != nil {
+	result := tx.Model(&models.CreditActivity{}).Where("id = ? AND version = ?", activity.ID, activity.Version).Updates(updates)
+	if result.Error != nil {
 		tx.Rollback()
-		utils.SendInternalServerError(c, err)
+		utils.SendInternalServerError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		if current, err := h.base.GetActivityByID(activity.ID); err == nil {
+			h.sendActivityConflict(c, *current)
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
 		return
 	}
 
@@ -119,20 +154,56 @@ func (h *ActivityHandler) ReviewActivity(c *gin.Context) {
 		return
 	}
 
+	eventType := models.EventActivityApproved
+	if req.Status == models.StatusRejected {
+		eventType = models.EventActivityRejected
+	}
+	if err := recordStatusEvent(tx, eventType, activity, req.Status, userID, req.ReviewComments); err != nil {
+		tx.Rollback()
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
 
+	audit.Record(c, audit.Entry{
+		Action:       "activities.review",
+		ResourceType: "activities",
+		ResourceID:   activity.ID,
+		Before:       gin.H{"status": activity.Status, "reviewer_id": activity.ReviewerID, "review_comments": activity.ReviewComments},
+		After:        gin.H{"status": req.Status, "reviewer_id": userID, "review_comments": req.ReviewComments},
+	})
+
+	utils.SetETag(c, activity.Version+1)
 	utils.SendSuccessResponse(c, gin.H{
 		"id":              activity.ID,
 		"status":          req.Status,
+		"version":         activity.Version + 1,
 		"reviewer_id":     userID,
 		"review_comments": req.ReviewComments,
 		"reviewed_at":     now,
 	})
 }
 
+// recordStatusEvent 在状态变更事务中写入活动状态事件
+func recordStatusEvent(tx *gorm.DB, eventType string, activity *models.CreditActivity, newStatus, actorID, comments string) error {
+	payload := map[string]interface{}{
+		"activity_id": activity.ID,
+		"title":       activity.Title,
+		"category":    activity.Category,
+		"owner_id":    activity.OwnerID,
+		"from_status": activity.Status,
+		"to_status":   newStatus,
+	}
+	if comments != "" {
+		payload["review_comments"] = comments
+	}
+	return outbox.Record(tx, eventType, activity.ID, actorID, payload)
+}
+
 func (h *ActivityHandler) GetPendingActivities(c *gin.Context) {
 	// 使用统一的验证器处理分页参数
 	page, limit, _ := h.validator.ValidatePagination(
@@ -140,21 +211,22 @@ func (h *ActivityHandler) GetPendingActivities(c *gin.Context) {
 		c.DefaultQuery("limit", "10"),
 	)
 
-	// 使用数据库基类获取待审核活动
-	activities, total, err := h.base.GetPendingActivities(page, limit)
+	scope, err := utils.ResolveDataScope(c, h.db)
 	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
 
-	var responses []models.ActivityResponse
-	authToken := ""
-	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
-		authToken = authHeader
+	// 使用数据库基类获取待审核活动
+	activities, total, err := h.base.GetPendingActivities(scope, page, limit)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
 	}
 
+	var responses []models.ActivityResponse
 	for _, activity := range activities {
-		response := h.enrichActivityResponse(activity, authToken)
+		response := h.enrichActivityResponse(activity)
 		responses = append(responses, response)
 	}
 
@@ -185,7 +257,7 @@ func (h *ActivityHandler) WithdrawActivity(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID {
+	if !utils.CanEditActivity(utils.GetActivityRole(h.db, activity, userID)) {
 		utils.SendForbidden(c, "无权限撤回此活动")
 		return
 	}
@@ -202,7 +274,10 @@ func (h *ActivityHandler) WithdrawActivity(c *gin.Context) {
 		}
 	}()
 
-	if err := tx.Model(&models.CreditActivity{}).Where("id = ?", activity.ID).Update("status", models.StatusDraft).Error; err != nil {
+	if err := tx.Model(&models.CreditActivity{}).Where("id = ?", activity.ID).Updates(map[string]interface{}{
+		"status":  models.StatusDraft,
+		"version": gorm.Expr("version + 1"),
+	}).Error; err != nil {
 		tx.Rollback()
 		utils.SendInternalServerError(c, err)
 		return
@@ -214,6 +289,12 @@ func (h *ActivityHandler) WithdrawActivity(c *gin.Context) {
 		return
 	}
 
+	if err := recordStatusEvent(tx, models.EventActivityWithdrawn, activity, models.StatusDraft, userID, ""); err != nil {
+		tx.Rollback()
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
@@ -246,13 +327,8 @@ func (h *ActivityHandler) GetDeletableActivities(c *gin.Context) {
 	}
 
 	var responses []models.ActivityResponse
-	authToken := ""
-	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
-		authToken = authHeader
-	}
-
 	for _, activity := range activities {
-		response := h.enrichActivityResponse(activity, authToken)
+		response := h.enrichActivityResponse(activity)
 		responses = append(responses, response)
 	}
 
@@ -1,11 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"time"
 
 	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/outbox"
 
 	"gorm.io/gorm"
 )
@@ -32,6 +33,7 @@ func (h *ActivityHandler) generateApplicationsForParticipants(tx *gorm.DB, activ
 		return err
 	}
 
+	granted := make([]map[string]interface{}, 0, len(participants))
 	for _, participant := range participants {
 		// 检查是否存在申请记录（包括软删除的）
 		var existingApp models.Application
@@ -52,6 +54,7 @@ func (h *ActivityHandler) generateApplicationsForParticipants(tx *gorm.DB, activ
 					}).Error; err != nil {
 					return err
 				}
+				granted = append(granted, map[string]interface{}{"user_id": participant.UUID, "credits": participant.Credits})
 			}
 			// 如果记录已存在且未删除，跳过
 			continue
@@ -71,53 +74,101 @@ func (h *ActivityHandler) generateApplicationsForParticipants(tx *gorm.DB, activ
 		if err := tx.Create(&app).Error; err != nil {
 			return err
 		}
+		granted = append(granted, map[string]interface{}{"user_id": participant.UUID, "credits": participant.Credits})
 	}
 
-	return nil
+	if len(granted) == 0 {
+		return nil
+	}
+	return outbox.Record(tx, models.EventApplicationsGranted, activityID, "", map[string]interface{}{
+		"activity_id":  activityID,
+		"applications": granted,
+	})
 }
 
 func (h *ActivityHandler) softDeleteApplications(tx *gorm.DB, activityID string) error {
-	return tx.Where("activity_id = ?", activityID).Delete(&models.Application{}).Error
+	var userIDs []string
+	if err := tx.Model(&models.Application{}).Where("activity_id = ?", activityID).Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("activity_id = ?", activityID).Delete(&models.Application{}).Error; err != nil {
+		return err
+	}
+	return outbox.Record(tx, models.EventApplicationsRevoked, activityID, "", map[string]interface{}{
+		"activity_id": activityID,
+		"user_ids":    userIDs,
+	})
 }
 
-func (h *ActivityHandler) softDeleteActivityRelations(tx *gorm.DB, activityID string) ([]models.Attachment, error) {
-	var attachments []models.Attachment
-	if err := tx.Where("activity_id = ? AND deleted_at IS NULL", activityID).Find(&attachments).Error; err != nil {
-		return nil, err
+// softDeleteActivity 将活动及其参与者、申请、附件以同一个删除时间戳软删除，
+// 以便恢复时能够精确区分“随活动一起删除”和“此前已被单独删除”的关联记录
+func (h *ActivityHandler) softDeleteActivity(tx *gorm.DB, activityID string, deletedAt time.Time) error {
+	relations := []interface{}{
+		&models.ActivityParticipant{},
+		&models.Application{},
+		&models.Attachment{},
 	}
+	for _, model := range relations {
+		if err := tx.Model(model).
+			Where("activity_id = ? AND deleted_at IS NULL", activityID).
+			Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.CreditActivity{}).
+		Where("id = ? AND deleted_at IS NULL", activityID).
+		Update("deleted_at", deletedAt).Error
+}
 
-	if err := tx.Where("activity_id = ?", activityID).Delete(&models.ActivityParticipant{}).Error; err != nil {
-		return nil, err
+// restoreActivity 撤销 softDeleteActivity，只恢复与活动删除时间戳一致的关联记录
+func (h *ActivityHandler) restoreActivity(tx *gorm.DB, activityID string, deletedAt time.Time) error {
+	if err := tx.Unscoped().Model(&models.CreditActivity{}).
+		Where("id = ? AND deleted_at = ?", activityID, deletedAt).
+		Update("deleted_at", nil).Error; err != nil {
+		return err
 	}
-	if err := tx.Where("activity_id = ?", activityID).Delete(&models.Application{}).Error; err != nil {
-		return nil, err
+
+	relations := []interface{}{
+		&models.ActivityParticipant{},
+		&models.Application{},
+		&models.Attachment{},
 	}
-	if err := tx.Where("activity_id = ?", activityID).Delete(&models.Attachment{}).Error; err != nil {
-		return nil, err
+	for _, model := range relations {
+		if err := tx.Unscoped().Model(model).
+			Where("activity_id = ? AND deleted_at = ?", activityID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
 	}
 
-	return attachments, nil
+	return nil
+}
+
+// isAttachmentFileReferenced 判断物理文件是否仍被其他附件记录引用。
+// 回收站中活动的附件同样视为引用，保证宽限期内仍可恢复。
+func isAttachmentFileReferenced(db *gorm.DB, fileName, excludeID string) (bool, error) {
+	var count int64
+	err := db.Unscoped().Model(&models.Attachment{}).
+		Where("file_name = ? AND id <> ?", fileName, excludeID).
+		Where("deleted_at IS NULL OR activity_id IN (SELECT id FROM credit_activities WHERE deleted_at IS NOT NULL)").
+		Count(&count).Error
+	return count > 0, err
 }
 
+// cleanupAttachmentFiles 删除已被彻底清除的附件所对应的物理文件
 func (h *ActivityHandler) cleanupAttachmentFiles(attachments []models.Attachment) {
 	for _, attachment := range attachments {
-		if attachment.MD5Hash == "" {
-			continue
-		}
-
-		var otherCount int64
-		if err := h.db.Model(&models.Attachment{}).
-			Where("md5_hash = ? AND deleted_at IS NULL", attachment.MD5Hash).
-			Count(&otherCount).Error; err != nil {
-			continue
-		}
-
-		if otherCount > 0 {
+		referenced, err := isAttachmentFileReferenced(h.db, attachment.FileName, attachment.ID)
+		if err != nil || referenced {
 			continue
 		}
 
-		filePath := filepath.Join("uploads/attachments", attachment.FileName)
-		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		if err := h.files.Delete(context.Background(), attachmentKey(attachment.FileName)); err != nil {
 			fmt.Printf("删除物理文件失败: %v\n", err)
 		}
 	}
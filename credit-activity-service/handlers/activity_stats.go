@@ -15,9 +15,15 @@ func (h *ActivityHandler) GetActivityStats(c *gin.Context) {
 
 	var stats models.ActivityStats
 
-	// 构建基础查询构建函数，应用权限过滤
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	// 构建基础查询构建函数，应用权限过滤和数据范围
 	buildActivityQuery := func() *gorm.DB {
-		query := h.db.Model(&models.CreditActivity{})
+		query := scope.Activities(h.db.Model(&models.CreditActivity{}))
 		// 权限过滤：学生只能看到自己拥有或参与的活动
 		if userType == "student" && userID != "" {
 			query = query.Where("owner_id = ? OR id IN (SELECT activity_id FROM activity_participants WHERE user_id = ? AND deleted_at IS NULL)", userID, userID)
@@ -34,7 +40,7 @@ func (h *ActivityHandler) GetActivityStats(c *gin.Context) {
 
 	// 构建参与者查询构建函数
 	buildParticipantQuery := func() *gorm.DB {
-		query := h.db.Model(&models.ActivityParticipant{})
+		query := scope.ActivityIDs(h.db.Model(&models.ActivityParticipant{}), "activity_id")
 		if userType == "student" && userID != "" {
 			// 学生只能看到他们参与的活动中的参与者统计
 			query = query.Where("activity_id IN (SELECT id FROM credit_activities WHERE (owner_id = ? OR id IN (SELECT activity_id FROM activity_participants WHERE user_id = ? AND deleted_at IS NULL)) AND deleted_at IS NULL)", userID, userID)
@@ -123,15 +129,21 @@ func (h *ActivityHandler) GetActivityReport(c *gin.Context) {
 		start = end.AddDate(0, 0, -30)
 	}
 
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
 	var report interface{}
 
 	switch reportType {
 	case "monthly":
-		report = h.generateMonthlyReport(start, end)
+		report = h.generateMonthlyReport(scope, start, end)
 	case "category":
-		report = h.generateCategoryReport(start, end)
+		report = h.generateCategoryReport(scope, start, end)
 	case "status":
-		report = h.generateStatusReport(start, end)
+		report = h.generateStatusReport(scope, start, end)
 	default:
 		utils.SendBadRequest(c, "不支持的报表类型")
 		return
@@ -141,8 +153,9 @@ func (h *ActivityHandler) GetActivityReport(c *gin.Context) {
 }
 
 // generateMonthlyReport 生成月度报表
-func (h *ActivityHandler) generateMonthlyReport(start, end time.Time) map[string]interface{} {
+func (h *ActivityHandler) generateMonthlyReport(scope *utils.DataScope, start, end time.Time) map[string]interface{} {
 	var result []map[string]interface{}
+	cond, args := scope.ActivityCondition("")
 
 	// 按月份统计活动数量
 	rows, err := h.db.Raw(`
@@ -153,10 +166,10 @@ func (h *ActivityHandler) generateMonthlyReport(start, end time.Time) map[string
 			COUNT(CASE WHEN status = 'pending_review' THEN 1 END) as pending_activities,
 			COUNT(CASE WHEN status = 'rejected' THEN 1 END) as rejected_activities
 		FROM credit_activities 
-		WHERE created_at BETWEEN ? AND ?
+		WHERE created_at BETWEEN ? AND ? AND `+cond+`
 		GROUP BY DATE_TRUNC('month', created_at)
 		ORDER BY month
-	`, start, end).Rows()
+	`, append([]interface{}{start, end}, args...)...).Rows()
 
 	if err == nil {
 		defer rows.Close()
@@ -183,8 +196,9 @@ func (h *ActivityHandler) generateMonthlyReport(start, end time.Time) map[string
 }
 
 // generateCategoryReport 生成分类报表
-func (h *ActivityHandler) generateCategoryReport(start, end time.Time) map[string]interface{} {
+func (h *ActivityHandler) generateCategoryReport(scope *utils.DataScope, start, end time.Time) map[string]interface{} {
 	var result []map[string]interface{}
+	cond, args := scope.ActivityCondition("")
 
 	rows, err := h.db.Raw(`
 		SELECT 
@@ -193,10 +207,10 @@ func (h *ActivityHandler) generateCategoryReport(start, end time.Time) map[strin
 			COUNT(CASE WHEN status = 'approved' THEN 1 END) as approved_activities,
 			AVG(EXTRACT(EPOCH FROM (end_date - start_date))/86400) as avg_duration_days
 		FROM credit_activities 
-		WHERE created_at BETWEEN ? AND ?
+		WHERE created_at BETWEEN ? AND ? AND `+cond+`
 		GROUP BY category
 		ORDER BY total_activities DESC
-	`, start, end).Rows()
+	`, append([]interface{}{start, end}, args...)...).Rows()
 
 	if err == nil {
 		defer rows.Close()
@@ -223,8 +237,9 @@ func (h *ActivityHandler) generateCategoryReport(start, end time.Time) map[strin
 }
 
 // generateStatusReport 生成状态报表
-func (h *ActivityHandler) generateStatusReport(start, end time.Time) map[string]interface{} {
+func (h *ActivityHandler) generateStatusReport(scope *utils.DataScope, start, end time.Time) map[string]interface{} {
 	var result []map[string]interface{}
+	cond, args := scope.ActivityCondition("")
 
 	rows, err := h.db.Raw(`
 		SELECT 
@@ -232,10 +247,10 @@ func (h *ActivityHandler) generateStatusReport(start, end time.Time) map[string]
 			COUNT(*) as count,
 			COUNT(CASE WHEN created_at >= NOW() - INTERVAL '7 days' THEN 1 END) as recent_count
 		FROM credit_activities 
-		WHERE created_at BETWEEN ? AND ?
+		WHERE created_at BETWEEN ? AND ? AND `+cond+`
 		GROUP BY status
 		ORDER BY count DESC
-	`, start, end).Rows()
+	`, append([]interface{}{start, end}, args...)...).Rows()
 
 	if err == nil {
 		defer rows.Close()
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// trashPurgeBatchSize 单次清理任务处理的活动数量上限
+const trashPurgeBatchSize = 100
+
+// GetDeletedActivities 回收站列表：管理员可见全部，其他用户仅可见自己创建或共同所有的活动
+func (h *ActivityHandler) GetDeletedActivities(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+
+	page, limit, _ := h.validator.ValidatePagination(
+		c.DefaultQuery("page", "1"),
+		c.DefaultQuery("page_size", c.DefaultQuery("limit", "10")),
+	)
+
+	query := h.db.Unscoped().Model(&models.CreditActivity{}).Where("deleted_at IS NOT NULL")
+	// 非管理员只能看到自己创建或作为共同所有者（owner 协作者）的活动，与删除活动的权限一致
+	if userType != "admin" {
+		query = query.Where(
+			"owner_id = ? OR id IN (SELECT activity_id FROM activity_collaborators WHERE user_id = ? AND role = ? AND deleted_at IS NULL)",
+			userID, userID, models.CollaboratorRoleOwner,
+		)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var activities []models.CreditActivity
+	if err := query.Order("deleted_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&activities).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	retention := utils.TrashRetention()
+	responses := make([]models.DeletedActivityResponse, 0, len(activities))
+	for _, a := range activities {
+		responses = append(responses, models.DeletedActivityResponse{
+			ID:        a.ID,
+			Title:     a.Title,
+			Status:    a.Status,
+			Category:  a.Category,
+			OwnerID:   a.OwnerID,
+			CreatedAt: a.CreatedAt,
+			DeletedAt: a.DeletedAt.Time,
+			PurgeAt:   a.DeletedAt.Time.Add(retention),
+		})
+	}
+
+	utils.SendPaginatedResponse(c, responses, total, page, limit)
+}
+
+// RestoreActivity 从回收站恢复活动及随其一起删除的参与者、申请和附件
+func (h *ActivityHandler) RestoreActivity(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.validator.ValidateUUID(id); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+
+	var activity models.CreditActivity
+	if err := h.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&activity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "回收站中不存在该活动")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+
+	if userType != "admin" && utils.GetActivityRole(h.db, &activity, userID) != models.CollaboratorRoleOwner {
+		utils.SendForbidden(c, "无权限恢复该活动")
+		return
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := h.restoreActivity(tx, activity.ID, activity.DeletedAt.Time); err != nil {
+		tx.Rollback()
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	restored, err := h.base.GetActivityByID(activity.ID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	response := h.enrichActivityResponse(*restored)
+	utils.SendSuccessResponse(c, response)
+}
+
+// PurgeExpiredTrash 彻底删除超过保留期的活动及其关联记录，并清理不再被引用的物理文件
+func (h *ActivityHandler) PurgeExpiredTrash(retention time.Duration) (int, error) {
+	cutoff := time.Now().Add(-retention)
+
+	var activities []models.CreditActivity
+	if err := h.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at ASC").
+		Limit(trashPurgeBatchSize).
+		Find(&activities).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, activity := range activities {
+		var attachments []models.Attachment
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Where("activity_id = ?", activity.ID).Find(&attachments).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("activity_id = ?", activity.ID).Delete(&models.Attachment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("activity_id = ?", activity.ID).Delete(&models.Application{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("activity_id = ?", activity.ID).Delete(&models.ActivityParticipant{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("activity_id = ?", activity.ID).Delete(&models.ActivityCollaborator{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.CreditActivity{ID: activity.ID}).Error
+		})
+		if err != nil {
+			log.Printf("PurgeExpiredTrash: failed to purge activity %s: %v", activity.ID, err)
+			continue
+		}
+
+		h.cleanupAttachmentFiles(attachments)
+		purged++
+	}
+
+	return purged, nil
+}
@@ -10,7 +10,10 @@ import (
 	"time"
 
 	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/realtime"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/shared/audit"
+	"credit-management/shared/imports"
 
 	"mime/multipart"
 
@@ -58,7 +61,7 @@ func (h *ActivityHandler) CopyActivity(c *gin.Context) {
 		return
 	}
 
-	response := h.enrichActivityResponse(newActivity, "")
+	response := h.enrichActivityResponse(newActivity)
 	utils.SendCreatedResponse(c, "活动复制成功", response)
 }
 
@@ -103,7 +106,13 @@ func (h *ActivityHandler) ExportActivities(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
-	dbQuery := h.db.Model(&models.CreditActivity{})
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	dbQuery := scope.Activities(h.db.Model(&models.CreditActivity{}))
 
 	if category != "" {
 		dbQuery = dbQuery.Where("category = ?", category)
@@ -417,28 +426,32 @@ func (h *ActivityHandler) parseExcelFile(file *multipart.FileHeader) ([][]string
 	return records, nil
 }
 
+// importService 导入任务表中本服务任务的 service 标识
+const importService = "credit-activity-service"
+
+// importKindActivities 活动批量导入任务
+const importKindActivities = "activities"
+
+// processImportData 校验标题行后创建导入任务并立即返回任务 ID，数据行由后台任务分批处理
 func (h *ActivityHandler) processImportData(c *gin.Context, records [][]string, userID string, fileName string) {
 	if len(records) < 2 {
 		utils.SendBadRequest(c, "文件至少需要包含标题行和一行数据")
 		return
 	}
 
-	if len(records) > 1001 { // 标题行 + 1000行数据
-		utils.SendBadRequest(c, "文件最多支持1000行数据")
-		return
+	// 列名不区分大小写，统一为小写后保存
+	headers := make([]string, len(records[0]))
+	headerMap := make(map[string]bool)
+	for i, header := range records[0] {
+		headers[i] = strings.ToLower(strings.TrimSpace(header))
+		headerMap[headers[i]] = true
 	}
+	records[0] = headers
 
-	headers := records[0]
 	expectedHeaders := []string{"title", "description", "start_date", "end_date", "category"}
-
-	headerMap := make(map[string]int)
-	for i, header := range headers {
-		headerMap[strings.ToLower(strings.TrimSpace(header))] = i
-	}
-
 	missingHeaders := []string{}
 	for _, expected := range expectedHeaders {
-		if _, exists := headerMap[expected]; !exists {
+		if !headerMap[expected] {
 			missingHeaders = append(missingHeaders, expected)
 		}
 	}
@@ -448,109 +461,70 @@ func (h *ActivityHandler) processImportData(c *gin.Context, records [][]string,
 		return
 	}
 
-	var activities []models.ActivityRequest
-	var errors []string
-
-	for i, record := range records[1:] {
-		rowNum := i + 2
-
-		if len(record) < len(headers) {
-			errors = append(errors, fmt.Sprintf("第%d行: 列数不匹配", rowNum))
-			continue
-		}
-
-		activity := models.ActivityRequest{
-			Title:       strings.TrimSpace(record[headerMap["title"]]),
-			Description: strings.TrimSpace(record[headerMap["description"]]),
-			StartDate:   strings.TrimSpace(record[headerMap["start_date"]]),
-			EndDate:     strings.TrimSpace(record[headerMap["end_date"]]),
-			Category:    strings.TrimSpace(record[headerMap["category"]]),
-		}
-
-		if err := h.validateActivityRequest(activity); err != nil {
-			errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-			continue
-		}
-
-		activities = append(activities, activity)
+	job := models.ImportJob{
+		Service:   importService,
+		Kind:      importKindActivities,
+		CreatedBy: userID,
+		FileName:  fileName,
 	}
-
-	if len(errors) > 0 {
-		utils.SendBadRequestWithData(c, "数据验证失败", gin.H{
-			"errors":       errors,
-			"total_rows":   len(records) - 1,
-			"valid_rows":   len(activities),
-			"invalid_rows": len(errors),
-		})
+	if err := imports.Submit(h.db, &job, records); err != nil {
+		utils.SendInternalServerError(c, err)
 		return
 	}
 
-	var createdActivities []models.ActivityCreateResponse
-	var createErrors []string
-
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	for i, activityReq := range activities {
-		startDate, endDate, err := utils.ParseDateRange(activityReq.StartDate, activityReq.EndDate)
-		if err != nil {
-			createErrors = append(createErrors, fmt.Sprintf("第%d个活动: %s", i+1, err.Error()))
-			continue
-		}
-
-		activity := models.CreditActivity{
-			Title:       activityReq.Title,
-			Description: activityReq.Description,
-			StartDate:   startDate,
-			EndDate:     endDate,
-			Status:      models.StatusDraft,
-			Category:    activityReq.Category,
-			OwnerID:     userID,
-		}
-
-		if err := tx.Create(&activity).Error; err != nil {
-			createErrors = append(createErrors, fmt.Sprintf("第%d个活动创建失败: %s", i+1, err.Error()))
-			continue
-		}
+	realtime.NotifyImportProgress(job)
+	audit.Record(c, audit.Entry{
+		Action:       "activities.import",
+		ResourceType: "import_job",
+		ResourceID:   job.ID,
+		After:        gin.H{"file_name": fileName, "total_rows": job.TotalRows},
+	})
+	utils.SendAcceptedResponse(c, "导入任务已创建", job)
+}
 
-		response := models.ActivityCreateResponse{
-			ID:          activity.ID,
-			Title:       activity.Title,
-			Description: activity.Description,
-			Category:    activity.Category,
-			Status:      activity.Status,
-			CreatedAt:   activity.CreatedAt,
-		}
+// NewImportRunner 创建处理活动导入任务的后台执行器，每批提交后向发起人推送进度
+func (h *ActivityHandler) NewImportRunner(interval time.Duration, batchSize int) *imports.Runner {
+	runner := imports.NewRunner(h.db, importService, interval, batchSize)
+	runner.Register(importKindActivities, h.importActivityRow)
+	runner.OnProgress = realtime.NotifyImportProgress
+	return runner
+}
 
-		createdActivities = append(createdActivities, response)
+// importActivityRow 校验并创建导入文件中的一个活动（草稿状态，创建者为任务提交人）
+func (h *ActivityHandler) importActivityRow(tx *gorm.DB, job *models.ImportJob, headerMap map[string]int, record []string) error {
+	if len(record) < len(job.Header) {
+		return fmt.Errorf("列数不匹配")
 	}
 
-	if len(createErrors) > 0 {
-		tx.Rollback()
-		utils.SendBadRequestWithData(c, "部分活动创建失败", gin.H{
-			"errors":           createErrors,
-			"total_activities": len(activities),
-			"created":          len(createdActivities),
-			"failed":           len(createErrors),
-		})
-		return
+	activityReq := models.ActivityRequest{
+		Title:       strings.TrimSpace(record[headerMap["title"]]),
+		Description: strings.TrimSpace(record[headerMap["description"]]),
+		StartDate:   strings.TrimSpace(record[headerMap["start_date"]]),
+		EndDate:     strings.TrimSpace(record[headerMap["end_date"]]),
+		Category:    strings.TrimSpace(record[headerMap["category"]]),
+	}
+	if err := h.validateActivityRequest(activityReq); err != nil {
+		return err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		utils.SendInternalServerError(c, err)
-		return
+	startDate, endDate, err := utils.ParseDateRange(activityReq.StartDate, activityReq.EndDate)
+	if err != nil {
+		return err
 	}
 
-	utils.SendSuccessResponse(c, gin.H{
-		"message":            "批量导入成功",
-		"total_activities":   len(activities),
-		"created_activities": createdActivities,
-		"file_name":          fileName,
-	})
+	activity := models.CreditActivity{
+		Title:       activityReq.Title,
+		Description: activityReq.Description,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Status:      models.StatusDraft,
+		Category:    activityReq.Category,
+		OwnerID:     job.CreatedBy,
+	}
+	if err := tx.Create(&activity).Error; err != nil {
+		return fmt.Errorf("活动创建失败: %s", err.Error())
+	}
+	return nil
 }
 
 func (h *ActivityHandler) GetCSVTemplate(c *gin.Context) {
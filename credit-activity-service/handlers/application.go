@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 
 	"credit-management/credit-activity-service/models"
@@ -36,14 +37,22 @@ func (h *ApplicationHandler) GetUserApplications(c *gin.Context) {
 		c.DefaultQuery("page", "1"),
 		c.DefaultQuery("page_size", "10"),
 	)
+	pager, err := utils.NewPager(c, page, limit)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 
 	// 必须显式指定 Model，否则 GORM 无法推断表名，会报 "Table not set" 错误
 	applications, total, err := h.getApplicationsWithPagination(
 		h.db.Model(&models.Application{}).Where("user_id = ?", userID),
 		status,
-		page,
-		limit,
+		pager,
 	)
+	if errors.Is(err, utils.ErrCursorMismatch) {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 	if err != nil {
 		log.Printf("[GetUserApplications] query error: %+v", err)
 		utils.SendInternalServerError(c, err)
@@ -52,7 +61,7 @@ func (h *ApplicationHandler) GetUserApplications(c *gin.Context) {
 
 	// 获取用户信息（学生查看自己的申请，用户信息应该相同）
 	responses := h.buildApplicationResponsesWithUserInfo(applications, c.GetHeader("Authorization"))
-	utils.SendPaginatedResponse(c, responses, total, page, limit)
+	pager.Send(c, responses, total)
 }
 
 func (h *ApplicationHandler) GetApplication(c *gin.Context) {
@@ -85,9 +94,25 @@ func (h *ApplicationHandler) GetApplication(c *gin.Context) {
 		return
 	}
 
+	// 范围外活动的申请与申请不存在一样返回 404
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	allowed, err := scope.AllowsActivity(h.db, application.ActivityID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if !allowed {
+		utils.SendNotFound(c, "申请不存在")
+		return
+	}
+
 	response := h.buildApplicationResponse(application, c.GetHeader("Authorization"))
 	// 获取用户信息
-	if userInfo, err := utils.GetUserInfo(application.UUID, c.GetHeader("Authorization")); err == nil {
+	if userInfo, err := utils.GetUserInfo(application.UUID); err == nil {
 		response.UserInfo = userInfo
 	} else {
 		log.Printf("[GetApplication] failed to get user info for user_id=%s: %v", application.UUID, err)
@@ -102,8 +127,19 @@ func (h *ApplicationHandler) GetAllApplications(c *gin.Context) {
 		c.DefaultQuery("page", "1"),
 		c.DefaultQuery("page_size", "10"),
 	)
+	pager, err := utils.NewPager(c, page, limit)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 
-	query := h.db.Model(&models.Application{})
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	query := scope.Users(h.db.Model(&models.Application{}), "user_id")
 	if activityID != "" {
 		query = query.Where("activity_id = ?", activityID)
 	}
@@ -112,7 +148,11 @@ func (h *ApplicationHandler) GetAllApplications(c *gin.Context) {
 		query = query.Where("user_id = ?", userID)
 	}
 
-	applications, total, err := h.getApplicationsWithPagination(query, "", page, limit)
+	applications, total, err := h.getApplicationsWithPagination(query, "", pager)
+	if errors.Is(err, utils.ErrCursorMismatch) {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
@@ -120,10 +160,13 @@ func (h *ApplicationHandler) GetAllApplications(c *gin.Context) {
 
 	// 获取所有申请的用户信息
 	responses := h.buildApplicationResponsesWithUserInfo(applications, c.GetHeader("Authorization"))
-	utils.SendPaginatedResponse(c, responses, total, page, limit)
+	pager.Send(c, responses, total)
 }
 
-func (h *ApplicationHandler) getApplicationsWithPagination(query *gorm.DB, status string, page, limit int) ([]models.Application, int64, error) {
+// applicationCreatedAtKey 申请列表按创建时间倒序，时间相同时按 id 排序
+var applicationCreatedAtKey = utils.SortKey{Column: "created_at", IDColumn: "id", Desc: true}
+
+func (h *ApplicationHandler) getApplicationsWithPagination(query *gorm.DB, status string, pager *utils.Pager) ([]models.Application, int64, error) {
 	// 确保始终设置了 Model，避免出现 "Table not set" 的 GORM 错误
 	query = query.Model(&models.Application{})
 
@@ -131,19 +174,24 @@ func (h *ApplicationHandler) getApplicationsWithPagination(query *gorm.DB, statu
 		query = query.Where("status = ?", status)
 	}
 
-	var total int64
-	query.Count(&total)
+	total, err := pager.Count(query)
+	if err != nil {
+		return nil, 0, err
+	}
 
+	query, err = pager.Apply(query.Preload("Activity"), applicationCreatedAtKey)
+	if err != nil {
+		return nil, 0, err
+	}
 	var applications []models.Application
-	offset := (page - 1) * limit
-	err := query.
-		Preload("Activity").
-		Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&applications).Error
-
-	return applications, total, err
+	if err := query.Find(&applications).Error; err != nil {
+		return nil, 0, err
+	}
+	applications = utils.PageRows(pager, applications, applicationCreatedAtKey, func(a models.Application) (interface{}, string) {
+		return a.CreatedAt, a.ID
+	})
+
+	return applications, total, nil
 }
 
 func (h *ApplicationHandler) buildApplicationResponses(applications []models.Application, authToken string) []models.ApplicationResponse {
@@ -155,15 +203,16 @@ func (h *ApplicationHandler) buildApplicationResponses(applications []models.App
 }
 
 func (h *ApplicationHandler) buildApplicationResponsesWithUserInfo(applications []models.Application, authToken string) []models.ApplicationResponse {
+	userIDs := make([]string, 0, len(applications))
+	for _, app := range applications {
+		userIDs = append(userIDs, app.UUID)
+	}
+	users := lookupUsers(h.db, userIDs)
+
 	responses := make([]models.ApplicationResponse, 0, len(applications))
 	for _, app := range applications {
 		response := h.buildApplicationResponse(app, authToken)
-		// 获取用户信息
-		if userInfo, err := utils.GetUserInfo(app.UUID, authToken); err == nil {
-			response.UserInfo = userInfo
-		} else {
-			log.Printf("[buildApplicationResponsesWithUserInfo] failed to get user info for user_id=%s: %v", app.UUID, err)
-		}
+		response.UserInfo = users[app.UUID]
 		responses = append(responses, response)
 	}
 	return responses
@@ -222,7 +271,13 @@ func (h *ApplicationHandler) ExportApplications(c *gin.Context) {
 	activityID := c.Query("activity_id")
 	userID := c.Query("id")
 
-	query := h.db.Model(&models.Application{}).Preload("Activity")
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	query := scope.Users(h.db.Model(&models.Application{}).Preload("Activity"), "user_id")
 	if activityID != "" {
 		query = query.Where("activity_id = ?", activityID)
 	}
@@ -1,19 +1,20 @@
 package handlers
 
 import (
+	"bytes"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"credit-management/credit-activity-service/models"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/shared/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -21,12 +22,28 @@ import (
 
 // AttachmentHandler 附件处理器
 type AttachmentHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	files storage.Storage
 }
 
-// NewAttachmentHandler 创建附件处理器
-func NewAttachmentHandler(db *gorm.DB) *AttachmentHandler {
-	return &AttachmentHandler{db: db}
+// NewAttachmentHandler 创建附件处理器，附件文件保存在 files 中
+func NewAttachmentHandler(db *gorm.DB, files storage.Storage) *AttachmentHandler {
+	return &AttachmentHandler{db: db, files: files}
+}
+
+// attachmentKey 附件文件在存储中的 key
+func attachmentKey(fileName string) string {
+	return storage.Key("attachments", fileName)
+}
+
+// previewableTypes 支持在浏览器中预览的文件类型
+var previewableTypes = map[string]bool{
+	".pdf":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".txt":  true,
 }
 
 func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
@@ -79,7 +96,11 @@ func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
 	categoryCount := make(map[string]int64)
 	fileTypeCount := make(map[string]int64)
 
-	authToken := c.GetHeader("Authorization")
+	uploaderIDs := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		uploaderIDs = append(uploaderIDs, attachment.UploadedBy)
+	}
+	uploaders := lookupUsers(h.db, uploaderIDs)
 
 	for _, attachment := range attachments {
 		response := models.AttachmentResponse{
@@ -97,7 +118,7 @@ func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
 			DownloadURL:   fmt.Sprintf("/api/activities/%s/attachments/%s/download", activityID, attachment.ID),
 		}
 
-		if userInfo, err := utils.GetUserInfo(attachment.UploadedBy, authToken); err == nil {
+		if userInfo, ok := uploaders[attachment.UploadedBy]; ok {
 			response.Uploader = *userInfo
 		}
 
@@ -169,19 +190,16 @@ func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
 
 	fileExt := filepath.Ext(header.Filename)
 
-	// 创建存储目录
-	uploadDir := "uploads/attachments"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		utils.SendInternalServerError(c, err)
-		return
-	}
-
-	md5FilePath := filepath.Join(uploadDir, md5Hash+fileExt)
-	if _, err := os.Stat(md5FilePath); os.IsNotExist(err) {
-		if err := os.WriteFile(md5FilePath, fileBytes, 0644); err != nil {
+	// 相同内容的文件只保存一份
+	key := attachmentKey(md5Hash + fileExt)
+	if _, err := h.files.Stat(c.Request.Context(), key); errors.Is(err, storage.ErrNotExist) {
+		if err := h.files.Put(c.Request.Context(), key, bytes.NewReader(fileBytes), int64(len(fileBytes)), storage.ContentTypeFor(fileExt)); err != nil {
 			utils.SendInternalServerError(c, err)
 			return
 		}
+	} else if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
 	}
 
 	attachment := models.Attachment{
@@ -260,12 +278,6 @@ func (h *AttachmentHandler) BatchUploadAttachments(c *gin.Context) {
 		return
 	}
 
-	uploadDir := "uploads/attachments"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		utils.SendInternalServerError(c, err)
-		return
-	}
-
 	var results []models.BatchUploadResult
 	successCount := 0
 	failCount := 0
@@ -330,8 +342,8 @@ func (h *AttachmentHandler) BatchUploadAttachments(c *gin.Context) {
 		fileName := fmt.Sprintf("%s_%d%s", md5Hash[:8], time.Now().UnixNano(), fileExt)
 
 		// 保存文件
-		filePath := filepath.Join(uploadDir, fileName)
-		if err := os.WriteFile(filePath, fileBytes, 0644); err != nil {
+		key := attachmentKey(fileName)
+		if err := h.files.Put(c.Request.Context(), key, bytes.NewReader(fileBytes), int64(len(fileBytes)), storage.ContentTypeFor(fileExt)); err != nil {
 			result.Status = "failed"
 			result.Message = "保存文件失败: " + err.Error()
 			results = append(results, result)
@@ -355,7 +367,7 @@ func (h *AttachmentHandler) BatchUploadAttachments(c *gin.Context) {
 
 		if err := tx.Create(&attachment).Error; err != nil {
 			// 删除已保存的文件
-			os.Remove(filePath)
+			h.files.Delete(c.Request.Context(), key)
 			result.Status = "failed"
 			result.Message = "创建附件记录失败: " + err.Error()
 			results = append(results, result)
@@ -422,46 +434,20 @@ func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
 		return
 	}
 
-	filePath := filepath.Join("uploads/attachments", attachment.FileName)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		utils.SendNotFound(c, "文件不存在")
+	reader, info, err := h.files.Open(c.Request.Context(), attachmentKey(attachment.FileName))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			utils.SendNotFound(c, "文件不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
 		return
 	}
+	defer reader.Close()
 
 	h.db.Model(&attachment).Update("download_count", attachment.DownloadCount+1)
 
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", attachment.OriginalName))
-
-	contentType := "application/octet-stream"
-	if attachment.FileType != "" {
-		switch strings.ToLower(attachment.FileType) {
-		case ".pdf":
-			contentType = "application/pdf"
-		case ".doc", ".docx":
-			contentType = "application/msword"
-		case ".xls", ".xlsx":
-			contentType = "application/vnd.ms-excel"
-		case ".ppt", ".pptx":
-			contentType = "application/vnd.ms-powerpoint"
-		case ".txt":
-			contentType = "text/plain"
-		case ".jpg", ".jpeg":
-			contentType = "image/jpeg"
-		case ".png":
-			contentType = "image/png"
-		case ".gif":
-			contentType = "image/gif"
-		case ".zip":
-			contentType = "application/zip"
-		case ".rar":
-			contentType = "application/x-rar-compressed"
-		}
-	}
-
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", strconv.FormatInt(attachment.FileSize, 10))
-
-	c.File(filePath)
+	serveAttachment(c, reader, info, attachment, false)
 }
 
 func (h *AttachmentHandler) PreviewAttachment(c *gin.Context) {
@@ -499,47 +485,115 @@ func (h *AttachmentHandler) PreviewAttachment(c *gin.Context) {
 		return
 	}
 
-	filePath := filepath.Join("uploads/attachments", attachment.FileName)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		utils.SendNotFound(c, "文件不存在")
+	// 检查文件类型是否支持预览
+	if !previewableTypes[strings.ToLower(attachment.FileType)] {
+		utils.SendBadRequest(c, "不支持预览此类型的文件")
 		return
 	}
 
-	// 检查文件类型是否支持预览
-	previewableTypes := map[string]bool{
-		".pdf":  true,
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
-		".txt":  true,
+	reader, info, err := h.files.Open(c.Request.Context(), attachmentKey(attachment.FileName))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			utils.SendNotFound(c, "文件不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
 	}
+	defer reader.Close()
 
-	if !previewableTypes[strings.ToLower(attachment.FileType)] {
+	c.Header("Cache-Control", "public, max-age=3600") // 缓存1小时
+	serveAttachment(c, reader, info, attachment, true)
+}
+
+// GetAttachmentDownloadURL 获取附件的下载地址。对象存储后端返回有效期内可直接访问的预签名 URL，
+// 文件不经服务转发，因此在发放地址时计入下载次数；本地目录后端返回经服务下载的地址。
+// disposition=inline 时返回用于预览的地址
+func (h *AttachmentHandler) GetAttachmentDownloadURL(c *gin.Context) {
+	activityID := c.Param("id")
+	attachmentID := c.Param("attachment_id")
+
+	if activityID == "" || attachmentID == "" {
+		utils.SendBadRequest(c, "活动ID或附件ID不能为空")
+		return
+	}
+
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	var attachment models.Attachment
+	if err := h.db.Where("id = ? AND activity_id = ? AND deleted_at IS NULL", attachmentID, activityID).First(&attachment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "附件不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+
+	inline := c.Query("disposition") == "inline"
+	if inline && !previewableTypes[strings.ToLower(attachment.FileType)] {
 		utils.SendBadRequest(c, "不支持预览此类型的文件")
 		return
 	}
 
-	// 设置适当的Content-Type
-	contentType := "application/octet-stream"
-	switch strings.ToLower(attachment.FileType) {
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".jpg", ".jpeg":
-		contentType = "image/jpeg"
-	case ".png":
-		contentType = "image/png"
-	case ".gif":
-		contentType = "image/gif"
-	case ".txt":
-		contentType = "text/plain"
+	key := attachmentKey(attachment.FileName)
+	if _, err := h.files.Stat(c.Request.Context(), key); err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			utils.SendNotFound(c, "文件不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+
+	ttl := utils.DownloadURLTTL()
+	downloadURL, err := h.files.PresignGet(c.Request.Context(), key, ttl, storage.DownloadOptions{
+		FileName:    attachment.OriginalName,
+		ContentType: storage.ContentTypeFor(attachment.FileType),
+		Inline:      inline,
+	})
+	if errors.Is(err, storage.ErrPresignUnsupported) {
+		action := "download"
+		if inline {
+			action = "preview"
+		}
+		utils.SendSuccessResponse(c, gin.H{
+			"url":        fmt.Sprintf("/api/activities/%s/attachments/%s/%s", activityID, attachment.ID, action),
+			"presigned":  false,
+			"expires_at": nil,
+		})
+		return
 	}
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	if !inline {
+		h.db.Model(&attachment).Update("download_count", attachment.DownloadCount+1)
+	}
+	utils.SendSuccessResponse(c, gin.H{
+		"url":        downloadURL,
+		"presigned":  true,
+		"expires_at": time.Now().Add(ttl),
+	})
+}
 
+// serveAttachment 经服务输出附件内容。本地文件支持 Range 请求，对象存储的内容直接转发
+func serveAttachment(c *gin.Context, reader io.Reader, info storage.ObjectInfo, attachment models.Attachment, inline bool) {
+	contentType := storage.ContentTypeFor(attachment.FileType)
+	c.Header("Content-Disposition", storage.ContentDisposition(attachment.OriginalName, inline))
 	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", strconv.FormatInt(attachment.FileSize, 10))
-	c.Header("Cache-Control", "public, max-age=3600") // 缓存1小时
 
-	c.File(filePath)
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime, seeker)
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, reader, nil)
 }
 
 func (h *AttachmentHandler) UpdateAttachment(c *gin.Context) {
@@ -621,32 +675,34 @@ func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
 		}
 		return
 	}
-	var otherAttachmentsCount int64
-	h.db.Model(&models.Attachment{}).
-		Where("md5_hash = ? AND activity_id != ? AND deleted_at IS NULL", attachment.MD5Hash, activityID).
-		Count(&otherAttachmentsCount)
-
 	if err := h.db.Delete(&attachment).Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
 
-	if otherAttachmentsCount == 0 {
-		filePath := filepath.Join("uploads/attachments", attachment.FileName)
-		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+	// 文件仍被其他附件（含回收站中的活动）引用时保留物理文件
+	referenced, err := isAttachmentFileReferenced(h.db, attachment.FileName, attachment.ID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	if !referenced {
+		key := attachmentKey(attachment.FileName)
+		if err := h.files.Delete(c.Request.Context(), key); err != nil {
 			// 记录错误但不影响响应
 			fmt.Printf("删除物理文件失败: %v\n", err)
 		} else {
-			fmt.Printf("彻底删除物理文件: %s\n", filePath)
+			fmt.Printf("彻底删除物理文件: %s\n", key)
 		}
 	} else {
-		fmt.Printf("文件被其他活动使用，保留物理文件: %s (其他活动数量: %d)\n", attachment.FileName, otherAttachmentsCount)
+		fmt.Printf("文件被其他附件引用，保留物理文件: %s\n", attachment.FileName)
 	}
 
 	utils.SendSuccessResponse(c, gin.H{
 		"attachment_id": attachmentID,
 		"deleted_at":    time.Now(),
-		"file_removed":  otherAttachmentsCount == 0,
+		"file_removed":  !referenced,
 	})
 }
 
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CollaboratorHandler struct {
+	db        *gorm.DB
+	validator *utils.Validator
+}
+
+func NewCollaboratorHandler(db *gorm.DB) *CollaboratorHandler {
+	return &CollaboratorHandler{
+		db:        db,
+		validator: utils.NewValidator(),
+	}
+}
+
+// canEditActivity 教师、管理员、活动创建者以及 owner/editor 协作者可以修改活动
+func canEditActivity(db *gorm.DB, activity *models.CreditActivity, userID, userType string) bool {
+	if userType == "teacher" || userType == "admin" {
+		return true
+	}
+	return utils.CanEditActivity(utils.GetActivityRole(db, activity, userID))
+}
+
+// canManageCollaborators 管理员、活动创建者以及 owner 协作者可以管理协作者
+func canManageCollaborators(db *gorm.DB, activity *models.CreditActivity, userID, userType string) bool {
+	if userType == "admin" {
+		return true
+	}
+	return utils.CanManageCollaborators(utils.GetActivityRole(db, activity, userID))
+}
+
+func (h *CollaboratorHandler) loadActivity(c *gin.Context) (*models.CreditActivity, bool) {
+	activityID := c.Param("id")
+	if err := h.validator.ValidateUUID(activityID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return nil, false
+	}
+
+	var activity models.CreditActivity
+	if err := h.db.Where("id = ?", activityID).First(&activity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "活动不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return nil, false
+	}
+	return &activity, true
+}
+
+// GetCollaborators 获取活动协作者列表（含创建者）
+func (h *CollaboratorHandler) GetCollaborators(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+
+	activity, ok := h.loadActivity(c)
+	if !ok {
+		return
+	}
+
+	if userType == "student" && utils.GetActivityRole(h.db, activity, userID) == "" {
+		utils.SendForbidden(c, "无权限查看此活动的协作者")
+		return
+	}
+
+	var collaborators []models.ActivityCollaborator
+	if err := h.db.Where("activity_id = ?", activity.ID).Order("created_at ASC").Find(&collaborators).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	responses := make([]models.CollaboratorResponse, 0, len(collaborators)+1)
+
+	owner := models.CollaboratorResponse{
+		UserID:    activity.OwnerID,
+		Role:      models.CollaboratorRoleOwner,
+		InvitedBy: activity.OwnerID,
+		CreatedAt: activity.CreatedAt,
+	}
+	userIDs := []string{activity.OwnerID}
+	for _, collaborator := range collaborators {
+		userIDs = append(userIDs, collaborator.UserID)
+	}
+	users := lookupUsers(h.db, userIDs)
+
+	owner.UserInfo = users[activity.OwnerID]
+	responses = append(responses, owner)
+
+	for _, collaborator := range collaborators {
+		response := models.CollaboratorResponse{
+			UserID:    collaborator.UserID,
+			Role:      collaborator.Role,
+			InvitedBy: collaborator.InvitedBy,
+			CreatedAt: collaborator.CreatedAt,
+		}
+		response.UserInfo = users[collaborator.UserID]
+		responses = append(responses, response)
+	}
+
+	utils.SendSuccessResponse(c, gin.H{
+		"owner_id":      activity.OwnerID,
+		"collaborators": responses,
+	})
+}
+
+// AddCollaborator 邀请协作者，已存在时更新其角色
+func (h *CollaboratorHandler) AddCollaborator(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+
+	var req models.CollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := h.validator.ValidateUUID(req.UserID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	activity, ok := h.loadActivity(c)
+	if !ok {
+		return
+	}
+
+	if !canManageCollaborators(h.db, activity, userID, userType) {
+		utils.SendForbidden(c, "只有活动所有者或管理员可以邀请协作者")
+		return
+	}
+
+	if req.UserID == activity.OwnerID {
+		utils.SendBadRequest(c, "该用户已是活动所有者")
+		return
+	}
+
+	userInfo, err := utils.GetUserInfo(req.UserID)
+	if err != nil {
+		utils.SendBadRequest(c, "用户不存在")
+		return
+	}
+
+	var collaborator models.ActivityCollaborator
+	err = h.db.Where("activity_id = ? AND user_id = ?", activity.ID, req.UserID).First(&collaborator).Error
+	switch {
+	case err == nil:
+		collaborator.Role = req.Role
+		if err := h.db.Model(&collaborator).Update("role", req.Role).Error; err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+	case err == gorm.ErrRecordNotFound:
+		collaborator = models.ActivityCollaborator{
+			ActivityID: activity.ID,
+			UserID:     req.UserID,
+			Role:       req.Role,
+			InvitedBy:  userID,
+		}
+		if err := h.db.Create(&collaborator).Error; err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+	default:
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "协作者添加成功", models.CollaboratorResponse{
+		UserID:    collaborator.UserID,
+		Role:      collaborator.Role,
+		InvitedBy: collaborator.InvitedBy,
+		CreatedAt: collaborator.CreatedAt,
+		UserInfo:  userInfo,
+	})
+}
+
+// UpdateCollaboratorRole 修改协作者角色
+func (h *CollaboratorHandler) UpdateCollaboratorRole(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+
+	var req models.CollaboratorRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	activity, ok := h.loadActivity(c)
+	if !ok {
+		return
+	}
+
+	if !canManageCollaborators(h.db, activity, userID, userType) {
+		utils.SendForbidden(c, "只有活动所有者或管理员可以修改协作者角色")
+		return
+	}
+
+	result := h.db.Model(&models.ActivityCollaborator{}).
+		Where("activity_id = ? AND user_id = ?", activity.ID, c.Param("user_id")).
+		Update("role", req.Role)
+	if result.Error != nil {
+		utils.SendInternalServerError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.SendNotFound(c, "协作者不存在")
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{
+		"user_id": c.Param("user_id"),
+		"role":    req.Role,
+	})
+}
+
+// RemoveCollaborator 移除协作者；协作者也可以主动退出
+func (h *CollaboratorHandler) RemoveCollaborator(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+	targetUserID := c.Param("user_id")
+
+	activity, ok := h.loadActivity(c)
+	if !ok {
+		return
+	}
+
+	if targetUserID != userID && !canManageCollaborators(h.db, activity, userID, userType) {
+		utils.SendForbidden(c, "只有活动所有者或管理员可以移除协作者")
+		return
+	}
+
+	result := h.db.Where("activity_id = ? AND user_id = ?", activity.ID, targetUserID).Delete(&models.ActivityCollaborator{})
+	if result.Error != nil {
+		utils.SendInternalServerError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.SendNotFound(c, "协作者不存在")
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{"message": "协作者移除成功"})
+}
+
+// TransferOwnership 将活动所有权转移给其他用户
+func (h *CollaboratorHandler) TransferOwnership(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
+
+	var req models.TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := h.validator.ValidateUUID(req.NewOwnerID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	activity, ok := h.loadActivity(c)
+	if !ok {
+		return
+	}
+
+	// 仅当前创建者或管理员可以转移所有权，共同所有者不可
+	if userType != "admin" && activity.OwnerID != userID {
+		utils.SendForbidden(c, "只有活动所有者或管理员可以转移所有权")
+		return
+	}
+
+	if req.NewOwnerID == activity.OwnerID {
+		utils.SendBadRequest(c, "新所有者与当前所有者相同")
+		return
+	}
+
+	if _, err := utils.GetUserInfo(req.NewOwnerID); err != nil {
+		utils.SendBadRequest(c, "新所有者不存在")
+		return
+	}
+
+	previousOwnerID := activity.OwnerID
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CreditActivity{}).Where("id = ?", activity.ID).Updates(map[string]interface{}{
+			"owner_id": req.NewOwnerID,
+			"version":  gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+
+		// 新所有者不再作为协作者记录
+		if err := tx.Where("activity_id = ? AND user_id = ?", activity.ID, req.NewOwnerID).
+			Delete(&models.ActivityCollaborator{}).Error; err != nil {
+			return err
+		}
+
+		if req.PreviousOwnerRole == "" {
+			return nil
+		}
+		return tx.Create(&models.ActivityCollaborator{
+			ActivityID: activity.ID,
+			UserID:     previousOwnerID,
+			Role:       req.PreviousOwnerRole,
+			InvitedBy:  userID,
+		}).Error
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{
+		"id":                  activity.ID,
+		"owner_id":            req.NewOwnerID,
+		"previous_owner_id":   previousOwnerID,
+		"previous_owner_role": req.PreviousOwnerRole,
+	})
+}
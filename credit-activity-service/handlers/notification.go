@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	db        *gorm.DB
+	validator *utils.Validator
+}
+
+func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	return &NotificationHandler{
+		db:        db,
+		validator: utils.NewValidator(),
+	}
+}
+
+// GetNotifications 获取当前用户的通知列表，支持 unread_only、category 过滤
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	page, limit, err := h.validator.ValidatePagination(
+		c.DefaultQuery("page", "1"),
+		c.DefaultQuery("limit", "10"),
+	)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	query := h.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread_only") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendPaginatedResponse(c, notifications, total, page, limit)
+}
+
+// GetUnreadCount 获取未读通知数量（总数及各类别数量）
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	var rows []struct {
+		Category string
+		Count    int64
+	}
+	if err := h.db.Model(&models.Notification{}).
+		Select("category, COUNT(*) AS count").
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Group("category").
+		Scan(&rows).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var total int64
+	byCategory := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		byCategory[row.Category] = row.Count
+		total += row.Count
+	}
+
+	utils.SendSuccessResponse(c, gin.H{
+		"unread_count": total,
+		"by_category":  byCategory,
+	})
+}
+
+// MarkNotificationRead 将单条通知标记为已读
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.validator.ValidateUUID(id); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	var notification models.Notification
+	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "通知不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := h.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	utils.SendSuccessResponse(c, notification)
+}
+
+// MarkAllNotificationsRead 将当前用户的未读通知全部标记为已读，可通过 category 限定类别
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	query := h.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.SendInternalServerError(c, result.Error)
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{"updated_count": result.RowsAffected})
+}
+
+// GetNotificationPreferences 获取当前用户各通知类别的屏蔽状态
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	preferences, err := h.loadPreferences(userID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, preferences)
+}
+
+// UpdateNotificationPreferences 以整体替换的方式设置当前用户屏蔽的通知类别
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	var req models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	valid := make(map[string]bool)
+	for _, category := range models.GetNotificationCategories() {
+		valid[category] = true
+	}
+	mutes := make([]models.NotificationMute, 0, len(req.MutedCategories))
+	seen := make(map[string]bool)
+	for _, category := range req.MutedCategories {
+		if !valid[category] {
+			utils.SendBadRequest(c, "无效的通知类别: "+category)
+			return
+		}
+		if seen[category] {
+			continue
+		}
+		seen[category] = true
+		mutes = append(mutes, models.NotificationMute{UserID: userID, Category: category})
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NotificationMute{}).Error; err != nil {
+			return err
+		}
+		if len(mutes) == 0 {
+			return nil
+		}
+		return tx.Create(&mutes).Error
+	}); err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	preferences, err := h.loadPreferences(userID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, preferences)
+}
+
+func (h *NotificationHandler) loadPreferences(userID string) ([]models.NotificationPreference, error) {
+	var muted []string
+	if err := h.db.Model(&models.NotificationMute{}).Where("user_id = ?", userID).Pluck("category", &muted).Error; err != nil {
+		return nil, err
+	}
+	mutedSet := make(map[string]bool, len(muted))
+	for _, category := range muted {
+		mutedSet[category] = true
+	}
+
+	categories := models.GetNotificationCategories()
+	preferences := make([]models.NotificationPreference, 0, len(categories))
+	for _, category := range categories {
+		preferences = append(preferences, models.NotificationPreference{Category: category, Muted: mutedSet[category]})
+	}
+	return preferences, nil
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/outbox"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/shared/audit"
 
 	"log"
 
@@ -28,8 +30,8 @@ func NewParticipantHandler(db *gorm.DB) *ParticipantHandler {
 
 func (h *ParticipantHandler) AddParticipants(c *gin.Context) {
 	activityID := c.Param("id")
-	userID, _ := c.Get("id")
-	userType, _ := c.Get("user_type")
+	userID := c.GetString("id")
+	userType := c.GetString("user_type")
 
 	var req models.AddParticipantsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -59,14 +61,23 @@ func (h *ParticipantHandler) AddParticipants(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID && userType != "admin" && userType != "teacher" {
+	if !canEditActivity(h.db, &activity, userID, userType) {
 		utils.SendForbidden(c, "权限不足，只有活动创建者、教师或管理员可以添加参与者")
 		return
 	}
 
-	authToken := c.GetHeader("Authorization")
+	// 活动结束后报名自动关闭，仅教师和管理员可继续补录参与者
+	if activity.EnrollmentClosedAt != nil && userType == "student" {
+		utils.SendForbidden(c, "活动报名已截止，请联系教师补录参与者")
+		return
+	}
+
+	users, err := utils.GetUsersInfo(req.UUIDs)
+	if err != nil {
+		log.Printf("AddParticipants user lookup failed: %v", err)
+	}
 	for _, targetUserID := range req.UUIDs {
-		if !h.isStudent(targetUserID, authToken) {
+		if user, ok := users[targetUserID]; !ok || user.UserType != "student" {
 			log.Printf("AddParticipants validation failed: targetUserID=%s not a student or user lookup failed", targetUserID)
 			utils.SendBadRequest(c, "只能添加学生用户作为参与者")
 			return
@@ -90,7 +101,17 @@ func (h *ParticipantHandler) AddParticipants(c *gin.Context) {
 			JoinedAt:   time.Now(),
 		}
 
-		if err := h.db.Create(&participant).Error; err != nil {
+		if err := h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&participant).Error; err != nil {
+				return err
+			}
+			return outbox.Record(tx, models.EventParticipantAdded, activityID, userID, map[string]interface{}{
+				"activity_id": activityID,
+				"title":       activity.Title,
+				"user_id":     targetUserID,
+				"credits":     participant.Credits,
+			})
+		}); err != nil {
 			log.Printf("Failed to create participant: activity=%s user=%s err=%v", activityID, targetUserID, err)
 			continue
 		}
@@ -101,14 +122,12 @@ func (h *ParticipantHandler) AddParticipants(c *gin.Context) {
 
 	var responses []models.ParticipantResponse
 	for _, participant := range participants {
-		userInfo, err := h.getUserInfo(participant.UUID, authToken)
-		if err != nil {
-			continue
-		}
+		userInfo := users[participant.UUID]
 
 		response := models.ParticipantResponse{
 			UUID:     participant.UUID,
 			Credits:  participant.Credits,
+			Version:  participant.Version,
 			JoinedAt: participant.JoinedAt,
 			UserInfo: userInfo,
 		}
@@ -124,8 +143,8 @@ func (h *ParticipantHandler) AddParticipants(c *gin.Context) {
 
 func (h *ParticipantHandler) BatchSetCredits(c *gin.Context) {
 	activityID := c.Param("id")
-	userID, _ := c.Get("id")
-	userType, _ := c.Get("user_type")
+	userID := c.GetString("id")
+	userType := c.GetString("user_type")
 
 	var req models.BatchCreditsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -143,34 +162,57 @@ func (h *ParticipantHandler) BatchSetCredits(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID && userType != "admin" && userType != "teacher" {
+	if !canEditActivity(h.db, &activity, userID, userType) {
 		utils.SendForbidden(c, "权限不足，只有活动创建者、教师或管理员可以设置学分")
 		return
 	}
 
-	authToken := c.GetHeader("Authorization")
+	participantIDs := make([]string, 0, len(req.CreditsMap))
+	for participantID := range req.CreditsMap {
+		participantIDs = append(participantIDs, participantID)
+	}
+	users := lookupUsers(h.db, participantIDs)
+
 	var updatedParticipants []models.ParticipantResponse
+	var conflicts []models.ParticipantResponse
 	updatedCount := 0
 
-	for userID, credits := range req.CreditsMap {
+	for participantID, credits := range req.CreditsMap {
 		var participant models.ActivityParticipant
-		if err := h.db.Where("activity_id = ? AND user_id = ?", activityID, userID).First(&participant).Error; err != nil {
+		if err := h.db.Where("activity_id = ? AND user_id = ?", activityID, participantID).First(&participant).Error; err != nil {
 			continue
 		}
 
-		participant.Credits = credits
-		if err := h.db.Save(&participant).Error; err != nil {
-			continue
+		expectedVersion := participant.Version
+		if v, ok := req.Versions[participantID]; ok {
+			expectedVersion = v
 		}
 
-		userInfo, err := h.getUserInfo(participant.UUID, authToken)
+		previousCredits := participant.Credits
+		updated, err := h.updateParticipantCredits(&participant, credits, expectedVersion, userID)
 		if err != nil {
 			continue
 		}
+		if !updated {
+			conflicts = append(conflicts, models.ParticipantResponse{
+				UUID:     participant.UUID,
+				Credits:  participant.Credits,
+				Version:  participant.Version,
+				JoinedAt: participant.JoinedAt,
+			})
+			continue
+		}
+		recordCreditsAudit(c, &participant, previousCredits)
+
+		userInfo, ok := users[participant.UUID]
+		if !ok {
+			continue
+		}
 
 		response := models.ParticipantResponse{
 			UUID:     participant.UUID,
 			Credits:  participant.Credits,
+			Version:  participant.Version,
 			JoinedAt: participant.JoinedAt,
 			UserInfo: userInfo,
 		}
@@ -179,17 +221,92 @@ func (h *ParticipantHandler) BatchSetCredits(c *gin.Context) {
 		updatedCount++
 	}
 
+	if len(conflicts) > 0 {
+		utils.SendConflict(c, "部分参与者学分已被他人修改，请刷新后重试", gin.H{
+			"updated_count": updatedCount,
+			"participants":  updatedParticipants,
+			"conflicts":     conflicts,
+		})
+		return
+	}
+
 	utils.SendSuccessResponse(c, gin.H{
 		"updated_count": updatedCount,
 		"participants":  updatedParticipants,
 	})
 }
 
+// updateParticipantCredits 以版本号为条件更新学分，返回 false 表示发生并发冲突，
+// 此时 participant 会被刷新为数据库中的最新值
+func (h *ParticipantHandler) updateParticipantCredits(participant *models.ActivityParticipant, credits float64, expectedVersion int64, actorID string) (bool, error) {
+	previousCredits := participant.Credits
+	var updated bool
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ActivityParticipant{}).
+			Where("id = ? AND version = ?", participant.ID, expectedVersion).
+			Updates(map[string]interface{}{
+				"credits": credits,
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected > 0
+		if !updated || previousCredits == credits {
+			return nil
+		}
+		return outbox.Record(tx, models.EventCreditsChanged, participant.ActivityID, actorID, map[string]interface{}{
+			"activity_id":      participant.ActivityID,
+			"user_id":          participant.UUID,
+			"previous_credits": previousCredits,
+			"credits":          credits,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := h.db.Where("id = ?", participant.ID).First(participant).Error; err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// recordCreditsAudit 记录学分变更审计日志，学分未变化时不记录
+func recordCreditsAudit(c *gin.Context, participant *models.ActivityParticipant, previousCredits float64) {
+	if participant.Credits == previousCredits {
+		return
+	}
+	audit.Record(c, audit.Entry{
+		Action:       "participants.credits_change",
+		ResourceType: "participants",
+		ResourceID:   participant.ID,
+		Before:       gin.H{"activity_id": participant.ActivityID, "user_id": participant.UUID, "credits": previousCredits},
+		After:        gin.H{"activity_id": participant.ActivityID, "user_id": participant.UUID, "credits": participant.Credits},
+	})
+}
+
+// deleteParticipant 移除参与者，确有记录被删除时写入对应事件
+func (h *ParticipantHandler) deleteParticipant(activityID, participantID, actorID, eventType string) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("activity_id = ? AND user_id = ?", activityID, participantID).Delete(&models.ActivityParticipant{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return outbox.Record(tx, eventType, activityID, actorID, map[string]interface{}{
+			"activity_id": activityID,
+			"user_id":     participantID,
+		})
+	})
+}
+
 func (h *ParticipantHandler) SetSingleCredits(c *gin.Context) {
 	activityID := c.Param("id")
 	participantID := c.Param("uuid")
-	userID, _ := c.Get("id")
-	userType, _ := c.Get("user_type")
+	userID := c.GetString("id")
+	userType := c.GetString("user_type")
 
 	var req models.SingleCreditsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -207,7 +324,7 @@ func (h *ParticipantHandler) SetSingleCredits(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID && userType != "admin" && userType != "teacher" {
+	if !canEditActivity(h.db, &activity, userID, userType) {
 		utils.SendForbidden(c, "权限不足，只有活动创建者、教师或管理员可以设置学分")
 		return
 	}
@@ -222,13 +339,34 @@ func (h *ParticipantHandler) SetSingleCredits(c *gin.Context) {
 		return
 	}
 
-	participant.Credits = req.Credits
-	if err := h.db.Save(&participant).Error; err != nil {
+	expectedVersion, checkVersion, err := utils.ExpectedVersion(c, req.Version)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+	if !checkVersion {
+		expectedVersion = participant.Version
+	}
+
+	previousCredits := participant.Credits
+	updated, err := h.updateParticipantCredits(&participant, req.Credits, expectedVersion, userID)
+	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	utils.SetETag(c, participant.Version)
+	if !updated {
+		utils.SendConflict(c, "参与者学分已被他人修改，请刷新后重试", models.ParticipantResponse{
+			UUID:     participant.UUID,
+			Credits:  participant.Credits,
+			Version:  participant.Version,
+			JoinedAt: participant.JoinedAt,
+		})
+		return
+	}
+	recordCreditsAudit(c, &participant, previousCredits)
 
-	userInfo, err := h.getUserInfo(participant.UUID, c.GetHeader("Authorization"))
+	userInfo, err := utils.GetUserInfo(participant.UUID)
 	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
@@ -237,6 +375,7 @@ func (h *ParticipantHandler) SetSingleCredits(c *gin.Context) {
 	response := models.ParticipantResponse{
 		UUID:     participant.UUID,
 		Credits:  participant.Credits,
+		Version:  participant.Version,
 		JoinedAt: participant.JoinedAt,
 		UserInfo: userInfo,
 	}
@@ -247,8 +386,8 @@ func (h *ParticipantHandler) SetSingleCredits(c *gin.Context) {
 func (h *ParticipantHandler) RemoveParticipant(c *gin.Context) {
 	activityID := c.Param("id")
 	participantID := c.Param("uuid")
-	userID, _ := c.Get("id")
-	userType, _ := c.Get("user_type")
+	userID := c.GetString("id")
+	userType := c.GetString("user_type")
 
 	var activity models.CreditActivity
 	if err := h.db.Where("id = ?", activityID).First(&activity).Error; err != nil {
@@ -260,12 +399,12 @@ func (h *ParticipantHandler) RemoveParticipant(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID && userType != "admin" && userType != "teacher" {
+	if !canEditActivity(h.db, &activity, userID, userType) {
 		utils.SendForbidden(c, "权限不足，只有活动创建者、教师或管理员可以移除参与者")
 		return
 	}
 
-	if err := h.db.Where("activity_id = ? AND user_id = ?", activityID, participantID).Delete(&models.ActivityParticipant{}).Error; err != nil {
+	if err := h.deleteParticipant(activityID, participantID, userID, models.EventParticipantRemoved); err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
@@ -275,9 +414,9 @@ func (h *ParticipantHandler) RemoveParticipant(c *gin.Context) {
 
 func (h *ParticipantHandler) LeaveActivity(c *gin.Context) {
 	activityID := c.Param("id")
-	userID, _ := c.Get("id")
+	userID := c.GetString("id")
 
-	if err := h.db.Where("activity_id = ? AND user_id = ?", activityID, userID).Delete(&models.ActivityParticipant{}).Error; err != nil {
+	if err := h.deleteParticipant(activityID, userID, userID, models.EventParticipantLeft); err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
@@ -287,6 +426,9 @@ func (h *ParticipantHandler) LeaveActivity(c *gin.Context) {
 
 func (h *ParticipantHandler) GetActivityParticipants(c *gin.Context) {
 	activityID := c.Param("id")
+	if !utils.RequireActivityInScope(c, h.db, activityID) {
+		return
+	}
 	page, limit, _ := h.validator.ValidatePagination(
 		c.DefaultQuery("page", "1"),
 		c.DefaultQuery("limit", "10"),
@@ -305,14 +447,13 @@ func (h *ParticipantHandler) GetActivityParticipants(c *gin.Context) {
 	}
 
 	var responses []models.ParticipantResponse
-	authToken := c.GetHeader("Authorization")
+	users := participantUsers(h.db, participants)
 	for _, participant := range participants {
-		userInfo, err := h.getUserInfo(participant.UUID, authToken)
-		if err != nil {
+		userInfo, ok := users[participant.UUID]
+		if !ok {
 			// 如果获取用户信息失败，创建一个基本的用户信息
 			userInfo = &models.UserInfo{
 				UUID:     participant.UUID,
-				Username: userInfo.Username,
 				RealName: "未知用户",
 				UserType: "unknown",
 				Status:   "unknown",
@@ -322,6 +463,7 @@ func (h *ParticipantHandler) GetActivityParticipants(c *gin.Context) {
 		response := models.ParticipantResponse{
 			UUID:     participant.UUID,
 			Credits:  participant.Credits,
+			Version:  participant.Version,
 			JoinedAt: participant.JoinedAt,
 			UserInfo: userInfo,
 		}
@@ -334,8 +476,8 @@ func (h *ParticipantHandler) GetActivityParticipants(c *gin.Context) {
 
 func (h *ParticipantHandler) BatchRemoveParticipants(c *gin.Context) {
 	activityID := c.Param("id")
-	userID, _ := c.Get("id")
-	userType, _ := c.Get("user_type")
+	userID := c.GetString("id")
+	userType := c.GetString("user_type")
 
 	var req models.BatchRemoveRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -353,14 +495,14 @@ func (h *ParticipantHandler) BatchRemoveParticipants(c *gin.Context) {
 		return
 	}
 
-	if activity.OwnerID != userID && userType != "admin" && userType != "teacher" {
+	if !canEditActivity(h.db, &activity, userID, userType) {
 		utils.SendForbidden(c, "权限不足，只有活动创建者、教师或管理员可以批量移除参与者")
 		return
 	}
 
 	removedCount := 0
 	for _, participantID := range req.UUIDs {
-		if err := h.db.Where("activity_id = ? AND user_id = ?", activityID, participantID).Delete(&models.ActivityParticipant{}).Error; err == nil {
+		if err := h.deleteParticipant(activityID, participantID, userID, models.EventParticipantRemoved); err == nil {
 			removedCount++
 		}
 	}
@@ -373,12 +515,17 @@ func (h *ParticipantHandler) BatchRemoveParticipants(c *gin.Context) {
 
 func (h *ParticipantHandler) GetParticipantStats(c *gin.Context) {
 	activityID := c.Param("id")
+	if !utils.RequireActivityInScope(c, h.db, activityID) {
+		return
+	}
 
 	var stats struct {
-		TotalParticipants int64   `json:"total_participants"`
-		TotalCredits      float64 `json:"total_credits"`
-		AverageCredits    float64 `json:"average_credits"`
-		RecentParticipants int64  `json:"recent_participants"`
+		TotalParticipants  int64            `json:"total_participants"`
+		TotalCredits       float64          `json:"total_credits"`
+		AverageCredits     float64          `json:"average_credits"`
+		RecentParticipants int64            `json:"recent_participants"`
+		ByCollege          map[string]int64 `json:"by_college"`
+		ByGrade            map[string]int64 `json:"by_grade"`
 	}
 
 	h.db.Model(&models.ActivityParticipant{}).Where("activity_id = ?", activityID).Count(&stats.TotalParticipants)
@@ -394,12 +541,39 @@ func (h *ParticipantHandler) GetParticipantStats(c *gin.Context) {
 		Where("activity_id = ? AND created_at >= ?", activityID, sevenDaysAgo).
 		Count(&stats.RecentParticipants)
 
+	// 按学部、年级分布：关联本地用户快照，尚未同步的用户计入空键
+	stats.ByCollege = h.participantBreakdown(activityID, "college")
+	stats.ByGrade = h.participantBreakdown(activityID, "grade")
+
 	utils.SendSuccessResponse(c, stats)
 }
 
+// participantBreakdown 按用户快照中的某一列统计活动参与人数
+func (h *ParticipantHandler) participantBreakdown(activityID, column string) map[string]int64 {
+	var rows []struct {
+		Value string
+		Count int64
+	}
+	h.db.Model(&models.ActivityParticipant{}).
+		Select("COALESCE(s."+column+", '') AS value, COUNT(*) AS count").
+		Joins("LEFT JOIN user_snapshots s ON s.uuid = activity_participants.user_id").
+		Where("activity_participants.activity_id = ?", activityID).
+		Group("value").
+		Scan(&rows)
+
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.Value] = row.Count
+	}
+	return result
+}
+
 func (h *ParticipantHandler) ExportParticipants(c *gin.Context) {
 	activityID := c.Param("id")
 	format := c.DefaultQuery("format", "json")
+	if !utils.RequireActivityInScope(c, h.db, activityID) {
+		return
+	}
 
 	var participants []models.ActivityParticipant
 	if err := h.db.Where("activity_id = ?", activityID).Order("joined_at DESC").Find(&participants).Error; err != nil {
@@ -410,10 +584,10 @@ func (h *ParticipantHandler) ExportParticipants(c *gin.Context) {
 	switch format {
 	case "json":
 		var responses []models.ParticipantResponse
-		authToken := c.GetHeader("Authorization")
+		users := participantUsers(h.db, participants)
 		for _, participant := range participants {
-			userInfo, err := h.getUserInfo(participant.UUID, authToken)
-			if err != nil {
+			userInfo, ok := users[participant.UUID]
+			if !ok {
 				continue
 			}
 
@@ -442,23 +616,27 @@ func (h *ParticipantHandler) ExportParticipants(c *gin.Context) {
 		defer writer.Flush()
 		
 		// 写入表头
-		headers := []string{"学号/工号", "姓名", "用户名", "用户类型", "学分", "加入时间"}
+		headers := []string{"学号/工号", "姓名", "用户名", "用户类型", "学部", "专业", "班级", "年级", "学分", "加入时间"}
 		if err := writer.Write(headers); err != nil {
 			utils.SendInternalServerError(c, err)
 			return
 		}
 		
 		// 获取用户信息并写入数据
-		authToken := c.GetHeader("Authorization")
+		users := participantUsers(h.db, participants)
 		for _, participant := range participants {
-			userInfo, err := h.getUserInfo(participant.UUID, authToken)
-			if err != nil {
+			userInfo, ok := users[participant.UUID]
+			if !ok {
 				// 如果无法获取用户信息，使用基本信息
 				record := []string{
 					participant.UUID,
 					"未知用户",
 					"",
 					"",
+					"",
+					"",
+					"",
+					"",
 					fmt.Sprintf("%.2f", participant.Credits),
 					participant.JoinedAt.Format("2006-01-02 15:04:05"),
 				}
@@ -482,6 +660,10 @@ func (h *ParticipantHandler) ExportParticipants(c *gin.Context) {
 				userInfo.RealName,
 				userInfo.Username,
 				userInfo.UserType,
+				userInfo.College,
+				userInfo.Major,
+				userInfo.Class,
+				userInfo.Grade,
 				fmt.Sprintf("%.2f", participant.Credits),
 				participant.JoinedAt.Format("2006-01-02 15:04:05"),
 			}
@@ -545,11 +727,11 @@ func (h *ParticipantHandler) GetUserParticipatedActivities(c *gin.Context) {
 	utils.SendPaginatedResponse(c, responses, total, page, limit)
 }
 
-func (h *ParticipantHandler) isStudent(userID string, authToken string) bool {
-	userInfo, err := utils.GetUserInfo(userID, authToken)
-	return err == nil && userInfo != nil && userInfo.UserType == "student"
-}
-
-func (h *ParticipantHandler) getUserInfo(userID string, authToken string) (*models.UserInfo, error) {
-	return utils.GetUserInfo(userID, authToken)
+// participantUsers 批量获取参与者的用户信息，获取不到的由调用方按未知用户处理
+func participantUsers(db *gorm.DB, participants []models.ActivityParticipant) map[string]*models.UserInfo {
+	userIDs := make([]string, 0, len(participants))
+	for _, participant := range participants {
+		userIDs = append(userIDs, participant.UUID)
+	}
+	return lookupUsers(db, userIDs)
 }
\ No newline at end of file
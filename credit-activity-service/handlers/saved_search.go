@@ -0,0 +1,560 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"credit-management/credit-activity-service/fulltext"
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/notifications"
+	"credit-management/credit-activity-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// savedSearchMatchedEvent 保存的搜索出现新结果时通知的事件类型，不属于领域事件
+	savedSearchMatchedEvent = "saved_search.matched"
+	// savedSearchCheckLimit 每次检查单个订阅时最多处理的新结果数
+	savedSearchCheckLimit = 500
+)
+
+type SavedSearchHandler struct {
+	db        *gorm.DB
+	search    *SearchHandler
+	validator *utils.Validator
+}
+
+func NewSavedSearchHandler(db *gorm.DB, search *SearchHandler) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		db:        db,
+		search:    search,
+		validator: utils.NewValidator(),
+	}
+}
+
+// GetSavedSearches 获取本人保存的搜索以及共享给本人所在部门（或数据范围内部门）的搜索，可按 kind 过滤
+func (h *SavedSearchHandler) GetSavedSearches(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	departments, err := h.visibleDepartments(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	query := h.db.Where("owner_id = ?", userID)
+	if len(departments) > 0 {
+		query = query.Or("visibility = ? AND department_id IN ?", models.SavedSearchDepartment, departments)
+	}
+	query = h.db.Where(query)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var searches []models.SavedSearch
+	if err := query.Order("created_at DESC").Find(&searches).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	ids := make([]string, 0, len(searches))
+	for _, s := range searches {
+		ids = append(ids, s.ID)
+	}
+	var subscribed []string
+	if len(ids) > 0 {
+		if err := h.db.Model(&models.SavedSearchSubscription{}).
+			Where("user_id = ? AND saved_search_id IN ?", userID, ids).
+			Pluck("saved_search_id", &subscribed).Error; err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+	}
+	subscribedSet := make(map[string]bool, len(subscribed))
+	for _, id := range subscribed {
+		subscribedSet[id] = true
+	}
+
+	responses := make([]models.SavedSearchResponse, 0, len(searches))
+	for _, s := range searches {
+		responses = append(responses, models.SavedSearchResponse{
+			SavedSearch: s,
+			IsOwner:     s.OwnerID == userID,
+			Subscribed:  subscribedSet[s.ID],
+		})
+	}
+	utils.SendSuccessResponse(c, responses)
+}
+
+// GetSavedSearch 获取单个保存的搜索
+func (h *SavedSearchHandler) GetSavedSearch(c *gin.Context) {
+	search, ok := h.loadVisible(c)
+	if !ok {
+		return
+	}
+	utils.SendSuccessResponse(c, h.buildResponse(search, c.GetString("id")))
+}
+
+// CreateSavedSearch 保存搜索条件；共享到部门时记录创建者所在部门
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	var req models.SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := validateSavedSearchFilters(req.Kind, req.Filters); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	search := models.SavedSearch{
+		OwnerID: userID,
+		Name:    req.Name,
+		Kind:    req.Kind,
+		Filters: savedSearchFilters(req.Filters),
+	}
+	if !h.applyVisibility(c, &search, req.Visibility) {
+		return
+	}
+	if err := h.db.Create(&search).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "搜索已保存", h.buildResponse(&search, userID))
+}
+
+// UpdateSavedSearch 修改保存的搜索，仅创建者可以修改
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	search, ok := h.loadOwned(c)
+	if !ok {
+		return
+	}
+
+	var req models.SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := validateSavedSearchFilters(req.Kind, req.Filters); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	search.Name = req.Name
+	search.Kind = req.Kind
+	search.Filters = savedSearchFilters(req.Filters)
+	if !h.applyVisibility(c, search, req.Visibility) {
+		return
+	}
+	if err := h.db.Model(search).Select("name", "kind", "filters", "visibility", "department_id").Updates(search).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, h.buildResponse(search, c.GetString("id")))
+}
+
+// DeleteSavedSearch 删除保存的搜索及其订阅，仅创建者可以删除
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	search, ok := h.loadOwned(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("saved_search_id = ?", search.ID).Delete(&models.SavedSearchSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("saved_search_id = ?", search.ID).Delete(&models.SavedSearchHit{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(search).Error
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{"message": "保存的搜索已删除"})
+}
+
+// RunSavedSearch 按保存的条件执行搜索，分页参数（page、page_size、cursor、total）取自本次请求。
+// 共享的搜索按执行者本人的权限和数据范围返回结果
+func (h *SavedSearchHandler) RunSavedSearch(c *gin.Context) {
+	search, ok := h.loadVisible(c)
+	if !ok {
+		return
+	}
+
+	switch search.Kind {
+	case models.SavedSearchActivities:
+		h.search.searchActivities(c, activitySearchRequest(search.Filters))
+	case models.SavedSearchApplications:
+		h.search.searchApplications(c, applicationSearchRequest(search.Filters))
+	default:
+		utils.SendBadRequest(c, "不支持的搜索类型: "+search.Kind)
+	}
+}
+
+// SubscribeSavedSearch 订阅保存的搜索，此后出现的新结果会以站内通知提醒
+func (h *SavedSearchHandler) SubscribeSavedSearch(c *gin.Context) {
+	search, ok := h.loadVisible(c)
+	if !ok {
+		return
+	}
+
+	subscription := models.SavedSearchSubscription{
+		SavedSearchID: search.ID,
+		UserID:        c.GetString("id"),
+		UserType:      c.GetString("user_type"),
+		LastCheckedAt: time.Now(),
+	}
+	if err := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&subscription).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{"message": "订阅成功"})
+}
+
+// UnsubscribeSavedSearch 取消订阅
+func (h *SavedSearchHandler) UnsubscribeSavedSearch(c *gin.Context) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	searchID := c.Param("id")
+	if err := h.validator.ValidateUUID(searchID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("saved_search_id = ? AND user_id = ?", searchID, userID).Delete(&models.SavedSearchSubscription{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("saved_search_id = ? AND user_id = ?", searchID, userID).Delete(&models.SavedSearchHit{}).Error
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, gin.H{"message": "已取消订阅"})
+}
+
+// CheckSubscriptions 定时检查所有订阅：自上次检查以来新建或修改、且在订阅者权限范围内匹配的结果，
+// 每条只通知一次，每个订阅合并为一条站内通知
+func (h *SavedSearchHandler) CheckSubscriptions(ctx context.Context) error {
+	var subscriptions []models.SavedSearchSubscription
+	if err := h.db.WithContext(ctx).
+		Where("saved_search_id IN (?)", h.db.Model(&models.SavedSearch{}).Select("id")).
+		Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := h.checkSubscription(ctx, subscription); err != nil {
+			log.Printf("[saved-search] failed to check subscription %s/%s: %v", subscription.SavedSearchID, subscription.UserID, err)
+		}
+	}
+	return nil
+}
+
+func (h *SavedSearchHandler) checkSubscription(ctx context.Context, subscription models.SavedSearchSubscription) error {
+	var search models.SavedSearch
+	if err := h.db.WithContext(ctx).Where("id = ?", subscription.SavedSearchID).First(&search).Error; err != nil {
+		return err
+	}
+	// 创建者取消共享后，其他订阅者不再收到通知
+	if search.OwnerID != subscription.UserID && search.Visibility != models.SavedSearchDepartment {
+		return nil
+	}
+
+	scope, err := utils.NewDataScope(h.db.WithContext(ctx), subscription.UserID, subscription.UserType)
+	if err != nil {
+		return err
+	}
+
+	// 查询条件基于带 ctx 的会话构建；在已构建的查询上调用 WithContext 会丢失条件
+	searcher := &SearchHandler{db: h.db.WithContext(ctx), validator: h.search.validator}
+	var query *gorm.DB
+	switch search.Kind {
+	case models.SavedSearchActivities:
+		req := activitySearchRequest(search.Filters)
+		query = searcher.activitySearchQuery(scope, subscription.UserID, subscription.UserType, req, fulltext.ParseQuery(req.Query))
+	case models.SavedSearchApplications:
+		req := applicationSearchRequest(search.Filters)
+		query = searcher.applicationSearchQuery(scope, subscription.UserID, subscription.UserType, req)
+	default:
+		return fmt.Errorf("unsupported saved search kind %q", search.Kind)
+	}
+
+	checkedAt := time.Now()
+	var rows []struct {
+		ID        string
+		UpdatedAt time.Time
+	}
+	if err := query.
+		Select("id, updated_at").
+		Where("updated_at > ?", subscription.LastCheckedAt).
+		Order("updated_at").Limit(savedSearchCheckLimit).
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	// 达到单次上限时只推进到已处理的位置，其余结果留给下次检查。
+	// 退到最后一个时间戳之前，与它同时修改但未取到的结果下次仍会被检查（已记录的结果不会重复通知）
+	if len(rows) == savedSearchCheckLimit {
+		checkedAt = rows[len(rows)-1].UpdatedAt
+		for i := len(rows) - 2; i >= 0; i-- {
+			if rows[i].UpdatedAt.Before(checkedAt) {
+				checkedAt = rows[i].UpdatedAt
+				break
+			}
+		}
+	}
+
+	var matched int64
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(rows) > 0 {
+			hits := make([]models.SavedSearchHit, 0, len(rows))
+			for _, row := range rows {
+				hits = append(hits, models.SavedSearchHit{SavedSearchID: search.ID, UserID: subscription.UserID, ItemID: row.ID})
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&hits)
+			if result.Error != nil {
+				return result.Error
+			}
+			matched = result.RowsAffected
+		}
+		return tx.Model(&models.SavedSearchSubscription{}).
+			Where("saved_search_id = ? AND user_id = ?", subscription.SavedSearchID, subscription.UserID).
+			Update("last_checked_at", checkedAt).Error
+	})
+	if err != nil || matched == 0 {
+		return err
+	}
+
+	return notifications.Send(ctx, h.db, []models.Notification{{
+		UserID:    subscription.UserID,
+		EventID:   uuid.New().String(),
+		EventType: savedSearchMatchedEvent,
+		Category:  models.NotificationCategorySavedSearch,
+		Title:     "保存的搜索有新结果",
+		Content:   fmt.Sprintf("保存的搜索「%s」有 %d 条新结果", search.Name, matched),
+	}})
+}
+
+// loadVisible 加载当前用户可见（本人创建或共享给其部门）的保存搜索
+func (h *SavedSearchHandler) loadVisible(c *gin.Context) (*models.SavedSearch, bool) {
+	search, ok := h.load(c)
+	if !ok || search.OwnerID == c.GetString("id") {
+		return search, ok
+	}
+
+	if search.Visibility == models.SavedSearchDepartment && search.DepartmentID != nil {
+		departments, err := h.visibleDepartments(c)
+		if err != nil {
+			utils.SendInternalServerError(c, err)
+			return nil, false
+		}
+		for _, id := range departments {
+			if id == *search.DepartmentID {
+				return search, true
+			}
+		}
+	}
+	utils.SendNotFound(c, "保存的搜索不存在")
+	return nil, false
+}
+
+// loadOwned 加载当前用户创建的保存搜索
+func (h *SavedSearchHandler) loadOwned(c *gin.Context) (*models.SavedSearch, bool) {
+	search, ok := h.loadVisible(c)
+	if !ok {
+		return nil, false
+	}
+	if search.OwnerID != c.GetString("id") {
+		utils.SendForbidden(c, "只有创建者可以修改或删除保存的搜索")
+		return nil, false
+	}
+	return search, true
+}
+
+func (h *SavedSearchHandler) load(c *gin.Context) (*models.SavedSearch, bool) {
+	if c.GetString("id") == "" {
+		utils.SendUnauthorized(c)
+		return nil, false
+	}
+	searchID := c.Param("id")
+	if err := h.validator.ValidateUUID(searchID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return nil, false
+	}
+
+	var search models.SavedSearch
+	if err := h.db.Where("id = ?", searchID).First(&search).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "保存的搜索不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return nil, false
+	}
+	return &search, true
+}
+
+// visibleDepartments 当前用户可以看到其共享搜索的部门：本人所在部门以及数据范围内的部门
+func (h *SavedSearchHandler) visibleDepartments(c *gin.Context) ([]string, error) {
+	var departments []string
+	if err := h.db.Model(&models.UserSnapshot{}).Where("uuid = ? AND department_id IS NOT NULL", c.GetString("id")).
+		Pluck("department_id", &departments).Error; err != nil {
+		return nil, err
+	}
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		return nil, err
+	}
+	return append(departments, scope.DepartmentIDs...), nil
+}
+
+// applyVisibility 设置可见范围；共享到部门时要求创建者已归属部门
+func (h *SavedSearchHandler) applyVisibility(c *gin.Context, search *models.SavedSearch, visibility string) bool {
+	search.Visibility = models.SavedSearchPrivate
+	search.DepartmentID = nil
+	if visibility != models.SavedSearchDepartment {
+		return true
+	}
+
+	var departments []string
+	if err := h.db.Model(&models.UserSnapshot{}).Where("uuid = ? AND department_id IS NOT NULL", search.OwnerID).
+		Pluck("department_id", &departments).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return false
+	}
+	if len(departments) == 0 {
+		utils.SendBadRequest(c, "您尚未归属任何部门，无法共享搜索")
+		return false
+	}
+	search.Visibility = models.SavedSearchDepartment
+	search.DepartmentID = &departments[0]
+	return true
+}
+
+func (h *SavedSearchHandler) buildResponse(search *models.SavedSearch, userID string) models.SavedSearchResponse {
+	var count int64
+	h.db.Model(&models.SavedSearchSubscription{}).
+		Where("saved_search_id = ? AND user_id = ?", search.ID, userID).Count(&count)
+	return models.SavedSearchResponse{
+		SavedSearch: *search,
+		IsOwner:     search.OwnerID == userID,
+		Subscribed:  count > 0,
+	}
+}
+
+// validateSavedSearchFilters 校验过滤字段名、排序字段和相对日期范围，避免保存执行时才会失败的条件
+func validateSavedSearchFilters(kind string, filters map[string]string) error {
+	allowed := map[string]bool{}
+	for _, key := range models.SavedSearchFilterKeys[kind] {
+		allowed[key] = true
+	}
+	for key := range filters {
+		if !allowed[key] {
+			return fmt.Errorf("不支持的过滤条件: %s", key)
+		}
+	}
+
+	if sortBy := filters["sort_by"]; sortBy != "" {
+		_, activitySortable := activitySortValues[sortBy]
+		_, applicationSortable := applicationSortValues[sortBy]
+		if (kind == models.SavedSearchActivities && !activitySortable && sortBy != "relevance") ||
+			(kind == models.SavedSearchApplications && !applicationSortable) {
+			return fmt.Errorf("不支持的排序字段: %s", sortBy)
+		}
+	}
+	if dateRange := filters["date_range"]; dateRange != "" {
+		if _, _, err := utils.ResolveDateRange(dateRange, time.Now()); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{"start_date", "end_date"} {
+		if value := filters[key]; value != "" {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return fmt.Errorf("%s 日期格式错误，应为 YYYY-MM-DD", key)
+			}
+		}
+	}
+	return nil
+}
+
+// savedSearchFilters 保存非空的过滤条件
+func savedSearchFilters(filters map[string]string) datatypes.JSONMap {
+	result := datatypes.JSONMap{}
+	for key, value := range filters {
+		if value != "" {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func filterValue(filters datatypes.JSONMap, key string) string {
+	value, _ := filters[key].(string)
+	return value
+}
+
+// activitySearchRequest 由保存的过滤条件构造活动搜索请求，字段与 SearchActivities 的查询参数一致
+func activitySearchRequest(filters datatypes.JSONMap) models.ActivitySearchRequest {
+	return models.ActivitySearchRequest{
+		Query:     filterValue(filters, "query"),
+		Category:  filterValue(filters, "category"),
+		Status:    filterValue(filters, "status"),
+		OwnerID:   filterValue(filters, "owner_id"),
+		College:   filterValue(filters, "college"),
+		StartDate: filterValue(filters, "start_date"),
+		EndDate:   filterValue(filters, "end_date"),
+		DateRange: filterValue(filters, "date_range"),
+		SortBy:    filterValue(filters, "sort_by"),
+		SortOrder: filterValue(filters, "sort_order"),
+	}
+}
+
+// applicationSearchRequest 由保存的过滤条件构造申请搜索请求，字段与 SearchApplications 的查询参数一致
+func applicationSearchRequest(filters datatypes.JSONMap) models.ApplicationSearchRequest {
+	return models.ApplicationSearchRequest{
+		Query:      filterValue(filters, "query"),
+		ActivityID: filterValue(filters, "activity_id"),
+		UUID:       filterValue(filters, "id"),
+		Status:     filterValue(filters, "status"),
+		StartDate:  filterValue(filters, "start_date"),
+		EndDate:    filterValue(filters, "end_date"),
+		DateRange:  filterValue(filters, "date_range"),
+		MinCredits: filterValue(filters, "min_credits"),
+		MaxCredits: filterValue(filters, "max_credits"),
+		College:    filterValue(filters, "college"),
+		SortBy:     filterValue(filters, "sort_by"),
+		SortOrder:  filterValue(filters, "sort_order"),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"credit-management/credit-activity-service/fulltext"
 	"credit-management/credit-activity-service/models"
 	"credit-management/credit-activity-service/utils"
 
@@ -26,21 +27,30 @@ func NewSearchHandler(db *gorm.DB) *SearchHandler {
 }
 
 func (h *SearchHandler) SearchActivities(c *gin.Context) {
-	userID := c.GetString("id")
-	if userID == "" {
-		utils.SendUnauthorized(c)
-		return
-	}
-	userType := c.GetString("user_type")
-
 	var req models.ActivitySearchRequest
 
 	req.Query = c.Query("query")
 	req.Category = c.Query("category")
 	req.Status = c.Query("status")
 	req.OwnerID = c.Query("owner_id")
+	req.College = c.Query("college")
 	req.StartDate = c.Query("start_date")
 	req.EndDate = c.Query("end_date")
+	req.DateRange = c.Query("date_range")
+	req.SortBy = c.Query("sort_by")
+	req.SortOrder = c.Query("sort_order")
+
+	h.searchActivities(c, req)
+}
+
+// searchActivities 按请求条件搜索活动并返回分页结果，保存的搜索也通过它执行；分页参数取自当前请求
+func (h *SearchHandler) searchActivities(c *gin.Context, req models.ActivitySearchRequest) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
 
 	page, limit, _ := h.validator.ValidatePagination(
 		c.DefaultQuery("page", "1"),
@@ -49,61 +59,143 @@ func (h *SearchHandler) SearchActivities(c *gin.Context) {
 	req.Page = page
 	req.PageSize = limit
 
-	req.SortBy = c.DefaultQuery("sort_by", "created_at")
-	req.SortOrder = c.DefaultQuery("sort_order", "desc")
+	// 有关键词时默认按相关度排序
+	search := fulltext.ParseQuery(req.Query)
+	if req.SortBy == "" {
+		req.SortBy = "created_at"
+		if search != nil {
+			req.SortBy = "relevance"
+		}
+	}
+	if req.SortOrder == "" {
+		req.SortOrder = "desc"
+	}
+	if req.SortBy == "relevance" && search == nil {
+		req.SortBy = "created_at"
+	}
+	sortValue, sortable := activitySortValues[req.SortBy]
+	if req.SortBy != "relevance" && !sortable {
+		utils.SendBadRequest(c, "不支持的排序字段: "+req.SortBy)
+		return
+	}
 
-	// 构建基础查询
-	dbQuery := h.db.Model(&models.CreditActivity{})
+	pager, err := utils.NewPager(c, page, limit)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+	if req.SortBy == "relevance" && pager.CursorMode() {
+		utils.SendBadRequest(c, "按相关度排序时不支持游标分页，请指定 sort_by 或使用 page 翻页")
+		return
+	}
 
-	// 权限过滤：学生只能看到自己创建或参与的活动
-	if userType == "student" {
-		dbQuery = h.applyStudentPermissionFilter(dbQuery, userID)
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
 	}
 
-	// 应用搜索条件
-	dbQuery = h.applySearchConditions(dbQuery, req)
+	dbQuery := h.activitySearchQuery(scope, userID, userType, req, search)
 
 	// 获取总数
-	var total int64
-	if err := dbQuery.Count(&total).Error; err != nil {
+	total, err := pager.Count(dbQuery)
+	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
 
 	// 应用排序和分页
 	var activities []models.CreditActivity
-	query := dbQuery.
-		Order(fmt.Sprintf("%s %s", req.SortBy, strings.ToUpper(req.SortOrder))).
-		Offset((req.Page - 1) * req.PageSize).
-		Limit(req.PageSize)
+	key := sortKey(req.SortBy, req.SortOrder)
+	query := dbQuery
+	if req.SortBy == "relevance" {
+		query = query.Order(search.OrderByRank()).Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize)
+	} else if query, err = pager.Apply(query, key); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 
 	if err := query.Find(&activities).Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	if sortable {
+		activities = utils.PageRows(pager, activities, key, func(a models.CreditActivity) (interface{}, string) {
+			return sortValue(a), a.ID
+		})
+	}
 
 	// 转换为响应格式
 	responses := h.buildActivityResponses(activities)
+	if search != nil {
+		for i := range responses {
+			responses[i].Highlights = &models.ActivityHighlights{
+				Title:       search.Highlight(responses[i].Title, 0),
+				Description: search.Highlight(responses[i].Description, fulltext.SnippetRunes),
+			}
+		}
+	}
 
-	utils.SendPaginatedResponse(c, responses, total, page, limit)
+	pager.Send(c, responses, total)
+}
+
+// activitySortValues 活动搜索可用的排序字段（均为非空列）及其在记录中的取值，用于生成游标
+var activitySortValues = map[string]func(models.CreditActivity) interface{}{
+	"created_at": func(a models.CreditActivity) interface{} { return a.CreatedAt },
+	"updated_at": func(a models.CreditActivity) interface{} { return a.UpdatedAt },
+	"start_date": func(a models.CreditActivity) interface{} { return a.StartDate },
+	"end_date":   func(a models.CreditActivity) interface{} { return a.EndDate },
+	"title":      func(a models.CreditActivity) interface{} { return a.Title },
+	"status":     func(a models.CreditActivity) interface{} { return a.Status },
+	"category":   func(a models.CreditActivity) interface{} { return a.Category },
+}
+
+// applicationSortValues 申请搜索可用的排序字段（均为非空列）及其在记录中的取值
+var applicationSortValues = map[string]func(models.Application) interface{}{
+	"submitted_at":    func(a models.Application) interface{} { return a.SubmittedAt },
+	"created_at":      func(a models.Application) interface{} { return a.CreatedAt },
+	"updated_at":      func(a models.Application) interface{} { return a.UpdatedAt },
+	"applied_credits": func(a models.Application) interface{} { return a.AppliedCredits },
+	"awarded_credits": func(a models.Application) interface{} { return a.AwardedCredits },
+	"status":          func(a models.Application) interface{} { return a.Status },
+}
+
+// sortKey 按请求的排序字段排序，值相同时按 id 排序保证翻页稳定；sort_order 不是 asc 时按倒序
+func sortKey(sortBy, sortOrder string) utils.SortKey {
+	return utils.SortKey{Column: sortBy, IDColumn: "id", Desc: !strings.EqualFold(sortOrder, "asc")}
+}
+
+// activitySearchQuery 构建活动搜索的查询条件（不含排序和分页）
+func (h *SearchHandler) activitySearchQuery(scope *utils.DataScope, userID, userType string, req models.ActivitySearchRequest, search *fulltext.Query) *gorm.DB {
+	// 构建基础查询，教师和部门管理员限制在所管理的部门范围内
+	dbQuery := scope.Activities(h.db.Model(&models.CreditActivity{}))
+
+	// 权限过滤：学生只能看到自己创建或参与的活动
+	if userType == "student" {
+		dbQuery = h.applyStudentPermissionFilter(dbQuery, userID)
+	}
+
+	// 应用搜索条件
+	return h.applySearchConditions(dbQuery, req, search)
 }
 
 // applyStudentPermissionFilter 应用学生权限过滤
 func (h *SearchHandler) applyStudentPermissionFilter(query *gorm.DB, userID string) *gorm.DB {
 	return query.Where(
-		"owner_id = ? OR id IN (SELECT activity_id FROM activity_participants WHERE user_id = ?)",
-		userID, userID,
+		"owner_id = ? OR id IN (SELECT activity_id FROM activity_participants WHERE user_id = ?) OR id IN (SELECT activity_id FROM activity_collaborators WHERE user_id = ? AND deleted_at IS NULL)",
+		userID, userID, userID,
 	)
 }
 
 // applySearchConditions 应用搜索条件
-func (h *SearchHandler) applySearchConditions(query *gorm.DB, req models.ActivitySearchRequest) *gorm.DB {
-	// 文本搜索
-	if req.Query != "" {
-		searchQuery := "%" + req.Query + "%"
+func (h *SearchHandler) applySearchConditions(query *gorm.DB, req models.ActivitySearchRequest, search *fulltext.Query) *gorm.DB {
+	// 全文搜索：标题、描述和详情，也可以按创建者姓名（含拼音）查找
+	if search != nil {
+		owners := matchUserNames(h.db.Model(&models.UserSnapshot{}).Select("uuid"), strings.TrimSpace(req.Query))
 		query = query.Where(
-			"title ILIKE ? OR description ILIKE ? OR category ILIKE ?",
-			searchQuery, searchQuery, searchQuery,
+			h.db.Where(search.Condition()).
+				Or("category = ?", strings.TrimSpace(req.Query)).
+				Or("owner_id IN (?)", owners),
 		)
 	}
 
@@ -122,6 +214,16 @@ func (h *SearchHandler) applySearchConditions(query *gorm.DB, req models.Activit
 		query = query.Where("owner_id = ?", req.OwnerID)
 	}
 
+	// 创建者学部过滤：在本地用户快照中匹配
+	if req.College != "" {
+		query = query.Where("owner_id IN (?)", h.db.Model(&models.UserSnapshot{}).Select("uuid").Where("college = ?", req.College))
+	}
+
+	// 相对日期范围：活动起止日期都在范围内
+	if req.DateRange != "" && req.StartDate == "" && req.EndDate == "" {
+		req.StartDate, req.EndDate, _ = utils.ResolveDateRange(req.DateRange, time.Now())
+	}
+
 	// 开始日期过滤
 	if req.StartDate != "" {
 		if parsedDate, err := time.Parse("2006-01-02", req.StartDate); err == nil {
@@ -145,21 +247,24 @@ func (h *SearchHandler) buildActivityResponses(activities []models.CreditActivit
 
 	for _, activity := range activities {
 		response := models.ActivityResponse{
-			ID:             activity.ID,
-			Title:          activity.Title,
-			Description:    activity.Description,
-			StartDate:      activity.StartDate,
-			EndDate:        activity.EndDate,
-			Status:         activity.Status,
-			Category:       activity.Category,
-			OwnerID:        activity.OwnerID,
-			ReviewerID:     activity.ReviewerID,
-			ReviewComments: activity.ReviewComments,
-			ReviewedAt:     activity.ReviewedAt,
-			CreatedAt:      activity.CreatedAt,
-			UpdatedAt:      activity.UpdatedAt,
-			Participants:   []models.ParticipantResponse{},
-			Applications:   []models.ApplicationResponse{},
+			ID:                 activity.ID,
+			Title:              activity.Title,
+			Description:        activity.Description,
+			StartDate:          activity.StartDate,
+			EndDate:            activity.EndDate,
+			Status:             activity.Status,
+			Category:           activity.Category,
+			OwnerID:            activity.OwnerID,
+			ReviewerID:         activity.ReviewerID,
+			ReviewComments:     activity.ReviewComments,
+			ReviewedAt:         activity.ReviewedAt,
+			Version:            activity.Version,
+			EnrollmentClosedAt: activity.EnrollmentClosedAt,
+			OverdueFlaggedAt:   activity.OverdueFlaggedAt,
+			CreatedAt:          activity.CreatedAt,
+			UpdatedAt:          activity.UpdatedAt,
+			Participants:       []models.ParticipantResponse{},
+			Applications:       []models.ApplicationResponse{},
 		}
 		responses = append(responses, response)
 	}
@@ -168,13 +273,6 @@ func (h *SearchHandler) buildActivityResponses(activities []models.CreditActivit
 }
 
 func (h *SearchHandler) SearchApplications(c *gin.Context) {
-	userID := c.GetString("id")
-	if userID == "" {
-		utils.SendUnauthorized(c)
-		return
-	}
-	userType := c.GetString("user_type")
-
 	var req models.ApplicationSearchRequest
 
 	req.Query = c.Query("query")
@@ -183,8 +281,24 @@ func (h *SearchHandler) SearchApplications(c *gin.Context) {
 	req.Status = c.Query("status")
 	req.StartDate = c.Query("start_date")
 	req.EndDate = c.Query("end_date")
+	req.DateRange = c.Query("date_range")
 	req.MinCredits = c.Query("min_credits")
 	req.MaxCredits = c.Query("max_credits")
+	req.College = c.Query("college")
+	req.SortBy = c.Query("sort_by")
+	req.SortOrder = c.Query("sort_order")
+
+	h.searchApplications(c, req)
+}
+
+// searchApplications 按请求条件搜索申请并返回分页结果，保存的搜索也通过它执行；分页参数取自当前请求
+func (h *SearchHandler) searchApplications(c *gin.Context, req models.ApplicationSearchRequest) {
+	userID := c.GetString("id")
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userType := c.GetString("user_type")
 
 	page, limit, _ := h.validator.ValidatePagination(
 		c.DefaultQuery("page", "1"),
@@ -193,43 +307,74 @@ func (h *SearchHandler) SearchApplications(c *gin.Context) {
 	req.Page = page
 	req.PageSize = limit
 
-	req.SortBy = c.DefaultQuery("sort_by", "submitted_at")
-	req.SortOrder = c.DefaultQuery("sort_order", "desc")
+	if req.SortBy == "" {
+		req.SortBy = "submitted_at"
+	}
+	if req.SortOrder == "" {
+		req.SortOrder = "desc"
+	}
+	sortValue, ok := applicationSortValues[req.SortBy]
+	if !ok {
+		utils.SendBadRequest(c, "不支持的排序字段: "+req.SortBy)
+		return
+	}
 
-	// 构建基础查询
-	dbQuery := h.db.Model(&models.Application{}).Preload("Activity")
+	pager, err := utils.NewPager(c, page, limit)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 
-	// 应用权限过滤
-	if userType == "student" {
-		dbQuery = dbQuery.Where("user_id = ?", userID)
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
 	}
 
-	// 应用搜索条件
-	dbQuery = h.applyApplicationSearchConditions(dbQuery, req)
+	dbQuery := h.applicationSearchQuery(scope, userID, userType, req)
 
 	// 获取总数
-	var total int64
-	if err := dbQuery.Count(&total).Error; err != nil {
+	total, err := pager.Count(dbQuery)
+	if err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
 
 	// 应用排序和分页
 	var applications []models.Application
-	query := dbQuery.
-		Order(fmt.Sprintf("%s %s", req.SortBy, strings.ToUpper(req.SortOrder))).
-		Offset((req.Page - 1) * req.PageSize).
-		Limit(req.PageSize)
+	key := sortKey(req.SortBy, req.SortOrder)
+	query, err := pager.Apply(dbQuery.Preload("Activity"), key)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 
 	if err := query.Find(&applications).Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	applications = utils.PageRows(pager, applications, key, func(a models.Application) (interface{}, string) {
+		return sortValue(a), a.ID
+	})
 
 	// 转换为响应格式（列表场景不附带 UserInfo，避免高频调用用户服务）
 	responses := h.buildApplicationResponses(applications)
 
-	utils.SendPaginatedResponse(c, responses, total, page, limit)
+	pager.Send(c, responses, total)
+}
+
+// applicationSearchQuery 构建申请搜索的查询条件（不含排序和分页）
+func (h *SearchHandler) applicationSearchQuery(scope *utils.DataScope, userID, userType string, req models.ApplicationSearchRequest) *gorm.DB {
+	// 构建基础查询，教师和部门管理员只能看到范围内用户的申请
+	dbQuery := scope.Users(h.db.Model(&models.Application{}), "user_id")
+
+	// 应用权限过滤
+	if userType == "student" {
+		dbQuery = dbQuery.Where("user_id = ?", userID)
+	}
+
+	// 应用搜索条件
+	return h.applyApplicationSearchConditions(dbQuery, req)
 }
 
 // applyApplicationSearchConditions 应用申请搜索条件
@@ -258,6 +403,16 @@ func (h *SearchHandler) applyApplicationSearchConditions(query *gorm.DB, req mod
 		query = query.Where("status = ?", req.Status)
 	}
 
+	// 学部过滤：在本地用户快照中匹配申请人
+	if req.College != "" {
+		query = query.Where("user_id IN (?)", h.db.Model(&models.UserSnapshot{}).Select("uuid").Where("college = ?", req.College))
+	}
+
+	// 相对日期范围：按提交时间
+	if req.DateRange != "" && req.StartDate == "" && req.EndDate == "" {
+		req.StartDate, req.EndDate, _ = utils.ResolveDateRange(req.DateRange, time.Now())
+	}
+
 	// 开始日期过滤
 	if req.StartDate != "" {
 		if start, err := time.Parse("2006-01-02", req.StartDate); err == nil {
@@ -328,14 +483,18 @@ func (h *SearchHandler) SearchParticipants(c *gin.Context) {
 		return
 	}
 	userType := c.GetString("user_type")
-	authToken := c.GetHeader("Authorization")
 
 	var req models.ParticipantSearchRequest
 
+	req.Query = strings.TrimSpace(c.Query("query"))
 	req.ActivityID = c.Query("activity_id")
 	req.UUID = c.Query("id")
 	req.MinCredits = c.Query("min_credits")
 	req.MaxCredits = c.Query("max_credits")
+	req.College = c.Query("college")
+	req.Major = c.Query("major")
+	req.Class = c.Query("class")
+	req.Grade = c.Query("grade")
 
 	page, limit, _ := h.validator.ValidatePagination(
 		c.DefaultQuery("page", "1"),
@@ -347,8 +506,14 @@ func (h *SearchHandler) SearchParticipants(c *gin.Context) {
 	req.SortBy = c.DefaultQuery("sort_by", "joined_at")
 	req.SortOrder = c.DefaultQuery("sort_order", "desc")
 
-	// 构建基础查询
-	dbQuery := h.db.Model(&models.ActivityParticipant{})
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	// 构建基础查询，教师和部门管理员只能看到范围内用户的参与记录
+	dbQuery := scope.Users(h.db.Model(&models.ActivityParticipant{}), "user_id")
 
 	// 应用权限过滤
 	if userType == "student" {
@@ -378,7 +543,7 @@ func (h *SearchHandler) SearchParticipants(c *gin.Context) {
 	}
 
 	// 转换为响应格式
-	responses := h.buildParticipantResponses(participants, authToken)
+	responses := h.buildParticipantResponses(participants)
 
 	utils.SendPaginatedResponse(c, responses, total, page, limit)
 }
@@ -409,22 +574,56 @@ func (h *SearchHandler) applyParticipantSearchConditions(query *gorm.DB, req mod
 		}
 	}
 
+	// 学生属性过滤：在本地用户快照中匹配
+	snapshots := h.db.Model(&models.UserSnapshot{}).Select("uuid")
+	filtered := false
+	if req.Query != "" {
+		snapshots = matchUserNames(snapshots, req.Query)
+		filtered = true
+	}
+	for column, value := range map[string]string{
+		"college": req.College,
+		"major":   req.Major,
+		"class":   req.Class,
+		"grade":   req.Grade,
+	} {
+		if value != "" {
+			snapshots = snapshots.Where(column+" = ?", value)
+			filtered = true
+		}
+	}
+	if filtered {
+		query = query.Where("user_id IN (?)", snapshots)
+	}
+
 	return query
 }
 
+// matchUserNames 按姓名、用户名、学号或姓名拼音（全拼或首字母前缀，如 zhangs、zs）匹配用户快照
+func matchUserNames(snapshots *gorm.DB, query string) *gorm.DB {
+	searchQuery := "%" + query + "%"
+	pinyinQuery := "% " + strings.ToLower(strings.ReplaceAll(query, " ", "")) + "%"
+	return snapshots.Where(
+		"real_name ILIKE ? OR username ILIKE ? OR student_id = ? OR name_pinyin LIKE ?",
+		searchQuery, searchQuery, query, pinyinQuery,
+	)
+}
+
 // buildParticipantResponses 构建参与者响应列表
-func (h *SearchHandler) buildParticipantResponses(participants []models.ActivityParticipant, authToken string) []models.ParticipantResponse {
+func (h *SearchHandler) buildParticipantResponses(participants []models.ActivityParticipant) []models.ParticipantResponse {
 	responses := make([]models.ParticipantResponse, 0, len(participants))
+	users := participantUsers(h.db, participants)
 
 	for _, participant := range participants {
-		userInfo, err := utils.GetUserInfo(participant.UUID, authToken)
-		if err != nil {
+		userInfo, ok := users[participant.UUID]
+		if !ok {
 			continue
 		}
 
 		response := models.ParticipantResponse{
 			UUID:     participant.UUID,
 			Credits:  participant.Credits,
+			Version:  participant.Version,
 			JoinedAt: participant.JoinedAt,
 			UserInfo: userInfo,
 		}
@@ -463,8 +662,14 @@ func (h *SearchHandler) SearchAttachments(c *gin.Context) {
 	req.SortBy = c.DefaultQuery("sort_by", "uploaded_at")
 	req.SortOrder = c.DefaultQuery("sort_order", "desc")
 
-	// 构建基础查询
-	dbQuery := h.db.Model(&models.Attachment{})
+	scope, err := utils.ResolveDataScope(c, h.db)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	// 构建基础查询，教师和部门管理员只能看到范围内活动的附件
+	dbQuery := scope.ActivityIDs(h.db.Model(&models.Attachment{}), "activity_id")
 
 	// 应用权限过滤
 	if userType == "student" {
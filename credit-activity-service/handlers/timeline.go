@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 单次最多返回的时间线条目数，调用方按页合并时需要取前 page*page_size 条
+const maxTimelineLimit = 2000
+
+// 各类时间线条目的查询，均以用户 UUID 为唯一参数
+var timelineSources = map[string]string{
+	models.TimelineActivityCreated: `
+		SELECT 'activity_created' AS type, a.id::text AS resource_id, a.title, a.created_at AS occurred_at,
+		       NULL::numeric AS credits, a.status AS status
+		FROM credit_activities a
+		WHERE a.owner_id = @user_id`,
+	models.TimelineActivitySubmitted: `
+		SELECT 'activity_submitted' AS type, e.aggregate_id::text AS resource_id, e.payload->>'title' AS title, e.created_at AS occurred_at,
+		       NULL::numeric AS credits, e.payload->>'to_status' AS status
+		FROM outbox_events e
+		WHERE e.event_type = 'activity.submitted' AND e.actor_id = @user_id`,
+	models.TimelineReview: `
+		SELECT 'review' AS type, e.aggregate_id::text AS resource_id, e.payload->>'title' AS title, e.created_at AS occurred_at,
+		       NULL::numeric AS credits, e.payload->>'to_status' AS status
+		FROM outbox_events e
+		WHERE e.event_type IN ('activity.approved', 'activity.rejected') AND e.actor_id = @user_id`,
+	models.TimelineCreditsReceived: `
+		SELECT 'credits_received' AS type, ap.activity_id::text AS resource_id, a.title, ap.created_at AS occurred_at,
+		       ap.awarded_credits AS credits, ap.status AS status
+		FROM applications ap
+		JOIN credit_activities a ON a.id = ap.activity_id
+		WHERE ap.user_id = @user_id AND ap.deleted_at IS NULL`,
+}
+
+// 固定顺序拼接 UNION，保证 SQL 稳定
+var timelineSourceOrder = []string{
+	models.TimelineActivityCreated,
+	models.TimelineActivitySubmitted,
+	models.TimelineReview,
+	models.TimelineCreditsReceived,
+}
+
+type TimelineHandler struct {
+	db        *gorm.DB
+	validator *utils.Validator
+}
+
+func NewTimelineHandler(db *gorm.DB) *TimelineHandler {
+	return &TimelineHandler{
+		db:        db,
+		validator: utils.NewValidator(),
+	}
+}
+
+// GetUserTimeline 内部接口：返回用户在学分活动中的动态（创建、提交、审核、获得学分），按时间倒序。
+// limit 为返回条数上限，types 为逗号分隔的条目类型过滤
+func (h *TimelineHandler) GetUserTimeline(c *gin.Context) {
+	userID := c.Param("id")
+	if err := h.validator.ValidateUUID(userID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		utils.SendBadRequest(c, "limit 参数错误")
+		return
+	}
+	if limit > maxTimelineLimit {
+		limit = maxTimelineLimit
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range strings.Split(c.Query("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			wanted[t] = true
+		}
+	}
+
+	var parts []string
+	for _, t := range timelineSourceOrder {
+		if len(wanted) == 0 || wanted[t] {
+			parts = append(parts, timelineSources[t])
+		}
+	}
+	if len(parts) == 0 {
+		utils.SendSuccessResponse(c, gin.H{"items": []models.TimelineItem{}, "total": 0})
+		return
+	}
+	union := strings.Join(parts, "\nUNION ALL\n")
+	args := map[string]interface{}{"user_id": userID}
+
+	var total int64
+	if err := h.db.Raw("SELECT COUNT(*) FROM ("+union+") t", args).Scan(&total).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var rows []struct {
+		Type       string
+		ResourceID string
+		Title      string
+		OccurredAt time.Time
+		Credits    *float64
+		Status     *string
+	}
+	args["limit"] = limit
+	if err := h.db.Raw("SELECT * FROM ("+union+") t ORDER BY occurred_at DESC LIMIT @limit", args).Scan(&rows).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	items := make([]models.TimelineItem, 0, len(rows))
+	for _, row := range rows {
+		details := map[string]interface{}{}
+		if row.Status != nil {
+			details["status"] = *row.Status
+		}
+		if row.Credits != nil {
+			details["credits"] = *row.Credits
+		}
+		items = append(items, models.TimelineItem{
+			Type:         row.Type,
+			Title:        row.Title,
+			OccurredAt:   row.OccurredAt,
+			Source:       "credit-activity-service",
+			ResourceType: "activities",
+			ResourceID:   row.ResourceID,
+			Details:      details,
+		})
+	}
+
+	utils.SendSuccessResponse(c, gin.H{"items": items, "total": total})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/usersync"
+	"credit-management/credit-activity-service/utils"
+
+	"gorm.io/gorm"
+)
+
+// lookupUsers 获取展示用的用户信息：优先读取本地用户快照，快照中没有的（如刚创建尚未同步）再批量查询用户服务，
+// 因此用户服务不可用时列表、导出仍能显示已同步的用户。返回以 UUID 为键的结果
+func lookupUsers(db *gorm.DB, userIDs []string) map[string]*models.UserInfo {
+	users, err := usersync.Lookup(db, userIDs)
+	if err != nil {
+		log.Printf("读取用户快照失败: %v", err)
+	}
+
+	var missing []string
+	for _, id := range userIDs {
+		if _, ok := users[id]; !ok && id != "" {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return users
+	}
+
+	remote, err := utils.GetUsersInfo(missing)
+	if err != nil {
+		log.Printf("批量获取用户信息失败: %v", err)
+	}
+	for id, user := range remote {
+		users[id] = user
+	}
+	return users
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+	"credit-management/credit-activity-service/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 连通性测试事件类型，不属于领域事件，仅由 ping 接口产生
+const webhookPingEvent = "webhook.ping"
+
+type WebhookHandler struct {
+	db        *gorm.DB
+	validator *utils.Validator
+}
+
+func NewWebhookHandler(db *gorm.DB) *WebhookHandler {
+	return &WebhookHandler{
+		db:        db,
+		validator: utils.NewValidator(),
+	}
+}
+
+// GetWebhookEventTypes 获取可订阅的事件类型
+func (h *WebhookHandler) GetWebhookEventTypes(c *gin.Context) {
+	utils.SendSuccessResponse(c, gin.H{
+		"event_types": models.DomainEventTypes,
+		"wildcard":    models.WebhookEventAll,
+	})
+}
+
+// GetWebhookSubscriptions 获取 webhook 订阅列表
+func (h *WebhookHandler) GetWebhookSubscriptions(c *gin.Context) {
+	var subs []models.WebhookSubscription
+	if err := h.db.Order("created_at DESC").Find(&subs).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, subs)
+}
+
+// GetWebhookSubscription 获取单个 webhook 订阅
+func (h *WebhookHandler) GetWebhookSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	utils.SendSuccessResponse(c, sub)
+}
+
+// CreateWebhookSubscription 创建 webhook 订阅，未提供密钥时自动生成；密钥只在创建时返回
+func (h *WebhookHandler) CreateWebhookSubscription(c *gin.Context) {
+	var req models.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhooks.GenerateSecret()
+		if err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+		secret = generated
+	}
+
+	sub := models.WebhookSubscription{
+		Name:       req.Name,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: datatypes.NewJSONSlice(req.EventTypes),
+		Active:     req.Active == nil || *req.Active,
+		CreatedBy:  c.GetString("id"),
+	}
+	if err := h.db.Create(&sub).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "webhook 订阅创建成功", models.WebhookSubscriptionCreatedResponse{
+		WebhookSubscription: sub,
+		Secret:              secret,
+	})
+}
+
+// UpdateWebhookSubscription 更新 webhook 订阅；secret 为空时保留原密钥
+func (h *WebhookHandler) UpdateWebhookSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":        req.Name,
+		"url":         req.URL,
+		"event_types": datatypes.NewJSONSlice(req.EventTypes),
+	}
+	if req.Secret != "" {
+		updates["secret"] = req.Secret
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if err := h.db.Model(&sub).Updates(updates).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if err := h.db.First(&sub, "id = ?", sub.ID).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, sub)
+}
+
+// DeleteWebhookSubscription 删除 webhook 订阅，尚未发送的投递随之失效
+func (h *WebhookHandler) DeleteWebhookSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	if err := h.db.Delete(&sub).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, gin.H{"message": "webhook 订阅已删除"})
+}
+
+// RotateWebhookSecret 重新生成签名密钥并返回新密钥
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if err := h.db.Model(&sub).Update("secret", secret).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, gin.H{"id": sub.ID, "secret": secret})
+}
+
+// PingWebhook 向订阅地址发送一条测试事件，用于验证接收端与签名配置
+func (h *WebhookHandler) PingWebhook(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	envelope := webhooks.Envelope{
+		ID:         uuid.New().String(),
+		Type:       webhookPingEvent,
+		ActorID:    c.GetString("id"),
+		OccurredAt: time.Now(),
+		Data:       map[string]interface{}{"subscription_id": sub.ID},
+	}
+	if err := webhooks.Enqueue(h.db, sub.ID, envelope); err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, gin.H{"message": "测试事件已入队", "event_id": envelope.ID})
+}
+
+// GetWebhookDeliveries 获取订阅的投递日志，支持 status、event_type 过滤
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	page, limit, err := h.validator.ValidatePagination(
+		c.DefaultQuery("page", "1"),
+		c.DefaultQuery("limit", "20"),
+	)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	query := h.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", sub.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendPaginatedResponse(c, deliveries, total, page, limit)
+}
+
+// GetWebhookDeliveryAttempts 获取投递记录的每次发送尝试（响应状态、响应体、错误），按尝试顺序排列
+func (h *WebhookHandler) GetWebhookDeliveryAttempts(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := h.db.Where("id = ? AND subscription_id = ?", c.Param("delivery_id"), sub.ID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendNotFound(c, "投递记录不存在")
+			return
+		}
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var attempts []models.WebhookDeliveryAttempt
+	if err := h.db.Where("delivery_id = ?", delivery.ID).Order("attempt ASC, id ASC").Find(&attempts).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, attempts)
+}
+
+// RedeliverWebhook 将投递记录重新入队，下一轮立即发送；重投使用原始负载，接收方可按 X-Webhook-Id 去重。
+// 保留累计的尝试次数，记录重投时间和重投时的次数，之后的重试次数从此处重新计算
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := h.db.Where("id = ? AND subscription_id = ?", c.Param("delivery_id"), sub.ID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendNotFound(c, "投递记录不存在")
+			return
+		}
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		utils.SendConflict(c, "该投递正在等待发送", delivery)
+		return
+	}
+
+	now := time.Now()
+	if err := h.db.Model(&delivery).Updates(map[string]interface{}{
+		"status":                     models.WebhookDeliveryPending,
+		"attempts_before_redelivery": delivery.Attempts,
+		"redelivered_at":             now,
+		"next_attempt_at":            now,
+		"last_error":                 "",
+	}).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	utils.SendSuccessResponse(c, gin.H{"message": "已重新入队", "delivery_id": delivery.ID})
+}
+
+func (h *WebhookHandler) loadSubscription(c *gin.Context) (models.WebhookSubscription, bool) {
+	var sub models.WebhookSubscription
+	if err := h.db.Where("id = ?", c.Param("id")).First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendNotFound(c, "webhook 订阅不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return sub, false
+	}
+	return sub, true
+}
+
+func validateWebhookEventTypes(eventTypes []string) error {
+	known := make(map[string]bool, len(models.DomainEventTypes))
+	for _, t := range models.DomainEventTypes {
+		known[t] = true
+	}
+	for _, t := range eventTypes {
+		if t != models.WebhookEventAll && !known[t] {
+			return errors.New("不支持的事件类型: " + t)
+		}
+	}
+	return nil
+}
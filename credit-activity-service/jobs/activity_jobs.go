@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+)
+
+// Reminder 审核催办通知的发送方
+type Reminder interface {
+	RemindStaleReviews(ctx context.Context, activities []models.CreditActivity) error
+}
+
+// LogReminder 仅记录日志的催办实现，未配置其他实现时使用
+type LogReminder struct{}
+
+func (LogReminder) RemindStaleReviews(ctx context.Context, activities []models.CreditActivity) error {
+	for _, activity := range activities {
+		log.Printf("[jobs] activity %s (%s) has been pending review since %s",
+			activity.ID, activity.Title, activity.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// ActivityJobs 活动状态相关的定时任务。
+// 任务只维护系统字段，使用 UpdateColumn 不刷新 updated_at、不递增 version，避免与用户编辑产生冲突。
+type ActivityJobs struct {
+	db          *gorm.DB
+	reminder    Reminder
+	submitGrace time.Duration
+	reviewStale time.Duration
+	now         func() time.Time
+}
+
+func NewActivityJobs(db *gorm.DB, reminder Reminder, submitGrace, reviewStale time.Duration) *ActivityJobs {
+	if reminder == nil {
+		reminder = LogReminder{}
+	}
+	return &ActivityJobs{
+		db:          db,
+		reminder:    reminder,
+		submitGrace: submitGrace,
+		reviewStale: reviewStale,
+		now:         time.Now,
+	}
+}
+
+// CloseEnrollment 活动结束后自动关闭报名
+func (j *ActivityJobs) CloseEnrollment(ctx context.Context) error {
+	now := j.now()
+	result := j.db.WithContext(ctx).Model(&models.CreditActivity{}).
+		Where("end_date < ? AND enrollment_closed_at IS NULL", now).
+		UpdateColumn("enrollment_closed_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[jobs] closed enrollment for %d activities", result.RowsAffected)
+	}
+	return nil
+}
+
+// FlagOverdueDrafts 标记活动结束超过宽限期仍未提交的草稿
+func (j *ActivityJobs) FlagOverdueDrafts(ctx context.Context) error {
+	now := j.now()
+	result := j.db.WithContext(ctx).Model(&models.CreditActivity{}).
+		Where("status = ? AND end_date < ? AND overdue_flagged_at IS NULL", models.StatusDraft, now.Add(-j.submitGrace)).
+		UpdateColumn("overdue_flagged_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[jobs] flagged %d overdue drafts", result.RowsAffected)
+	}
+	return nil
+}
+
+// RemindStaleReviews 催办长时间处于待审核状态的活动，仍未审核时每隔 reviewStale 再提醒一次
+func (j *ActivityJobs) RemindStaleReviews(ctx context.Context) error {
+	now := j.now()
+	cutoff := now.Add(-j.reviewStale)
+
+	var activities []models.CreditActivity
+	if err := j.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", models.StatusPendingReview, cutoff).
+		Where("review_reminded_at IS NULL OR review_reminded_at < ?", cutoff).
+		Order("updated_at ASC").
+		Limit(200).
+		Find(&activities).Error; err != nil {
+		return err
+	}
+	if len(activities) == 0 {
+		return nil
+	}
+
+	if err := j.reminder.RemindStaleReviews(ctx, activities); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(activities))
+	for _, activity := range activities {
+		ids = append(ids, activity.ID)
+	}
+	return j.db.WithContext(ctx).Model(&models.CreditActivity{}).
+		Where("id IN ?", ids).
+		UpdateColumn("review_reminded_at", now).Error
+}
@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobFunc 定时任务函数
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       JobFunc
+	lastRun  time.Time
+}
+
+// Runner 定时任务调度器。
+// 多副本部署时通过 Postgres 会话级 advisory lock 选主，只有持有锁的实例执行任务。
+type Runner struct {
+	db       *gorm.DB
+	lockKey  int64
+	tick     time.Duration
+	mu       sync.Mutex
+	jobs     []*job
+	leader   *sql.Conn
+	isLeader bool
+}
+
+// NewRunner 创建调度器，lockName 用于区分不同服务的选主锁
+func NewRunner(db *gorm.DB, lockName string, tick time.Duration) *Runner {
+	return &Runner{
+		db:      db,
+		lockKey: advisoryLockKey(lockName),
+		tick:    tick,
+	}
+}
+
+// Register 注册一个按固定间隔执行的任务
+func (r *Runner) Register(name string, interval time.Duration, fn JobFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, &job{name: name, interval: interval, fn: fn})
+}
+
+// Start 在后台启动调度循环，ctx 取消后释放选主锁并退出
+func (r *Runner) Start(ctx context.Context) {
+	go r.loop(ctx)
+}
+
+// IsLeader 当前实例是否为任务执行者
+func (r *Runner) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isLeader
+}
+
+func (r *Runner) loop(ctx context.Context) {
+	ticker := time.NewTicker(r.tick)
+	defer ticker.Stop()
+	defer r.resign()
+
+	for {
+		if r.ensureLeader(ctx) {
+			r.runDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureLeader 检查或尝试获取选主锁。锁绑定在一个专用连接上，连接断开即自动释放。
+func (r *Runner) ensureLeader(ctx context.Context) bool {
+	if r.leader != nil {
+		if err := r.leader.PingContext(ctx); err == nil {
+			return true
+		}
+		log.Printf("[jobs] lost leader connection, resigning")
+		r.resign()
+	}
+
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		log.Printf("[jobs] failed to get sql.DB: %v", err)
+		return false
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		log.Printf("[jobs] failed to open leader connection: %v", err)
+		return false
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", r.lockKey).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			log.Printf("[jobs] failed to try advisory lock: %v", err)
+		}
+		conn.Close()
+		return false
+	}
+
+	log.Printf("[jobs] acquired leader lock %d", r.lockKey)
+	r.mu.Lock()
+	r.leader = conn
+	r.isLeader = true
+	r.mu.Unlock()
+	return true
+}
+
+func (r *Runner) resign() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.leader == nil {
+		return
+	}
+	// 使用独立的 context，保证调度循环退出时也能释放锁
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.leader.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", r.lockKey); err != nil {
+		log.Printf("[jobs] failed to release advisory lock: %v", err)
+	}
+	r.leader.Close()
+	r.leader = nil
+	r.isLeader = false
+}
+
+func (r *Runner) runDue(ctx context.Context) {
+	r.mu.Lock()
+	due := make([]*job, 0, len(r.jobs))
+	now := time.Now()
+	for _, j := range r.jobs {
+		if j.lastRun.IsZero() || now.Sub(j.lastRun) >= j.interval {
+			j.lastRun = now
+			due = append(due, j)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, j := range due {
+		start := time.Now()
+		if err := j.fn(ctx); err != nil {
+			log.Printf("[jobs] %s failed: %v", j.name, err)
+			continue
+		}
+		log.Printf("[jobs] %s finished in %s", j.name, time.Since(start))
+	}
+}
+
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
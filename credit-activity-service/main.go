@@ -1,15 +1,34 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"credit-management/credit-activity-service/channels"
+	"credit-management/credit-activity-service/fulltext"
 	"credit-management/credit-activity-service/handlers"
+	"credit-management/credit-activity-service/jobs"
+	"credit-management/credit-activity-service/migrations"
+	"credit-management/credit-activity-service/notifications"
+	"credit-management/credit-activity-service/outbox"
+	"credit-management/credit-activity-service/realtime"
+	"credit-management/credit-activity-service/usersync"
 	"credit-management/credit-activity-service/utils"
+	"credit-management/credit-activity-service/webhooks"
+	"credit-management/shared/audit"
+	"credit-management/shared/imports"
+	"credit-management/shared/servicetoken"
+	"credit-management/shared/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -26,11 +45,12 @@ func main() {
 	gin.SetMode(gin.ReleaseMode)
 	log.Println("Gin模式已设置")
 
-	log.Println("正在创建必要的目录...")
-	if err := createDirectories(); err != nil {
-		log.Printf("Warning: Failed to create directories, but continuing: %v", err)
-	} else {
-		log.Println("目录创建成功")
+	// storage 子命令：把本地目录中的附件复制到配置的对象存储后退出（./main storage migrate [--dry-run] [--delete-source]）
+	if len(os.Args) > 1 && os.Args[1] == "storage" {
+		if err := storage.Command(context.Background(), storageConfig(), "attachments/", os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("附件迁移失败: ", err)
+		}
+		return
 	}
 
 	log.Println("正在连接数据库...")
@@ -40,22 +60,99 @@ func main() {
 	}
 	log.Println("数据库连接成功")
 
-	activityHandler := handlers.NewActivityHandler(db)
+	// migrate 子命令：执行数据库迁移后退出（./main migrate up | down [N] | status）
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.Command(db, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("数据库迁移失败: ", err)
+		}
+		return
+	}
+
+	// 数据库结构版本与程序要求不一致时拒绝启动
+	if err := migrations.CheckVersion(db); err != nil {
+		log.Fatal("数据库结构版本检查失败: ", err)
+	}
+
+	// 全文搜索：配置了中文分词（如 zhparser）时叠加使用，否则只用内置的二元切分和拼音
+	fulltext.Configure(db, getEnv("SEARCH_TS_CONFIG", ""))
+
+	// 用户信息缓存与本地用户快照：用户服务修改用户后通过 PostgreSQL NOTIFY 通知各副本淘汰缓存、同步快照
+	userSyncer := usersync.NewSyncer(db, getEnv("USER_SERVICE_URL", "http://user-service:8084"), utils.ServiceAuth(servicetoken.UserService))
+	userClient := utils.DefaultUserClient()
+	userClient.OnChange = userSyncer.Notify
+	go userSyncer.Run(context.Background())
+	go userClient.ListenInvalidations(context.Background(), databaseDSN())
+
+	// 附件存储：默认本地目录；多副本部署时使用 S3 兼容的对象存储
+	files, err := storage.New(storageConfig())
+	if err != nil {
+		log.Fatal("初始化附件存储失败: ", err)
+	}
+
+	activityHandler := handlers.NewActivityHandler(db, files)
 	participantHandler := handlers.NewParticipantHandler(db)
 	applicationHandler := handlers.NewApplicationHandler(db)
-	attachmentHandler := handlers.NewAttachmentHandler(db)
+	attachmentHandler := handlers.NewAttachmentHandler(db, files)
 	searchHandler := handlers.NewSearchHandler(db)
+	savedSearchHandler := handlers.NewSavedSearchHandler(db, searchHandler)
+	collaboratorHandler := handlers.NewCollaboratorHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(db)
+	webhookHandler := handlers.NewWebhookHandler(db)
+	timelineHandler := handlers.NewTimelineHandler(db)
+
+	// 实时推送：通过 Redis 发布给网关的 /api/events/stream
+	if getEnv("REALTIME_PUSH_ENABLED", "false") == "true" {
+		realtime.SetPublisher(realtime.NewRedisPublisher(getRedisClient()))
+	}
+
+	// 站内通知与实时推送始终启用；外部通知渠道（邮件 / webhook / 企业微信 / 钉钉）按配置启用
+	builtinSinks := []outbox.Sink{notifications.NewSink(db), realtime.NewSink(db)}
+	var digest *channels.Digest
+	if getEnv("NOTIFY_CHANNELS_ENABLED", "false") == "true" {
+		registry := newChannelRegistry()
+		directory := channels.NewDirectory(getEnv("USER_SERVICE_URL", "http://user-service:8084"), utils.ServiceAuth(servicetoken.UserService))
+		builtinSinks = append(builtinSinks, channels.NewSink(db, registry, directory))
+
+		digestHour, err := strconv.Atoi(getEnv("NOTIFY_DIGEST_HOUR", "8"))
+		if err != nil || digestHour < 0 || digestHour > 23 {
+			digestHour = 8
+		}
+		digest = channels.NewDigest(db, registry, directory, digestHour)
+	}
+
+	// 出站 webhook：发件箱事件按订阅入队，由独立的投递协程签名发送并重试
+	if getEnv("WEBHOOKS_ENABLED", "true") == "true" {
+		builtinSinks = append(builtinSinks, webhooks.NewSink(db))
+		startWebhookWorker(db)
+	}
+
+	// 异步导入任务：服务重启后从已提交的进度继续
+	startImportRunner(activityHandler)
+
+	// 领域事件分发：把发件箱中的事件投递到配置的 Sink
+	startOutboxDispatcher(db, builtinSinks...)
+
+	// 定时任务：多副本部署时只有持有选主锁的实例执行
+	if getEnv("JOBS_ENABLED", "true") == "true" {
+		startJobRunner(db, activityHandler, savedSearchHandler, digest, userSyncer)
+	}
 
 	authMiddleware := utils.NewHeaderAuthMiddleware()
 	permissionMiddleware := utils.NewPermissionMiddleware(db)
 
 	log.Println("正在创建路由...")
 	r := gin.New()
+	// 只采信网关（TRUSTED_PROXIES）在 X-Real-IP 中转发的客户端地址，审计日志记录的是真实的客户端 IP
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置错误: ", err)
+	}
 
 	// 使用新的中间件
 	r.Use(utils.RecoveryMiddleware())
 	r.Use(utils.LoggingMiddleware())
 	r.Use(utils.CORSMiddleware())
+	r.Use(audit.NewLogger(db, "credit-activity-service").Middleware())
 
 	// 注册公共配置接口（无需鉴权）
 	registerActivityOptionsRoute(r)
@@ -79,9 +176,16 @@ func main() {
 					allUsers.POST("/:id/submit", activityHandler.SubmitActivity)
 					allUsers.POST("/:id/withdraw", activityHandler.WithdrawActivity)
 					allUsers.GET("/deletable", activityHandler.GetDeletableActivities)
+					allUsers.GET("/trash", activityHandler.GetDeletedActivities)
+					allUsers.POST("/:id/restore", activityHandler.RestoreActivity)
 					allUsers.POST("/:id/copy", activityHandler.CopyActivity)
 					allUsers.POST("/:id/save-template", activityHandler.SaveAsTemplate)
 					allUsers.POST("/import", activityHandler.ImportActivities)
+					allUsers.GET("/import-jobs", activityHandler.GetImportJobs)
+					allUsers.GET("/import-jobs/:id", activityHandler.GetImportJob)
+					allUsers.GET("/import-jobs/:id/errors", activityHandler.GetImportJobErrors)
+					allUsers.POST("/import-jobs/:id/cancel", activityHandler.CancelImportJob)
+					allUsers.POST("/import-jobs/:id/resume", activityHandler.ResumeImportJob)
 					allUsers.GET("/csv-template", activityHandler.GetCSVTemplate)
 					allUsers.GET("/excel-template", activityHandler.GetExcelTemplate)
 					allUsers.POST("", activityHandler.CreateActivity)
@@ -133,6 +237,17 @@ func main() {
 				}
 			}
 
+			// 协作者管理路由：角色校验在 Handler 内部完成（所有者 / 共同所有者 / 管理员）
+			collaborators := activities.Group(":id")
+			collaborators.Use(authMiddleware.AuthRequired())
+			{
+				collaborators.GET("/collaborators", collaboratorHandler.GetCollaborators)
+				collaborators.POST("/collaborators", collaboratorHandler.AddCollaborator)
+				collaborators.PUT("/collaborators/:user_id", collaboratorHandler.UpdateCollaboratorRole)
+				collaborators.DELETE("/collaborators/:user_id", collaboratorHandler.RemoveCollaborator)
+				collaborators.POST("/transfer-ownership", collaboratorHandler.TransferOwnership)
+			}
+
 			// 附件管理路由（单独抽出，保证所有认证用户都能访问预览/下载）
 			attachments := activities.Group(":id/attachments")
 			attachments.Use(authMiddleware.AuthRequired())
@@ -143,6 +258,7 @@ func main() {
 					allUsers.GET("", attachmentHandler.GetAttachments)
 					allUsers.GET("/:attachment_id/download", attachmentHandler.DownloadAttachment)
 					allUsers.GET("/:attachment_id/preview", attachmentHandler.PreviewAttachment)
+					allUsers.GET("/:attachment_id/download-url", attachmentHandler.GetAttachmentDownloadURL)
 				}
 
 				ownerOrTeacherOrAdmin := attachments.Group("")
@@ -175,6 +291,40 @@ func main() {
 			}
 		}
 
+		notificationRoutes := api.Group("/notifications")
+		notificationRoutes.Use(authMiddleware.AuthRequired())
+		{
+			notificationRoutes.GET("", notificationHandler.GetNotifications)
+			notificationRoutes.GET("/unread-count", notificationHandler.GetUnreadCount)
+			notificationRoutes.POST("/read-all", notificationHandler.MarkAllNotificationsRead)
+			notificationRoutes.POST("/:id/read", notificationHandler.MarkNotificationRead)
+			notificationRoutes.GET("/preferences", notificationHandler.GetNotificationPreferences)
+			notificationRoutes.PUT("/preferences", notificationHandler.UpdateNotificationPreferences)
+		}
+
+		webhookRoutes := api.Group("/webhooks")
+		webhookRoutes.Use(authMiddleware.AuthRequired(), permissionMiddleware.AdminOnly())
+		{
+			webhookRoutes.GET("", webhookHandler.GetWebhookSubscriptions)
+			webhookRoutes.POST("", webhookHandler.CreateWebhookSubscription)
+			webhookRoutes.GET("/event-types", webhookHandler.GetWebhookEventTypes)
+			webhookRoutes.GET("/:id", webhookHandler.GetWebhookSubscription)
+			webhookRoutes.PUT("/:id", webhookHandler.UpdateWebhookSubscription)
+			webhookRoutes.DELETE("/:id", webhookHandler.DeleteWebhookSubscription)
+			webhookRoutes.POST("/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+			webhookRoutes.POST("/:id/ping", webhookHandler.PingWebhook)
+			webhookRoutes.GET("/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+			webhookRoutes.GET("/:id/deliveries/:delivery_id/attempts", webhookHandler.GetWebhookDeliveryAttempts)
+			webhookRoutes.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.RedeliverWebhook)
+		}
+
+		// 内部服务接口（不经网关暴露）
+		internal := api.Group("/internal")
+		internal.Use(authMiddleware.InternalOnly())
+		{
+			internal.GET("/users/:id/timeline", timelineHandler.GetUserTimeline)
+		}
+
 		search := api.Group("/search")
 		search.Use(authMiddleware.AuthRequired())
 		{
@@ -185,6 +335,16 @@ func main() {
 				allUsers.GET("/applications", searchHandler.SearchApplications)
 				allUsers.GET("/participants", searchHandler.SearchParticipants)
 				allUsers.GET("/attachments", searchHandler.SearchAttachments)
+
+				// 保存的搜索：修改和删除在 Handler 内部限制为创建者
+				allUsers.GET("/saved", savedSearchHandler.GetSavedSearches)
+				allUsers.POST("/saved", savedSearchHandler.CreateSavedSearch)
+				allUsers.GET("/saved/:id", savedSearchHandler.GetSavedSearch)
+				allUsers.PUT("/saved/:id", savedSearchHandler.UpdateSavedSearch)
+				allUsers.DELETE("/saved/:id", savedSearchHandler.DeleteSavedSearch)
+				allUsers.GET("/saved/:id/run", savedSearchHandler.RunSavedSearch)
+				allUsers.POST("/saved/:id/subscribe", savedSearchHandler.SubscribeSavedSearch)
+				allUsers.DELETE("/saved/:id/subscribe", savedSearchHandler.UnsubscribeSavedSearch)
 			}
 		}
 	}
@@ -201,15 +361,20 @@ func main() {
 	}
 }
 
-func initDatabase() (*gorm.DB, error) {
+// databaseDSN 数据库连接串，GORM 与监听用户变更通知的独立连接共用
+func databaseDSN() string {
 	host := getEnv("DB_HOST", "localhost")
 	port := getEnv("DB_PORT", "5432")
 	user := getEnv("DB_USER", "postgres")
 	password := getEnv("DB_PASSWORD", "password")
 	dbname := getEnv("DB_NAME", "credit_management")
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Shanghai",
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Shanghai",
 		host, port, user, password, dbname)
+}
+
+func initDatabase() (*gorm.DB, error) {
+	dsn := databaseDSN()
 
 	config := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
@@ -229,25 +394,160 @@ func initDatabase() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// 保障: 确保 credit_activities.details JSONB 列存在
-	if err := ensureDetailsColumn(db); err != nil {
-		return nil, err
-	}
-
 	log.Println("Database connected successfully")
 	return db, nil
 }
 
-// handlersAutoMigrate 用于触发 CreditActivity 的自动迁移（包含 details JSONB）
-// ensureDetailsColumn adds details JSONB column if missing (idempotent)
-func ensureDetailsColumn(db *gorm.DB) error {
-	if db.Migrator().HasColumn("credit_activities", "details") {
-		return nil
+// startJobRunner 注册并启动定时任务
+func startJobRunner(db *gorm.DB, activityHandler *handlers.ActivityHandler, savedSearchHandler *handlers.SavedSearchHandler, digest *channels.Digest, userSyncer *usersync.Syncer) {
+	runner := jobs.NewRunner(db, "credit-activity-service:jobs", time.Minute)
+	activityJobs := jobs.NewActivityJobs(db, notifications.NewReviewReminder(db), utils.DraftSubmitGrace(), utils.ReviewStaleAfter())
+
+	runner.Register("close-enrollment", 10*time.Minute, activityJobs.CloseEnrollment)
+	runner.Register("flag-overdue-drafts", time.Hour, activityJobs.FlagOverdueDrafts)
+	runner.Register("remind-stale-reviews", time.Hour, activityJobs.RemindStaleReviews)
+	// 回收站清理：超过保留期的活动被彻底删除，物理文件随之清理
+	runner.Register("purge-trash", time.Hour, func(ctx context.Context) error {
+		purged, err := activityHandler.PurgeExpiredTrash(utils.TrashRetention())
+		if err == nil && purged > 0 {
+			log.Printf("Purged %d expired activities from trash", purged)
+		}
+		return err
+	})
+
+	if digest != nil {
+		runner.Register("daily-review-digest", time.Hour, digest.Run)
+	}
+
+	// 用户快照兜底同步：增量同步弥补监听断开期间错过的通知，每日全量同步修正部门名称等变化
+	runner.Register("sync-user-snapshots", 5*time.Minute, userSyncer.SyncChanges)
+	runner.Register("resync-user-snapshots", 24*time.Hour, userSyncer.SyncAll)
+
+	// 活动全文索引：为新建和修改过的活动重建 tsvector，首次运行时也为已有活动补建
+	runner.Register("index-activity-search", time.Minute, func(ctx context.Context) error {
+		indexed, err := fulltext.IndexPending(ctx, db, 500)
+		if err == nil && indexed > 0 {
+			log.Printf("Indexed %d activities for search", indexed)
+		}
+		return err
+	})
+
+	// 保存的搜索订阅：检查新结果并发送站内通知
+	runner.Register("check-saved-searches", 15*time.Minute, savedSearchHandler.CheckSubscriptions)
+
+	runner.Start(context.Background())
+}
+
+// newChannelRegistry 创建外部通知渠道，未配置 SMTP_HOST 时不启用邮件
+func newChannelRegistry() *channels.Registry {
+	list := []channels.Channel{
+		channels.NewWebhookChannel(),
+		channels.NewWeComChannel(),
+		channels.NewDingTalkChannel(),
+	}
+	if host := getEnv("SMTP_HOST", ""); host != "" {
+		list = append(list, channels.NewSMTPChannel(channels.SMTPConfig{
+			Host:     host,
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", ""),
+		}))
+	}
+	return channels.NewRegistry(list...)
+}
+
+var (
+	redisOnce   sync.Once
+	redisClient *redis.Client
+)
+
+// getRedisClient 返回共享的 Redis 客户端（按需创建）
+func getRedisClient() *redis.Client {
+	redisOnce.Do(func() {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", getEnv("REDIS_HOST", "localhost"), getEnv("REDIS_PORT", "6379")),
+			Password: getEnv("REDIS_PASSWORD", ""),
+		})
+	})
+	return redisClient
+}
+
+// startOutboxDispatcher 按 OUTBOX_SINKS 配置创建投递目标并启动分发器
+func startOutboxDispatcher(db *gorm.DB, builtin ...outbox.Sink) {
+	sinks := append([]outbox.Sink{}, builtin...)
+	for _, name := range strings.Split(getEnv("OUTBOX_SINKS", "log"), ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			sinks = append(sinks, outbox.NewLogSink())
+		case "redis":
+			sinks = append(sinks, outbox.NewRedisStreamSink(getRedisClient(), getEnv("OUTBOX_REDIS_STREAM", "credit:events"), 100000))
+		case "webhook":
+			url := getEnv("OUTBOX_WEBHOOK_URL", "")
+			if url == "" {
+				log.Println("Warning: OUTBOX_WEBHOOK_URL is empty, webhook sink disabled")
+				continue
+			}
+			sinks = append(sinks, outbox.NewWebhookSink(url))
+		case "":
+		default:
+			log.Printf("Warning: unknown outbox sink %q ignored", name)
+		}
+	}
+	if len(sinks) == 0 {
+		log.Println("No outbox sinks configured, events will stay in outbox_events")
+		return
+	}
+
+	interval, err := strconv.Atoi(getEnv("OUTBOX_POLL_SECONDS", "2"))
+	if err != nil || interval <= 0 {
+		interval = 2
+	}
+	outbox.NewDispatcher(db, time.Duration(interval)*time.Second, sinks...).Start(context.Background())
+}
+
+// startWebhookWorker 启动出站 webhook 投递协程
+func startWebhookWorker(db *gorm.DB) {
+	interval, err := strconv.Atoi(getEnv("WEBHOOK_POLL_SECONDS", "5"))
+	if err != nil || interval <= 0 {
+		interval = 5
 	}
-	if err := db.Exec("ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS details JSONB NOT NULL DEFAULT '{}'::jsonb").Error; err != nil {
-		return fmt.Errorf("failed to add details column: %w", err)
+	maxAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", strconv.Itoa(webhooks.DefaultMaxAttempts)))
+	if err != nil || maxAttempts <= 0 {
+		maxAttempts = webhooks.DefaultMaxAttempts
+	}
+	worker := webhooks.NewWorker(db, webhooks.NewSender(nil), time.Duration(interval)*time.Second, maxAttempts)
+	worker.Start(context.Background())
+}
+
+// startImportRunner 启动导入任务执行协程
+func startImportRunner(activityHandler *handlers.ActivityHandler) {
+	interval, err := strconv.Atoi(getEnv("IMPORT_POLL_SECONDS", "3"))
+	if err != nil || interval <= 0 {
+		interval = 3
+	}
+	batchSize, err := strconv.Atoi(getEnv("IMPORT_BATCH_SIZE", strconv.Itoa(imports.DefaultBatchSize)))
+	if err != nil || batchSize <= 0 {
+		batchSize = imports.DefaultBatchSize
+	}
+	activityHandler.NewImportRunner(time.Duration(interval)*time.Second, batchSize).Start(context.Background())
+}
+
+// storageConfig 附件存储配置：STORAGE_BACKEND=local 时保存在 STORAGE_LOCAL_DIR，s3 时保存在 S3 兼容的对象存储
+func storageConfig() storage.Config {
+	return storage.Config{
+		Backend:  getEnv("STORAGE_BACKEND", storage.BackendLocal),
+		LocalDir: getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		S3: storage.S3Config{
+			Endpoint:       getEnv("S3_ENDPOINT", ""),
+			PublicEndpoint: getEnv("S3_PUBLIC_ENDPOINT", ""),
+			Region:         getEnv("S3_REGION", "us-east-1"),
+			Bucket:         getEnv("S3_BUCKET", ""),
+			AccessKey:      getEnv("S3_ACCESS_KEY", ""),
+			SecretKey:      getEnv("S3_SECRET_KEY", ""),
+			Prefix:         getEnv("S3_PREFIX", ""),
+		},
 	}
-	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -257,17 +557,14 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func createDirectories() error {
-	dirs := []string{
-		"uploads",
-		"uploads/attachments",
-	}
-
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %v", dir, err)
+// trustedProxies 受信任的反向代理（TRUSTED_PROXIES，逗号分隔的 IP 或 CIDR）。
+// 只有来自这些地址的请求才采信 X-Forwarded-For / X-Real-IP，未配置时不采信任何代理
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
 		}
 	}
-
-	return nil
+	return proxies
 }
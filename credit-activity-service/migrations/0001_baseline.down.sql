@@ -0,0 +1,17 @@
+DROP VIEW IF EXISTS detailed_applications_view;
+DROP VIEW IF EXISTS detailed_credit_activity_view;
+
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS saved_search_hits;
+DROP TABLE IF EXISTS saved_search_subscriptions;
+DROP TABLE IF EXISTS saved_searches;
+DROP TABLE IF EXISTS user_snapshots;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS notification_mutes;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS activity_collaborators;
+DROP TABLE IF EXISTS applications;
+DROP TABLE IF EXISTS activity_participants;
+DROP TABLE IF EXISTS credit_activities;
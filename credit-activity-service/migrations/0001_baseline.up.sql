@@ -0,0 +1,360 @@
+-- 学分活动服务基线结构：活动、参与者、申请、协作者、附件、发件箱、站内通知、出站 webhook、用户快照、保存的搜索。
+-- 依赖用户服务的 users 表（需先执行用户服务的迁移）。与原 database/init.sql 一致，全部使用 IF NOT EXISTS，
+-- 由旧版 init.sql 创建的数据库执行本迁移即可纳入版本管理
+
+-- 创建学分活动表
+CREATE TABLE IF NOT EXISTS credit_activities
+(
+    id              UUID PRIMARY KEY      DEFAULT gen_random_uuid(),
+    title           VARCHAR(200) NOT NULL CHECK (LENGTH(TRIM(title)) > 0),
+    description     TEXT,
+    start_date      DATE         NOT NULL,
+    end_date        DATE         NOT NULL CHECK (end_date >= start_date),
+    status          VARCHAR(20)  NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'pending_review', 'approved', 'rejected')),
+    category        VARCHAR(100) NOT NULL CHECK (LENGTH(TRIM(category)) > 0),
+    owner_id        UUID         NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    reviewer_id     UUID         REFERENCES users (uuid) ON DELETE SET NULL,
+    review_comments TEXT,
+    reviewed_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      TIMESTAMPTZ,
+    details         JSONB        NOT NULL DEFAULT '{}'::jsonb,
+    version         BIGINT       NOT NULL DEFAULT 1, -- 乐观锁版本号，每次修改 +1
+    -- 以下字段由定时任务维护
+    enrollment_closed_at TIMESTAMPTZ, -- 活动结束后自动关闭报名的时间
+    overdue_flagged_at   TIMESTAMPTZ, -- 草稿超过提交宽限期被标记的时间
+    review_reminded_at   TIMESTAMPTZ, -- 最近一次催办审核的时间
+    -- 全文搜索，由学分活动服务的后台任务维护
+    search_vector        TSVECTOR,    -- 标题（A）、描述（B）、详情（C）的二元切分、拼音及可选中文分词结果
+    search_indexed_at    TIMESTAMPTZ  -- 建立索引时活动的 updated_at，不一致表示需要重建
+);
+-- 旧版 init.sql 创建的活动表缺少以下列（此前由服务启动时补齐）
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS details JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS enrollment_closed_at TIMESTAMPTZ;
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS overdue_flagged_at TIMESTAMPTZ;
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS review_reminded_at TIMESTAMPTZ;
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+ALTER TABLE credit_activities ADD COLUMN IF NOT EXISTS search_indexed_at TIMESTAMPTZ;
+
+-- 创建活动参与者表
+CREATE TABLE IF NOT EXISTS activity_participants
+(
+    id          UUID PRIMARY KEY       DEFAULT gen_random_uuid(),
+    activity_id UUID          NOT NULL REFERENCES credit_activities (id) ON DELETE CASCADE,
+    user_id UUID          NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    credits     DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (credits >= 0),
+    joined_at   TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version     BIGINT        NOT NULL DEFAULT 1, -- 乐观锁版本号（学分修改）
+    created_at  TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  TIMESTAMPTZ
+);
+ALTER TABLE activity_participants ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- 创建申请表
+CREATE TABLE IF NOT EXISTS applications
+(
+    id              UUID PRIMARY KEY       DEFAULT gen_random_uuid(),
+    activity_id     UUID          NOT NULL REFERENCES credit_activities (id) ON DELETE CASCADE,
+    user_id    UUID          NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    status          VARCHAR(20)   NOT NULL DEFAULT 'approved' CHECK (status IN ('approved')),
+    applied_credits DECIMAL(5, 2) NOT NULL CHECK (applied_credits >= 0),
+    awarded_credits DECIMAL(5, 2) NOT NULL CHECK (awarded_credits >= 0),
+    submitted_at    TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      TIMESTAMPTZ,
+    UNIQUE (activity_id, user_id)
+);
+
+-- 创建活动协作者表（共同所有者 / 编辑者 / 查看者）
+CREATE TABLE IF NOT EXISTS activity_collaborators
+(
+    id          UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    activity_id UUID        NOT NULL REFERENCES credit_activities (id) ON DELETE CASCADE,
+    user_id     UUID        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    role        VARCHAR(20) NOT NULL DEFAULT 'viewer' CHECK (role IN ('owner', 'editor', 'viewer')),
+    invited_by  UUID        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  TIMESTAMPTZ
+);
+
+-- 创建领域事件发件箱表（与业务修改同一事务写入，由分发器异步投递；不设外键，事件需在活动被清理后保留）
+CREATE TABLE IF NOT EXISTS outbox_events
+(
+    id              UUID PRIMARY KEY      DEFAULT gen_random_uuid(),
+    event_type      VARCHAR(100) NOT NULL,
+    aggregate_type  VARCHAR(50)  NOT NULL,
+    aggregate_id    UUID         NOT NULL,
+    actor_id        VARCHAR(64),
+    payload         JSONB        NOT NULL DEFAULT '{}'::jsonb,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建站内通知表（由领域事件生成，(event_id, user_id) 唯一保证事件重投不重复通知）
+CREATE TABLE IF NOT EXISTS notifications
+(
+    id          UUID PRIMARY KEY      DEFAULT gen_random_uuid(),
+    user_id     UUID         NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    event_id    UUID         NOT NULL,
+    event_type  VARCHAR(100) NOT NULL,
+    category    VARCHAR(50)  NOT NULL,
+    title       VARCHAR(200) NOT NULL,
+    content     TEXT,
+    activity_id UUID,
+    read_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uniq_notifications_event_user UNIQUE (event_id, user_id)
+);
+
+-- 创建通知类别屏蔽表
+CREATE TABLE IF NOT EXISTS notification_mutes
+(
+    user_id    UUID        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    category   VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category)
+);
+
+-- 创建出站 webhook 订阅表（签名密钥不对外返回，event_types 为订阅的事件类型数组，"*" 表示全部）
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id          UUID PRIMARY KEY      DEFAULT gen_random_uuid(),
+    name        VARCHAR(200)  NOT NULL,
+    url         VARCHAR(1000) NOT NULL,
+    secret      VARCHAR(200)  NOT NULL,
+    event_types JSONB         NOT NULL DEFAULT '[]'::jsonb,
+    active      BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_by  UUID,
+    created_at  TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  TIMESTAMPTZ
+);
+
+-- 创建出站 webhook 投递日志表（兼作重试队列，(subscription_id, event_id) 唯一保证事件重投不重复发送）
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              UUID PRIMARY KEY      DEFAULT gen_random_uuid(),
+    subscription_id UUID         NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        UUID         NOT NULL,
+    event_type      VARCHAR(100) NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          VARCHAR(20)  NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    response_status INTEGER,
+    response_body   TEXT,
+    last_error      TEXT,
+    delivered_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_webhook_delivery_event UNIQUE (subscription_id, event_id)
+);
+
+-- 创建用户快照表（学分活动服务维护的用户信息本地副本，由用户服务变更通知和定时同步更新）
+CREATE TABLE IF NOT EXISTS user_snapshots
+(
+    uuid       UUID PRIMARY KEY,
+    username   VARCHAR(20),
+    real_name  VARCHAR(50),
+    name_pinyin VARCHAR(255), -- 姓名全拼和首字母，形如 ' zhangsan zs'
+    user_type  VARCHAR(20),
+    status     VARCHAR(20),
+    student_id VARCHAR(18),
+    college    VARCHAR(100),
+    major      VARCHAR(100),
+    class      VARCHAR(50),
+    grade      VARCHAR(4),
+    department VARCHAR(100),
+    title      VARCHAR(50),
+    deleted    BOOLEAN     NOT NULL DEFAULT FALSE,
+    changed_at TIMESTAMPTZ NOT NULL,
+    synced_at  TIMESTAMPTZ
+);
+
+-- 创建保存的搜索表（filters 与搜索接口的查询参数同名；共享到部门时记录创建者所在部门）
+CREATE TABLE IF NOT EXISTS saved_searches
+(
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id      UUID         NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    kind          VARCHAR(20)  NOT NULL CHECK (kind IN ('activities', 'applications')),
+    filters       JSONB        NOT NULL DEFAULT '{}'::jsonb,
+    visibility    VARCHAR(20)  NOT NULL DEFAULT 'private' CHECK (visibility IN ('private', 'department')),
+    department_id UUID,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at    TIMESTAMPTZ
+);
+
+-- 创建保存搜索的订阅表（user_type 为订阅时的用户类型，后台检查新结果时据此确定数据范围）
+CREATE TABLE IF NOT EXISTS saved_search_subscriptions
+(
+    saved_search_id UUID        NOT NULL REFERENCES saved_searches (id) ON DELETE CASCADE,
+    user_id         UUID        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    user_type       VARCHAR(20) NOT NULL,
+    last_checked_at TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (saved_search_id, user_id)
+);
+
+-- 创建保存搜索的已通知结果表（同一条结果只通知订阅者一次）
+CREATE TABLE IF NOT EXISTS saved_search_hits
+(
+    saved_search_id UUID        NOT NULL,
+    user_id         UUID        NOT NULL,
+    item_id         UUID        NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (saved_search_id, user_id, item_id)
+);
+
+-- 创建附件表
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id             UUID PRIMARY KEY      DEFAULT gen_random_uuid(),
+    activity_id    UUID         NOT NULL REFERENCES credit_activities (id) ON DELETE CASCADE,
+    file_name      VARCHAR(255) NOT NULL CHECK (LENGTH(TRIM(file_name)) > 0),
+    original_name  VARCHAR(255) NOT NULL CHECK (LENGTH(TRIM(original_name)) > 0),
+    file_size      BIGINT       NOT NULL CHECK (file_size > 0 AND file_size <= 20971520), -- 最大20MB
+    file_type      VARCHAR(20)  NOT NULL CHECK (file_type IN
+                                                ('.pdf', '.doc', '.docx', '.txt', '.rtf', '.odt', '.jpg', '.jpeg',
+                                                 '.png', '.gif', '.bmp', '.webp', '.mp4', '.avi', '.mov', '.wmv',
+                                                 '.flv', '.mp3', '.wav', '.ogg', '.aac', '.zip', '.rar', '.7z', '.tar',
+                                                 '.gz', '.xls', '.xlsx', '.csv', '.ppt', '.pptx')),
+    file_category  VARCHAR(50)  NOT NULL CHECK (file_category IN
+                                                ('document', 'image', 'video', 'audio', 'archive', 'spreadsheet',
+                                                 'presentation', 'other')),
+    description    TEXT,
+    uploaded_by    UUID         NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    uploaded_at    TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    download_count INTEGER      NOT NULL DEFAULT 0 CHECK (download_count >= 0),
+    md5_hash       VARCHAR(32),
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at     TIMESTAMPTZ
+);
+
+-- 活动表索引
+CREATE INDEX IF NOT EXISTS idx_credit_activities_status ON credit_activities (status);
+CREATE INDEX IF NOT EXISTS idx_credit_activities_owner_id ON credit_activities (owner_id);
+CREATE INDEX IF NOT EXISTS idx_credit_activities_deleted_at ON credit_activities (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_credit_activities_end_date ON credit_activities (end_date) WHERE enrollment_closed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_activities_owner_status ON credit_activities (owner_id, status);
+CREATE INDEX IF NOT EXISTS idx_activities_category_status ON credit_activities (category, status);
+CREATE INDEX IF NOT EXISTS idx_credit_activities_search_vector ON credit_activities USING GIN (search_vector);
+
+-- 参与者表索引
+CREATE INDEX IF NOT EXISTS idx_activity_participants_activity_id ON activity_participants (activity_id);
+CREATE INDEX IF NOT EXISTS idx_activity_participants_user_id ON activity_participants (user_id);
+CREATE INDEX IF NOT EXISTS idx_activity_participants_deleted_at ON activity_participants (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_activity_participants_active ON activity_participants (activity_id, user_id) WHERE deleted_at IS NULL;
+
+-- 申请表索引
+CREATE INDEX IF NOT EXISTS idx_applications_activity_id ON applications (activity_id);
+CREATE INDEX IF NOT EXISTS idx_applications_user_id ON applications (user_id);
+CREATE INDEX IF NOT EXISTS idx_applications_status ON applications (status);
+CREATE INDEX IF NOT EXISTS idx_applications_deleted_at ON applications (deleted_at);
+
+-- 协作者表索引
+CREATE INDEX IF NOT EXISTS idx_activity_collaborators_activity_id ON activity_collaborators (activity_id);
+CREATE INDEX IF NOT EXISTS idx_activity_collaborators_user_id ON activity_collaborators (user_id);
+CREATE INDEX IF NOT EXISTS idx_activity_collaborators_deleted_at ON activity_collaborators (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_activity_collaborators_active ON activity_collaborators (activity_id, user_id) WHERE deleted_at IS NULL;
+
+-- 发件箱、站内通知、出站 webhook 索引
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (next_attempt_at, created_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate_id ON outbox_events (aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_event_type ON outbox_events (event_type);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications (user_id, category) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, created_at DESC);
+
+-- 用户快照表索引
+CREATE INDEX IF NOT EXISTS idx_user_snapshots_real_name ON user_snapshots (real_name);
+CREATE INDEX IF NOT EXISTS idx_user_snapshots_student_id ON user_snapshots (student_id);
+CREATE INDEX IF NOT EXISTS idx_user_snapshots_changed_at ON user_snapshots (changed_at);
+
+-- 保存的搜索表索引
+CREATE INDEX IF NOT EXISTS idx_saved_searches_owner_id ON saved_searches (owner_id);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_department_id ON saved_searches (department_id);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_deleted_at ON saved_searches (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_saved_search_subscriptions_user_id ON saved_search_subscriptions (user_id);
+
+-- 附件表索引
+CREATE INDEX IF NOT EXISTS idx_attachments_activity_id ON attachments (activity_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_uploaded_by ON attachments (uploaded_by);
+CREATE INDEX IF NOT EXISTS idx_attachments_file_category ON attachments (file_category);
+CREATE INDEX IF NOT EXISTS idx_attachments_file_type ON attachments (file_type);
+CREATE INDEX IF NOT EXISTS idx_attachments_md5_hash ON attachments (md5_hash);
+CREATE INDEX IF NOT EXISTS idx_attachments_deleted_at ON attachments (deleted_at);
+
+-- 活动详情视图
+CREATE OR REPLACE VIEW detailed_credit_activity_view AS
+SELECT ca.id                        AS activity_id,
+       ca.title,
+       ca.description,
+       ca.start_date,
+       ca.end_date,
+       ca.status,
+       ca.category,
+       ca.owner_id                  AS creator_id,
+       creator.real_name            AS creator_name,
+       creator.username             AS creator_username,
+       ca.created_at,
+       ca.updated_at,
+       (SELECT json_agg(json_build_object(
+               'id', p.id,
+               'username', u.username,
+               'real_name', u.real_name,
+               'credits', p.credits
+                        ))
+        FROM activity_participants p
+                 JOIN users u ON p.user_id = u.uuid
+        WHERE p.activity_id = ca.id
+          AND p.deleted_at IS NULL
+          AND u.deleted_at IS NULL) AS participants
+FROM credit_activities ca
+         JOIN
+     users creator ON ca.owner_id = creator.uuid
+WHERE ca.deleted_at IS NULL
+  AND creator.deleted_at IS NULL;
+
+-- 申请详情视图
+CREATE OR REPLACE VIEW detailed_applications_view AS
+SELECT
+    -- 申请本身
+    app.id            AS application_id,
+    app.status        AS application_status,
+    app.applied_credits,
+    app.awarded_credits,
+    app.submitted_at,
+
+    -- 申请人（学生）
+    u.uuid            AS applicant_id,
+    u.real_name       AS applicant_name,
+    u.username        AS applicant_username,
+    u.student_id AS student_id,        -- 学号
+    coll.name         AS applicant_college, -- 学部
+    maj.name          AS applicant_major,   -- 专业
+
+    -- 活动
+    act.id            AS activity_id,
+    act.title         AS activity_title,
+    act.category      AS activity_category
+FROM applications app
+         JOIN users u ON u.uuid = app.user_id-- 注意：一般是 app.user_id
+         JOIN credit_activities act ON act.id = app.activity_id
+         LEFT JOIN departments coll ON coll.id = u.department_id -- 学部
+         LEFT JOIN departments maj ON maj.id = coll.parent_id -- 如果专业存的是 parent_id，可再关联
+WHERE app.deleted_at IS NULL
+  AND u.deleted_at IS NULL
+  AND act.deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- 发件箱事件租约：分发器领取事件后提交事务，在事务外投递；租约到期前其他副本不会重复领取，
+-- 分发器崩溃时事件在租约到期后重新投递
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS channel_digest_runs;
@@ -0,0 +1,8 @@
+-- 外部渠道汇总的发送记录：每种汇总一行，记录最后发送的日期，
+-- 服务重启或定时任务切换到其他副本后不会重复发送当天的汇总
+CREATE TABLE IF NOT EXISTS channel_digest_runs
+(
+    name         VARCHAR(50) PRIMARY KEY,
+    last_sent_on DATE        NOT NULL,
+    sent_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
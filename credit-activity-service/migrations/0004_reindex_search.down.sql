@@ -0,0 +1,2 @@
+-- 旧的分词规则能匹配的查询新索引同样能匹配，回滚时无需重建索引
+SELECT 1;
@@ -0,0 +1,2 @@
+-- 分词规则改为从每个汉字开始索引拼音首字母，清空索引时间让 IndexPending 重建全部活动的搜索索引
+UPDATE credit_activities SET search_indexed_at = NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS attempts_before_redelivery;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS redelivered_at;
@@ -0,0 +1,4 @@
+-- 手动重投不再清零 attempts：记录重投时间和重投时已尝试的次数，
+-- 投递任务按本轮的尝试次数退避和判断是否放弃，attempts 保留累计次数
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS redelivered_at TIMESTAMPTZ;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS attempts_before_redelivery INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- webhook 投递租约：投递任务领取记录后提交事务，在事务外发送；租约到期前其他副本不会重复领取，
+-- 任务崩溃时记录在租约到期后重新发送
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
@@ -0,0 +1,13 @@
+-- webhook 投递尝试记录：投递记录只保留最近一次的结果，每次发送另记一行，便于排查接收方的历史响应
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts
+(
+    id              BIGSERIAL PRIMARY KEY,
+    delivery_id     UUID        NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempt         INTEGER     NOT NULL,
+    response_status INTEGER,
+    response_body   TEXT,
+    error           TEXT,
+    attempted_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, attempt);
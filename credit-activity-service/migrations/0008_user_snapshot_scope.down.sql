@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_snapshots_department_id;
+ALTER TABLE user_snapshots DROP COLUMN IF EXISTS scope_department_ids;
+ALTER TABLE user_snapshots DROP COLUMN IF EXISTS scope_restricted;
+ALTER TABLE user_snapshots DROP COLUMN IF EXISTS department_id;
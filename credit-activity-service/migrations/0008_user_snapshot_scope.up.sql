@@ -0,0 +1,11 @@
+-- 用户快照增加所在部门和数据范围：教师 / 管理员可见的部门节点由用户服务展开后随增量同步下发，
+-- 本服务按快照过滤，不再读取用户服务的 users、departments、department_scopes 表。
+-- 同步前按受限且范围为空处理（只能看到本人的数据），不会放大权限
+ALTER TABLE user_snapshots ADD COLUMN IF NOT EXISTS department_id UUID;
+ALTER TABLE user_snapshots ADD COLUMN IF NOT EXISTS scope_restricted BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE user_snapshots ADD COLUMN IF NOT EXISTS scope_department_ids JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_user_snapshots_department_id ON user_snapshots (department_id);
+
+-- 已有快照缺少新列：把同步游标退回起点，启动后的增量同步会重新拉取全部用户
+UPDATE user_snapshots SET changed_at = 'epoch';
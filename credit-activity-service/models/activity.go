@@ -50,9 +50,14 @@ type CreditActivity struct {
 	ReviewComments string            `json:"review_comments"`
 	ReviewedAt     *time.Time        `json:"reviewed_at"`
 	Details        datatypes.JSONMap `json:"details" gorm:"type:jsonb;default:'{}'::jsonb"`
-	CreatedAt      time.Time         `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt      time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt      gorm.DeletedAt    `json:"deleted_at" gorm:"index"`
+	Version        int64             `json:"version" gorm:"not null;default:1"` // 乐观锁版本号
+	// 定时任务维护的状态字段
+	EnrollmentClosedAt *time.Time     `json:"enrollment_closed_at"` // 活动结束后自动关闭报名的时间
+	OverdueFlaggedAt   *time.Time     `json:"overdue_flagged_at"`   // 草稿超期未提交被标记的时间
+	ReviewRemindedAt   *time.Time     `json:"review_reminded_at"`   // 最近一次催办审核的时间
+	CreatedAt          time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// 关联关系
 	Participants []ActivityParticipant `json:"participants" gorm:"foreignKey:ActivityID"`
@@ -63,6 +68,9 @@ func (ca *CreditActivity) BeforeCreate(tx *gorm.DB) error {
 	if ca.ID == "" {
 		ca.ID = uuid.New().String()
 	}
+	if ca.Version == 0 {
+		ca.Version = 1
+	}
 	return nil
 }
 
@@ -77,6 +85,7 @@ type ActivityParticipant struct {
 	UUID       string         `json:"user_id" gorm:"column:user_id;type:uuid;not null;index"`
 	Credits    float64        `json:"credits" gorm:"type:decimal(5,2);not null;default:0"`
 	JoinedAt   time.Time      `json:"joined_at" gorm:"default:CURRENT_TIMESTAMP"`
+	Version    int64          `json:"version" gorm:"not null;default:1"` // 乐观锁版本号（学分修改）
 	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
@@ -90,6 +99,9 @@ func (ap *ActivityParticipant) BeforeCreate(tx *gorm.DB) error {
 	if ap.ID == "" {
 		ap.ID = uuid.New().String()
 	}
+	if ap.Version == 0 {
+		ap.Version = 1
+	}
 	return nil
 }
 
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 协作者角色常量
+const (
+	CollaboratorRoleOwner  = "owner"  // 共同所有者：可编辑活动并管理协作者
+	CollaboratorRoleEditor = "editor" // 编辑者：可编辑活动、参与者和附件
+	CollaboratorRoleViewer = "viewer" // 查看者：只读
+)
+
+// ActivityCollaborator 活动协作者表
+type ActivityCollaborator struct {
+	ID         string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ActivityID string         `json:"activity_id" gorm:"type:uuid;not null;index"`
+	UserID     string         `json:"user_id" gorm:"type:uuid;not null;index"`
+	Role       string         `json:"role" gorm:"not null;default:'viewer'"`
+	InvitedBy  string         `json:"invited_by" gorm:"type:uuid;not null"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+func (ac *ActivityCollaborator) BeforeCreate(tx *gorm.DB) error {
+	if ac.ID == "" {
+		ac.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (ActivityCollaborator) TableName() string {
+	return "activity_collaborators"
+}
+
+// CollaboratorRequest 邀请协作者请求
+type CollaboratorRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// CollaboratorRoleRequest 修改协作者角色请求
+type CollaboratorRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// TransferOwnershipRequest 转移活动所有权请求
+type TransferOwnershipRequest struct {
+	NewOwnerID string `json:"new_owner_id" binding:"required"`
+	// PreviousOwnerRole 原所有者转移后保留的协作者角色，为空时移除原所有者
+	PreviousOwnerRole string `json:"previous_owner_role" binding:"omitempty,oneof=owner editor viewer"`
+}
+
+// CollaboratorResponse 协作者响应
+type CollaboratorResponse struct {
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UserInfo  *UserInfo `json:"user_info,omitempty"`
+}
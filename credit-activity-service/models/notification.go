@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 通知类别，用户可以按类别屏蔽
+const (
+	NotificationCategoryReview      = "review"       // 活动审核结果、待审核催办
+	NotificationCategoryParticipant = "participant"  // 被加入或移出活动
+	NotificationCategoryCredits     = "credits"      // 学分变动、学分申请生效或撤销
+	NotificationCategorySavedSearch = "saved_search" // 订阅的保存搜索出现新结果
+)
+
+// GetNotificationCategories 获取通知类别列表
+func GetNotificationCategories() []string {
+	return []string{
+		NotificationCategoryReview,
+		NotificationCategoryParticipant,
+		NotificationCategoryCredits,
+		NotificationCategorySavedSearch,
+	}
+}
+
+// Notification 站内通知表
+type Notification struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     string     `json:"user_id" gorm:"type:uuid;not null;index;uniqueIndex:uniq_notifications_event_user"`
+	EventID    string     `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:uniq_notifications_event_user"`
+	EventType  string     `json:"event_type" gorm:"not null"`
+	Category   string     `json:"category" gorm:"not null;index"`
+	Title      string     `json:"title" gorm:"not null"`
+	Content    string     `json:"content"`
+	ActivityID *string    `json:"activity_id" gorm:"type:uuid"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationMute 用户屏蔽的通知类别
+type NotificationMute struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	Category  string    `json:"category" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (NotificationMute) TableName() string {
+	return "notification_mutes"
+}
+
+// NotificationPreferencesRequest 更新通知偏好请求
+type NotificationPreferencesRequest struct {
+	MutedCategories []string `json:"muted_categories"`
+}
+
+// NotificationPreference 单个通知类别的偏好
+type NotificationPreference struct {
+	Category string `json:"category"`
+	Muted    bool   `json:"muted"`
+}
+
+// ChannelDigestRun 外部渠道汇总的发送记录
+type ChannelDigestRun struct {
+	Name       string    `json:"name" gorm:"primaryKey;size:50"`
+	LastSentOn string    `json:"last_sent_on" gorm:"type:date;not null"`
+	SentAt     time.Time `json:"sent_at" gorm:"not null"`
+}
+
+func (ChannelDigestRun) TableName() string {
+	return "channel_digest_runs"
+}
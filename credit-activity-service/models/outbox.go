@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 领域事件类型
+const (
+	EventActivitySubmitted   = "activity.submitted"
+	EventActivityWithdrawn   = "activity.withdrawn"
+	EventActivityApproved    = "activity.approved"
+	EventActivityRejected    = "activity.rejected"
+	EventApplicationsGranted = "applications.granted"
+	EventApplicationsRevoked = "applications.revoked"
+	EventParticipantAdded    = "participant.added"
+	EventParticipantRemoved  = "participant.removed"
+	EventParticipantLeft     = "participant.left"
+	EventCreditsChanged      = "credits.changed"
+)
+
+// DomainEventTypes 所有可订阅的领域事件类型
+var DomainEventTypes = []string{
+	EventActivitySubmitted,
+	EventActivityWithdrawn,
+	EventActivityApproved,
+	EventActivityRejected,
+	EventApplicationsGranted,
+	EventApplicationsRevoked,
+	EventParticipantAdded,
+	EventParticipantRemoved,
+	EventParticipantLeft,
+	EventCreditsChanged,
+}
+
+// 事件聚合类型
+const (
+	AggregateActivity = "activity"
+)
+
+// OutboxEvent 事务性发件箱事件表，与业务数据在同一事务中写入，由分发器异步投递
+type OutboxEvent struct {
+	ID            string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	EventType     string            `json:"event_type" gorm:"not null;index"`
+	AggregateType string            `json:"aggregate_type" gorm:"not null"`
+	AggregateID   string            `json:"aggregate_id" gorm:"type:uuid;not null;index"`
+	ActorID       string            `json:"actor_id"`
+	Payload       datatypes.JSONMap `json:"payload" gorm:"type:jsonb;default:'{}'::jsonb"`
+	Attempts      int               `json:"attempts" gorm:"not null;default:0"`
+	LastError     string            `json:"last_error"`
+	NextAttemptAt time.Time         `json:"next_attempt_at" gorm:"not null"`
+	LockedUntil   *time.Time        `json:"locked_until"` // 分发器领取后的租约，到期前其他分发器不会重复领取
+	PublishedAt   *time.Time        `json:"published_at"`
+	CreatedAt     time.Time         `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.NextAttemptAt.IsZero() {
+		e.NextAttemptAt = time.Now()
+	}
+	return nil
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -28,6 +28,7 @@ type ActivityCreateResponse struct {
 	Status      string    `json:"status"`
 	Category    string    `json:"category"`
 	OwnerID     string    `json:"owner_id"`
+	Version     int64     `json:"version,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -41,6 +42,9 @@ type ActivityUpdateRequest struct {
 	Category    *string `json:"category"`
 
 	Details map[string]any `json:"details"`
+
+	// Version 客户端读取时的版本号，未通过 If-Match 传递时可放在请求体中
+	Version *int64 `json:"version"`
 }
 
 // ActivityReviewRequest 审核活动请求
@@ -63,6 +67,9 @@ type ActivityResponse struct {
 	ReviewerID         *string               `json:"reviewer_id"`
 	ReviewComments     string                `json:"review_comments"`
 	ReviewedAt         *time.Time            `json:"reviewed_at"`
+	Version            int64                 `json:"version"`
+	EnrollmentClosedAt *time.Time            `json:"enrollment_closed_at,omitempty"`
+	OverdueFlaggedAt   *time.Time            `json:"overdue_flagged_at,omitempty"`
 	CreatedAt          time.Time             `json:"created_at"`
 	UpdatedAt          time.Time             `json:"updated_at"`
 	// 列表场景下使用的聚合字段，避免一次性加载全部关联数据
@@ -71,6 +78,25 @@ type ActivityResponse struct {
 	Participants       []ParticipantResponse `json:"participants,omitempty"`
 	Applications       []ApplicationResponse `json:"applications,omitempty"`
 	Details            map[string]any        `json:"details"`
+	Highlights         *ActivityHighlights   `json:"highlights,omitempty"` // 关键词搜索时返回，标出命中位置
+}
+
+// ActivityHighlights 搜索结果中标出命中关键词的标题和描述摘要（HTML，已转义，命中处以 <mark> 包裹）
+type ActivityHighlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// DeletedActivityResponse 回收站中的活动
+type DeletedActivityResponse struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	Category  string    `json:"category"`
+	OwnerID   string    `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
 }
 
 // ApplicationResponse 申请响应
@@ -113,17 +139,21 @@ type AddParticipantsRequest struct {
 // BatchCreditsRequest 批量设置学分请求
 type BatchCreditsRequest struct {
 	CreditsMap map[string]float64 `json:"credits_map" binding:"required"`
+	// Versions 可选，用户ID -> 读取时的参与者版本号，用于冲突检测
+	Versions map[string]int64 `json:"versions"`
 }
 
 // SingleCreditsRequest 单个设置学分请求
 type SingleCreditsRequest struct {
 	Credits float64 `json:"credits" binding:"required,min=0"`
+	Version *int64  `json:"version"`
 }
 
 // ParticipantResponse 参与者响应
 type ParticipantResponse struct {
 	UUID     string    `json:"id"`
 	Credits  float64   `json:"credits"`
+	Version  int64     `json:"version,omitempty"`
 	JoinedAt time.Time `json:"joined_at"`
 	UserInfo *UserInfo `json:"user_info,omitempty"`
 }
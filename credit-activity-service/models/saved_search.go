@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 保存的搜索针对的列表
+const (
+	SavedSearchActivities   = "activities"   // 活动搜索（ActivitySearchRequest）
+	SavedSearchApplications = "applications" // 申请搜索（ApplicationSearchRequest）
+)
+
+// 保存的搜索的可见范围
+const (
+	SavedSearchPrivate    = "private"    // 仅创建者
+	SavedSearchDepartment = "department" // 共享给创建者所在部门，以及数据范围包含该部门的教师和管理员
+)
+
+// SavedSearchFilterKeys 各类搜索可以保存的过滤字段（与搜索接口的查询参数同名）
+var SavedSearchFilterKeys = map[string][]string{
+	SavedSearchActivities: {
+		"query", "category", "status", "owner_id", "college", "start_date", "end_date", "date_range", "sort_by", "sort_order",
+	},
+	SavedSearchApplications: {
+		"query", "activity_id", "id", "status", "start_date", "end_date", "date_range",
+		"min_credits", "max_credits", "college", "sort_by", "sort_order",
+	},
+}
+
+// SavedSearch 用户保存的搜索条件
+type SavedSearch struct {
+	ID           string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID      string            `json:"owner_id" gorm:"type:uuid;not null;index"`
+	Name         string            `json:"name" gorm:"size:100;not null"`
+	Kind         string            `json:"kind" gorm:"size:20;not null"`
+	Filters      datatypes.JSONMap `json:"filters" gorm:"type:jsonb;not null;default:'{}'::jsonb"`
+	Visibility   string            `json:"visibility" gorm:"size:20;not null;default:'private'"`
+	DepartmentID *string           `json:"department_id" gorm:"type:uuid;index"` // 共享时记录创建者所在部门
+	CreatedAt    time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt    gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+func (s *SavedSearch) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+// SavedSearchSubscription 订阅保存的搜索：出现新的匹配结果时发送站内通知
+type SavedSearchSubscription struct {
+	SavedSearchID string    `json:"saved_search_id" gorm:"primaryKey;type:uuid"`
+	UserID        string    `json:"user_id" gorm:"primaryKey;type:uuid;index"`
+	UserType      string    `json:"user_type" gorm:"size:20;not null"` // 订阅时的用户类型，后台检查时据此确定数据范围
+	LastCheckedAt time.Time `json:"last_checked_at" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (SavedSearchSubscription) TableName() string {
+	return "saved_search_subscriptions"
+}
+
+// SavedSearchHit 已通知过订阅者的匹配结果，同一条结果只通知一次
+type SavedSearchHit struct {
+	SavedSearchID string    `gorm:"primaryKey;type:uuid"`
+	UserID        string    `gorm:"primaryKey;type:uuid"`
+	ItemID        string    `gorm:"primaryKey;type:uuid"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+func (SavedSearchHit) TableName() string {
+	return "saved_search_hits"
+}
+
+// SavedSearchRequest 创建或修改保存的搜索
+type SavedSearchRequest struct {
+	Name       string            `json:"name" binding:"required,max=100"`
+	Kind       string            `json:"kind" binding:"required,oneof=activities applications"`
+	Filters    map[string]string `json:"filters"`
+	Visibility string            `json:"visibility" binding:"omitempty,oneof=private department"`
+}
+
+// SavedSearchResponse 保存的搜索
+type SavedSearchResponse struct {
+	SavedSearch
+	IsOwner    bool `json:"is_owner"`
+	Subscribed bool `json:"subscribed"`
+}
@@ -6,11 +6,13 @@ type ActivitySearchRequest struct {
 	Category  string `json:"category" form:"category"`     // 活动类别
 	Status    string `json:"status" form:"status"`         // 活动状态
 	OwnerID   string `json:"owner_id" form:"owner_id"`     // 创建者ID
+	College   string `json:"college" form:"college"`       // 创建者所在学部
 	StartDate string `json:"start_date" form:"start_date"` // 开始日期
 	EndDate   string `json:"end_date" form:"end_date"`     // 结束日期
+	DateRange string `json:"date_range" form:"date_range"` // 相对日期范围（this_term 等），未指定起止日期时生效
 	Page      int    `json:"page" form:"page"`             // 页码
 	PageSize  int    `json:"page_size" form:"page_size"`   // 每页数量
-	SortBy    string `json:"sort_by" form:"sort_by"`       // 排序字段
+	SortBy    string `json:"sort_by" form:"sort_by"`       // 排序字段，relevance 按相关度（有关键词时默认）
 	SortOrder string `json:"sort_order" form:"sort_order"` // 排序方向
 }
 
@@ -22,8 +24,10 @@ type ApplicationSearchRequest struct {
 	Status     string `json:"status" form:"status"`           // 申请状态
 	StartDate  string `json:"start_date" form:"start_date"`   // 开始日期
 	EndDate    string `json:"end_date" form:"end_date"`       // 结束日期
+	DateRange  string `json:"date_range" form:"date_range"`   // 相对日期范围（this_term 等），未指定起止日期时生效
 	MinCredits string `json:"min_credits" form:"min_credits"` // 最小学分
 	MaxCredits string `json:"max_credits" form:"max_credits"` // 最大学分
+	College    string `json:"college" form:"college"`         // 申请人所在学部
 	Page       int    `json:"page" form:"page"`               // 页码
 	PageSize   int    `json:"page_size" form:"page_size"`     // 每页数量
 	SortBy     string `json:"sort_by" form:"sort_by"`         // 排序字段
@@ -32,11 +36,15 @@ type ApplicationSearchRequest struct {
 
 // ParticipantSearchRequest 参与者搜索请求
 type ParticipantSearchRequest struct {
-	Query      string `json:"query" form:"query"`             // 关键词搜索
+	Query      string `json:"query" form:"query"`             // 关键词搜索（姓名、用户名或学号）
 	ActivityID string `json:"activity_id" form:"activity_id"` // 活动ID
 	UUID       string `json:"id" form:"id"`                   // 用户UUID
 	MinCredits string `json:"min_credits" form:"min_credits"` // 最小学分
 	MaxCredits string `json:"max_credits" form:"max_credits"` // 最大学分
+	College    string `json:"college" form:"college"`         // 学部
+	Major      string `json:"major" form:"major"`             // 专业
+	Class      string `json:"class" form:"class"`             // 班级
+	Grade      string `json:"grade" form:"grade"`             // 年级
 	Page       int    `json:"page" form:"page"`               // 页码
 	PageSize   int    `json:"page_size" form:"page_size"`     // 每页数量
 	SortBy     string `json:"sort_by" form:"sort_by"`         // 排序字段
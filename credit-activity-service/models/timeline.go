@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 用户动态时间线条目类型（学分活动服务提供的部分）
+const (
+	TimelineActivityCreated   = "activity_created"
+	TimelineActivitySubmitted = "activity_submitted"
+	TimelineReview            = "review"
+	TimelineCreditsReceived   = "credits_received"
+)
+
+// TimelineItem 用户动态时间线条目，由 user-service 与其他来源合并后按时间倒序展示
+type TimelineItem struct {
+	Type         string                 `json:"type"`
+	Title        string                 `json:"title"`
+	OccurredAt   time.Time              `json:"occurred_at"`
+	Source       string                 `json:"source"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   string                 `json:"resource_id"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+}
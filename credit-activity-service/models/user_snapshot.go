@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// UserSnapshot 用户服务中用户信息的本地副本，供搜索、导出和统计在本库内按学生属性关联查询。
+// 由用户服务的变更通知和定时增量同步维护；已删除的用户保留并标记 deleted，历史记录仍可显示姓名
+type UserSnapshot struct {
+	UUID       string    `json:"uuid" gorm:"primaryKey;column:uuid;type:uuid"`
+	Username   string    `json:"username" gorm:"size:20"`
+	RealName   string    `json:"real_name" gorm:"size:50;index"`
+	NamePinyin string    `json:"-" gorm:"size:255"` // 姓名全拼和首字母，形如 " zhangsan zs"，用于拼音搜索
+	UserType   string    `json:"user_type" gorm:"size:20"`
+	Status     string    `json:"status" gorm:"size:20"`
+	StudentID  string    `json:"student_id" gorm:"size:18;index"` // 学号；教师为工号
+	College    string    `json:"college" gorm:"size:100"`
+	Major      string    `json:"major" gorm:"size:100"`
+	Class      string    `json:"class" gorm:"size:50"`
+	Grade      string    `json:"grade" gorm:"size:4"`
+	Department string    `json:"department" gorm:"size:100"`
+	Title      string    `json:"title" gorm:"size:50"`
+	Deleted    bool      `json:"deleted" gorm:"not null;default:false"`
+	ChangedAt  time.Time `json:"changed_at" gorm:"not null;index"` // 用户服务中最后修改 / 删除的时间，作为增量同步的游标
+	SyncedAt   time.Time `json:"synced_at" gorm:"autoUpdateTime"`
+
+	// 所在部门节点，以及教师 / 管理员的数据范围（由用户服务展开为可见的部门节点）
+	DepartmentID       *string                     `json:"department_id" gorm:"type:uuid;index"`
+	ScopeRestricted    bool                        `json:"-" gorm:"not null"`
+	ScopeDepartmentIDs datatypes.JSONSlice[string] `json:"-" gorm:"type:jsonb;not null"`
+}
+
+func (UserSnapshot) TableName() string {
+	return "user_snapshots"
+}
+
+// ToUserInfo 转换为与用户服务查询结果相同的结构
+func (s UserSnapshot) ToUserInfo() *UserInfo {
+	return &UserInfo{
+		UUID:       s.UUID,
+		Username:   s.Username,
+		RealName:   s.RealName,
+		UserType:   s.UserType,
+		Status:     s.Status,
+		StudentID:  s.StudentID,
+		College:    s.College,
+		Major:      s.Major,
+		Class:      s.Class,
+		Grade:      s.Grade,
+		Department: s.Department,
+		Title:      s.Title,
+	}
+}
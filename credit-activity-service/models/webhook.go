@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 出站 webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEventAll 订阅全部事件类型
+const WebhookEventAll = "*"
+
+// WebhookSubscription 外部系统的 webhook 订阅，由管理员维护
+type WebhookSubscription struct {
+	ID         string                      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name       string                      `json:"name" gorm:"not null"`
+	URL        string                      `json:"url" gorm:"not null"`
+	Secret     string                      `json:"-" gorm:"not null"`
+	EventTypes datatypes.JSONSlice[string] `json:"event_types" gorm:"type:jsonb;not null;default:'[]'::jsonb"`
+	Active     bool                        `json:"active" gorm:"not null;default:true"`
+	CreatedBy  string                      `json:"created_by" gorm:"type:uuid"`
+	CreatedAt  time.Time                   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time                   `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt  gorm.DeletedAt              `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Matches 判断订阅是否关心指定事件类型
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == WebhookEventAll || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 单次事件到单个订阅的投递记录，同时作为重试队列
+type WebhookDelivery struct {
+	ID             string `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionID string `json:"subscription_id" gorm:"type:uuid;not null;uniqueIndex:uk_webhook_delivery_event"`
+	EventID        string `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:uk_webhook_delivery_event"`
+	EventType      string `json:"event_type" gorm:"not null"`
+	Payload        string `json:"payload" gorm:"type:text;not null"`
+	Status         string `json:"status" gorm:"not null;default:'pending';index"`
+	Attempts       int    `json:"attempts" gorm:"not null;default:0"`
+	// 最近一次手动重投的时间，以及重投时已尝试的次数（attempts 为累计次数，重投不清零）
+	RedeliveredAt            *time.Time `json:"redelivered_at"`
+	AttemptsBeforeRedelivery int        `json:"attempts_before_redelivery" gorm:"not null;default:0"`
+	NextAttemptAt            time.Time  `json:"next_attempt_at" gorm:"not null;index"`
+	LockedUntil              *time.Time `json:"locked_until"` // 投递任务领取后的租约，到期前其他副本不会重复领取
+	ResponseStatus           int        `json:"response_status"`
+	ResponseBody             string     `json:"response_body" gorm:"type:text"`
+	LastError                string     `json:"last_error"`
+	DeliveredAt              *time.Time `json:"delivered_at"`
+	CreatedAt                time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt                time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RoundAttempts 本轮（创建或最近一次手动重投以来）已尝试的次数，用于退避和判断是否放弃
+func (d *WebhookDelivery) RoundAttempts() int {
+	return d.Attempts - d.AttemptsBeforeRedelivery
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = time.Now()
+	}
+	return nil
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryAttempt 投递记录的一次发送尝试，投递记录本身只保留最近一次的结果
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id" gorm:"primaryKey"`
+	DeliveryID     string    `json:"delivery_id" gorm:"type:uuid;not null;index"`
+	Attempt        int       `json:"attempt" gorm:"not null"` // 累计的第几次尝试，与投递记录的 attempts 一致
+	ResponseStatus int       `json:"response_status"`
+	ResponseBody   string    `json:"response_body" gorm:"type:text"`
+	Error          string    `json:"error"`
+	AttemptedAt    time.Time `json:"attempted_at" gorm:"not null"`
+}
+
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
+
+// WebhookSubscriptionRequest 创建或更新 webhook 订阅请求
+type WebhookSubscriptionRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	URL        string   `json:"url" binding:"required,url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookSubscriptionCreatedResponse 创建订阅的响应，签名密钥仅在此时返回一次
+type WebhookSubscriptionCreatedResponse struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
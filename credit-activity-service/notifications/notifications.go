@@ -0,0 +1,199 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/realtime"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sink 把发件箱中的领域事件转换为站内通知，作为 outbox 分发器的一个投递目标。
+// 通知以 (event_id, user_id) 唯一，事件重投不会产生重复通知。
+type Sink struct {
+	db *gorm.DB
+}
+
+func NewSink(db *gorm.DB) *Sink {
+	return &Sink{db: db}
+}
+
+func (s *Sink) Name() string { return "notifications" }
+
+func (s *Sink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	return Send(ctx, s.db, Build(event, s.activityTitle(ctx, event)))
+}
+
+// Send 创建站内通知并实时推送：跳过屏蔽了该类别的用户，(event_id, user_id) 已存在的通知不重复创建。
+// 同一批通知须属于同一类别
+func Send(ctx context.Context, db *gorm.DB, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	muted, err := mutedUsers(ctx, db, notifications[0].Category, recipients(notifications))
+	if err != nil {
+		return err
+	}
+
+	toCreate := make([]models.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if !muted[n.UserID] {
+			toCreate = append(toCreate, n)
+		}
+	}
+	if len(toCreate) == 0 {
+		return nil
+	}
+
+	if err := db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&toCreate).Error; err != nil {
+		return err
+	}
+
+	// 冲突跳过的行不会写入本次生成的 ID，只推送确实新建的通知，事件重投时不重复推送
+	ids := make([]string, 0, len(toCreate))
+	for _, n := range toCreate {
+		ids = append(ids, n.ID)
+	}
+	var created []string
+	if err := db.WithContext(ctx).Model(&models.Notification{}).Where("id IN ?", ids).Pluck("id", &created).Error; err != nil {
+		return err
+	}
+	inserted := make(map[string]bool, len(created))
+	for _, id := range created {
+		inserted[id] = true
+	}
+	for _, n := range toCreate {
+		if inserted[n.ID] {
+			realtime.NotifyUser(n.UserID, realtime.EventNotification, n)
+		}
+	}
+	return nil
+}
+
+func (s *Sink) activityTitle(ctx context.Context, event models.OutboxEvent) string {
+	if title, ok := event.Payload["title"].(string); ok && title != "" {
+		return title
+	}
+	var titles []string
+	s.db.WithContext(ctx).Unscoped().Model(&models.CreditActivity{}).
+		Where("id = ?", event.AggregateID).Limit(1).Pluck("title", &titles)
+	if len(titles) > 0 {
+		return titles[0]
+	}
+	return ""
+}
+
+func mutedUsers(ctx context.Context, db *gorm.DB, category string, userIDs []string) (map[string]bool, error) {
+	var mutes []models.NotificationMute
+	if err := db.WithContext(ctx).
+		Where("category = ? AND user_id IN ?", category, userIDs).
+		Find(&mutes).Error; err != nil {
+		return nil, err
+	}
+	muted := make(map[string]bool, len(mutes))
+	for _, m := range mutes {
+		muted[m.UserID] = true
+	}
+	return muted, nil
+}
+
+func recipients(notifications []models.Notification) []string {
+	ids := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		ids = append(ids, n.UserID)
+	}
+	return ids
+}
+
+// Build 根据事件生成需要发送的通知（未过滤屏蔽设置），不关心的事件返回 nil
+func Build(event models.OutboxEvent, activityTitle string) []models.Notification {
+	activityID := event.AggregateID
+	name := fmt.Sprintf("「%s」", activityTitle)
+	newNotification := func(userID, category, title, content string) models.Notification {
+		return models.Notification{
+			UserID:     userID,
+			EventID:    event.ID,
+			EventType:  event.EventType,
+			Category:   category,
+			Title:      title,
+			Content:    content,
+			ActivityID: &activityID,
+		}
+	}
+
+	switch event.EventType {
+	case models.EventActivityApproved, models.EventActivityRejected:
+		ownerID := stringField(event.Payload, "owner_id")
+		if ownerID == "" {
+			return nil
+		}
+		title := "活动审核通过"
+		content := fmt.Sprintf("您的活动%s已审核通过", name)
+		if event.EventType == models.EventActivityRejected {
+			title = "活动审核未通过"
+			content = fmt.Sprintf("您的活动%s未通过审核", name)
+		}
+		if comments := stringField(event.Payload, "review_comments"); comments != "" {
+			content += "，审核意见：" + comments
+		}
+		return []models.Notification{newNotification(ownerID, models.NotificationCategoryReview, title, content)}
+
+	case models.EventParticipantAdded, models.EventParticipantRemoved:
+		userID := stringField(event.Payload, "user_id")
+		if userID == "" || userID == event.ActorID {
+			return nil
+		}
+		if event.EventType == models.EventParticipantAdded {
+			return []models.Notification{newNotification(userID, models.NotificationCategoryParticipant,
+				"您已被加入活动", fmt.Sprintf("您已被加入活动%s", name))}
+		}
+		return []models.Notification{newNotification(userID, models.NotificationCategoryParticipant,
+			"您已被移出活动", fmt.Sprintf("您已被移出活动%s", name))}
+
+	case models.EventCreditsChanged:
+		userID := stringField(event.Payload, "user_id")
+		if userID == "" {
+			return nil
+		}
+		return []models.Notification{newNotification(userID, models.NotificationCategoryCredits, "学分已调整",
+			fmt.Sprintf("您在活动%s中的学分由 %v 调整为 %v", name, event.Payload["previous_credits"], event.Payload["credits"]))}
+
+	case models.EventApplicationsGranted:
+		items, _ := event.Payload["applications"].([]interface{})
+		var result []models.Notification
+		for _, item := range items {
+			app, _ := item.(map[string]interface{})
+			userID := stringField(app, "user_id")
+			if userID == "" {
+				continue
+			}
+			result = append(result, newNotification(userID, models.NotificationCategoryCredits, "学分申请已生效",
+				fmt.Sprintf("活动%s已审核通过，您获得 %v 学分", name, app["credits"])))
+		}
+		return result
+
+	case models.EventApplicationsRevoked:
+		userIDs, _ := event.Payload["user_ids"].([]interface{})
+		var result []models.Notification
+		for _, id := range userIDs {
+			userID, _ := id.(string)
+			if userID == "" {
+				continue
+			}
+			result = append(result, newNotification(userID, models.NotificationCategoryCredits, "学分申请已撤销",
+				fmt.Sprintf("活动%s状态变更，相关学分申请已撤销", name)))
+		}
+		return result
+	}
+
+	return nil
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	value, _ := m[key].(string)
+	return value
+}
@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reviewOverdueEvent 审核催办通知的事件类型，由定时任务直接创建，不经过发件箱
+const reviewOverdueEvent = "activity.review_overdue"
+
+// reviewReminderNamespace 生成催办通知事件 ID 的命名空间
+var reviewReminderNamespace = uuid.MustParse("6f1c7f8e-3d52-4b8a-9a57-0c2e7f64a1d3")
+
+// ReviewReminder 把积压的待审核活动以站内通知提醒给数据范围包含该活动的在职教师和管理员
+type ReviewReminder struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewReviewReminder(db *gorm.DB) *ReviewReminder {
+	return &ReviewReminder{db: db, now: time.Now}
+}
+
+// RemindStaleReviews 审核人从本地用户快照中查询，不依赖用户服务可用
+func (r *ReviewReminder) RemindStaleReviews(ctx context.Context, activities []models.CreditActivity) error {
+	ids := make([]string, 0, len(activities))
+	for _, activity := range activities {
+		ids = append(ids, activity.ID)
+	}
+	reviewers, err := utils.ActivityReviewers(r.db.WithContext(ctx), ids)
+	if err != nil {
+		return err
+	}
+	return Send(ctx, r.db, ReviewReminders(activities, reviewers, r.now()))
+}
+
+// ReviewReminders 为每个积压活动给其审核人（以活动 ID 为键）各生成一条催办通知。
+// 事件 ID 由活动 ID、进入待审核的时间和上一次催办的时间确定：任务在标记已提醒前失败重试时不会重复通知，
+// 标记后到下一个催办周期再提醒时是新的事件
+func ReviewReminders(activities []models.CreditActivity, reviewers map[string][]string, now time.Time) []models.Notification {
+	var result []models.Notification
+	for _, activity := range activities {
+		activityID := activity.ID
+		round := ""
+		if activity.ReviewRemindedAt != nil {
+			round = activity.ReviewRemindedAt.UTC().Format(time.RFC3339Nano)
+		}
+		eventID := uuid.NewSHA1(reviewReminderNamespace, []byte(activity.ID+"|"+activity.UpdatedAt.UTC().Format(time.RFC3339Nano)+"|"+round)).String()
+		days := int(now.Sub(activity.UpdatedAt).Hours() / 24)
+		for _, reviewerID := range reviewers[activity.ID] {
+			result = append(result, models.Notification{
+				UserID:     reviewerID,
+				EventID:    eventID,
+				EventType:  reviewOverdueEvent,
+				Category:   models.NotificationCategoryReview,
+				Title:      "活动待审核",
+				Content:    fmt.Sprintf("活动「%s」已等待审核 %d 天，请尽快处理", activity.Title, days),
+				ActivityID: &activityID,
+			})
+		}
+	}
+	return result
+}
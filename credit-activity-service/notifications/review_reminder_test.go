@@ -0,0 +1,60 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewRemindersNotifyActivityReviewers(t *testing.T) {
+	now := time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC)
+	stale := models.CreditActivity{ID: "act-1", Title: "志愿服务", UpdatedAt: now.Add(-8 * 24 * time.Hour)}
+	other := models.CreditActivity{ID: "act-2", Title: "学科竞赛", UpdatedAt: now.Add(-8 * 24 * time.Hour)}
+	reviewers := map[string][]string{"act-1": {"teacher-1", "admin-1"}}
+
+	// 没有审核人的数据范围包含 act-2，不为它生成通知
+	result := ReviewReminders([]models.CreditActivity{stale, other}, reviewers, now)
+
+	require.Len(t, result, 2)
+	for i, reviewerID := range []string{"teacher-1", "admin-1"} {
+		n := result[i]
+		assert.Equal(t, reviewerID, n.UserID)
+		assert.Equal(t, models.NotificationCategoryReview, n.Category)
+		assert.Equal(t, reviewOverdueEvent, n.EventType)
+		assert.Equal(t, "活动「志愿服务」已等待审核 8 天，请尽快处理", n.Content)
+		require.NotNil(t, n.ActivityID)
+		assert.Equal(t, "act-1", *n.ActivityID)
+	}
+	assert.Equal(t, result[0].EventID, result[1].EventID, "同一活动的催办属于同一事件")
+}
+
+func TestReviewRemindersEventIDPerRound(t *testing.T) {
+	now := time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC)
+	stale := models.CreditActivity{ID: "act-1", Title: "志愿服务", UpdatedAt: now.Add(-8 * 24 * time.Hour)}
+	reviewers := map[string][]string{"act-1": {"teacher-1"}}
+
+	first := ReviewReminders([]models.CreditActivity{stale}, reviewers, now)
+	retried := ReviewReminders([]models.CreditActivity{stale}, reviewers, now.Add(time.Hour))
+	assert.Equal(t, first[0].EventID, retried[0].EventID, "标记已提醒前重试时事件 ID 不变，唯一索引去重")
+
+	// 已提醒过、到下一个催办周期仍未审核，是新的事件
+	remindedAt := now
+	stale.ReviewRemindedAt = &remindedAt
+	second := ReviewReminders([]models.CreditActivity{stale}, reviewers, now.Add(3*24*time.Hour))
+	assert.NotEqual(t, first[0].EventID, second[0].EventID)
+
+	// 活动被退回后重新提交，进入新的积压周期
+	stale.ReviewRemindedAt = nil
+	stale.UpdatedAt = now.Add(-7 * 24 * time.Hour)
+	next := ReviewReminders([]models.CreditActivity{stale}, reviewers, now)
+	assert.NotEqual(t, first[0].EventID, next[0].EventID)
+}
+
+func TestReviewRemindersWithoutReviewers(t *testing.T) {
+	stale := models.CreditActivity{ID: "act-1", Title: "志愿服务"}
+	assert.Empty(t, ReviewReminders([]models.CreditActivity{stale}, nil, time.Now()))
+}
@@ -0,0 +1,162 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+	defaultLease       = 10 * time.Minute
+	maxBackoff         = time.Hour
+)
+
+// Dispatcher 轮询发件箱并把未投递的事件发送给所有 Sink。
+// 在短事务中用 FOR UPDATE SKIP LOCKED 领取一批事件并写入租约（locked_until），提交后在事务外投递，
+// 投递结果再逐条用单独的语句记录。租约到期前其他副本不会领取同一批事件，分发器崩溃时事件在租约到期后重新投递；
+// 某个 Sink 失败时整条事件会重试，因此投递语义为至少一次，消费方应按事件 ID 去重。
+type Dispatcher struct {
+	db          *gorm.DB
+	sinks       []Sink
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	lease       time.Duration
+}
+
+func NewDispatcher(db *gorm.DB, interval time.Duration, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		sinks:       sinks,
+		interval:    interval,
+		batchSize:   defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		lease:       defaultLease,
+	}
+}
+
+// Start 在后台持续分发事件，直到 ctx 取消
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := d.DispatchOnce(ctx); err != nil {
+				log.Printf("[outbox] dispatch failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DispatchOnce 领取并投递一批到期事件，返回成功投递的数量。
+// 投递期间不持有事务和行锁，慢的 Sink 不会占用数据库连接
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	// 数据库时间精度为微秒，截断后才能在记录结果时按租约值匹配
+	lockedUntil := time.Now().Add(d.lease).Truncate(time.Microsecond)
+	events, err := d.claim(ctx, lockedUntil)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		// 租约已过期的事件可能已被其他副本领取，留给对方投递
+		if time.Now().After(lockedUntil) {
+			break
+		}
+		if err := d.publish(ctx, event); err != nil {
+			attempts := event.Attempts + 1
+			if attempts >= d.maxAttempts {
+				log.Printf("[outbox] giving up on event %s (%s) after %d attempts: %v", event.ID, event.EventType, attempts, err)
+			}
+			if err := d.release(ctx, event.ID, lockedUntil, map[string]interface{}{
+				"attempts":        attempts,
+				"last_error":      err.Error(),
+				"next_attempt_at": time.Now().Add(backoff(attempts)),
+			}); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err := d.release(ctx, event.ID, lockedUntil, map[string]interface{}{
+			"attempts":     event.Attempts + 1,
+			"last_error":   "",
+			"published_at": time.Now(),
+		}); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// claim 在短事务中锁定一批到期且未被租用的事件，写入租约后立即提交
+func (d *Dispatcher) claim(ctx context.Context, lockedUntil time.Time) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND attempts < ? AND next_attempt_at <= ?", d.maxAttempts, now).
+			Where("locked_until IS NULL OR locked_until <= ?", now).
+			Order("created_at ASC").
+			Limit(d.batchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("locked_until", lockedUntil).Error
+	})
+	return events, err
+}
+
+// release 记录投递结果并释放租约；租约已被其他副本重新领取时不覆盖对方的状态
+func (d *Dispatcher) release(ctx context.Context, eventID string, lockedUntil time.Time, updates map[string]interface{}) error {
+	updates["locked_until"] = nil
+	return d.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("id = ? AND locked_until = ?", eventID, lockedUntil).
+		Updates(updates).Error
+}
+
+func (d *Dispatcher) publish(ctx context.Context, event models.OutboxEvent) error {
+	var failures []string
+	for _, sink := range d.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", sink.Name(), err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// backoff 指数退避：2s, 4s, 8s ... 最长 1 小时
+func backoff(attempts int) time.Duration {
+	delay := time.Second << uint(attempts)
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,23 @@
+package outbox
+
+import (
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+)
+
+// Record 在调用方的事务中写入一条领域事件。
+// 必须传入与业务修改相同的 tx，事件才会与业务数据一起提交或回滚。
+func Record(tx *gorm.DB, eventType, aggregateID, actorID string, payload map[string]interface{}) error {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	event := models.OutboxEvent{
+		EventType:     eventType,
+		AggregateType: models.AggregateActivity,
+		AggregateID:   aggregateID,
+		ActorID:       actorID,
+		Payload:       payload,
+	}
+	return tx.Create(&event).Error
+}
@@ -0,0 +1,20 @@
+package realtime
+
+import "credit-management/shared/realtime"
+
+// 推送事件类型，发布者实现在共享模块的 realtime 包中（用户服务的导入进度也经同一通道推送）
+const (
+	EventNotification   = realtime.EventNotification
+	EventPendingReview  = realtime.EventPendingReview
+	EventImportProgress = realtime.EventImportProgress
+)
+
+// RedisPublisher 写入接收方的 Stream 并通过 pub/sub 广播给在线连接
+type RedisPublisher = realtime.RedisPublisher
+
+var (
+	NewRedisPublisher    = realtime.NewRedisPublisher
+	SetPublisher         = realtime.SetPublisher
+	NotifyUser           = realtime.NotifyUser
+	NotifyImportProgress = realtime.NotifyImportProgress
+)
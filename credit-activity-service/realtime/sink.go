@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"context"
+	"log"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/credit-activity-service/utils"
+
+	"gorm.io/gorm"
+)
+
+// Sink 把需要实时提醒的领域事件推送给在线用户，作为 outbox 分发器的投递目标。
+// 面向个人的审核结果等通过站内通知推送，这里只处理需要提醒审核人的事件。
+type Sink struct {
+	db *gorm.DB
+}
+
+func NewSink(db *gorm.DB) *Sink {
+	return &Sink{db: db}
+}
+
+func (s *Sink) Name() string { return "realtime" }
+
+func (s *Sink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	switch event.EventType {
+	case models.EventActivitySubmitted:
+		// 只推送给数据范围包含该活动的审核人；推送是尽力而为的，查询失败时不让 outbox 重投
+		reviewers, err := utils.ActivityReviewers(s.db.WithContext(ctx), []string{event.AggregateID})
+		if err != nil {
+			log.Printf("[realtime] failed to load reviewers of activity %s: %v", event.AggregateID, err)
+			return nil
+		}
+		data := map[string]interface{}{
+			"event_id":    event.ID,
+			"activity_id": event.AggregateID,
+			"title":       event.Payload["title"],
+			"category":    event.Payload["category"],
+			"owner_id":    event.Payload["owner_id"],
+		}
+		for _, reviewerID := range reviewers[event.AggregateID] {
+			NotifyUser(reviewerID, EventPendingReview, data)
+		}
+	}
+	return nil
+}
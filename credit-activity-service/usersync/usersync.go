@@ -0,0 +1,270 @@
+package usersync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"credit-management/credit-activity-service/fulltext"
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+const (
+	pageSize = 500
+	// 收到变更通知后稍等片刻再同步，把批量操作产生的一连串通知合并为一次
+	debounce = time.Second
+	// 增量同步从快照中最新的 changed_at 往回多读这么久：changed_at 取自事务内的 updated_at，
+	// 事务提交晚于后来者时，其行的 changed_at 会落在游标之前，只按游标翻页会漏掉。
+	// 重复读取的行按 UUID 覆盖写入，没有副作用；超过窗口的长事务由每日全量同步兜底
+	lookback = 5 * time.Minute
+)
+
+// Syncer 从用户服务的 /api/internal/users/changes 增量同步用户快照（user_snapshots）
+type Syncer struct {
+	db      *gorm.DB
+	baseURL string
+	auth    func(*http.Request) error
+	client  *http.Client
+
+	trigger chan struct{}
+	full    atomic.Bool
+}
+
+func NewSyncer(db *gorm.DB, baseURL string, auth func(*http.Request) error) *Syncer {
+	return &Syncer{
+		db:      db,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		auth:    auth,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Notify 收到用户变更通知时调用，不阻塞；"*"（部门结构变化）会触发一次全量同步，其余触发增量同步
+func (s *Syncer) Notify(key string) {
+	if key == "*" {
+		s.full.Store(true)
+	}
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run 启动时先做一次增量同步（快照为空时即全量），之后按通知触发，直到 ctx 取消。
+// 存在缺少姓名拼音的快照（升级前同步的数据）时，启动时改为全量同步以补齐
+func (s *Syncer) Run(ctx context.Context) {
+	var missingPinyin bool
+	s.db.WithContext(ctx).Raw(
+		"SELECT EXISTS (SELECT 1 FROM user_snapshots WHERE real_name ~ '[一-龥]' AND COALESCE(name_pinyin, '') = '')",
+	).Scan(&missingPinyin)
+	if err := s.Sync(ctx, missingPinyin); err != nil {
+		log.Printf("[usersync] initial sync failed: %v", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.trigger:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(debounce):
+		}
+		if err := s.Sync(ctx, s.full.Swap(false)); err != nil {
+			log.Printf("[usersync] sync failed: %v", err)
+		}
+	}
+}
+
+// SyncChanges 增量同步，供定时任务调用
+func (s *Syncer) SyncChanges(ctx context.Context) error {
+	return s.Sync(ctx, false)
+}
+
+// SyncAll 全量同步，修复错过的部门变更等无法通过用户修改时间发现的变化
+func (s *Syncer) SyncAll(ctx context.Context) error {
+	return s.Sync(ctx, true)
+}
+
+// Sync 从快照中最新的 changed_at 往前 lookback 处（full 时从头）拉取变更并写入快照
+func (s *Syncer) Sync(ctx context.Context, full bool) error {
+	var cursor struct {
+		ChangedAt time.Time
+		UUID      string
+	}
+	if !full {
+		if err := s.db.WithContext(ctx).Model(&models.UserSnapshot{}).
+			Select("changed_at, uuid").
+			Order("changed_at DESC, uuid DESC").
+			Limit(1).
+			Scan(&cursor).Error; err != nil {
+			return err
+		}
+		if !cursor.ChangedAt.IsZero() {
+			cursor.ChangedAt, cursor.UUID = cursor.ChangedAt.Add(-lookback), ""
+		}
+	}
+
+	synced := 0
+	for {
+		users, hasMore, err := s.fetch(ctx, cursor.ChangedAt, cursor.UUID)
+		if err != nil {
+			return err
+		}
+		if len(users) > 0 {
+			snapshots := make([]models.UserSnapshot, 0, len(users))
+			for _, user := range users {
+				snapshots = append(snapshots, user.toSnapshot())
+			}
+			if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&snapshots).Error; err != nil {
+				return err
+			}
+			last := users[len(users)-1]
+			cursor.ChangedAt, cursor.UUID = last.ChangedAt, last.UUID
+			synced += len(users)
+		}
+		if !hasMore {
+			break
+		}
+	}
+	if synced > 0 {
+		log.Printf("[usersync] synced %d users (full=%t)", synced, full)
+	}
+	return nil
+}
+
+// profile 用户服务返回的用户信息
+type profile struct {
+	UUID       string    `json:"uuid"`
+	Username   string    `json:"username"`
+	RealName   string    `json:"real_name"`
+	UserType   string    `json:"user_type"`
+	Status     string    `json:"status"`
+	StudentID  string    `json:"student_id"`
+	TeacherID  string    `json:"teacher_id"`
+	Grade      string    `json:"grade"`
+	Title      string    `json:"title"`
+	Department string    `json:"department"`
+	College    string    `json:"college"`
+	Major      string    `json:"major"`
+	Class      string    `json:"class"`
+	Deleted    bool      `json:"deleted"`
+	ChangedAt  time.Time `json:"changed_at"`
+
+	DepartmentID       *string  `json:"department_id"`
+	ScopeRestricted    bool     `json:"scope_restricted"`
+	ScopeDepartmentIDs []string `json:"scope_department_ids"`
+}
+
+func (p profile) toSnapshot() models.UserSnapshot {
+	studentID := p.StudentID
+	if p.UserType == "teacher" {
+		studentID = p.TeacherID
+	}
+	scopeDepartments := p.ScopeDepartmentIDs
+	if scopeDepartments == nil {
+		scopeDepartments = []string{}
+	}
+	return models.UserSnapshot{
+		UUID:       p.UUID,
+		Username:   p.Username,
+		RealName:   p.RealName,
+		NamePinyin: fulltext.NamePinyin(p.RealName),
+		UserType:   p.UserType,
+		Status:     p.Status,
+		StudentID:  studentID,
+		College:    p.College,
+		Major:      p.Major,
+		Class:      p.Class,
+		Grade:      p.Grade,
+		Department: p.Department,
+		Title:      p.Title,
+		Deleted:    p.Deleted,
+		ChangedAt:  p.ChangedAt,
+
+		DepartmentID:       p.DepartmentID,
+		ScopeRestricted:    p.ScopeRestricted,
+		ScopeDepartmentIDs: scopeDepartments,
+	}
+}
+
+func (s *Syncer) fetch(ctx context.Context, since time.Time, after string) ([]profile, bool, error) {
+	query := url.Values{}
+	query.Set("since", since.UTC().Format(time.RFC3339Nano))
+	if after != "" {
+		query.Set("after", after)
+	}
+	query.Set("limit", strconv.Itoa(pageSize))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/internal/users/changes?"+query.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if s.auth != nil {
+		if err := s.auth(req); err != nil {
+			return nil, false, err
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("请求用户服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("用户服务返回错误状态码: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Users   []profile `json:"users"`
+			HasMore bool      `json:"has_more"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if body.Code != 0 {
+		return nil, false, fmt.Errorf("用户服务返回错误: %s", body.Message)
+	}
+	return body.Data.Users, body.Data.HasMore, nil
+}
+
+// Lookup 从快照读取用户信息，返回以 UUID 为键的结果（包含已删除的用户）
+func Lookup(db *gorm.DB, uuids []string) (map[string]*models.UserInfo, error) {
+	result := make(map[string]*models.UserInfo, len(uuids))
+	ids := make([]string, 0, len(uuids))
+	for _, id := range uuids {
+		if uuidPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var snapshots []models.UserSnapshot
+	if err := db.Where("uuid IN ?", ids).Find(&snapshots).Error; err != nil {
+		return result, err
+	}
+	for _, snapshot := range snapshots {
+		result[snapshot.UUID] = snapshot.ToUserInfo()
+	}
+	return result, nil
+}
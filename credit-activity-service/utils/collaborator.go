@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+)
+
+// GetActivityRole 返回用户在活动中的角色：活动创建者视为 owner，
+// 其余用户取 activity_collaborators 中的角色，无关系时返回空字符串
+func GetActivityRole(db *gorm.DB, activity *models.CreditActivity, userID string) string {
+	if activity.OwnerID == userID {
+		return models.CollaboratorRoleOwner
+	}
+
+	var collaborator models.ActivityCollaborator
+	if err := db.Where("activity_id = ? AND user_id = ?", activity.ID, userID).First(&collaborator).Error; err != nil {
+		return ""
+	}
+	return collaborator.Role
+}
+
+// CanEditActivity 角色是否可编辑活动内容、参与者和附件
+func CanEditActivity(role string) bool {
+	return role == models.CollaboratorRoleOwner || role == models.CollaboratorRoleEditor
+}
+
+// CanManageCollaborators 角色是否可管理协作者
+func CanManageCollaborators(role string) bool {
+	return role == models.CollaboratorRoleOwner
+}
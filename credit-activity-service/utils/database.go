@@ -96,19 +96,19 @@ func (h *BaseHandler) GetUserParticipatedActivities(userID string, page, limit i
 	return activities, total, err
 }
 
-// GetPendingActivities 获取待审核活动
-func (h *BaseHandler) GetPendingActivities(page, limit int) ([]models.CreditActivity, int64, error) {
+// GetPendingActivities 获取数据范围内的待审核活动
+func (h *BaseHandler) GetPendingActivities(scope *DataScope, page, limit int) ([]models.CreditActivity, int64, error) {
 	var activities []models.CreditActivity
 	var total int64
 
-	err := h.db.Model(&models.CreditActivity{}).
+	err := scope.Activities(h.db.Model(&models.CreditActivity{})).
 		Where("status = ?", models.StatusPendingReview).
 		Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = h.db.Where("status = ?", models.StatusPendingReview).
+	err = scope.Activities(h.db).Where("status = ?", models.StatusPendingReview).
 		Offset((page - 1) * limit).
 		Limit(limit).
 		Order("created_at DESC").
@@ -139,16 +139,21 @@ func (h *BaseHandler) GetDeletableActivities(userID string, page, limit int) ([]
 	return activities, total, err
 }
 
-// SearchActivities 搜索活动
-func (h *BaseHandler) SearchActivities(query, status, category, ownerID string, userID, userType string, page, limit int) ([]models.CreditActivity, int64, error) {
+// ActivityCreatedAtKey 活动列表的默认排序：按创建时间倒序
+var ActivityCreatedAtKey = SortKey{Column: "created_at", IDColumn: "id", Desc: true}
+
+// SearchActivities 搜索活动，教师和部门管理员限制在 scope 范围内
+func (h *BaseHandler) SearchActivities(query, status, category, ownerID string, userID, userType string, scope *DataScope, pager *Pager) ([]models.CreditActivity, int64, error) {
 	var activities []models.CreditActivity
-	var total int64
 
-	dbQuery := h.db.Model(&models.CreditActivity{})
+	dbQuery := scope.Activities(h.db.Model(&models.CreditActivity{}))
 
 	// 权限过滤
 	if userType == "student" {
-		dbQuery = dbQuery.Where("owner_id = ? OR id IN (SELECT activity_id FROM activity_participants WHERE user_id = ?)", userID, userID)
+		dbQuery = dbQuery.Where(
+			"owner_id = ? OR id IN (SELECT activity_id FROM activity_participants WHERE user_id = ?) OR id IN (SELECT activity_id FROM activity_collaborators WHERE user_id = ? AND deleted_at IS NULL)",
+			userID, userID, userID,
+		)
 	}
 
 	// 搜索条件
@@ -170,18 +175,24 @@ func (h *BaseHandler) SearchActivities(query, status, category, ownerID string,
 	}
 
 	// 获取总数
-	err := dbQuery.Count(&total).Error
+	total, err := pager.Count(dbQuery)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取数据
-	err = dbQuery.Offset((page - 1) * limit).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&activities).Error
+	dbQuery, err = pager.Apply(dbQuery, ActivityCreatedAtKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := dbQuery.Find(&activities).Error; err != nil {
+		return nil, 0, err
+	}
+	activities = PageRows(pager, activities, ActivityCreatedAtKey, func(a models.CreditActivity) (interface{}, string) {
+		return a.CreatedAt, a.ID
+	})
 
-	return activities, total, err
+	return activities, total, nil
 }
 
 // GetActivityParticipants 获取活动参与者
@@ -72,4 +72,43 @@ func IsValidDateRange(startDate, endDate time.Time) bool {
 		return true // 允许空日期
 	}
 	return !startDate.After(endDate)
-} 
\ No newline at end of file
+} 
+// 相对日期范围，保存的搜索中使用，每次执行时按当天换算为具体日期
+const (
+	DateRangeThisTerm   = "this_term"    // 本学期
+	DateRangeThisMonth  = "this_month"   // 本月
+	DateRangeThisYear   = "this_year"    // 本年
+	DateRangeLast30Days = "last_30_days" // 最近 30 天
+)
+
+// ResolveDateRange 把相对日期范围换算为起止日期（YYYY-MM-DD）。
+// 学期按春季 2 月 1 日至 7 月 31 日、秋季 8 月 1 日至次年 1 月 31 日计算
+func ResolveDateRange(name string, now time.Time) (string, string, error) {
+	year, month, day := now.Date()
+	loc := now.Location()
+	var start, end time.Time
+	switch name {
+	case DateRangeThisTerm:
+		switch {
+		case month >= time.August:
+			start = time.Date(year, time.August, 1, 0, 0, 0, 0, loc)
+		case month == time.January:
+			start = time.Date(year-1, time.August, 1, 0, 0, 0, 0, loc)
+		default:
+			start = time.Date(year, time.February, 1, 0, 0, 0, 0, loc)
+		}
+		end = start.AddDate(0, 6, -1)
+	case DateRangeThisMonth:
+		start = time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 1, -1)
+	case DateRangeThisYear:
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		end = time.Date(year, time.December, 31, 0, 0, 0, 0, loc)
+	case DateRangeLast30Days:
+		end = time.Date(year, month, day, 0, 0, 0, 0, loc)
+		start = end.AddDate(0, 0, -29)
+	default:
+		return "", "", fmt.Errorf("不支持的日期范围: %s", name)
+	}
+	return FormatDate(start), FormatDate(end), nil
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FormatETag 将资源版本号格式化为强 ETag
+func FormatETag(version int64) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// SetETag 在响应头中写入资源版本号
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", FormatETag(version))
+}
+
+// ParseIfMatch 解析 If-Match 请求头中的版本号。
+// 未携带或为 "*" 时返回 ok=false，表示调用方未要求版本校验。
+func ParseIfMatch(header string) (version int64, ok bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+
+	// 只接受单个 ETag，兼容弱校验前缀
+	header = strings.TrimPrefix(header, "W/")
+	header = strings.Trim(header, "\"")
+	version, err = strconv.ParseInt(header, 10, 64)
+	if err != nil || version < 1 {
+		return 0, false, fmt.Errorf("无效的 If-Match 版本号")
+	}
+	return version, true, nil
+}
+
+// ExpectedVersion 优先使用 If-Match 请求头，其次使用请求体中的版本号
+func ExpectedVersion(c *gin.Context, bodyVersion *int64) (int64, bool, error) {
+	version, ok, err := ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil || ok {
+		return version, ok, err
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true, nil
+	}
+	return 0, false, nil
+}
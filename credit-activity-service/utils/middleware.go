@@ -2,24 +2,64 @@ package utils
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 
+	"credit-management/credit-activity-service/models"
+	"credit-management/shared/servicetoken"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// HeaderAuthMiddleware 基于请求头的认证中间件
-type HeaderAuthMiddleware struct{}
+// HeaderAuthMiddleware 基于服务令牌的认证中间件：请求必须携带认证服务签发的 X-Service-Token，
+// 只有网关的请求才采信其传递的 X-User-* 用户信息，其他内部服务以系统身份调用
+type HeaderAuthMiddleware struct {
+	verifier *servicetoken.Verifier
+}
 
 // NewHeaderAuthMiddleware 创建新的认证中间件
 func NewHeaderAuthMiddleware() *HeaderAuthMiddleware {
-	return &HeaderAuthMiddleware{}
+	verifier, err := DefaultServiceVerifier()
+	if err != nil {
+		log.Fatal("服务令牌校验器初始化失败: ", err)
+	}
+	return &HeaderAuthMiddleware{verifier: verifier}
+}
+
+// verifyService 校验服务令牌，返回调用方服务名；失败时已写入响应
+func (m *HeaderAuthMiddleware) verifyService(c *gin.Context) (string, bool) {
+	token := c.GetHeader(ServiceTokenHeader)
+	if token == "" {
+		SendUnauthorized(c)
+		c.Abort()
+		return "", false
+	}
+	service, err := m.verifier.Verify(c.Request.Context(), token)
+	if err != nil {
+		log.Printf("服务令牌校验失败: %v", err)
+		SendUnauthorized(c)
+		c.Abort()
+		return "", false
+	}
+	c.Set("service", service)
+	return service, true
 }
 
 // AuthRequired 需要认证的中间件
 func (m *HeaderAuthMiddleware) AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		service, ok := m.verifyService(c)
+		if !ok {
+			return
+		}
+		if service != GatewayService {
+			setSystemIdentity(c)
+			c.Next()
+			return
+		}
+
 		userID := c.GetHeader("X-User-ID")
 		username := c.GetHeader("X-Username")
 		userType := c.GetHeader("X-User-Type")
@@ -39,6 +79,24 @@ func (m *HeaderAuthMiddleware) AuthRequired() gin.HandlerFunc {
 	}
 }
 
+// InternalOnly 仅允许内部服务调用（网关不转发 /api/internal，且须持有有效的服务令牌）
+func (m *HeaderAuthMiddleware) InternalOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := m.verifyService(c); !ok {
+			return
+		}
+		setSystemIdentity(c)
+		c.Next()
+	}
+}
+
+// setSystemIdentity 内部服务调用以系统管理员身份执行
+func setSystemIdentity(c *gin.Context) {
+	c.Set("id", "system")
+	c.Set("username", "system")
+	c.Set("user_type", "admin")
+}
+
 // PermissionMiddleware 权限控制中间件
 type PermissionMiddleware struct{
 	db *gorm.DB
@@ -117,7 +175,7 @@ func (m *PermissionMiddleware) AdminOnly() gin.HandlerFunc {
 	}
 }
 
-// ActivityOwnerOrTeacherOrAdmin 活动所有者、教师或管理员可以访问
+// ActivityOwnerOrTeacherOrAdmin 活动所有者（含共同所有者、编辑者协作者）、教师或管理员可以访问
 func (m *PermissionMiddleware) ActivityOwnerOrTeacherOrAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("id")
@@ -130,13 +188,17 @@ func (m *PermissionMiddleware) ActivityOwnerOrTeacherOrAdmin() gin.HandlerFunc {
 		userType, _ := c.Get("user_type")
 		activityID := c.Param("id")
 
-		// 教师或管理员可以直接访问
+		// 教师或管理员可以访问数据范围内的活动
 		if userType == "teacher" || userType == "admin" {
+			if activityID != "" && !RequireActivityInScope(c, m.db, activityID) {
+				c.Abort()
+				return
+			}
 			c.Next()
 			return
 		}
 
-		// 学生需要检查是否为活动所有者
+		// 学生需要检查是否为活动所有者或具有编辑权限的协作者
 		if userType == "student" {
 			if activityID == "" {
 				SendForbidden(c, "缺少活动ID")
@@ -144,14 +206,8 @@ func (m *PermissionMiddleware) ActivityOwnerOrTeacherOrAdmin() gin.HandlerFunc {
 				return
 			}
 
-			// 查询活动是否存在以及所有者是否为当前用户
-			var activity struct {
-				OwnerID string
-			}
-			if err := m.db.Table("credit_activities").
-				Select("owner_id").
-				Where("id = ? AND deleted_at IS NULL", activityID).
-				First(&activity).Error; err != nil {
+			var activity models.CreditActivity
+			if err := m.db.Select("id", "owner_id").Where("id = ?", activityID).First(&activity).Error; err != nil {
 				if err == gorm.ErrRecordNotFound {
 					SendNotFound(c, "活动不存在")
 				} else {
@@ -161,8 +217,7 @@ func (m *PermissionMiddleware) ActivityOwnerOrTeacherOrAdmin() gin.HandlerFunc {
 				return
 			}
 
-			// 检查是否为活动所有者
-			if activity.OwnerID != userID.(string) {
+			if !CanEditActivity(GetActivityRole(m.db, &activity, userID.(string))) {
 				SendForbidden(c, "无权限访问此资源")
 				c.Abort()
 				return
@@ -198,7 +253,8 @@ func CORSMiddleware() gin.HandlerFunc {
 			}
 		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-User-ID, X-Username, X-User-Type")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-User-ID, X-Username, X-User-Type, If-Match")
+		c.Header("Access-Control-Expose-Headers", "ETag")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"credit-management/shared/servicetoken"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuth(t *testing.T) (*HeaderAuthMiddleware, *servicetoken.Signer) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := servicetoken.NewSigner(privateKey)
+	verifier, err := servicetoken.NewVerifier("http://auth-service:8081", base64.StdEncoding.EncodeToString(signer.PublicKey()), servicetoken.CreditActivityService)
+	require.NoError(t, err)
+	return &HeaderAuthMiddleware{verifier: verifier}, signer
+}
+
+// serveAuth 经过 AuthRequired 后返回上下文中的用户身份
+func serveAuth(m *HeaderAuthMiddleware, headers map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me", m.AuthRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.GetString("id"), "user_type": c.GetString("user_type")})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func mint(t *testing.T, signer *servicetoken.Signer, service, audience string) string {
+	token, _, err := signer.Mint(service, audience)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthRequiredTrustsGatewayUserHeaders(t *testing.T) {
+	m, signer := newTestAuth(t)
+	w := serveAuth(m, map[string]string{
+		ServiceTokenHeader: mint(t, signer, GatewayService, servicetoken.CreditActivityService),
+		"X-User-ID":        "user-1",
+		"X-Username":       "alice",
+		"X-User-Type":      "teacher",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"user-1","user_type":"teacher"}`, w.Body.String())
+}
+
+func TestAuthRequiredIgnoresUserHeadersFromOtherServices(t *testing.T) {
+	m, signer := newTestAuth(t)
+	w := serveAuth(m, map[string]string{
+		ServiceTokenHeader: mint(t, signer, servicetoken.UserService, servicetoken.CreditActivityService),
+		"X-User-ID":        "user-1",
+		"X-Username":       "alice",
+		"X-User-Type":      "student",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"system","user_type":"admin"}`, w.Body.String())
+}
+
+func TestAuthRequiredRejectsMissingOrForeignTokens(t *testing.T) {
+	m, signer := newTestAuth(t)
+	user := map[string]string{"X-User-ID": "user-1", "X-Username": "alice", "X-User-Type": "student"}
+
+	assert.Equal(t, http.StatusUnauthorized, serveAuth(m, user).Code, "缺少服务令牌")
+
+	user[ServiceTokenHeader] = mint(t, signer, GatewayService, servicetoken.UserService)
+	assert.Equal(t, http.StatusUnauthorized, serveAuth(m, user).Code, "发给其他服务的令牌")
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	user[ServiceTokenHeader] = mint(t, servicetoken.NewSigner(otherKey), GatewayService, servicetoken.CreditActivityService)
+	assert.Equal(t, http.StatusUnauthorized, serveAuth(m, user).Code, "非认证服务签发的令牌")
+}
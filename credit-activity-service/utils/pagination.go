@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"credit-management/shared/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pager 列表分页参数（偏移分页或游标分页），实现在共享模块的 pagination 包中
+type Pager = pagination.Pager
+
+// SortKey 列表的排序方式
+type SortKey = pagination.SortKey
+
+// ErrCursorMismatch 游标与本次请求的排序方式不一致
+var ErrCursorMismatch = pagination.ErrCursorMismatch
+
+// NewPager 在已校验的 page / limit 基础上解析 cursor 和 total 参数
+func NewPager(c *gin.Context, page, limit int) (*Pager, error) {
+	return pagination.New(c, page, limit)
+}
+
+// PageRows 游标分页时去掉多取的一条并生成下一页游标
+func PageRows[T any](p *Pager, rows []T, key SortKey, value func(T) (interface{}, string)) []T {
+	return pagination.PageRows(p, rows, key, value)
+}
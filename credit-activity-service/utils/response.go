@@ -21,6 +21,10 @@ type PaginatedResponse struct {
 	Page       int         `json:"page"`
 	Limit      int         `json:"limit"`
 	TotalPages int         `json:"total_pages"`
+	// 游标分页（请求带 cursor 参数）时返回；total=estimate 时 total 为估算值
+	NextCursor     string `json:"next_cursor,omitempty"`
+	HasMore        *bool  `json:"has_more,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
 }
 
 // SendErrorResponse 发送错误响应
@@ -59,6 +63,15 @@ func SendCreatedResponse(c *gin.Context, message string, data interface{}) {
 	})
 }
 
+// SendAcceptedResponse 发送已受理响应（异步处理的任务）
+func SendAcceptedResponse(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusAccepted, Response{
+		Code:    0,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // SendPaginatedResponse 发送分页响应
 func SendPaginatedResponse(c *gin.Context, data interface{}, total int64, page, limit int) {
 	totalPages := (int(total) + limit - 1) / limit
@@ -84,6 +97,11 @@ func SendBadRequestWithData(c *gin.Context, message string, data interface{}) {
 	SendErrorResponseWithData(c, http.StatusBadRequest, message, data)
 }
 
+// SendConflict 发送并发冲突响应，data 通常为资源的最新表示
+func SendConflict(c *gin.Context, message string, data interface{}) {
+	SendErrorResponseWithData(c, http.StatusConflict, message, data)
+}
+
 func SendUnauthorized(c *gin.Context) {
 	SendErrorResponse(c, http.StatusUnauthorized, "未认证")
 }
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"credit-management/credit-activity-service/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const ctxDataScope = "data_scope"
+
+// scopedUsersSQL 所在部门在数据范围内的用户，部门取自本地用户快照
+const scopedUsersSQL = "SELECT uuid FROM user_snapshots WHERE department_id IN ?"
+
+// DataScope 教师 / 部门管理员可见的数据范围：所管理部门子树内的用户，
+// 以及由这些用户创建或参与的活动。Restricted 为 false 时不做过滤
+type DataScope struct {
+	UserID        string
+	Restricted    bool
+	DepartmentIDs []string
+}
+
+// ResolveDataScope 解析当前请求用户的数据范围并缓存在上下文中
+func ResolveDataScope(c *gin.Context, db *gorm.DB) (*DataScope, error) {
+	if cached, ok := c.Get(ctxDataScope); ok {
+		return cached.(*DataScope), nil
+	}
+	scope, err := NewDataScope(db, c.GetString("id"), c.GetString("user_type"))
+	if err != nil {
+		return nil, err
+	}
+	c.Set(ctxDataScope, scope)
+	return scope, nil
+}
+
+// NewDataScope 解析指定用户的数据范围，供没有请求上下文的后台任务使用。
+// 范围由用户服务计算（教师：绑定的部门节点加上本人所在部门；管理员：有绑定时受限，否则不受限），
+// 随用户快照同步到本地；快照尚未同步时按受限且范围为空处理。
+// 学生沿用各接口已有的本人过滤，内部服务调用不受限
+func NewDataScope(db *gorm.DB, userID, userType string) (*DataScope, error) {
+	if len(userID) != 36 || (userType != "teacher" && userType != "admin") {
+		return &DataScope{UserID: userID}, nil
+	}
+
+	var snapshots []models.UserSnapshot
+	if err := db.Select("uuid", "scope_restricted", "scope_department_ids").
+		Where("uuid = ?", userID).Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return &DataScope{UserID: userID, Restricted: true}, nil
+	}
+	return snapshotScope(snapshots[0]), nil
+}
+
+// snapshotScope 由用户快照中同步的范围构造数据范围
+func snapshotScope(snapshot models.UserSnapshot) *DataScope {
+	scope := &DataScope{UserID: snapshot.UUID, Restricted: snapshot.ScopeRestricted}
+	if scope.Restricted {
+		scope.DepartmentIDs = snapshot.ScopeDepartmentIDs
+	}
+	return scope
+}
+
+// ActivityCondition 返回限制活动可见范围的 SQL 条件，prefix 为活动表别名（如 "a."，可为空）。
+// 可见：本人创建或协作的活动、范围内用户创建的活动、有范围内用户参与的活动
+func (s *DataScope) ActivityCondition(prefix string) (string, []interface{}) {
+	if !s.Restricted {
+		return "TRUE", nil
+	}
+	return "(" + prefix + "owner_id = ? OR " +
+			prefix + "id IN (SELECT activity_id FROM activity_collaborators WHERE user_id = ? AND deleted_at IS NULL) OR " +
+			prefix + "owner_id IN (" + scopedUsersSQL + ") OR " +
+			prefix + "id IN (SELECT activity_id FROM activity_participants WHERE deleted_at IS NULL AND user_id IN (" + scopedUsersSQL + ")))",
+		[]interface{}{s.UserID, s.UserID, s.DepartmentIDs, s.DepartmentIDs}
+}
+
+// AllowsActivity 判断活动是否在数据范围内（活动不存在时返回 false）
+func (s *DataScope) AllowsActivity(db *gorm.DB, activityID string) (bool, error) {
+	if !s.Restricted {
+		return true, nil
+	}
+	cond, args := s.ActivityCondition("")
+	var allowed bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM credit_activities WHERE id = ? AND "+cond+")",
+		append([]interface{}{activityID}, args...)...).Scan(&allowed).Error
+	return allowed, err
+}
+
+// RequireActivityInScope 按 ID 读取、导出或审核单个活动前检查当前用户的数据范围。
+// 不在范围内时与活动不存在一样返回 404，不暴露范围外的活动是否存在
+func RequireActivityInScope(c *gin.Context, db *gorm.DB, activityID string) bool {
+	scope, err := ResolveDataScope(c, db)
+	if err != nil {
+		SendInternalServerError(c, err)
+		return false
+	}
+	allowed, err := scope.AllowsActivity(db, activityID)
+	if err != nil {
+		SendInternalServerError(c, err)
+		return false
+	}
+	if !allowed {
+		SendNotFound(c, "活动不存在")
+		return false
+	}
+	return true
+}
+
+// FilterActivityIDs 返回 activityIDs 中在数据范围内的活动
+func (s *DataScope) FilterActivityIDs(db *gorm.DB, activityIDs []string) ([]string, error) {
+	if !s.Restricted || len(activityIDs) == 0 {
+		return activityIDs, nil
+	}
+	var allowed []string
+	err := s.Activities(db.Model(&models.CreditActivity{}).Unscoped().Where("id IN ?", activityIDs)).Pluck("id", &allowed).Error
+	return allowed, err
+}
+
+// ActivityReviewers 返回每个活动的审核人（以活动 ID 为键）：本地用户快照中在职的教师和管理员，
+// 且活动在其数据范围内。催办、汇总、实时提醒等按此发送，审核人只会收到自己能审核的活动
+func ActivityReviewers(db *gorm.DB, activityIDs []string) (map[string][]string, error) {
+	var reviewers []models.UserSnapshot
+	if err := db.Select("uuid", "scope_restricted", "scope_department_ids").
+		Where("user_type IN ? AND status = ? AND deleted = ?", []string{"teacher", "admin"}, "active", false).
+		Find(&reviewers).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]string, len(activityIDs))
+	for _, reviewer := range reviewers {
+		allowed, err := snapshotScope(reviewer).FilterActivityIDs(db, activityIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range allowed {
+			result[id] = append(result[id], reviewer.UUID)
+		}
+	}
+	return result, nil
+}
+
+// Activities 将活动查询限制在数据范围内
+func (s *DataScope) Activities(query *gorm.DB) *gorm.DB {
+	if !s.Restricted {
+		return query
+	}
+	cond, args := s.ActivityCondition("")
+	return query.Where(cond, args...)
+}
+
+// ActivityIDs 将按活动关联的查询（附件等）限制在可见活动内，column 为活动 ID 列
+func (s *DataScope) ActivityIDs(query *gorm.DB, column string) *gorm.DB {
+	if !s.Restricted {
+		return query
+	}
+	cond, args := s.ActivityCondition("")
+	return query.Where(column+" IN (SELECT id FROM credit_activities WHERE "+cond+")", args...)
+}
+
+// Users 将按用户关联的查询（申请、参与记录）限制在范围内的用户，column 为用户 ID 列；本人始终可见
+func (s *DataScope) Users(query *gorm.DB, column string) *gorm.DB {
+	if !s.Restricted {
+		return query
+	}
+	return query.Where("("+column+" = ? OR "+column+" IN ("+scopedUsersSQL+"))", s.UserID, s.DepartmentIDs)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"net/http"
+	"sync"
+
+	"credit-management/shared/servicetoken"
+)
+
+const (
+	// ServiceTokenHeader 服务间调用携带服务令牌的请求头
+	ServiceTokenHeader = servicetoken.Header
+	// GatewayService 网关的服务名，只有网关的请求才会采信 X-User-* 用户头
+	GatewayService = servicetoken.GatewayService
+)
+
+var (
+	defaultTokenSource     *servicetoken.Source
+	defaultTokenSourceOnce sync.Once
+	defaultVerifier        *servicetoken.Verifier
+	defaultVerifierOnce    sync.Once
+	defaultVerifierErr     error
+)
+
+// serviceName 本服务的服务名，既是申请令牌时的身份，也是校验令牌时要求的 aud
+func serviceName() string {
+	return GetEnv("INTERNAL_SERVICE_NAME", "credit-activity-service")
+}
+
+// DefaultServiceTokenSource 本服务的令牌来源（AUTH_SERVICE_URL、INTERNAL_SERVICE_NAME、SERVICE_CLIENT_SECRET）
+func DefaultServiceTokenSource() *servicetoken.Source {
+	defaultTokenSourceOnce.Do(func() {
+		defaultTokenSource = servicetoken.NewSource(
+			GetEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
+			serviceName(),
+			GetEnv("SERVICE_CLIENT_SECRET", ""),
+		)
+	})
+	return defaultTokenSource
+}
+
+// ServiceAuth 返回为发往 audience 服务的请求带上服务令牌的函数
+func ServiceAuth(audience string) func(*http.Request) error {
+	return DefaultServiceTokenSource().For(audience)
+}
+
+// DefaultServiceVerifier 本服务使用的服务令牌校验器（AUTH_SERVICE_URL、SERVICE_JWT_PUBLIC_KEY），
+// 只接受 aud 为本服务名的令牌
+func DefaultServiceVerifier() (*servicetoken.Verifier, error) {
+	defaultVerifierOnce.Do(func() {
+		defaultVerifier, defaultVerifierErr = servicetoken.NewVerifier(
+			GetEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
+			GetEnv("SERVICE_JWT_PUBLIC_KEY", ""),
+			serviceName(),
+		)
+	})
+	return defaultVerifier, defaultVerifierErr
+}
@@ -0,0 +1,449 @@
+package utils
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+	"credit-management/shared/servicetoken"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// UserChangedChannel 用户服务在用户信息变更后发出通知的 PostgreSQL 频道（负载为 UUID / 学号，"*" 表示全部）
+	UserChangedChannel = "user_changed"
+
+	// 单次批量查询的上限，与用户服务接口保持一致
+	userBatchLimit = 500
+	// 不存在的用户只短暂缓存，避免刚创建的用户长时间查不到
+	userMissingTTL = 30 * time.Second
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// UserClient 通过用户服务的内部批量接口查询用户信息。
+// 结果缓存在进程内的 LRU 中；同一用户的并发查询合并为一次请求；
+// 用户服务通过 PostgreSQL NOTIFY 广播变更，所有副本据此淘汰缓存，TTL 作为兜底
+type UserClient struct {
+	baseURL string
+	auth    func(*http.Request) error
+	client  *http.Client
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	cache    *userLRU
+	inflight map[string]*userLookup
+	// 每次失效递增；查询期间发生过失效时，结果不写入缓存，避免写回旧数据
+	generation uint64
+
+	// OnChange 收到变更通知并淘汰缓存后调用（如同步本地用户快照）；
+	// 监听连接（重新）建立后以空键调用一次，表示期间可能错过了通知
+	OnChange func(key string)
+}
+
+// userLookup 一次进行中的批量查询，等待者在 done 关闭后读取结果
+type userLookup struct {
+	done  chan struct{}
+	users map[string]*models.UserInfo
+	err   error
+}
+
+func NewUserClient(baseURL string, auth func(*http.Request) error, size int, ttl time.Duration) *UserClient {
+	return &UserClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		auth:     auth,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		ttl:      ttl,
+		cache:    newUserLRU(size),
+		inflight: map[string]*userLookup{},
+	}
+}
+
+var (
+	defaultUserClient     *UserClient
+	defaultUserClientOnce sync.Once
+)
+
+// DefaultUserClient 按环境变量创建的共享客户端：
+// USER_SERVICE_URL、USER_CACHE_SIZE（默认 10000）、USER_CACHE_TTL_SECONDS（默认 300，0 表示不缓存）
+func DefaultUserClient() *UserClient {
+	defaultUserClientOnce.Do(func() {
+		size, err := strconv.Atoi(GetEnv("USER_CACHE_SIZE", "10000"))
+		if err != nil || size < 0 {
+			size = 10000
+		}
+		ttl, err := strconv.Atoi(GetEnv("USER_CACHE_TTL_SECONDS", "300"))
+		if err != nil || ttl < 0 {
+			ttl = 300
+		}
+		defaultUserClient = NewUserClient(
+			GetEnv("USER_SERVICE_URL", "http://user-service:8084"),
+			ServiceAuth(servicetoken.UserService),
+			size,
+			time.Duration(ttl)*time.Second,
+		)
+	})
+	return defaultUserClient
+}
+
+// Get 按 UUID 或学号 / 工号查询单个用户
+func (c *UserClient) Get(ctx context.Context, id string) (*models.UserInfo, error) {
+	users, err := c.GetMany(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	user, ok := users[id]
+	if !ok {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	return user, nil
+}
+
+// GetMany 批量查询用户，返回以请求的 ID 为键的结果，不存在的用户不在结果中
+func (c *UserClient) GetMany(ctx context.Context, ids []string) (map[string]*models.UserInfo, error) {
+	result := make(map[string]*models.UserInfo, len(ids))
+	waits := map[*userLookup][]string{}
+	var missing []string
+
+	now := time.Now()
+	c.mu.Lock()
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if _, done := result[id]; done {
+			continue
+		}
+		if entry, ok := c.cache.get(id, now); ok {
+			if entry != nil {
+				result[id] = entry
+			}
+			continue
+		}
+		if lookup, ok := c.inflight[id]; ok {
+			waits[lookup] = append(waits[lookup], id)
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	var own *userLookup
+	generation := c.generation
+	if len(missing) > 0 {
+		own = &userLookup{done: make(chan struct{})}
+		for _, id := range missing {
+			c.inflight[id] = own
+		}
+	}
+	c.mu.Unlock()
+
+	if own != nil {
+		// 使用独立的超时，避免发起者的请求被取消时影响合并进来的其他等待者
+		fetchCtx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+		own.users, own.err = c.fetch(fetchCtx, missing)
+		cancel()
+
+		c.mu.Lock()
+		for _, id := range missing {
+			delete(c.inflight, id)
+			if own.err == nil && c.ttl > 0 && c.generation == generation {
+				if user, ok := own.users[id]; ok {
+					c.cache.put(id, user, user.UUID, now.Add(c.ttl))
+				} else {
+					c.cache.put(id, nil, "", now.Add(min(c.ttl, userMissingTTL)))
+				}
+			}
+		}
+		c.mu.Unlock()
+		close(own.done)
+		waits[own] = missing
+	}
+
+	for lookup, keys := range waits {
+		select {
+		case <-lookup.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if lookup.err != nil {
+			return nil, lookup.err
+		}
+		for _, id := range keys {
+			if user, ok := lookup.users[id]; ok {
+				result[id] = user
+			}
+		}
+	}
+	return result, nil
+}
+
+// fetch 调用用户服务的批量接口，按请求的 ID 建立索引
+func (c *UserClient) fetch(ctx context.Context, ids []string) (map[string]*models.UserInfo, error) {
+	result := make(map[string]*models.UserInfo, len(ids))
+	for start := 0; start < len(ids); start += userBatchLimit {
+		end := min(start+userBatchLimit, len(ids))
+		var uuids, studentIDs []string
+		for _, id := range ids[start:end] {
+			switch {
+			case uuidPattern.MatchString(id):
+				uuids = append(uuids, id)
+			case len(id) <= 18: // 更长的既不是学号也不是工号，直接视为不存在
+				studentIDs = append(studentIDs, id)
+			}
+		}
+
+		if len(uuids) == 0 && len(studentIDs) == 0 {
+			continue
+		}
+		users, err := c.batch(ctx, uuids, studentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			info := user.toUserInfo()
+			result[user.UUID] = info
+			if user.StudentID != "" {
+				result[user.StudentID] = info
+			}
+			if user.TeacherID != "" {
+				result[user.TeacherID] = info
+			}
+		}
+	}
+	return result, nil
+}
+
+// batchUser 用户服务批量接口返回的用户
+type batchUser struct {
+	UUID       string `json:"uuid"`
+	Username   string `json:"username"`
+	RealName   string `json:"real_name"`
+	UserType   string `json:"user_type"`
+	Status     string `json:"status"`
+	StudentID  string `json:"student_id"`
+	TeacherID  string `json:"teacher_id"`
+	Grade      string `json:"grade"`
+	Title      string `json:"title"`
+	Department string `json:"department"`
+	College    string `json:"college"`
+	Major      string `json:"major"`
+	Class      string `json:"class"`
+}
+
+func (u batchUser) toUserInfo() *models.UserInfo {
+	studentID := u.StudentID
+	if u.UserType == "teacher" {
+		studentID = u.TeacherID // 对于教师，工号也存储在StudentID字段中以便统一处理
+	}
+	return &models.UserInfo{
+		UUID:       u.UUID,
+		Username:   u.Username,
+		RealName:   u.RealName,
+		UserType:   u.UserType,
+		Status:     u.Status,
+		StudentID:  studentID,
+		College:    u.College,
+		Major:      u.Major,
+		Class:      u.Class,
+		Grade:      u.Grade,
+		Department: u.Department,
+		Title:      u.Title,
+	}
+}
+
+func (c *UserClient) batch(ctx context.Context, uuids, studentIDs []string) ([]batchUser, error) {
+	payload, _ := json.Marshal(map[string][]string{"uuids": uuids, "student_ids": studentIDs})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/internal/users/batch", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.auth != nil {
+		if err := c.auth(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求用户服务失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("用户服务返回错误状态码: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Users []batchUser `json:"users"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if body.Code != 0 {
+		return nil, fmt.Errorf("用户服务返回错误: %s", body.Message)
+	}
+	return body.Data.Users, nil
+}
+
+// Invalidate 淘汰缓存：key 为 UUID 时同时淘汰以其学号 / 工号缓存的条目，"*" 清空全部
+func (c *UserClient) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if key == "*" {
+		c.cache.clear()
+		return
+	}
+	c.cache.remove(key)
+}
+
+// ListenInvalidations 监听用户服务的变更通知并淘汰缓存，连接断开后自动重连，直到 ctx 取消。
+// 重连期间可能错过通知，因此每次（重新）连接后清空缓存
+func (c *UserClient) ListenInvalidations(ctx context.Context, dsn string) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := c.listen(ctx, dsn, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[users] invalidation listener stopped: %v, retrying in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func (c *UserClient) listen(ctx context.Context, dsn string, connected func()) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+UserChangedChannel); err != nil {
+		return err
+	}
+	connected()
+	c.Invalidate("*")
+	c.changed("")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		c.Invalidate(notification.Payload)
+		c.changed(notification.Payload)
+	}
+}
+
+func (c *UserClient) changed(key string) {
+	if c.OnChange != nil {
+		c.OnChange(key)
+	}
+}
+
+// userLRU 按最近使用淘汰的缓存，value 为 nil 表示用户不存在。
+// 同一用户可能以 UUID 和学号 / 工号两个键缓存，aliases 记录 UUID 对应的全部键以便一并淘汰。
+// 调用方负责加锁
+type userLRU struct {
+	size    int
+	order   *list.List
+	items   map[string]*list.Element
+	aliases map[string]map[string]struct{}
+}
+
+type userLRUEntry struct {
+	key       string
+	uuid      string
+	user      *models.UserInfo
+	expiresAt time.Time
+}
+
+func newUserLRU(size int) *userLRU {
+	return &userLRU{
+		size:    size,
+		order:   list.New(),
+		items:   map[string]*list.Element{},
+		aliases: map[string]map[string]struct{}{},
+	}
+}
+
+func (l *userLRU) get(key string, now time.Time) (*models.UserInfo, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*userLRUEntry)
+	if now.After(entry.expiresAt) {
+		l.removeElement(elem)
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return entry.user, true
+}
+
+func (l *userLRU) put(key string, user *models.UserInfo, uuid string, expiresAt time.Time) {
+	if l.size <= 0 {
+		return
+	}
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+	l.items[key] = l.order.PushFront(&userLRUEntry{key: key, uuid: uuid, user: user, expiresAt: expiresAt})
+	if uuid != "" {
+		if l.aliases[uuid] == nil {
+			l.aliases[uuid] = map[string]struct{}{}
+		}
+		l.aliases[uuid][key] = struct{}{}
+	}
+	for l.order.Len() > l.size {
+		l.removeElement(l.order.Back())
+	}
+}
+
+func (l *userLRU) remove(key string) {
+	for alias := range l.aliases[key] {
+		if elem, ok := l.items[alias]; ok {
+			l.removeElement(elem)
+		}
+	}
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+func (l *userLRU) removeElement(elem *list.Element) {
+	entry := l.order.Remove(elem).(*userLRUEntry)
+	delete(l.items, entry.key)
+	if keys, ok := l.aliases[entry.uuid]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(l.aliases, entry.uuid)
+		}
+	}
+}
+
+func (l *userLRU) clear() {
+	l.order.Init()
+	l.items = map[string]*list.Element{}
+	l.aliases = map[string]map[string]struct{}{}
+}
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUUIDA = "00000000-0000-0000-0000-00000000000a"
+	testUUIDB = "00000000-0000-0000-0000-00000000000b"
+	testUUIDC = "00000000-0000-0000-0000-00000000000c"
+)
+
+// fakeUserService 模拟用户服务的批量接口，记录收到的请求次数和每次请求的 ID
+type fakeUserService struct {
+	*httptest.Server
+	calls int32
+	// 不为 nil 时，每个请求在返回前等待其关闭
+	release chan struct{}
+	started chan struct{}
+
+	mu        sync.Mutex
+	requested [][]string
+}
+
+func newFakeUserService(t *testing.T) *fakeUserService {
+	users := map[string]batchUser{
+		testUUIDA: {UUID: testUUIDA, Username: "a", UserType: "student", StudentID: "2024001"},
+		testUUIDB: {UUID: testUUIDB, Username: "b", UserType: "student", StudentID: "2024002"},
+		testUUIDC: {UUID: testUUIDC, Username: "c", UserType: "teacher", TeacherID: "T001"},
+	}
+	f := &fakeUserService{started: make(chan struct{}, 100)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/internal/users/batch", r.URL.Path)
+		var body struct {
+			UUIDs      []string `json:"uuids"`
+			StudentIDs []string `json:"student_ids"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		atomic.AddInt32(&f.calls, 1)
+		f.mu.Lock()
+		f.requested = append(f.requested, append(body.UUIDs, body.StudentIDs...))
+		f.mu.Unlock()
+		f.started <- struct{}{}
+		if f.release != nil {
+			<-f.release
+		}
+
+		var found []batchUser
+		for _, id := range append(body.UUIDs, body.StudentIDs...) {
+			for _, user := range users {
+				if user.UUID == id || user.StudentID == id || user.TeacherID == id {
+					found = append(found, user)
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{"users": found}})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeUserService) callCount() int {
+	return int(atomic.LoadInt32(&f.calls))
+}
+
+func TestUserClientCachesResults(t *testing.T) {
+	server := newFakeUserService(t)
+	client := NewUserClient(server.URL+"/", nil, 10, time.Minute)
+	ctx := context.Background()
+
+	users, err := client.GetMany(ctx, []string{testUUIDA, "2024002", "T001", "missing", ""})
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.callCount())
+	assert.Equal(t, "a", users[testUUIDA].Username)
+	assert.Equal(t, "b", users["2024002"].Username)
+	assert.Equal(t, "T001", users["T001"].StudentID, "教师的工号放在 StudentID 中")
+	assert.NotContains(t, users, "missing")
+
+	// 已缓存的用户和不存在的用户都不再请求用户服务
+	_, err = client.Get(ctx, "missing")
+	assert.Error(t, err)
+	user, err := client.Get(ctx, "2024002")
+	require.NoError(t, err)
+	assert.Equal(t, testUUIDB, user.UUID)
+	assert.Equal(t, 1, server.callCount())
+}
+
+func TestUserClientEvictsLeastRecentlyUsed(t *testing.T) {
+	server := newFakeUserService(t)
+	client := NewUserClient(server.URL, nil, 2, time.Minute)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, testUUIDA)
+	require.NoError(t, err)
+	_, err = client.Get(ctx, testUUIDB)
+	require.NoError(t, err)
+	_, err = client.Get(ctx, testUUIDA) // A 变为最近使用
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.callCount())
+
+	_, err = client.Get(ctx, testUUIDC) // 超出容量，淘汰最久未使用的 B
+	require.NoError(t, err)
+	assert.Equal(t, 3, server.callCount())
+
+	_, err = client.Get(ctx, testUUIDA)
+	require.NoError(t, err)
+	assert.Equal(t, 3, server.callCount(), "A 仍在缓存中")
+
+	_, err = client.Get(ctx, testUUIDB)
+	require.NoError(t, err)
+	assert.Equal(t, 4, server.callCount(), "B 已被淘汰，需要重新查询")
+	assert.Equal(t, []string{testUUIDB}, server.requested[3])
+}
+
+func TestUserClientExpiresEntries(t *testing.T) {
+	server := newFakeUserService(t)
+	client := NewUserClient(server.URL, nil, 10, time.Millisecond)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, testUUIDA)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = client.Get(ctx, testUUIDA)
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.callCount())
+}
+
+func TestUserClientCoalescesConcurrentLookups(t *testing.T) {
+	server := newFakeUserService(t)
+	server.release = make(chan struct{})
+	client := NewUserClient(server.URL, nil, 10, time.Minute)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	errs := make([]error, callers)
+	get := func(i int) {
+		defer wg.Done()
+		user, err := client.Get(context.Background(), testUUIDA)
+		errs[i] = err
+		if err == nil {
+			results[i] = user.Username
+		}
+	}
+
+	wg.Add(callers)
+	go get(0)
+	<-server.started // 第一个请求已发出且阻塞在用户服务中
+	for i := 1; i < callers; i++ {
+		go get(i)
+	}
+	// 留出时间让其余调用者加入进行中的查询；来得晚的调用者直接命中缓存，同样不会再请求用户服务
+	time.Sleep(20 * time.Millisecond)
+	close(server.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, server.callCount(), "并发查询同一用户只请求一次用户服务")
+	for i := range callers {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "a", results[i])
+	}
+}
+
+func TestUserClientInvalidate(t *testing.T) {
+	server := newFakeUserService(t)
+	client := NewUserClient(server.URL, nil, 10, time.Minute)
+	ctx := context.Background()
+
+	_, err := client.GetMany(ctx, []string{testUUIDA, "2024001", testUUIDB})
+	require.NoError(t, err)
+	require.Equal(t, 1, server.callCount())
+
+	// 按 UUID 淘汰时，以学号缓存的同一用户一并淘汰，其他用户不受影响
+	client.Invalidate(testUUIDA)
+	_, err = client.Get(ctx, "2024001")
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.callCount())
+	_, err = client.Get(ctx, testUUIDB)
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.callCount())
+
+	client.Invalidate("*")
+	_, err = client.Get(ctx, testUUIDB)
+	require.NoError(t, err)
+	assert.Equal(t, 3, server.callCount())
+}
+
+func TestUserClientInvalidateDuringLookupSkipsCache(t *testing.T) {
+	server := newFakeUserService(t)
+	server.release = make(chan struct{})
+	client := NewUserClient(server.URL, nil, 10, time.Minute)
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Get(context.Background(), testUUIDA)
+		done <- err
+	}()
+	<-server.started
+	client.Invalidate(testUUIDA) // 查询期间用户发生变更，结果可能已过时
+	close(server.release)
+	require.NoError(t, <-done)
+
+	_, err := client.Get(context.Background(), testUUIDA)
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.callCount(), "失效前发出的查询结果不写入缓存")
+}
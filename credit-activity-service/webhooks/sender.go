@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+)
+
+// 响应体只保留前 maxResponseBody 字节写入投递日志
+const maxResponseBody = 2048
+
+// Result 单次投递尝试的结果
+type Result struct {
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+// OK 是否投递成功（2xx）
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Error 失败原因描述，成功时为空
+func (r Result) Error() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	if !r.OK() {
+		return fmt.Sprintf("receiver responded with status %d", r.StatusCode)
+	}
+	return ""
+}
+
+// Sender 负责签名并发送一次 webhook 请求
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewSender(client *http.Client) *Sender {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Sender{client: client, now: time.Now}
+}
+
+// Send 将投递记录中保存的负载原样发送到订阅地址。
+// 每次尝试都使用新的时间戳重新签名，重投时负载字节保持不变。
+func (s *Sender) Send(ctx context.Context, sub models.WebhookSubscription, delivery models.WebhookDelivery) Result {
+	body := []byte(delivery.Payload)
+	timestamp := s.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "credit-activity-service-webhooks/1.0")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, fmt.Sprintf("%d", timestamp))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return Result{StatusCode: resp.StatusCode, Body: string(respBody)}
+}
@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Envelope 发送给外部系统的事件负载
+type Envelope struct {
+	ID            string                 `json:"id"`
+	Type          string                 `json:"type"`
+	AggregateType string                 `json:"aggregate_type,omitempty"`
+	AggregateID   string                 `json:"aggregate_id,omitempty"`
+	ActorID       string                 `json:"actor_id,omitempty"`
+	OccurredAt    time.Time              `json:"occurred_at"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+// Sink 作为发件箱的投递目标，为每个匹配的订阅生成一条待投递记录。
+// 实际发送由 Worker 完成，单个订阅的失败与重试不会阻塞发件箱或其他订阅。
+type Sink struct {
+	db *gorm.DB
+}
+
+func NewSink(db *gorm.DB) *Sink {
+	return &Sink{db: db}
+}
+
+func (s *Sink) Name() string { return "webhooks" }
+
+func (s *Sink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	var subs []models.WebhookSubscription
+	if err := s.db.WithContext(ctx).Where("active = ?", true).Find(&subs).Error; err != nil {
+		return err
+	}
+
+	envelope := Envelope{
+		ID:            event.ID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		ActorID:       event.ActorID,
+		OccurredAt:    event.CreatedAt,
+		Data:          event.Payload,
+	}
+	for _, sub := range subs {
+		if !sub.Matches(event.EventType) {
+			continue
+		}
+		if err := Enqueue(s.db.WithContext(ctx), sub.ID, envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue 为订阅创建一条待投递记录；同一事件对同一订阅只会入队一次，
+// 因此发件箱重复投递事件时不会产生重复请求
+func Enqueue(db *gorm.DB, subscriptionID string, envelope Envelope) error {
+	if envelope.Data == nil {
+		envelope.Data = map[string]interface{}{}
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	delivery := models.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        envelope.ID,
+		EventType:      envelope.Type,
+		Payload:        string(payload),
+		Status:         models.WebhookDeliveryPending,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(&delivery).Error
+}
@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"credit-management/credit-activity-service/models"
+)
+
+// TestSendSignedDelivery 使用本地 httptest 接收端校验请求头与签名
+func TestSendSignedDelivery(t *testing.T) {
+	const secret = "whsec_test"
+	var received Envelope
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if err := Verify(secret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, 5*time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "evt-1", r.Header.Get(HeaderEventID))
+		assert.Equal(t, "dlv-1", r.Header.Get(HeaderDeliveryID))
+		assert.Equal(t, models.EventCreditsChanged, r.Header.Get(HeaderEventType))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	payload, err := json.Marshal(Envelope{
+		ID:   "evt-1",
+		Type: models.EventCreditsChanged,
+		Data: map[string]interface{}{"credits": 2.5},
+	})
+	require.NoError(t, err)
+
+	sender := NewSender(receiver.Client())
+	sub := models.WebhookSubscription{URL: receiver.URL, Secret: secret}
+	delivery := models.WebhookDelivery{ID: "dlv-1", EventID: "evt-1", EventType: models.EventCreditsChanged, Payload: string(payload)}
+
+	result := sender.Send(context.Background(), sub, delivery)
+	assert.True(t, result.OK(), result.Error())
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Equal(t, 2.5, received.Data["credits"])
+
+	// 密钥不一致时接收端拒绝，结果记为失败
+	sub.Secret = "whsec_other"
+	result = sender.Send(context.Background(), sub, delivery)
+	assert.False(t, result.OK())
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+	assert.Contains(t, result.Body, ErrInvalidSignature.Error())
+}
+
+// TestVerifyRejectsStaleTimestamp 超出容忍窗口的时间戳视为重放
+func TestVerifyRejectsStaleTimestamp(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	old := time.Now().Add(-time.Hour).Unix()
+	signature := Sign("s", old, body)
+
+	assert.NoError(t, Verify("s", strconv.FormatInt(old, 10), signature, body, 0))
+	assert.ErrorIs(t, Verify("s", strconv.FormatInt(old, 10), signature, body, 5*time.Minute), ErrStaleTimestamp)
+	assert.ErrorIs(t, Verify("s", strconv.FormatInt(old, 10), "sha256=deadbeef", body, 0), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("s", "", signature, body, 0), ErrMissingSignature)
+}
+
+// TestBackoff 指数退避并在上限处截断
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, maxBackoff, Backoff(20))
+	assert.Equal(t, maxBackoff, Backoff(200))
+}
+
+// TestRedeliveryRestartsRound 手动重投后累计次数保留，退避和最大次数按本轮重新计算
+func TestRedeliveryRestartsRound(t *testing.T) {
+	delivery := models.WebhookDelivery{Attempts: DefaultMaxAttempts}
+	assert.Equal(t, DefaultMaxAttempts, delivery.RoundAttempts())
+
+	delivery.AttemptsBeforeRedelivery = delivery.Attempts
+	delivery.Attempts++
+	assert.Equal(t, DefaultMaxAttempts+1, delivery.Attempts)
+	assert.Equal(t, 1, delivery.RoundAttempts())
+	assert.Equal(t, 30*time.Second, Backoff(delivery.RoundAttempts()))
+}
@@ -0,0 +1,185 @@
+package webhooks
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"credit-management/credit-activity-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultBatchSize   = 50
+	DefaultMaxAttempts = 8
+	baseBackoff        = 30 * time.Second
+	maxBackoff         = 12 * time.Hour
+	// 一批最多 50 条、每条发送超时 10 秒，租约需覆盖整批的发送时间
+	defaultLease = 10 * time.Minute
+)
+
+// Worker 轮询到期的投递记录并发送。
+// 与发件箱分发器一样在短事务中用 FOR UPDATE SKIP LOCKED 领取一批记录并写入租约（locked_until），
+// 提交后在事务外发送，每条的结果和尝试记录再用单独的短事务写入，慢的接收方不会占用数据库事务；
+// 失败后按指数退避重试，超过最大次数标记为 failed，可通过重投接口重新入队（重投后重新计算次数）。
+type Worker struct {
+	db          *gorm.DB
+	sender      *Sender
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	lease       time.Duration
+}
+
+func NewWorker(db *gorm.DB, sender *Sender, interval time.Duration, maxAttempts int) *Worker {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	return &Worker{
+		db:          db,
+		sender:      sender,
+		interval:    interval,
+		batchSize:   defaultBatchSize,
+		maxAttempts: maxAttempts,
+		lease:       defaultLease,
+	}
+}
+
+// Start 在后台持续投递，直到 ctx 取消
+func (w *Worker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := w.DeliverOnce(ctx); err != nil {
+				log.Printf("[webhooks] delivery run failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DeliverOnce 领取并发送一批到期记录，返回成功数量。发送期间不持有事务和行锁
+func (w *Worker) DeliverOnce(ctx context.Context) (int, error) {
+	// 数据库时间精度为微秒，截断后才能在记录结果时按租约值匹配
+	lockedUntil := time.Now().Add(w.lease).Truncate(time.Microsecond)
+	deliveries, err := w.claim(ctx, lockedUntil)
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		// 租约已过期的记录可能已被其他副本领取，留给对方发送
+		if time.Now().After(lockedUntil) {
+			break
+		}
+
+		var sub models.WebhookSubscription
+		if err := w.db.WithContext(ctx).Unscoped().Where("id = ?", delivery.SubscriptionID).First(&sub).Error; err != nil {
+			return delivered, err
+		}
+		if !sub.Active || sub.DeletedAt.Valid {
+			if err := w.release(ctx, delivery.ID, lockedUntil, map[string]interface{}{
+				"status":     models.WebhookDeliveryFailed,
+				"last_error": "subscription inactive",
+			}, nil); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		result := w.sender.Send(ctx, sub, delivery)
+		delivery.Attempts++
+		attempts := delivery.RoundAttempts()
+		updates := map[string]interface{}{
+			"attempts":        delivery.Attempts,
+			"response_status": result.StatusCode,
+			"response_body":   result.Body,
+			"last_error":      result.Error(),
+		}
+		switch {
+		case result.OK():
+			updates["status"] = models.WebhookDeliverySucceeded
+			updates["delivered_at"] = time.Now()
+			delivered++
+		case attempts >= w.maxAttempts:
+			updates["status"] = models.WebhookDeliveryFailed
+			log.Printf("[webhooks] giving up on delivery %s to %s after %d attempts: %s", delivery.ID, sub.URL, attempts, result.Error())
+		default:
+			updates["next_attempt_at"] = time.Now().Add(Backoff(attempts))
+		}
+		attempt := &models.WebhookDeliveryAttempt{
+			DeliveryID:     delivery.ID,
+			Attempt:        delivery.Attempts,
+			ResponseStatus: result.StatusCode,
+			ResponseBody:   result.Body,
+			Error:          result.Error(),
+			AttemptedAt:    time.Now(),
+		}
+		if err := w.release(ctx, delivery.ID, lockedUntil, updates, attempt); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// claim 在短事务中锁定一批到期且未被租用的记录，写入租约后立即提交
+func (w *Worker) claim(ctx context.Context, lockedUntil time.Time) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Where("locked_until IS NULL OR locked_until <= ?", now).
+			Order("next_attempt_at ASC").
+			Limit(w.batchSize).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("locked_until", lockedUntil).Error
+	})
+	return deliveries, err
+}
+
+// release 记录发送结果并释放租约；租约已被其他副本重新领取时不覆盖对方的状态。
+// 本次确实发送过时同时写入尝试记录，即使租约已失效，这次发送也会留下记录
+func (w *Worker) release(ctx context.Context, deliveryID string, lockedUntil time.Time, updates map[string]interface{}, attempt *models.WebhookDeliveryAttempt) error {
+	updates["locked_until"] = nil
+	return w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if attempt != nil {
+			if err := tx.Create(attempt).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND locked_until = ?", deliveryID, lockedUntil).
+			Updates(updates).Error
+	})
+}
+
+// Backoff 指数退避：30s, 1m, 2m, 4m ... 最长 12 小时
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff << uint(attempts-1)
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -83,8 +83,8 @@ export const handleImport = async (
     const response = await importApiCall(formData);
 
     if (response.data.code === 0) {
-      // 成功：仅在右上角给一个成功提示
-      toast.success("批量导入成功");
+      // 成功：导入在后台异步执行，仅在右上角提示任务已提交
+      toast.success("导入任务已提交，正在后台处理");
       onSuccess?.();
       return null;
     }
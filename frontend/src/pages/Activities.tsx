@@ -355,7 +355,7 @@ export default function ActivitiesPage() {
       });
 
       if (response.data.code === 0) {
-        toast.success("活动导入成功");
+        toast.success("导入任务已提交，正在后台处理");
         setIsImportDialogOpen(false);
         setImportFile(null);
         setImportErrors([]);
@@ -0,0 +1,190 @@
+// Package audit 写入各服务共用的 audit_log 表：中间件为每个请求分配请求 ID，
+// 并为成功的变更类请求写通用条目；处理函数可通过 Record 写入带前后快照和字段差异的条目
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HeaderRequestID 请求 ID 头，由网关生成并透传给下游服务
+const HeaderRequestID = "X-Request-ID"
+
+const (
+	ctxLogger   = "audit.logger"
+	ctxRecorded = "audit.recorded"
+	ctxRequest  = "request_id"
+)
+
+// 写入审计日志前脱敏的字段
+var sensitiveFields = map[string]bool{
+	"password":     true,
+	"new_password": true,
+	"old_password": true,
+	"secret":       true,
+	"token":        true,
+}
+
+// Entry 业务层显式记录的审计条目，Before / After 可以是任意可 JSON 序列化的值
+type Entry struct {
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Before       interface{}
+	After        interface{}
+}
+
+// Logger 写审计日志
+type Logger struct {
+	db      *gorm.DB
+	service string
+}
+
+func NewLogger(db *gorm.DB, service string) *Logger {
+	return &Logger{db: db, service: service}
+}
+
+// Middleware 为每个请求分配请求 ID，并在变更类请求（POST/PUT/PATCH/DELETE）成功后写入审计日志。
+// 处理函数已通过 Record 写入带前后快照的条目时，不再写通用条目。
+func (l *Logger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		c.Set(ctxRequest, requestID)
+		c.Set(ctxLogger, l)
+		c.Header(HeaderRequestID, requestID)
+
+		c.Next()
+
+		if !isMutation(c.Request.Method) || c.GetBool(ctxRecorded) {
+			return
+		}
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		l.write(c, &Log{
+			Action:       c.Request.Method + " " + route,
+			ResourceType: resourceFromRoute(route),
+			ResourceID:   c.Param("id"),
+		})
+	}
+}
+
+// Record 在处理函数中记录带前后快照的审计条目，自动计算字段差异
+func Record(c *gin.Context, entry Entry) {
+	value, ok := c.Get(ctxLogger)
+	if !ok {
+		return
+	}
+	l := value.(*Logger)
+	before := toMap(entry.Before)
+	after := toMap(entry.After)
+	l.write(c, &Log{
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		Before:       before,
+		After:        after,
+		Changes:      Diff(before, after),
+	})
+	c.Set(ctxRecorded, true)
+}
+
+// Diff 比较两个快照的顶层字段，返回 {字段: {"from": 旧值, "to": 新值}}
+func Diff(before, after map[string]interface{}) map[string]interface{} {
+	if before == nil || after == nil {
+		return nil
+	}
+	changes := map[string]interface{}{}
+	for key, oldValue := range before {
+		newValue, exists := after[key]
+		if !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changes[key] = map[string]interface{}{"from": oldValue, "to": newValue}
+		}
+	}
+	for key, newValue := range after {
+		if _, exists := before[key]; !exists {
+			changes[key] = map[string]interface{}{"from": nil, "to": newValue}
+		}
+	}
+	return changes
+}
+
+func (l *Logger) write(c *gin.Context, entry *Log) {
+	entry.Service = l.service
+	entry.ActorID = actorID(c)
+	entry.ActorType = c.GetString("user_type")
+	entry.Method = c.Request.Method
+	entry.Path = c.Request.URL.Path
+	entry.StatusCode = c.Writer.Status()
+	entry.IP = c.ClientIP()
+	entry.RequestID = c.GetString(ctxRequest)
+	if err := l.db.Create(entry).Error; err != nil {
+		log.Printf("[audit] failed to write %s %s: %v", entry.Action, entry.ResourceID, err)
+	}
+}
+
+// actorID 当前用户的 ID：学分活动服务的认证中间件写入 id，用户服务写入 uuid（内部服务调用为 id = system）
+func actorID(c *gin.Context) string {
+	if id := c.GetString("id"); id != "" {
+		return id
+	}
+	return c.GetString("uuid")
+}
+
+// toMap 把快照转换为 map 并脱敏
+func toMap(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		// 非对象类型（如 ID 列表）统一包装成 value 字段
+		var raw interface{}
+		if json.Unmarshal(data, &raw) != nil {
+			return nil
+		}
+		return map[string]interface{}{"value": raw}
+	}
+	for key := range m {
+		if sensitiveFields[key] {
+			m[key] = "***"
+		}
+	}
+	return m
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// resourceFromRoute 取 /api 之后的第一段作为资源类型，例如 /api/users/:id -> users
+func resourceFromRoute(route string) string {
+	parts := strings.Split(strings.TrimPrefix(route, "/api/"), "/")
+	if len(parts) == 0 {
+		return ""
+	}
+	return parts[0]
+}
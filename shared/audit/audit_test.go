@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"name": "旧", "status": "draft", "removed": 1}
+	after := map[string]interface{}{"name": "新", "status": "draft", "added": true}
+	assert.Equal(t, map[string]interface{}{
+		"name":    map[string]interface{}{"from": "旧", "to": "新"},
+		"removed": map[string]interface{}{"from": 1, "to": nil},
+		"added":   map[string]interface{}{"from": nil, "to": true},
+	}, Diff(before, after))
+	assert.Nil(t, Diff(nil, after))
+}
+
+func TestToMapMasksSensitiveFields(t *testing.T) {
+	m := toMap(struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{"alice", "secret"})
+	assert.Equal(t, map[string]interface{}{"username": "alice", "password": "***"}, m)
+
+	assert.Equal(t, map[string]interface{}{"value": []interface{}{"a", "b"}}, toMap([]string{"a", "b"}))
+	assert.Nil(t, toMap(nil))
+}
+
+func TestResourceFromRoute(t *testing.T) {
+	assert.Equal(t, "users", resourceFromRoute("/api/users/:id"))
+	assert.Equal(t, "activities", resourceFromRoute("/api/activities"))
+}
+
+func TestActorID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, tc := range map[string]struct {
+		keys map[string]string
+		want string
+	}{
+		"id":        {map[string]string{"id": "u1"}, "u1"},
+		"uuid":      {map[string]string{"uuid": "u2"}, "u2"},
+		"both":      {map[string]string{"id": "system", "uuid": "u3"}, "system"},
+		"anonymous": {map[string]string{}, ""},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		for key, value := range tc.keys {
+			c.Set(key, value)
+		}
+		assert.Equal(t, tc.want, actorID(c), name)
+	}
+}
+
+func TestMiddlewareAssignsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use((&Logger{service: "test"}).Middleware())
+	r.GET("/api/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(ctxRequest))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ping", nil))
+	assert.NotEmpty(t, w.Header().Get(HeaderRequestID))
+	assert.Equal(t, w.Header().Get(HeaderRequestID), w.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set(HeaderRequestID, "from-gateway")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "from-gateway", w.Header().Get(HeaderRequestID))
+}
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Log 审计日志（只追加，数据库触发器禁止修改和删除），各服务共用同一张表
+type Log struct {
+	ID           int64             `json:"id" gorm:"primaryKey;autoIncrement"`
+	Service      string            `json:"service" gorm:"not null"`
+	ActorID      string            `json:"actor_id" gorm:"index"`
+	ActorType    string            `json:"actor_type"`
+	Action       string            `json:"action" gorm:"not null;index"`
+	ResourceType string            `json:"resource_type" gorm:"index:idx_audit_log_resource"`
+	ResourceID   string            `json:"resource_id" gorm:"index:idx_audit_log_resource"`
+	Method       string            `json:"method"`
+	Path         string            `json:"path"`
+	StatusCode   int               `json:"status_code"`
+	Before       datatypes.JSONMap `json:"before" gorm:"type:jsonb"`
+	After        datatypes.JSONMap `json:"after" gorm:"type:jsonb"`
+	Changes      datatypes.JSONMap `json:"changes" gorm:"type:jsonb"`
+	IP           string            `json:"ip"`
+	RequestID    string            `json:"request_id" gorm:"index"`
+	CreatedAt    time.Time         `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+func (Log) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,259 @@
+// Package imports 异步导入任务：上传的文件解析后保存为任务，由 Runner 分批处理，
+// 进度游标与数据在同一事务中提交，服务重启或副本切换后从游标处继续。
+// 任务表由各服务共用（按 service 区分），每个服务注册自己的行处理函数。
+package imports
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultBatchSize = 200
+	// 运行中的任务超过该时间没有心跳，视为处理它的实例已退出，可由其他实例接管
+	staleAfter = 2 * time.Minute
+)
+
+// RowFunc 处理一行数据。header 为列名到下标的映射；返回的错误写入错误报告，不中断任务。
+// 每行在独立的保存点中执行，失败行的写入会被回滚
+type RowFunc func(tx *gorm.DB, job *Job, header map[string]int, record []string) error
+
+// Runner 轮询并执行本服务的导入任务。
+// 每批行与进度游标在同一事务中提交，进程退出后由下一次轮询从游标处继续
+type Runner struct {
+	db         *gorm.DB
+	service    string
+	interval   time.Duration
+	batchSize  int
+	processors map[string]RowFunc
+
+	// OnProgress 每批提交后以及任务结束时调用，可用于实时推送进度
+	OnProgress func(job Job)
+}
+
+func NewRunner(db *gorm.DB, service string, interval time.Duration, batchSize int) *Runner {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Runner{
+		db:         db,
+		service:    service,
+		interval:   interval,
+		batchSize:  batchSize,
+		processors: map[string]RowFunc{},
+	}
+}
+
+// Register 注册某类导入任务的行处理函数
+func (r *Runner) Register(kind string, fn RowFunc) {
+	r.processors[kind] = fn
+}
+
+// Submit 保存新的导入任务，records 第一行为标题行
+func Submit(db *gorm.DB, job *Job, records [][]string) error {
+	if len(records) < 2 {
+		return fmt.Errorf("文件至少需要包含标题行和一行数据")
+	}
+	job.Header = records[0]
+	job.Rows = datatypes.NewJSONType(records[1:])
+	job.TotalRows = len(records) - 1
+	job.Status = StatusPending
+	return db.Create(job).Error
+}
+
+// Start 在后台持续处理任务，直到 ctx 取消
+func (r *Runner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			if err := r.RunOnce(ctx); err != nil {
+				log.Printf("[imports] run failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce 依次领取并处理所有待处理（或已失去心跳）的任务
+func (r *Runner) RunOnce(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		job, err := r.claim(ctx)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return nil
+		}
+		r.process(ctx, job)
+	}
+}
+
+// claim 用 FOR UPDATE SKIP LOCKED 领取一个任务，多副本部署时不会重复领取
+func (r *Runner) claim(ctx context.Context) (*Job, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE import_jobs
+		SET status = ?, heartbeat_at = NOW(), started_at = COALESCE(started_at, NOW()), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE service = ? AND (status = ? OR (status = ? AND heartbeat_at < ?))
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id`,
+		StatusRunning, r.service, StatusPending, StatusRunning, time.Now().Add(-staleAfter),
+	).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var job Job
+	if err := r.db.WithContext(ctx).Where("id = ?", ids[0]).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *Runner) process(ctx context.Context, job *Job) {
+	fn, ok := r.processors[job.Kind]
+	if !ok {
+		r.finish(job, StatusFailed, "未知的导入类型: "+job.Kind)
+		return
+	}
+
+	header := make(map[string]int, len(job.Header))
+	for i, name := range job.Header {
+		if key := strings.TrimSpace(name); key != "" {
+			header[key] = i
+		}
+	}
+	rows := job.Rows.Data()
+
+	for job.ProcessedRows < job.TotalRows {
+		if ctx.Err() != nil {
+			return
+		}
+		stopped, err := r.runBatch(ctx, job, fn, header, rows)
+		if err != nil {
+			log.Printf("[imports] job %s failed at row %d: %v", job.ID, job.ProcessedRows+2, err)
+			r.finish(job, StatusFailed, err.Error())
+			return
+		}
+		if r.OnProgress != nil {
+			r.OnProgress(*job)
+		}
+		if stopped {
+			return
+		}
+	}
+	r.finish(job, StatusCompleted, "")
+}
+
+// runBatch 处理一批行并推进游标；任务已被取消或被其他实例接管时返回 stopped
+func (r *Runner) runBatch(ctx context.Context, job *Job, fn RowFunc, header map[string]int, rows [][]string) (bool, error) {
+	stopped := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current Job
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("status", "processed_rows").
+			Where("id = ?", job.ID).
+			First(&current).Error; err != nil {
+			return err
+		}
+		if current.Status != StatusRunning || current.ProcessedRows != job.ProcessedRows {
+			stopped = true
+			return nil
+		}
+
+		end := job.ProcessedRows + r.batchSize
+		if end > job.TotalRows {
+			end = job.TotalRows
+		}
+		succeeded, failed := 0, 0
+		for i := job.ProcessedRows; i < end; i++ {
+			if err := runRow(tx, job, fn, header, rows[i]); err != nil {
+				failed++
+				if err := tx.Create(&JobError{
+					JobID:     job.ID,
+					RowNumber: i + 2,
+					Message:   err.Error(),
+					Record:    rows[i],
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			succeeded++
+		}
+
+		if err := tx.Model(&Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"processed_rows": end,
+			"succeeded_rows": gorm.Expr("succeeded_rows + ?", succeeded),
+			"failed_rows":    gorm.Expr("failed_rows + ?", failed),
+			"heartbeat_at":   time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		job.ProcessedRows = end
+		job.SucceededRows += succeeded
+		job.FailedRows += failed
+		return nil
+	})
+	return stopped, err
+}
+
+// runRow 在保存点中处理单行，失败（包括 panic）时回滚到保存点
+func runRow(tx *gorm.DB, job *Job, fn RowFunc, header map[string]int, record []string) (err error) {
+	if err := tx.SavePoint("import_row").Error; err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("处理失败: %v", p)
+		}
+		if err != nil {
+			tx.RollbackTo("import_row")
+		}
+	}()
+	return fn(tx, job, header, record)
+}
+
+func (r *Runner) finish(job *Job, status, lastError string) {
+	now := time.Now()
+	result := r.db.Model(&Job{}).
+		Where("id = ? AND status = ?", job.ID, StatusRunning).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"last_error":  lastError,
+			"finished_at": now,
+		})
+	if result.Error != nil {
+		log.Printf("[imports] failed to finish job %s: %v", job.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	job.Status = status
+	job.LastError = lastError
+	job.FinishedAt = &now
+	if r.OnProgress != nil {
+		r.OnProgress(*job)
+	}
+}
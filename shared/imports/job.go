@@ -0,0 +1,59 @@
+package imports
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 导入任务状态
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job 异步导入任务（各服务共用一张表，按 service 区分）。
+// 上传时解析好的数据行保存在 rows 中，processed_rows 是已处理的行数游标，
+// 服务重启后从游标处继续，已提交的批次不会重复处理
+type Job struct {
+	ID            string                         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Service       string                         `json:"service" gorm:"not null;size:50;index"`
+	Kind          string                         `json:"kind" gorm:"not null;size:50"`
+	CreatedBy     string                         `json:"created_by" gorm:"not null;size:64;index"`
+	FileName      string                         `json:"file_name" gorm:"size:255"`
+	Options       datatypes.JSONMap              `json:"options"`
+	Header        datatypes.JSONSlice[string]    `json:"header"`
+	Rows          datatypes.JSONType[[][]string] `json:"-"`
+	Status        string                         `json:"status" gorm:"not null;size:20;default:pending;index"`
+	TotalRows     int                            `json:"total_rows" gorm:"not null;default:0"`
+	ProcessedRows int                            `json:"processed_rows" gorm:"not null;default:0"`
+	SucceededRows int                            `json:"succeeded_rows" gorm:"not null;default:0"`
+	FailedRows    int                            `json:"failed_rows" gorm:"not null;default:0"`
+	LastError     string                         `json:"last_error,omitempty" gorm:"type:text"`
+	HeartbeatAt   *time.Time                     `json:"heartbeat_at,omitempty"`
+	StartedAt     *time.Time                     `json:"started_at,omitempty"`
+	FinishedAt    *time.Time                     `json:"finished_at,omitempty"`
+	CreatedAt     time.Time                      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time                      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Job) TableName() string {
+	return "import_jobs"
+}
+
+// JobError 导入失败的行，用于生成错误报告
+type JobError struct {
+	ID        int64                       `json:"id" gorm:"primaryKey;autoIncrement"`
+	JobID     string                      `json:"job_id" gorm:"type:uuid;not null;index"`
+	RowNumber int                         `json:"row_number" gorm:"not null"` // 文件中的行号（标题行为第 1 行）
+	Message   string                      `json:"message" gorm:"type:text;not null"`
+	Record    datatypes.JSONSlice[string] `json:"record"`
+	CreatedAt time.Time                   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (JobError) TableName() string {
+	return "import_job_errors"
+}
@@ -0,0 +1,309 @@
+// Package migrate 各服务共用的数据库迁移执行器。
+//
+// 迁移脚本按 <版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql 命名，由各服务编译时嵌入二进制；
+// 已执行的版本记录在各服务共用的 schema_migrations 表中（按 service 区分）。
+// 服务启动时只检查版本，不自动迁移；结构变更通过 `./main migrate up` 执行。
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockKey 迁移时持有的事务级咨询锁，多个服务、多个副本同时迁移时依次执行
+const lockKey = 7340101
+
+var scriptName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // up 脚本的 SHA-256，脚本在执行后被修改时启动检查会失败
+}
+
+// Runner 一个服务的迁移执行器，脚本由服务用 go:embed 嵌入后传入
+type Runner struct {
+	service string
+	scripts fs.FS
+}
+
+// New 创建迁移执行器：service 为 schema_migrations 中的服务标识，scripts 为迁移脚本所在目录
+func New(service string, scripts fs.FS) *Runner {
+	return &Runner{service: service, scripts: scripts}
+}
+
+// AppliedMigration schema_migrations 中的一条记录
+type AppliedMigration struct {
+	Service   string `gorm:"primaryKey"`
+	Version   int    `gorm:"primaryKey"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (AppliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    service    VARCHAR(50)  NOT NULL,
+    version    INTEGER      NOT NULL,
+    name       VARCHAR(200) NOT NULL,
+    checksum   VARCHAR(64)  NOT NULL,
+    applied_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service, version)
+)`
+
+// Load 读取嵌入的迁移脚本，按版本号排序。版本号必须从 1 开始连续，每个版本都要有 up 和 down 脚本
+func (r *Runner) Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(r.scripts, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := scriptName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("迁移脚本命名不符合 <版本号>_<名称>.up|down.sql: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(r.scripts, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本 %d 的 up / down 脚本名称不一致", version)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("迁移版本不连续：缺少版本 %d", i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("迁移 %s 缺少 up 或 down 脚本", migration.label())
+		}
+	}
+	return migrations, nil
+}
+
+func (m Migration) label() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Applied 返回本服务已执行的迁移，schema_migrations 表不存在时返回空
+func (r *Runner) Applied(db *gorm.DB) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	if !db.Migrator().HasTable(&AppliedMigration{}) {
+		return applied, nil
+	}
+	err := db.Where("service = ?", r.service).Order("version").Find(&applied).Error
+	return applied, err
+}
+
+// CheckVersion 服务启动时检查数据库结构版本与程序要求的版本一致，不一致时拒绝启动
+func (r *Runner) CheckVersion(db *gorm.DB) error {
+	migrations, err := r.Load()
+	if err != nil {
+		return err
+	}
+	applied, err := r.Applied(db)
+	if err != nil {
+		return fmt.Errorf("读取 schema_migrations 失败: %w", err)
+	}
+	if err := verify(migrations, applied); err != nil {
+		return err
+	}
+
+	expected := len(migrations)
+	if len(applied) < expected {
+		return fmt.Errorf("数据库结构版本为 %d，程序要求 %d（%s 尚未执行），请先执行 migrate up",
+			len(applied), expected, migrations[len(applied)].label())
+	}
+	return nil
+}
+
+// verify 已执行的迁移必须是程序已知迁移的前缀，且脚本未被修改
+func verify(migrations []Migration, applied []AppliedMigration) error {
+	for i, record := range applied {
+		if record.Version > len(migrations) {
+			return fmt.Errorf("数据库结构版本 %d 高于程序支持的 %d（%s 由更新的版本执行），拒绝在该结构上运行",
+				record.Version, len(migrations), fmt.Sprintf("%04d_%s", record.Version, record.Name))
+		}
+		if record.Version != i+1 {
+			return fmt.Errorf("schema_migrations 中缺少版本 %d 的记录", i+1)
+		}
+		migration := migrations[i]
+		if record.Checksum != migration.Checksum {
+			return fmt.Errorf("迁移 %s 执行后脚本被修改过（校验和不一致），请新增迁移而不是修改已发布的脚本", migration.label())
+		}
+	}
+	return nil
+}
+
+// Up 依次执行尚未执行的迁移，每个版本一个事务
+func (r *Runner) Up(db *gorm.DB, out io.Writer) error {
+	migrations, err := r.Load()
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			applied, err := r.lockAndLoad(tx, migrations)
+			if err != nil {
+				return err
+			}
+			if len(applied) >= migration.Version {
+				return nil
+			}
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("执行迁移 %s 失败: %w", migration.label(), err)
+			}
+			fmt.Fprintf(out, "applied %s\n", migration.label())
+			return tx.Create(&AppliedMigration{
+				Service:   r.service,
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 回滚最近执行的 steps 个迁移
+func (r *Runner) Down(db *gorm.DB, steps int, out io.Writer) error {
+	migrations, err := r.Load()
+	if err != nil {
+		return err
+	}
+	for ; steps > 0; steps-- {
+		done := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			applied, err := r.lockAndLoad(tx, migrations)
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				done = true
+				return nil
+			}
+			migration := migrations[len(applied)-1]
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("回滚迁移 %s 失败: %w", migration.label(), err)
+			}
+			fmt.Fprintf(out, "reverted %s\n", migration.label())
+			return tx.Where("service = ? AND version = ?", r.service, migration.Version).Delete(&AppliedMigration{}).Error
+		})
+		if err != nil {
+			return err
+		}
+		if done {
+			fmt.Fprintln(out, "no migrations to revert")
+			return nil
+		}
+	}
+	return nil
+}
+
+// Status 输出每个迁移的执行情况
+func (r *Runner) Status(db *gorm.DB, out io.Writer) error {
+	migrations, err := r.Load()
+	if err != nil {
+		return err
+	}
+	applied, err := r.Applied(db)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		state := "pending"
+		if migration.Version <= len(applied) {
+			state = "applied " + applied[migration.Version-1].AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(out, "%s  %s\n", migration.label(), state)
+	}
+	if err := verify(migrations, applied); err != nil {
+		fmt.Fprintf(out, "warning: %v\n", err)
+	}
+	return nil
+}
+
+// lockAndLoad 在事务中加锁、确保 schema_migrations 存在并读取已执行的迁移
+func (r *Runner) lockAndLoad(tx *gorm.DB, migrations []Migration) ([]AppliedMigration, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Exec(createTableSQL).Error; err != nil {
+		return nil, err
+	}
+	var applied []AppliedMigration
+	if err := tx.Where("service = ?", r.service).Order("version").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	return applied, verify(migrations, applied)
+}
+
+// ErrUsage migrate 子命令参数错误
+var ErrUsage = errors.New("usage: migrate up | down [N] | status")
+
+// Command 执行 migrate 子命令：up 执行全部未执行的迁移，down 回滚最近 N 个（默认 1 个），status 查看状态
+func (r *Runner) Command(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	switch args[0] {
+	case "up":
+		return r.Up(db, out)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return ErrUsage
+			}
+			steps = n
+		}
+		return r.Down(db, steps, out)
+	case "status":
+		return r.Status(db, out)
+	default:
+		return ErrUsage
+	}
+}
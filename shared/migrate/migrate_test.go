@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scripts(names ...string) fstest.MapFS {
+	files := fstest.MapFS{}
+	for _, name := range names {
+		files[name] = &fstest.MapFile{Data: []byte("-- " + name)}
+	}
+	return files
+}
+
+func TestLoadOrdersMigrations(t *testing.T) {
+	r := New("test-service", scripts(
+		"0002_add_index.up.sql", "0002_add_index.down.sql",
+		"0001_baseline.up.sql", "0001_baseline.down.sql",
+	))
+	migrations, err := r.Load()
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "0001_baseline", migrations[0].label())
+	assert.Equal(t, "0002_add_index", migrations[1].label())
+	assert.Equal(t, "-- 0001_baseline.up.sql", migrations[0].Up)
+	assert.Len(t, migrations[0].Checksum, 64)
+}
+
+func TestLoadRejectsBrokenScriptSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"命名不规范":   scripts("0001_baseline.sql"),
+		"缺少版本":    scripts("0001_a.up.sql", "0001_a.down.sql", "0003_c.up.sql", "0003_c.down.sql"),
+		"缺少 down": scripts("0001_a.up.sql"),
+		"名称不一致":   scripts("0001_a.up.sql", "0001_b.down.sql"),
+	}
+	for name, files := range cases {
+		_, err := New("test-service", files).Load()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	migrations, err := New("test-service", scripts(
+		"0001_a.up.sql", "0001_a.down.sql", "0002_b.up.sql", "0002_b.down.sql",
+	)).Load()
+	require.NoError(t, err)
+	applied := func(records ...AppliedMigration) []AppliedMigration { return records }
+	first := AppliedMigration{Version: 1, Name: "a", Checksum: migrations[0].Checksum}
+
+	assert.NoError(t, verify(migrations, nil))
+	assert.NoError(t, verify(migrations, applied(first)))
+	assert.ErrorContains(t, verify(migrations, applied(AppliedMigration{Version: 1, Name: "a", Checksum: "changed"})), "校验和不一致")
+	assert.ErrorContains(t, verify(migrations, applied(AppliedMigration{Version: 2, Name: "b", Checksum: migrations[1].Checksum})), "缺少版本 1")
+	assert.ErrorContains(t, verify(migrations, applied(first,
+		AppliedMigration{Version: 2, Name: "b", Checksum: migrations[1].Checksum},
+		AppliedMigration{Version: 3, Name: "c"})), "高于程序支持")
+}
@@ -0,0 +1,83 @@
+// Package netguard 防止服务按用户提供的地址发起请求时访问内网（SSRF）。
+//
+// 保存地址时用 CheckURL 解析域名并检查所有地址；发送时使用 NewClient 创建的客户端，
+// 在建立连接前再次检查实际连接的 IP，防止域名在保存后改为解析到内网地址（DNS rebinding）。
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress 目标地址是回环、内网、链路本地或未指定地址
+var ErrForbiddenAddress = errors.New("不允许访问内网或本机地址")
+
+// cgnat 运营商级 NAT 地址段（100.64.0.0/10），云环境中常用作内部网络
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Allowed 判断 IP 是否为可访问的公网地址
+func Allowed(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		cgnat.Contains(ip))
+}
+
+// CheckURL 检查地址是否为 http(s) 且主机的所有解析结果都是公网地址
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("无效的 http(s) 地址: %q", rawURL)
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !Allowed(ip) {
+			return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("无法解析主机 %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !Allowed(addr.IP) {
+			return fmt.Errorf("%s 解析到 %s: %w", host, addr.IP, ErrForbiddenAddress)
+		}
+	}
+	return nil
+}
+
+// control 在连接建立前检查实际连接的 IP
+func control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !Allowed(ip) {
+		return fmt.Errorf("%s: %w", address, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// NewClient 创建只能连接公网地址的 HTTP 客户端。不使用环境变量中的代理，否则检查的是代理地址
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
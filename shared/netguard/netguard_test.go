@@ -0,0 +1,62 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckURLRejectsInternalAddresses(t *testing.T) {
+	for _, target := range []string{
+		"http://127.0.0.1:8084/api/internal/users",
+		"http://localhost/",
+		"http://[::1]/",
+		"http://0.0.0.0/",
+		"http://10.0.0.5/hook",
+		"http://172.16.3.4/",
+		"https://192.168.1.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[fe80::1]/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://100.64.0.1/",
+	} {
+		err := CheckURL(context.Background(), target)
+		assert.ErrorIs(t, err, ErrForbiddenAddress, target)
+	}
+}
+
+func TestCheckURLRejectsInvalidURLs(t *testing.T) {
+	for _, target := range []string{"", "ftp://example.com/", "file:///etc/passwd", "http://", "not a url"} {
+		err := CheckURL(context.Background(), target)
+		require.Error(t, err, target)
+		assert.NotErrorIs(t, err, ErrForbiddenAddress, target)
+	}
+}
+
+func TestCheckURLAllowsPublicAddresses(t *testing.T) {
+	assert.NoError(t, CheckURL(context.Background(), "https://93.184.216.34/hook"))
+	assert.NoError(t, CheckURL(context.Background(), "http://[2606:2800:220:1:248:1893:25c8:1946]/"))
+}
+
+func TestAllowed(t *testing.T) {
+	assert.True(t, Allowed(net.ParseIP("8.8.8.8")))
+	assert.False(t, Allowed(net.ParseIP("127.0.0.2")))
+	assert.False(t, Allowed(net.ParseIP("fd00::1")))
+}
+
+func TestClientRefusesToDialLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("请求不应到达本机服务")
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
@@ -0,0 +1,226 @@
+// Package pagination 列表分页：page / page_size 偏移分页、按 (排序列, ID) 定位的游标分页，
+// 以及精确、估算或不统计三种总数方式。
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 总数统计方式（查询参数 total）
+const (
+	TotalExact    = "exact"    // 精确 COUNT，默认
+	TotalEstimate = "estimate" // 取查询计划的估算行数，大表上代价很小
+	TotalNone     = "none"     // 不统计，响应中 total 为 -1
+)
+
+// ErrCursorMismatch 游标与本次请求的排序方式不一致（客户端在翻页途中修改了排序参数）
+var ErrCursorMismatch = errors.New("游标与当前排序方式不一致，请从第一页重新查询")
+
+// SortKey 列表的排序方式。Column 必须是非空列，IDColumn 为唯一列，排序值相同时按它排序，保证顺序稳定
+type SortKey struct {
+	Column   string
+	IDColumn string
+	Desc     bool
+}
+
+func (k SortKey) direction() string {
+	if k.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// pageCursor 游标内容：上一页最后一条记录的排序值和 ID，以及生成游标时的排序方式
+type pageCursor struct {
+	Column string      `json:"c"`
+	Desc   bool        `json:"d"`
+	Value  interface{} `json:"v"`
+	ID     string      `json:"id"`
+}
+
+// Pager 列表分页参数。
+// 请求带 cursor 参数（第一页传空值）时使用游标分页：按 (排序列, ID) 定位上一页末尾，翻页时不会因新增或删除记录而重复、遗漏；
+// 不带 cursor 时保持原有的 page / page_size 偏移分页
+type Pager struct {
+	Page      int
+	Limit     int
+	TotalMode string
+
+	cursorMode bool
+	after      *pageCursor
+	estimated  bool
+	nextCursor string
+	hasMore    bool
+}
+
+// New 在已校验的 page / limit 基础上解析 cursor 和 total 参数
+func New(c *gin.Context, page, limit int) (*Pager, error) {
+	p := &Pager{Page: page, Limit: limit, TotalMode: c.DefaultQuery("total", TotalExact)}
+	switch p.TotalMode {
+	case TotalExact, TotalEstimate, TotalNone:
+	default:
+		return nil, fmt.Errorf("total 参数只能为 exact、estimate 或 none")
+	}
+
+	raw, ok := c.GetQuery("cursor")
+	if !ok {
+		return p, nil
+	}
+	p.cursorMode = true
+	if raw == "" {
+		return p, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("无效的游标")
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.Value == nil {
+		return nil, fmt.Errorf("无效的游标")
+	}
+	p.after = &cursor
+	return p, nil
+}
+
+// CursorMode 是否使用游标分页
+func (p *Pager) CursorMode() bool {
+	return p.cursorMode
+}
+
+// Count 按 total 参数统计总数；不统计时返回 -1
+func (p *Pager) Count(query *gorm.DB) (int64, error) {
+	switch p.TotalMode {
+	case TotalNone:
+		return -1, nil
+	case TotalEstimate:
+		total, err := EstimateCount(query)
+		if err != nil {
+			return 0, err
+		}
+		p.estimated = true
+		return total, nil
+	default:
+		var total int64
+		err := query.Count(&total).Error
+		return total, err
+	}
+}
+
+// Apply 添加排序和分页条件。游标分页时多取一条，用于判断是否还有下一页；
+// 游标与本次请求的排序方式不一致时返回错误
+func (p *Pager) Apply(query *gorm.DB, key SortKey) (*gorm.DB, error) {
+	query = query.Order(fmt.Sprintf("%s %s, %s %s", key.Column, key.direction(), key.IDColumn, key.direction()))
+	if !p.cursorMode {
+		return query.Offset((p.Page - 1) * p.Limit).Limit(p.Limit), nil
+	}
+	if p.after != nil {
+		if p.after.Column != key.Column || p.after.Desc != key.Desc {
+			return nil, ErrCursorMismatch
+		}
+		operator := ">"
+		if key.Desc {
+			operator = "<"
+		}
+		query = query.Where(clause.Expr{
+			SQL:  fmt.Sprintf("(%s, %s) %s (?, ?)", key.Column, key.IDColumn, operator),
+			Vars: []interface{}{p.after.Value, p.after.ID},
+		})
+	}
+	return query.Limit(p.Limit + 1), nil
+}
+
+// PageRows 游标分页时去掉多取的一条并生成下一页游标；value 返回记录的排序值和 ID
+func PageRows[T any](p *Pager, rows []T, key SortKey, value func(T) (interface{}, string)) []T {
+	if !p.cursorMode || len(rows) <= p.Limit {
+		return rows
+	}
+	rows = rows[:p.Limit]
+	sortValue, id := value(rows[len(rows)-1])
+	data, _ := json.Marshal(pageCursor{Column: key.Column, Desc: key.Desc, Value: sortValue, ID: id})
+	p.nextCursor = base64.RawURLEncoding.EncodeToString(data)
+	p.hasMore = true
+	return rows
+}
+
+// NextCursor 下一页的游标，没有下一页或使用偏移分页时为空
+func (p *Pager) NextCursor() string {
+	return p.nextCursor
+}
+
+// HasMore 游标分页时是否还有下一页
+func (p *Pager) HasMore() bool {
+	return p.hasMore
+}
+
+// Estimated Count 返回的是否为估算值
+func (p *Pager) Estimated() bool {
+	return p.estimated
+}
+
+// Result 分页响应的 data 部分，字段与各服务的 PaginatedResponse 一致
+type Result struct {
+	Data       interface{} `json:"data"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	Limit      int         `json:"limit"`
+	TotalPages int         `json:"total_pages"`
+	// 游标分页（请求带 cursor 参数）时返回；total=estimate 时 total 为估算值
+	NextCursor     string `json:"next_cursor,omitempty"`
+	HasMore        *bool  `json:"has_more,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+}
+
+// Result 组装分页响应：偏移分页与各服务的 SendPaginatedResponse 相同，游标分页额外返回 next_cursor 和 has_more
+func (p *Pager) Result(data interface{}, total int64) Result {
+	response := Result{
+		Data:           data,
+		Total:          total,
+		Page:           p.Page,
+		Limit:          p.Limit,
+		TotalEstimated: p.estimated,
+	}
+	if total > 0 {
+		response.TotalPages = (int(total) + p.Limit - 1) / p.Limit
+	}
+	if p.cursorMode {
+		response.NextCursor = p.nextCursor
+		response.HasMore = &p.hasMore
+	}
+	return response
+}
+
+// Send 以 {code, message, data} 的统一格式发送分页响应
+func (p *Pager) Send(c *gin.Context, data interface{}, total int64) {
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": p.Result(data, total)})
+}
+
+// EstimateCount 返回查询计划中估算的结果行数，避免在大表上做精确 COUNT
+func EstimateCount(query *gorm.DB) (int64, error) {
+	tx := query.Session(&gorm.Session{DryRun: true}).Find(&[]map[string]interface{}{})
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	stmt := tx.Statement
+	// 生成的 SQL 已是 $n 占位符，直接交给连接执行，不再经过 GORM 的 ? 占位符替换
+	var plan string
+	if err := stmt.ConnPool.QueryRowContext(stmt.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).Scan(&plan); err != nil {
+		return 0, err
+	}
+	var result []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &result); err != nil || len(result) == 0 {
+		return 0, fmt.Errorf("解析查询计划失败: %v", err)
+	}
+	return int64(result[0].Plan.Rows), nil
+}
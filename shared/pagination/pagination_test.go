@@ -0,0 +1,72 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPager(t *testing.T, query string) (*Pager, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return New(c, 2, 10)
+}
+
+type item struct {
+	ID        string
+	CreatedAt string
+}
+
+func TestOffsetPagination(t *testing.T) {
+	p, err := newPager(t, "")
+	require.NoError(t, err)
+	assert.False(t, p.CursorMode())
+
+	result := p.Result([]int{1, 2}, 25)
+	assert.Equal(t, 3, result.TotalPages)
+	assert.Nil(t, result.HasMore, "偏移分页不返回 has_more")
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := SortKey{Column: "created_at", IDColumn: "id", Desc: true}
+	first, err := newPager(t, "cursor=")
+	require.NoError(t, err)
+	require.True(t, first.CursorMode())
+
+	rows := make([]item, 11)
+	for i := range rows {
+		rows[i] = item{ID: string(rune('a' + i)), CreatedAt: "2026-01-01"}
+	}
+	page := PageRows(first, rows, key, func(it item) (interface{}, string) { return it.CreatedAt, it.ID })
+	assert.Len(t, page, 10, "多取的一条不返回")
+	require.True(t, first.HasMore())
+	require.NotEmpty(t, first.NextCursor())
+
+	next, err := newPager(t, "cursor="+first.NextCursor())
+	require.NoError(t, err)
+	assert.Equal(t, &pageCursor{Column: "created_at", Desc: true, Value: "2026-01-01", ID: "j"}, next.after)
+
+	last := PageRows(next, rows[:3], key, func(it item) (interface{}, string) { return it.CreatedAt, it.ID })
+	assert.Len(t, last, 3)
+	assert.False(t, next.HasMore())
+	assert.Empty(t, next.NextCursor())
+}
+
+func TestNewRejectsBadParameters(t *testing.T) {
+	_, err := newPager(t, "total=all")
+	assert.Error(t, err)
+	_, err = newPager(t, "cursor=not-base64!")
+	assert.Error(t, err)
+	_, err = newPager(t, "cursor=e30") // {}
+	assert.Error(t, err)
+
+	p, err := newPager(t, "total=none")
+	require.NoError(t, err)
+	total, err := p.Count(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), total)
+}
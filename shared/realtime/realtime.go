@@ -0,0 +1,122 @@
+// Package realtime 通过 Redis 向在线用户推送事件，由网关的 /api/events/stream 以 SSE 转发给浏览器
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"credit-management/shared/imports"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 推送事件类型
+const (
+	EventNotification   = "notification"   // 新的站内通知
+	EventPendingReview  = "pending_review" // 有新的待审核活动（推送给数据范围包含该活动的审核人）
+	EventImportProgress = "import.progress"
+)
+
+// Redis 中的键约定，需与网关 /api/events/stream 保持一致：
+// 每个用户有一个 Stream 用于断线重连时按 Last-Event-ID 补发，以及一个同名 pub/sub 频道用于实时推送。
+const (
+	userKeyPrefix  = "events:user:"
+	streamMaxLen   = 500
+	publishTimeout = 3 * time.Second
+)
+
+// Message 推送给网关的消息，ID 即 Redis Stream 条目 ID
+type Message struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Publisher 实时事件发布者
+type Publisher interface {
+	PublishToUser(ctx context.Context, userID, eventType string, data interface{}) error
+}
+
+// RedisPublisher 先写入接收方的 Stream 取得事件 ID，再通过 pub/sub 广播给在线连接
+type RedisPublisher struct {
+	client *redis.Client
+}
+
+func NewRedisPublisher(client *redis.Client) *RedisPublisher {
+	return &RedisPublisher{client: client}
+}
+
+func (p *RedisPublisher) PublishToUser(ctx context.Context, userID, eventType string, data interface{}) error {
+	return p.publish(ctx, userKeyPrefix+userID, eventType, data)
+}
+
+func (p *RedisPublisher) publish(ctx context.Context, key, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	id, err := p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"type": eventType, "data": string(payload)},
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(Message{ID: id, Type: eventType, Data: json.RawMessage(payload)})
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(ctx, key, message).Err()
+}
+
+// NoopPublisher 未启用实时推送时使用
+type NoopPublisher struct{}
+
+func (NoopPublisher) PublishToUser(ctx context.Context, userID, eventType string, data interface{}) error {
+	return nil
+}
+
+var (
+	mu        sync.RWMutex
+	publisher Publisher = NoopPublisher{}
+)
+
+// SetPublisher 设置全局发布者，在服务启动时调用
+func SetPublisher(p Publisher) {
+	mu.Lock()
+	defer mu.Unlock()
+	publisher = p
+}
+
+// NotifyUser 向单个用户推送事件。推送是尽力而为的，失败只记录日志，不影响业务流程。
+func NotifyUser(userID, eventType string, data interface{}) {
+	mu.RLock()
+	p := publisher
+	mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := p.PublishToUser(ctx, userID, eventType, data); err != nil {
+		log.Printf("[realtime] failed to push %s to user %s: %v", eventType, userID, err)
+	}
+}
+
+// NotifyImportProgress 向导入发起人推送任务进度，作为各服务导入执行器的 OnProgress
+func NotifyImportProgress(job imports.Job) {
+	NotifyUser(job.CreatedBy, EventImportProgress, map[string]interface{}{
+		"job_id":    job.ID,
+		"file_name": job.FileName,
+		"status":    job.Status,
+		"total":     job.TotalRows,
+		"processed": job.ProcessedRows,
+		"succeeded": job.SucceededRows,
+		"failed":    job.FailedRows,
+	})
+}
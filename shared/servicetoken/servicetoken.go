@@ -0,0 +1,27 @@
+// Package servicetoken 服务间调用令牌。
+//
+// 认证服务用 Ed25519 私钥签发短期令牌（Signer），sub 为调用方服务名，aud 为被调用的服务名；
+// 调用方用各自的客户端凭据换取令牌并按被调用方分别缓存（Source）；
+// 被调用方只持有公钥，校验签名、签发者、有效期，并要求 aud 为本服务（Verifier），
+// 因此发给某个服务的令牌不能被拿去调用其他服务。
+package servicetoken
+
+import "time"
+
+const (
+	// Header 服务间调用携带服务令牌的请求头
+	Header = "X-Service-Token"
+	// Issuer 服务令牌签发者
+	Issuer = "auth-service"
+	// TTL 服务令牌有效期，调用方在过期前自行续签
+	TTL = 5 * time.Minute
+)
+
+// 各服务的默认服务名（INTERNAL_SERVICE_NAME），也是令牌的 sub / aud
+const (
+	AuthService           = "auth-service"
+	UserService           = "user-service"
+	CreditActivityService = "credit-activity-service"
+	// GatewayService 只有网关的请求才会采信 X-User-* 用户头
+	GatewayService = "api-gateway"
+)
@@ -0,0 +1,185 @@
+package servicetoken
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigner(t *testing.T) *Signer {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return NewSigner(privateKey)
+}
+
+func pinnedVerifier(t *testing.T, signer *Signer, audience string) *Verifier {
+	v, err := NewVerifier("http://auth-service:8081", base64.StdEncoding.EncodeToString(signer.PublicKey()), audience)
+	require.NoError(t, err)
+	return v
+}
+
+func TestMintAndVerify(t *testing.T) {
+	signer := newSigner(t)
+	token, expiresAt, err := signer.Mint(GatewayService, UserService)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(TTL), expiresAt, time.Second)
+
+	service, err := pinnedVerifier(t, signer, UserService).Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, GatewayService, service)
+}
+
+func TestVerifyRejectsOtherAudience(t *testing.T) {
+	signer := newSigner(t)
+	token, _, err := signer.Mint(UserService, CreditActivityService)
+	require.NoError(t, err)
+
+	_, err = pinnedVerifier(t, signer, UserService).Verify(context.Background(), token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience, "发给学分活动服务的令牌不能调用用户服务")
+}
+
+func TestVerifyRejectsExpiredToken(t *testing.T) {
+	signer := newSigner(t)
+	signer.now = func() time.Time { return time.Now().Add(-TTL - time.Minute) }
+	token, _, err := signer.Mint(GatewayService, UserService)
+	require.NoError(t, err)
+
+	_, err = pinnedVerifier(t, signer, UserService).Verify(context.Background(), token)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+func TestVerifyRejectsWrongKey(t *testing.T) {
+	token, _, err := newSigner(t).Mint(GatewayService, UserService)
+	require.NoError(t, err)
+
+	_, err = pinnedVerifier(t, newSigner(t), UserService).Verify(context.Background(), token)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestVerifyRejectsWrongIssuer(t *testing.T) {
+	signer := newSigner(t)
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{
+		Issuer:    "someone-else",
+		Subject:   GatewayService,
+		Audience:  jwt.ClaimStrings{UserService},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	signed, err := token.SignedString(signer.privateKey)
+	require.NoError(t, err)
+
+	_, err = pinnedVerifier(t, signer, UserService).Verify(context.Background(), signed)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+}
+
+func TestVerifyRejectsOtherAlgorithms(t *testing.T) {
+	signer := newSigner(t)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    Issuer,
+		Subject:   GatewayService,
+		Audience:  jwt.ClaimStrings{UserService},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	// 用公钥作为 HMAC 密钥伪造令牌
+	signed, err := token.SignedString([]byte(signer.PublicKey()))
+	require.NoError(t, err)
+
+	_, err = pinnedVerifier(t, signer, UserService).Verify(context.Background(), signed)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestVerifierFetchesKeysByKID(t *testing.T) {
+	signer := newSigner(t)
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		assert.Equal(t, "/api/internal/service-keys", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"keys": []map[string]string{{"kid": signer.KeyID(), "public_key": base64.StdEncoding.EncodeToString(signer.PublicKey())}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	v, err := NewVerifier(server.URL, "", UserService)
+	require.NoError(t, err)
+	token, _, err := signer.Mint(GatewayService, UserService)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = v.Verify(context.Background(), token)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "公钥缓存后不再拉取")
+
+	// 未知 kid 在最小间隔内不会重新拉取
+	other, _, err := newSigner(t).Mint(GatewayService, UserService)
+	require.NoError(t, err)
+	_, err = v.Verify(context.Background(), other)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestNewVerifierRejectsBadInput(t *testing.T) {
+	_, err := NewVerifier("http://auth-service:8081", "", "")
+	assert.Error(t, err)
+	_, err = NewVerifier("http://auth-service:8081", "not-a-key", UserService)
+	assert.Error(t, err)
+}
+
+func TestSourceCachesTokenPerAudience(t *testing.T) {
+	signer := newSigner(t)
+	var requests []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		token, expiresAt, err := signer.Mint(body["service"], body["audience"])
+		require.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"token": token, "expires_at": expiresAt},
+		})
+	}))
+	defer server.Close()
+
+	source := NewSource(server.URL, GatewayService, "secret")
+	users := pinnedVerifier(t, signer, UserService)
+	credits := pinnedVerifier(t, signer, CreditActivityService)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, source.For(UserService)(req))
+	_, err := users.Verify(context.Background(), req.Header.Get(Header))
+	require.NoError(t, err)
+	require.NoError(t, source.Apply(req, UserService))
+
+	require.NoError(t, source.Apply(req, CreditActivityService))
+	_, err = credits.Verify(context.Background(), req.Header.Get(Header))
+	require.NoError(t, err)
+	_, err = users.Verify(context.Background(), req.Header.Get(Header))
+	assert.Error(t, err)
+
+	require.Len(t, requests, 2, "同一目标服务的令牌被缓存")
+	assert.Equal(t, map[string]string{"service": GatewayService, "secret": "secret", "audience": UserService}, requests[0])
+	assert.Equal(t, CreditActivityService, requests[1]["audience"])
+}
+
+func TestSourceReportsRejectedCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewSource(server.URL, GatewayService, "wrong").Token(context.Background(), UserService)
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,52 @@
+package servicetoken
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer 签发服务令牌，只在认证服务中使用
+type Signer struct {
+	privateKey ed25519.PrivateKey
+	keyID      string
+	now        func() time.Time
+}
+
+func NewSigner(privateKey ed25519.PrivateKey) *Signer {
+	sum := sha256.Sum256(privateKey.Public().(ed25519.PublicKey))
+	return &Signer{privateKey: privateKey, keyID: hex.EncodeToString(sum[:8]), now: time.Now}
+}
+
+// KeyID 令牌头中的 kid，下游服务据此选择公钥
+func (s *Signer) KeyID() string { return s.keyID }
+
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
+
+// Mint 为 service 签发调用 audience 的令牌
+func (s *Signer) Mint(service, audience string) (string, time.Time, error) {
+	if service == "" || audience == "" {
+		return "", time.Time{}, errors.New("服务令牌需要服务名和目标服务")
+	}
+	now := s.now()
+	expiresAt := now.Add(TTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{
+		Issuer:    Issuer,
+		Subject:   service,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	token.Header["kid"] = s.keyID
+	signed, err := token.SignedString(s.privateKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
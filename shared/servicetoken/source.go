@@ -0,0 +1,92 @@
+package servicetoken
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source 向认证服务申请并缓存本服务的短期令牌，每个目标服务一个令牌，过期前自动续签
+type Source struct {
+	authURL string
+	service string
+	secret  string
+	client  *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]cachedToken // 目标服务 -> 令牌
+}
+
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+func NewSource(authURL, service, secret string) *Source {
+	return &Source{
+		authURL: strings.TrimRight(authURL, "/"),
+		service: service,
+		secret:  secret,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		tokens:  map[string]cachedToken{},
+	}
+}
+
+// Token 返回调用 audience 的有效令牌，剩余有效期不足一分钟时重新申请
+func (s *Source) Token(ctx context.Context, audience string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.tokens[audience]; ok && time.Until(cached.expiresAt) > time.Minute {
+		return cached.token, nil
+	}
+
+	payload, _ := json.Marshal(map[string]string{"service": s.service, "secret": s.secret, "audience": audience})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.authURL+"/api/internal/service-token", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("申请服务令牌失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("申请服务令牌失败: auth-service responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Token     string    `json:"token"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("解析服务令牌失败: %w", err)
+	}
+	s.tokens[audience] = cachedToken{token: body.Data.Token, expiresAt: body.Data.ExpiresAt}
+	return body.Data.Token, nil
+}
+
+// Apply 在发往 audience 的请求上设置服务令牌
+func (s *Source) Apply(req *http.Request, audience string) error {
+	token, err := s.Token(req.Context(), audience)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(Header, token)
+	return nil
+}
+
+// For 返回为发往 audience 的请求设置令牌的函数，供只调用一个服务的客户端使用
+func (s *Source) For(audience string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		return s.Apply(req, audience)
+	}
+}
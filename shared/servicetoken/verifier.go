@@ -0,0 +1,127 @@
+package servicetoken
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 公钥未命中时重新拉取的最小间隔，避免伪造的 kid 打满认证服务
+const keysRefetchInterval = 30 * time.Second
+
+// Verifier 校验发给本服务的服务令牌。公钥可通过 SERVICE_JWT_PUBLIC_KEY 固定配置，
+// 否则从认证服务拉取并缓存，遇到未知 kid（认证服务轮换了密钥）时重新拉取
+type Verifier struct {
+	audience string
+	keysURL  string
+	pinned   ed25519.PublicKey
+	client   *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+// NewVerifier audience 为本服务的服务名，只接受 aud 为本服务的令牌
+func NewVerifier(authURL, pinnedKey, audience string) (*Verifier, error) {
+	if audience == "" {
+		return nil, errors.New("服务令牌校验器需要本服务的服务名")
+	}
+	v := &Verifier{
+		audience: audience,
+		keysURL:  strings.TrimRight(authURL, "/") + "/api/internal/service-keys",
+		client:   &http.Client{Timeout: 5 * time.Second},
+		keys:     map[string]ed25519.PublicKey{},
+	}
+	if pinnedKey != "" {
+		key, err := base64.StdEncoding.DecodeString(pinnedKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("SERVICE_JWT_PUBLIC_KEY 格式错误")
+		}
+		v.pinned = key
+	}
+	return v, nil
+}
+
+// Verify 校验服务令牌，返回调用方服务名
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errors.New("服务令牌缺少服务名")
+	}
+	return claims.Subject, nil
+}
+
+func (v *Verifier) publicKey(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	if v.pinned != nil {
+		return v.pinned, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetchedAt) < keysRefetchInterval {
+		return nil, fmt.Errorf("未知的服务令牌密钥: %s", kid)
+	}
+	v.fetchedAt = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.keysURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取服务令牌公钥失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取服务令牌公钥失败: auth-service responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Keys []struct {
+				KID       string `json:"kid"`
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("解析服务令牌公钥失败: %w", err)
+	}
+	keys := make(map[string]ed25519.PublicKey, len(body.Data.Keys))
+	for _, k := range body.Data.Keys {
+		if raw, err := base64.StdEncoding.DecodeString(k.PublicKey); err == nil && len(raw) == ed25519.PublicKeySize {
+			keys[k.KID] = raw
+		}
+	}
+	v.keys = keys
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未知的服务令牌密钥: %s", kid)
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeS3 进程内的 S3 兼容服务，对象保存在内存中，按真实服务的规则校验 SigV4 签名和预签名有效期。
+// 仅用于本包测试：httptest.NewServer(NewFakeS3(...))，只支持路径风格的对象读写、删除和 ListObjectsV2
+type FakeS3 struct {
+	mu      sync.Mutex
+	creds   credentials
+	buckets map[string]map[string]fakeObject
+	Now     func() time.Time
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// NewFakeS3 创建只接受给定密钥的假 S3，并预先创建 buckets
+func NewFakeS3(accessKey, secretKey, region string, buckets ...string) *FakeS3 {
+	f := &FakeS3{
+		creds:   credentials{accessKey: accessKey, secretKey: secretKey, region: region},
+		buckets: make(map[string]map[string]fakeObject),
+		Now:     time.Now,
+	}
+	for _, bucket := range buckets {
+		f.buckets[bucket] = make(map[string]fakeObject)
+	}
+	return f
+}
+
+// Object 返回对象内容，供测试断言
+func (f *FakeS3) Object(bucket, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.buckets[bucket][key]
+	return object.data, ok
+}
+
+func (f *FakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if code, message := f.authenticate(r); code != "" {
+		writeFakeError(w, http.StatusForbidden, code, message)
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket, ok := f.buckets[bucketName]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r, bucket)
+	case key == "":
+		writeFakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "bucket operation not supported")
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeFakeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		bucket[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: f.Now().UTC()}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := bucket[key]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		header := w.Header()
+		header.Set("Content-Type", object.contentType)
+		header.Set("Content-Length", strconv.Itoa(len(object.data)))
+		header.Set("Last-Modified", object.modTime.Format(http.TimeFormat))
+		if value := r.URL.Query().Get("response-content-type"); value != "" {
+			header.Set("Content-Type", value)
+		}
+		if value := r.URL.Query().Get("response-content-disposition"); value != "" {
+			header.Set("Content-Disposition", value)
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(bucket, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// list ListObjectsV2，支持 prefix、max-keys 与 continuation-token 分页
+func (f *FakeS3) list(w http.ResponseWriter, r *http.Request, bucket map[string]fakeObject) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	after := query.Get("continuation-token")
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	keys := make([]string, 0, len(bucket))
+	for key := range bucket {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var result listBucketResult
+	for i, key := range keys {
+		if i == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[i-1]
+			break
+		}
+		object := bucket[key]
+		result.Contents = append(result.Contents, listObject{Key: key, Size: int64(len(object.data)), LastModified: object.modTime})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: result})
+}
+
+// authenticate 重新计算签名并与请求中的比较，失败时返回 S3 错误码
+func (f *FakeS3) authenticate(r *http.Request) (string, string) {
+	query := r.URL.Query()
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+	if query.Get("X-Amz-Signature") != "" {
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		amzDate = query.Get("X-Amz-Date")
+		payloadHash = unsignedPayload
+		query.Del("X-Amz-Signature")
+	} else {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), signAlgorithm+" ")
+		for _, part := range strings.Split(auth, ", ") {
+			name, value, _ := strings.Cut(part, "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	}
+	if signature == "" {
+		return "AccessDenied", "request is not signed"
+	}
+
+	signedAt, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return "AccessDenied", "invalid X-Amz-Date"
+	}
+	if credential != f.creds.accessKey+"/"+f.creds.scope(signedAt) {
+		return "InvalidAccessKeyId", fmt.Sprintf("unexpected credential %q", credential)
+	}
+	if expires := query.Get("X-Amz-Expires"); expires != "" {
+		seconds, err := strconv.Atoi(expires)
+		if err != nil || f.Now().After(signedAt.Add(time.Duration(seconds)*time.Second)) {
+			return "AccessDenied", "Request has expired"
+		}
+	}
+
+	headers := make(map[string]string)
+	signed := strings.Split(signedHeaders, ";")
+	for _, name := range signed {
+		if name == "host" {
+			headers[name] = r.Host
+		} else {
+			headers[name] = strings.TrimSpace(strings.Join(r.Header.Values(name), ","))
+		}
+	}
+	expected := f.creds.signature(signedAt, canonicalRequest(r.Method, r.URL.Path, query, headers, signed, payloadHash))
+	if expected != signature {
+		return "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."
+	}
+	return "", ""
+}
+
+func writeFakeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		s3Error
+	}{s3Error: s3Error{Code: code, Message: message}})
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MigrateOptions 迁移选项
+type MigrateOptions struct {
+	DryRun       bool // 只列出需要复制的文件，不实际复制
+	DeleteSource bool // 复制并校验大小后删除源文件
+}
+
+// MigrateResult 迁移统计
+type MigrateResult struct {
+	Copied  int
+	Skipped int // 目标中已存在且大小一致
+	Failed  int
+}
+
+// Migrate 把 src 中 key 以 prefix 开头的对象复制到 dst。可以重复执行：目标中已存在且大小一致的对象跳过，
+// 单个对象失败不中断，最后返回失败数
+func Migrate(ctx context.Context, src, dst Storage, prefix string, opts MigrateOptions, out io.Writer) (MigrateResult, error) {
+	var result MigrateResult
+	objects, err := src.List(ctx, prefix)
+	if err != nil {
+		return result, fmt.Errorf("列出源文件失败: %w", err)
+	}
+
+	for _, object := range objects {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		existing, err := dst.Stat(ctx, object.Key)
+		if err == nil && existing.Size == object.Size {
+			result.Skipped++
+			if opts.DeleteSource && !opts.DryRun {
+				deleteSource(ctx, src, object.Key, out)
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrNotExist) {
+			fmt.Fprintf(out, "failed %s: %v\n", object.Key, err)
+			result.Failed++
+			continue
+		}
+
+		if opts.DryRun {
+			fmt.Fprintf(out, "would copy %s (%d bytes)\n", object.Key, object.Size)
+			result.Copied++
+			continue
+		}
+		if err := copyObject(ctx, src, dst, object); err != nil {
+			fmt.Fprintf(out, "failed %s: %v\n", object.Key, err)
+			result.Failed++
+			continue
+		}
+		fmt.Fprintf(out, "copied %s (%d bytes)\n", object.Key, object.Size)
+		result.Copied++
+		if opts.DeleteSource {
+			deleteSource(ctx, src, object.Key, out)
+		}
+	}
+	return result, nil
+}
+
+// copyObject 复制单个对象并校验目标大小
+func copyObject(ctx context.Context, src, dst Storage, object ObjectInfo) error {
+	reader, info, err := src.Open(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = object.ContentType
+	}
+	if err := dst.Put(ctx, object.Key, reader, info.Size, contentType); err != nil {
+		return err
+	}
+	copied, err := dst.Stat(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	if copied.Size != info.Size {
+		return fmt.Errorf("复制后大小不一致：源 %d 字节，目标 %d 字节", info.Size, copied.Size)
+	}
+	return nil
+}
+
+func deleteSource(ctx context.Context, src Storage, key string, out io.Writer) {
+	if err := src.Delete(ctx, key); err != nil {
+		fmt.Fprintf(out, "failed to delete source %s: %v\n", key, err)
+	}
+}
+
+// ErrUsage storage 子命令参数错误
+var ErrUsage = errors.New("usage: storage migrate [--dry-run] [--delete-source]")
+
+// Command 执行 storage 子命令。migrate 把本地目录中 prefix 下的文件复制到当前配置的存储后端
+func Command(ctx context.Context, cfg Config, prefix string, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "migrate" {
+		return ErrUsage
+	}
+	var opts MigrateOptions
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--dry-run":
+			opts.DryRun = true
+		case "--delete-source":
+			opts.DeleteSource = true
+		default:
+			return ErrUsage
+		}
+	}
+	if cfg.Backend == "" || cfg.Backend == BackendLocal {
+		return fmt.Errorf("当前存储后端为本地目录，无需迁移；请先配置 STORAGE_BACKEND=s3")
+	}
+
+	src, err := NewLocal(cfg.LocalDir)
+	if err != nil {
+		return err
+	}
+	dst, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	result, err := Migrate(ctx, src, dst, prefix, opts, out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "copied %d, skipped %d, failed %d\n", result.Copied, result.Skipped, result.Failed)
+	if result.Failed > 0 {
+		return fmt.Errorf("%d 个文件迁移失败，可重新执行继续迁移", result.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config S3 兼容存储配置
+type S3Config struct {
+	Endpoint       string // 服务访问对象存储的地址，如 http://minio:9000
+	PublicEndpoint string // 预签名 URL 使用的地址（需浏览器可访问），为空时与 Endpoint 相同
+	Region         string
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	Prefix         string // 对象 key 的公共前缀，多个环境共用一个桶时区分
+	HTTPClient     *http.Client
+}
+
+// S3 S3 兼容的对象存储，使用路径风格的 URL（<endpoint>/<bucket>/<key>），MinIO 与大多数兼容实现都支持
+type S3 struct {
+	endpoint *url.URL
+	public   *url.URL
+	bucket   string
+	prefix   string
+	creds    credentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 创建 S3 兼容存储，不检查桶是否存在
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 存储需要配置 endpoint 和 bucket")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint 无效: %q", cfg.Endpoint)
+	}
+	public := endpoint
+	if cfg.PublicEndpoint != "" {
+		public, err = url.Parse(strings.TrimRight(cfg.PublicEndpoint, "/"))
+		if err != nil || public.Host == "" {
+			return nil, fmt.Errorf("S3 public endpoint 无效: %q", cfg.PublicEndpoint)
+		}
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	return &S3{
+		endpoint: endpoint,
+		public:   public,
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		creds:    credentials{accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, region: region},
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL 对象的路径风格 URL；key 为空时指向桶本身
+func (s *S3) objectURL(base *url.URL, key string, query url.Values) *url.URL {
+	u := *base
+	u.Path = base.Path + "/" + s.bucket
+	if key != "" {
+		u.Path += "/" + s.prefix + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (s *S3) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.creds.signRequest(req, unsignedPayload, s.now().UTC())
+	return s.client.Do(req)
+}
+
+// Put 单次 PUT 上传；size 未知时先读入内存
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(s.endpoint, key, nil), body, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, "PUT", key)
+	}
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(s.endpoint, key, nil), nil, 0, nil)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, ObjectInfo{}, responseError(resp, "GET", key)
+	}
+	return resp.Body, responseInfo(key, resp), nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(s.endpoint, key, nil), nil, 0, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ObjectInfo{}, responseError(resp, "HEAD", key)
+	}
+	return responseInfo(key, resp), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(s.endpoint, key, nil), nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError(resp, "DELETE", key)
+	}
+}
+
+// listBucketResult ListObjectsV2 的响应
+type listBucketResult struct {
+	Contents              []listObject `xml:"Contents"`
+	IsTruncated           bool         `xml:"IsTruncated"`
+	NextContinuationToken string       `xml:"NextContinuationToken"`
+}
+
+type listObject struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// List 使用 ListObjectsV2 分页列出对象，返回的 key 不含配置的公共前缀
+func (s *S3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, s.objectURL(s.endpoint, "", query), nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := responseError(resp, "LIST", prefix)
+			resp.Body.Close()
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析 S3 列表响应失败: %w", err)
+		}
+
+		for _, item := range result.Contents {
+			key := strings.TrimPrefix(item.Key, s.prefix)
+			objects = append(objects, ObjectInfo{
+				Key:         key,
+				Size:        item.Size,
+				ContentType: ContentTypeFor(path.Ext(key)),
+				ModTime:     item.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// PresignGet 生成浏览器可直接访问的下载 URL，文件名和类型通过 response-* 参数让存储在响应头中返回
+func (s *S3) PresignGet(ctx context.Context, key string, ttl time.Duration, opts DownloadOptions) (string, error) {
+	query := url.Values{}
+	if opts.FileName != "" || opts.Inline {
+		query.Set("response-content-disposition", ContentDisposition(opts.FileName, opts.Inline))
+	}
+	if opts.ContentType != "" {
+		query.Set("response-content-type", opts.ContentType)
+	}
+	return s.creds.presign(http.MethodGet, s.objectURL(s.public, key, query), s.now().UTC(), ttl), nil
+}
+
+func responseInfo(key string, resp *http.Response) ObjectInfo {
+	info := ObjectInfo{Key: key, ContentType: resp.Header.Get("Content-Type")}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		info.Size = size
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info
+}
+
+// s3Error S3 错误响应体
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// responseError 把非成功响应转换为错误，对象不存在时返回 ErrNotExist
+func responseError(resp *http.Response, op, key string) error {
+	var body s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = xml.Unmarshal(data, &body)
+	if resp.StatusCode == http.StatusNotFound && body.Code != "NoSuchBucket" {
+		return ErrNotExist
+	}
+	if body.Code == "" {
+		body.Code = resp.Status
+	}
+	return fmt.Errorf("S3 %s %s 失败: %s %s", op, key, body.Code, body.Message)
+}
@@ -0,0 +1,133 @@
+// Package storage 附件、头像等上传文件的存储抽象。
+//
+// 文件按 key（如 attachments/<文件名>、avatars/<文件名>）存取，后端可以是本地目录或 S3 兼容的对象存储（MinIO 等）。
+// 使用对象存储时多个副本共享同一份文件，下载可以直接发放预签名 URL，不经服务转发。
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotExist 对象不存在
+	ErrNotExist = errors.New("storage: object does not exist")
+	// ErrPresignUnsupported 后端不支持预签名 URL（本地目录），调用方应改为经服务下载
+	ErrPresignUnsupported = errors.New("storage: presigned url not supported")
+)
+
+// ObjectInfo 对象的元信息
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// DownloadOptions 预签名下载 URL 的响应头：浏览器据此决定文件名以及内联预览还是下载
+type DownloadOptions struct {
+	FileName    string
+	ContentType string
+	Inline      bool
+}
+
+// Storage 文件存储后端
+type Storage interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Open 读取对象，不存在时返回 ErrNotExist
+	Open(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	// Stat 查询对象元信息，不存在时返回 ErrNotExist
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete 删除对象，对象不存在不视为错误
+	Delete(ctx context.Context, key string) error
+	// List 列出 key 以 prefix 开头的全部对象
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// PresignGet 生成有效期为 ttl 的下载 URL，不支持时返回 ErrPresignUnsupported
+	PresignGet(ctx context.Context, key string, ttl time.Duration, opts DownloadOptions) (string, error)
+}
+
+// 存储后端类型
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Config 存储配置
+type Config struct {
+	Backend  string
+	LocalDir string // 本地目录后端的根目录，也是 storage migrate 的源目录
+	S3       S3Config
+}
+
+// New 按配置创建存储后端
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocal(cfg.LocalDir)
+	case BackendS3:
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("未知的存储后端: %s", cfg.Backend)
+	}
+}
+
+// Key 拼接对象 key。name 只取最后一段，防止通过文件名访问其他目录
+func Key(dir, name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return dir + "/" + name
+}
+
+// ContentTypeFor 按扩展名返回下载时使用的 Content-Type
+func ContentTypeFor(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".pdf":
+		return "application/pdf"
+	case ".doc", ".docx":
+		return "application/msword"
+	case ".xls", ".xlsx":
+		return "application/vnd.ms-excel"
+	case ".ppt", ".pptx":
+		return "application/vnd.ms-powerpoint"
+	case ".txt":
+		return "text/plain"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".zip":
+		return "application/zip"
+	case ".rar":
+		return "application/x-rar-compressed"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// ContentDisposition 生成 Content-Disposition，非 ASCII 文件名同时给出 RFC 5987 编码
+func ContentDisposition(fileName string, inline bool) string {
+	kind := "attachment"
+	if inline {
+		kind = "inline"
+	}
+	if fileName == "" {
+		return kind
+	}
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, fileName)
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, kind, fallback, uriEncode(fileName, true))
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs 管理员查询审计日志（所有服务写入同一张表）。
+// 支持 actor_id、action、resource_type、resource_id、service、request_id 精确过滤，
+// from / to 按 RFC3339 或 YYYY-MM-DD 过滤时间范围
+func (h *UserHandler) GetAuditLogs(c *gin.Context) {
+	validator := utils.NewValidator()
+	page, pageSize, err := validator.ValidatePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("page_size", "20"))
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	query := h.db.Model(&models.AuditLog{})
+	for _, field := range []string{"actor_id", "action", "resource_type", "resource_id", "service", "request_id"} {
+		if value := c.Query(field); value != "" {
+			query = query.Where(field+" = ?", value)
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseAuditTime(from)
+		if err != nil {
+			utils.SendBadRequest(c, "from 时间格式错误")
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseAuditTime(to)
+		if err != nil {
+			utils.SendBadRequest(c, "to 时间格式错误")
+			return
+		}
+		// 仅给出日期时包含当天
+		if len(to) == len("2006-01-02") {
+			t = t.Add(24 * time.Hour)
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&logs).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendPaginatedResponse(c, logs, total, page, pageSize)
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
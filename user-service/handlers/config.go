@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"os"
 
@@ -99,8 +100,9 @@ func loadOptions() (*OptionsResponse, error) {
 	return result, nil
 }
 
-// GetOptions 读取配置文件并返回前端所需的下拉选项
-func GetOptions(c *gin.Context) {
+// GetOptions 返回前端所需的下拉选项。学部 / 专业 / 班级取自部门表，
+// 部门表为空或查询失败时回退到配置文件；年级、用户状态、教师职称仍来自配置文件
+func (h *UserHandler) GetOptions(c *gin.Context) {
 	options, err := loadOptions()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -110,6 +112,14 @@ func GetOptions(c *gin.Context) {
 		})
 		return
 	}
+
+	colleges, majors, classes, err := departmentOptions(h.db)
+	if err != nil {
+		log.Printf("从部门表生成下拉选项失败，使用配置文件: %v", err)
+	} else if len(colleges) > 0 {
+		options.Colleges = colleges
+		options.Majors = majors
+		options.Classes = classes
+	}
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "ok", "data": options})
 }
-
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// VerifyCredentials 内部接口：校验登录凭据。users 表只由用户服务读写，认证服务通过此接口登录，
+// 是否允许登录（账号状态）由认证服务判断
+func (h *UserHandler) VerifyCredentials(c *gin.Context) {
+	var req models.CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	query := h.db
+	switch {
+	case req.Username != "":
+		query = query.Where("username = ?", req.Username)
+	case req.StudentID != "":
+		query = query.Where("student_id = ?", req.StudentID)
+	case req.TeacherID != "":
+		query = query.Where("teacher_id = ?", req.TeacherID)
+	default:
+		utils.SendBadRequest(c, "必须提供用户名、学号或工号")
+		return
+	}
+
+	var user models.User
+	if err := query.First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendSuccessResponse(c, models.CredentialsResponse{Valid: false})
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+
+	valid := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) == nil
+	utils.SendSuccessResponse(c, models.CredentialsResponse{Valid: valid, User: models.NewUserIdentity(user)})
+}
+
+// GetUserIdentity 内部接口：获取认证服务校验和刷新令牌所需的用户信息
+func (h *UserHandler) GetUserIdentity(c *gin.Context) {
+	var user models.User
+	if err := h.db.Where("uuid = ?", c.Param("id")).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "用户不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+	utils.SendSuccessResponse(c, models.NewUserIdentity(user))
+}
+
+// RecordLogin 内部接口：记录最后登录时间。只更新 last_login_at，不刷新 updated_at，避免触发其他服务的快照同步
+func (h *UserHandler) RecordLogin(c *gin.Context) {
+	result := h.db.Model(&models.User{}).Where("uuid = ?", c.Param("id")).UpdateColumn("last_login_at", time.Now())
+	if result.Error != nil {
+		utils.SendInternalServerError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.SendNotFound(c, "用户不存在")
+		return
+	}
+	utils.SendSuccessResponse(c, gin.H{"message": "已记录登录时间"})
+}
+
+// InitializeAdminUser 不存在 admin 用户时创建默认管理员。密码取自 ADMIN_DEFAULT_PASSWORD，未设置时随机生成并打印到日志
+func InitializeAdminUser(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.User{}).Where("username = ?", "admin").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Println("管理员用户已存在")
+		return nil
+	}
+
+	password := os.Getenv("ADMIN_DEFAULT_PASSWORD")
+	if password == "" {
+		randomBytes := make([]byte, 16)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return err
+		}
+		password = base64.URLEncoding.EncodeToString(randomBytes)
+		log.Printf("⚠️  IMPORTANT: Generated random admin password: %s", password)
+		log.Printf("⚠️  Please save this password and change it after first login!")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	admin := models.User{
+		Username: "admin",
+		Password: string(hashedPassword),
+		Email:    "admin@example.com",
+		RealName: "Administrator",
+		UserType: "admin",
+		Status:   "active",
+	}
+	if err := db.Create(&admin).Error; err != nil {
+		return err
+	}
+	log.Println("管理员用户创建成功")
+	return nil
+}
+
+// InitializeDemoUsers 创建开发环境用的演示账号（教师 teacher、学生 student）。
+// 只在 SEED_DEMO_USERS=true 时执行，密码必须由 DEMO_USER_PASSWORD 提供，不存在公开的默认密码
+func InitializeDemoUsers(db *gorm.DB) error {
+	if os.Getenv("SEED_DEMO_USERS") != "true" {
+		return nil
+	}
+	password := os.Getenv("DEMO_USER_PASSWORD")
+	if password == "" {
+		return errors.New("SEED_DEMO_USERS=true 时必须设置 DEMO_USER_PASSWORD")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	teacherID, title := "T0000001", "副教授"
+	studentID, grade := "20240000", "2024"
+	demoUsers := []struct {
+		user     models.User
+		deptName string
+		deptType string
+	}{
+		{models.User{TeacherID: &teacherID, Username: "teacher", Email: "teacher@example.com", RealName: "Default Teacher", UserType: "teacher", Title: &title}, "软件工程", "major"},
+		{models.User{StudentID: &studentID, Username: "student", Email: "student@example.com", RealName: "Default Student", UserType: "student", Grade: &grade}, "2024222", "class"},
+	}
+	for _, demo := range demoUsers {
+		var count int64
+		if err := db.Model(&models.User{}).Where("username = ?", demo.user.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		user := demo.user
+		user.Password = string(hashedPassword)
+		user.Status = "active"
+		var dept models.Department
+		if err := db.Where("name = ? AND dept_type = ?", demo.deptName, demo.deptType).First(&dept).Error; err == nil {
+			user.DepartmentID = &dept.ID
+		}
+		if err := db.Create(&user).Error; err != nil {
+			return err
+		}
+		log.Printf("演示账号 %s 创建成功", user.Username)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const ctxDataScope = "data_scope"
+
+// subtreeSQL 展开部门节点的整棵子树（含节点本身）
+const subtreeSQL = `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM departments WHERE id IN ? AND deleted_at IS NULL
+		UNION
+		SELECT d.id FROM departments d JOIN subtree s ON d.parent_id = s.id WHERE d.deleted_at IS NULL
+	)
+	SELECT id FROM subtree`
+
+// dataScope 当前用户可见的数据范围。
+// 教师：绑定的部门节点加上本人所在部门；管理员：有绑定时为部门管理员，否则不受限；
+// 学生和内部服务调用不在此处限制（学生的可见范围由各接口自行处理）。
+type dataScope struct {
+	userID        string
+	restricted    bool
+	departmentIDs []string
+}
+
+func resolveDataScope(db *gorm.DB, userID, userType string) (*dataScope, error) {
+	scope := &dataScope{userID: userID}
+	if !isUUID(userID) || (userType != "teacher" && userType != "admin") {
+		return scope, nil
+	}
+
+	var roots []string
+	if err := db.Model(&models.DepartmentScope{}).Where("user_id = ?", userID).Pluck("department_id", &roots).Error; err != nil {
+		return nil, err
+	}
+	if userType == "admin" && len(roots) == 0 {
+		return scope, nil
+	}
+	if userType == "teacher" {
+		var own []string
+		if err := db.Model(&models.User{}).Where("uuid = ? AND department_id IS NOT NULL", userID).Pluck("department_id", &own).Error; err != nil {
+			return nil, err
+		}
+		roots = append(roots, own...)
+	}
+
+	scope.restricted = true
+	if len(roots) == 0 {
+		return scope, nil
+	}
+	if err := db.Raw(subtreeSQL, roots).Scan(&scope.departmentIDs).Error; err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+// currentDataScope 解析并缓存当前请求用户的数据范围
+func (h *UserHandler) currentDataScope(c *gin.Context) (*dataScope, error) {
+	if cached, ok := c.Get(ctxDataScope); ok {
+		return cached.(*dataScope), nil
+	}
+	scope, err := resolveDataScope(h.db, utils.GetCurrentUserID(c), utils.GetCurrentUserRole(c))
+	if err != nil {
+		return nil, err
+	}
+	c.Set(ctxDataScope, scope)
+	return scope, nil
+}
+
+// users 将查询限制在范围内的用户，column 为用户 UUID 所在列；本人始终可见
+func (s *dataScope) users(query *gorm.DB, column string) *gorm.DB {
+	if !s.restricted {
+		return query
+	}
+	return query.Where("("+column+" = ? OR "+column+" IN (SELECT uuid FROM users WHERE department_id IN ?))", s.userID, s.departmentIDs)
+}
+
+// allowsUser 判断部门为 departmentID 的用户是否在范围内；本人始终可见
+func (s *dataScope) allowsUser(userID string, departmentID *string) bool {
+	if !s.restricted || userID == s.userID {
+		return true
+	}
+	if departmentID == nil {
+		return false
+	}
+	for _, id := range s.departmentIDs {
+		if id == *departmentID {
+			return true
+		}
+	}
+	return false
+}
+
+// requireUserInScope 按 ID 读取单个用户的数据前检查数据范围。
+// 不在范围内时与用户不存在一样返回 404，不暴露范围外的用户是否存在
+func (h *UserHandler) requireUserInScope(c *gin.Context, userID string) bool {
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return false
+	}
+	if !scope.restricted || userID == scope.userID {
+		return true
+	}
+
+	var user models.User
+	if err := h.db.Select("uuid", "department_id").Where("uuid = ?", userID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "用户不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return false
+	}
+	if !scope.allowsUser(user.UUID, user.DepartmentID) {
+		utils.SendNotFound(c, "用户不存在")
+		return false
+	}
+	return true
+}
+
+// GetMyDataScope 获取当前用户的数据范围
+func (h *UserHandler) GetMyDataScope(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	h.sendDataScope(c, userID, utils.GetCurrentUserRole(c))
+}
+
+// GetDepartmentScopes 管理员查看指定用户绑定的部门节点
+func (h *UserHandler) GetDepartmentScopes(c *gin.Context) {
+	var user models.User
+	if err := h.db.Where("uuid = ?", c.Param("id")).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "用户不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+	h.sendDataScope(c, user.UUID, user.UserType)
+}
+
+// UpdateDepartmentScopes 设置教师 / 管理员管理的部门节点（整体替换）。
+// 只有不受部门范围限制的管理员可以修改，避免部门管理员为自己扩大范围
+func (h *UserHandler) UpdateDepartmentScopes(c *gin.Context) {
+	current, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if current.restricted {
+		utils.SendForbidden(c, "部门管理员不能修改数据范围")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("uuid = ?", c.Param("id")).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "用户不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return
+	}
+	if user.UserType != "teacher" && user.UserType != "admin" {
+		utils.SendBadRequest(c, "只能为教师或管理员设置数据范围")
+		return
+	}
+
+	var req models.DepartmentScopesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	ids := uniqueStrings(req.DepartmentIDs)
+	if len(ids) > 0 {
+		var count int64
+		if err := h.db.Table("departments").Where("id IN ? AND deleted_at IS NULL", ids).Count(&count).Error; err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+		if int(count) != len(ids) {
+			utils.SendBadRequest(c, "部分部门不存在")
+			return
+		}
+	}
+
+	operatorID := utils.GetCurrentUserID(c)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.UUID).Delete(&models.DepartmentScope{}).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			binding := models.DepartmentScope{UserID: user.UUID, DepartmentID: id}
+			if isUUID(operatorID) {
+				binding.CreatedBy = &operatorID
+			}
+			if err := tx.Create(&binding).Error; err != nil {
+				return err
+			}
+		}
+		// 其他服务通过用户增量同步获取数据范围，更新修改时间使其被同步
+		if err := tx.Model(&models.User{}).Where("uuid = ?", user.UUID).Update("updated_at", gorm.Expr("NOW()")).Error; err != nil {
+			return err
+		}
+		notifyUsersChanged(tx, user.UUID)
+		return nil
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	h.sendDataScope(c, user.UUID, user.UserType)
+}
+
+func (h *UserHandler) sendDataScope(c *gin.Context, userID, userType string) {
+	scope, err := resolveDataScope(h.db, userID, userType)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	bindings := []models.DepartmentRef{}
+	if err := h.db.Table("department_scopes ds").
+		Select("d.id, d.name, d.dept_type").
+		Joins("JOIN departments d ON d.id = ds.department_id").
+		Where("ds.user_id = ?", userID).
+		Order("d.level, d.name").
+		Scan(&bindings).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, models.DepartmentScopeResponse{
+		UserID:       userID,
+		Restricted:   scope.restricted,
+		Bindings:     bindings,
+		SubtreeCount: len(scope.departmentIDs),
+	})
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
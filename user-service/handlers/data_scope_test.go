@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataScopeAllowsUser(t *testing.T) {
+	college := "dept-college"
+	major := "dept-major"
+	other := "dept-other"
+	scope := &dataScope{userID: "teacher-1", restricted: true, departmentIDs: []string{college, major}}
+
+	assert.True(t, scope.allowsUser("student-1", &major), "子树内的用户可见")
+	assert.True(t, scope.allowsUser("teacher-1", nil), "本人始终可见")
+	assert.False(t, scope.allowsUser("student-2", &other), "范围外的用户不可见")
+	assert.False(t, scope.allowsUser("student-3", nil), "未分配部门的用户不在任何范围内")
+
+	empty := &dataScope{userID: "admin-1", restricted: true}
+	assert.False(t, empty.allowsUser("student-1", &major), "受限但未绑定部门时只能看到自己")
+
+	unrestricted := &dataScope{userID: "admin-2"}
+	assert.True(t, unrestricted.allowsUser("student-2", &other))
+}
@@ -0,0 +1,769 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"credit-management/shared/audit"
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// allowedParentTypes 各类型部门允许挂靠的父节点类型；为空表示只能作为根节点。
+// 学生视图按 班级 -> 专业 -> 学部 逐级关联，因此这三级的父子关系必须严格
+var allowedParentTypes = map[string][]string{
+	models.DeptTypeSchool:  {},
+	models.DeptTypeFaculty: {models.DeptTypeSchool},
+	models.DeptTypeCollege: {models.DeptTypeSchool, models.DeptTypeFaculty},
+	models.DeptTypeMajor:   {models.DeptTypeCollege},
+	models.DeptTypeClass:   {models.DeptTypeMajor},
+	models.DeptTypeOffice:  {models.DeptTypeSchool, models.DeptTypeFaculty, models.DeptTypeCollege},
+	models.DeptTypeOthers:  {models.DeptTypeSchool, models.DeptTypeFaculty, models.DeptTypeCollege, models.DeptTypeMajor, models.DeptTypeOffice, models.DeptTypeOthers},
+}
+
+// levelSQL 从指定节点开始重新计算整棵子树的 level。
+// path 记录已经过的节点，即使数据中出现环也不会无限递归
+const levelSQL = `
+	WITH RECURSIVE tree AS (
+		SELECT id, ?::int AS level, ARRAY[id] AS path FROM departments WHERE id = ?
+		UNION ALL
+		SELECT d.id, t.level + 1, t.path || d.id FROM departments d JOIN tree t ON d.parent_id = t.id
+		WHERE d.deleted_at IS NULL AND NOT d.id = ANY(t.path)
+	)
+	UPDATE departments SET level = tree.level, updated_at = NOW()
+	FROM tree WHERE departments.id = tree.id AND departments.level <> tree.level`
+
+// allLevelsSQL 从所有根节点开始重新计算整棵部门树的 level，与 levelSQL 一样用 path 防止环导致无限递归
+const allLevelsSQL = `
+	WITH RECURSIVE tree AS (
+		SELECT id, 0 AS level, ARRAY[id] AS path FROM departments WHERE parent_id IS NULL AND deleted_at IS NULL
+		UNION ALL
+		SELECT d.id, t.level + 1, t.path || d.id FROM departments d JOIN tree t ON d.parent_id = t.id
+		WHERE d.deleted_at IS NULL AND NOT d.id = ANY(t.path)
+	)
+	UPDATE departments SET level = tree.level, updated_at = NOW()
+	FROM tree WHERE departments.id = tree.id AND departments.level <> tree.level`
+
+// ListDepartments 部门列表，支持 dept_type、parent_id、query（名称 / 代码模糊匹配）过滤
+func (h *UserHandler) ListDepartments(c *gin.Context) {
+	query := h.db.Model(&models.Department{})
+	if deptType := c.Query("dept_type"); deptType != "" {
+		query = query.Where("dept_type = ?", deptType)
+	}
+	if parentID := c.Query("parent_id"); parentID != "" {
+		if !isUUID(parentID) {
+			utils.SendBadRequest(c, "parent_id 格式错误")
+			return
+		}
+		query = query.Where("parent_id = ?", parentID)
+	}
+	if keyword := strings.TrimSpace(c.Query("query")); keyword != "" {
+		query = query.Where("(name ILIKE ? OR code ILIKE ?)", "%"+keyword+"%", "%"+keyword+"%")
+	}
+
+	var departments []models.Department
+	if err := query.Order("level, name").Find(&departments).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	counts, err := h.departmentUserCounts()
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	responses := make([]*models.DepartmentResponse, 0, len(departments))
+	for _, dept := range departments {
+		responses = append(responses, toDepartmentResponse(dept, counts))
+	}
+	utils.SendSuccessResponse(c, responses)
+}
+
+// GetDepartmentTree 部门树；root_id 指定时只返回该节点的子树，user_count 为各节点直属用户数
+func (h *UserHandler) GetDepartmentTree(c *gin.Context) {
+	rootID := c.Query("root_id")
+	if rootID != "" && !isUUID(rootID) {
+		utils.SendBadRequest(c, "root_id 格式错误")
+		return
+	}
+
+	var departments []models.Department
+	if err := h.db.Order("level, name").Find(&departments).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	counts, err := h.departmentUserCounts()
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	nodes := make(map[string]*models.DepartmentResponse, len(departments))
+	for _, dept := range departments {
+		nodes[dept.ID] = toDepartmentResponse(dept, counts)
+	}
+	roots := []*models.DepartmentResponse{}
+	for _, dept := range departments {
+		node := nodes[dept.ID]
+		if dept.ParentID != nil {
+			if parent, ok := nodes[*dept.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	if rootID != "" {
+		node, ok := nodes[rootID]
+		if !ok {
+			utils.SendNotFound(c, "部门不存在")
+			return
+		}
+		roots = []*models.DepartmentResponse{node}
+	}
+	utils.SendSuccessResponse(c, roots)
+}
+
+// GetDepartment 获取部门详情（含直属子部门）
+func (h *UserHandler) GetDepartment(c *gin.Context) {
+	dept, ok := h.loadDepartment(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	counts, err := h.departmentUserCounts()
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var children []models.Department
+	if err := h.db.Where("parent_id = ?", dept.ID).Order("name").Find(&children).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	response := toDepartmentResponse(*dept, counts)
+	for _, child := range children {
+		response.Children = append(response.Children, toDepartmentResponse(child, counts))
+	}
+	utils.SendSuccessResponse(c, response)
+}
+
+// CreateDepartment 创建部门，level 由父节点推导
+func (h *UserHandler) CreateDepartment(c *gin.Context) {
+	var req models.DepartmentCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	dept := models.Department{Name: req.Name, Code: normalizeCode(req.Code), DeptType: req.DeptType}
+	var parent *models.Department
+	if req.ParentID != nil && *req.ParentID != "" {
+		var ok bool
+		if parent, ok = h.loadDepartment(c, *req.ParentID); !ok {
+			return
+		}
+		dept.ParentID = &parent.ID
+		dept.Level = parent.Level + 1
+	}
+	if err := checkParentType(dept.DeptType, parent); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	// 根节点只能由不受部门范围限制的管理员创建
+	managed := []string{}
+	if parent != nil {
+		managed = append(managed, parent.ID)
+	}
+	if !h.requireDepartmentScope(c, managed...) {
+		return
+	}
+	if !h.checkDepartmentUnique(c, "", dept.Name, dept.ParentID, dept.Code) {
+		return
+	}
+
+	if err := h.db.Create(&dept).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	// 新节点会扩大上级节点管理者的数据范围，其他服务同步的范围需要重新计算
+	notifyAllUsersChanged(h.db)
+
+	response := toDepartmentResponse(dept, nil)
+	audit.Record(c, audit.Entry{
+		Action:       "departments.create",
+		ResourceType: "departments",
+		ResourceID:   dept.ID,
+		After:        response,
+	})
+	utils.SendCreatedResponse(c, "部门创建成功", response)
+}
+
+// UpdateDepartment 修改部门名称或代码
+func (h *UserHandler) UpdateDepartment(c *gin.Context) {
+	dept, ok := h.loadDepartment(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireDepartmentScope(c, dept.ID) {
+		return
+	}
+
+	var req models.DepartmentUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	before := toDepartmentResponse(*dept, nil)
+	updates := map[string]interface{}{}
+	name := dept.Name
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+		updates["name"] = name
+	}
+	code := dept.Code
+	if req.Code != nil {
+		code = normalizeCode(req.Code)
+		updates["code"] = code
+	}
+	if len(updates) == 0 {
+		utils.SendBadRequest(c, "没有需要更新的字段")
+		return
+	}
+	if !h.checkDepartmentUnique(c, dept.ID, name, dept.ParentID, code) {
+		return
+	}
+
+	if err := h.db.Model(dept).Updates(updates).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if _, renamed := updates["name"]; renamed {
+		notifyAllUsersChanged(h.db)
+	}
+
+	response := toDepartmentResponse(*dept, nil)
+	audit.Record(c, audit.Entry{
+		Action:       "departments.update",
+		ResourceType: "departments",
+		ResourceID:   dept.ID,
+		Before:       before,
+		After:        response,
+	})
+	utils.SendSuccessResponse(c, response)
+}
+
+// MoveDepartment 将部门（连同子树）移动到新的父节点下，禁止移动到自身子树中，移动后重算子树 level
+func (h *UserHandler) MoveDepartment(c *gin.Context) {
+	dept, ok := h.loadDepartment(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req models.DepartmentMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	parent, ok := h.loadDepartment(c, req.ParentID)
+	if !ok {
+		return
+	}
+	if !h.requireDepartmentScope(c, dept.ID, parent.ID) {
+		return
+	}
+	if err := checkParentType(dept.DeptType, parent); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	cyclic, err := inDepartmentSubtree(h.db, dept.ID, parent.ID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if cyclic {
+		utils.SendBadRequest(c, "不能将部门移动到其自身或下级部门下")
+		return
+	}
+	if !h.checkDepartmentUnique(c, dept.ID, dept.Name, &parent.ID, dept.Code) {
+		return
+	}
+
+	before := toDepartmentResponse(*dept, nil)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// 加锁后重新检查：并发的两次移动都通过了上面的检查时，后执行的一次在这里发现环
+		if err := lockDepartmentTree(tx, dept.ID, parent.ID); err != nil {
+			return err
+		}
+		// 父部门的层级可能已被并发的移动修改，按锁内读到的值计算
+		if err := tx.First(parent, "id = ?", parent.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(dept).Update("parent_id", parent.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(levelSQL, parent.Level+1, dept.ID).Error
+	})
+	if errors.Is(err, errDepartmentCycle) {
+		utils.SendBadRequest(c, "不能将部门移动到其自身或下级部门下")
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendNotFound(c, "上级部门不存在")
+		return
+	}
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	notifyAllUsersChanged(h.db)
+
+	dept.Level = parent.Level + 1
+	response := toDepartmentResponse(*dept, nil)
+	audit.Record(c, audit.Entry{
+		Action:       "departments.move",
+		ResourceType: "departments",
+		ResourceID:   dept.ID,
+		Before:       before,
+		After:        response,
+	})
+	utils.SendSuccessResponse(c, response)
+}
+
+// MergeDepartment 将部门合并到同类型的目标部门：子部门挂到目标下，用户与数据范围绑定转到目标，原部门删除
+func (h *UserHandler) MergeDepartment(c *gin.Context) {
+	source, ok := h.loadDepartment(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req models.DepartmentMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.TargetID == source.ID {
+		utils.SendBadRequest(c, "不能将部门合并到自身")
+		return
+	}
+	target, ok := h.loadDepartment(c, req.TargetID)
+	if !ok {
+		return
+	}
+	if !h.requireDepartmentScope(c, source.ID, target.ID) {
+		return
+	}
+	if source.DeptType != target.DeptType {
+		utils.SendBadRequest(c, "只能合并同类型的部门")
+		return
+	}
+
+	cyclic, err := inDepartmentSubtree(h.db, source.ID, target.ID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if cyclic {
+		utils.SendBadRequest(c, "不能将部门合并到其下级部门")
+		return
+	}
+
+	// 子部门挂到目标下后不能与目标已有的子部门重名
+	var duplicated int64
+	if err := h.db.Raw(`
+		SELECT COUNT(*) FROM departments s
+		JOIN departments t ON t.name = s.name AND t.parent_id = ? AND t.deleted_at IS NULL
+		WHERE s.parent_id = ? AND s.deleted_at IS NULL`, target.ID, source.ID).Scan(&duplicated).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if duplicated > 0 {
+		utils.SendConflict(c, "两个部门存在同名的下级部门，请先重命名或合并下级部门")
+		return
+	}
+
+	var movedUsers, movedChildren int64
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockDepartmentTree(tx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := tx.First(target, "id = ?", target.ID).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.User{}).Where("department_id = ?", source.ID).Update("department_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		movedUsers = result.RowsAffected
+
+		result = tx.Model(&models.Department{}).Where("parent_id = ?", source.ID).Update("parent_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		movedChildren = result.RowsAffected
+
+		if err := tx.Exec(`
+			INSERT INTO department_scopes (user_id, department_id, created_by, created_at)
+			SELECT user_id, ?, created_by, created_at FROM department_scopes WHERE department_id = ?
+			ON CONFLICT DO NOTHING`, target.ID, source.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("department_id = ?", source.ID).Delete(&models.DepartmentScope{}).Error; err != nil {
+			return err
+		}
+		if err := softDeleteDepartment(tx, source.ID); err != nil {
+			return err
+		}
+		return tx.Exec(levelSQL, target.Level, target.ID).Error
+	})
+	if errors.Is(err, errDepartmentCycle) {
+		utils.SendBadRequest(c, "不能将部门合并到其下级部门")
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendNotFound(c, "目标部门不存在")
+		return
+	}
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	notifyAllUsersChanged(h.db)
+
+	audit.Record(c, audit.Entry{
+		Action:       "departments.merge",
+		ResourceType: "departments",
+		ResourceID:   source.ID,
+		Before:       toDepartmentResponse(*source, nil),
+		After: gin.H{
+			"target_id":      target.ID,
+			"moved_users":    movedUsers,
+			"moved_children": movedChildren,
+		},
+	})
+	utils.SendSuccessResponse(c, gin.H{
+		"source_id":      source.ID,
+		"target_id":      target.ID,
+		"moved_users":    movedUsers,
+		"moved_children": movedChildren,
+	})
+}
+
+// DeleteDepartment 删除部门。有下级部门时拒绝；部门下仍有用户时需通过 reassign_to 指定转移到的部门
+func (h *UserHandler) DeleteDepartment(c *gin.Context) {
+	dept, ok := h.loadDepartment(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireDepartmentScope(c, dept.ID) {
+		return
+	}
+
+	var childCount int64
+	if err := h.db.Model(&models.Department{}).Where("parent_id = ?", dept.ID).Count(&childCount).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if childCount > 0 {
+		utils.SendConflict(c, "部门下仍有下级部门，请先移动或删除")
+		return
+	}
+
+	var userCount int64
+	if err := h.db.Model(&models.User{}).Where("department_id = ?", dept.ID).Count(&userCount).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	var reassignTo *models.Department
+	if userCount > 0 {
+		reassignID := c.Query("reassign_to")
+		if reassignID == "" {
+			utils.SendConflict(c, fmt.Sprintf("部门下仍有 %d 名用户，请通过 reassign_to 指定转移到的部门", userCount))
+			return
+		}
+		if reassignID == dept.ID {
+			utils.SendBadRequest(c, "reassign_to 不能是待删除的部门")
+			return
+		}
+		if reassignTo, ok = h.loadDepartment(c, reassignID); !ok {
+			return
+		}
+		if !h.requireDepartmentScope(c, reassignTo.ID) {
+			return
+		}
+		if reassignTo.DeptType != dept.DeptType {
+			utils.SendBadRequest(c, "用户只能转移到同类型的部门")
+			return
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if reassignTo != nil {
+			if err := tx.Model(&models.User{}).Where("department_id = ?", dept.ID).Update("department_id", reassignTo.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("department_id = ?", dept.ID).Delete(&models.DepartmentScope{}).Error; err != nil {
+			return err
+		}
+		return softDeleteDepartment(tx, dept.ID)
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	notifyAllUsersChanged(h.db)
+
+	audit.Record(c, audit.Entry{
+		Action:       "departments.delete",
+		ResourceType: "departments",
+		ResourceID:   dept.ID,
+		Before:       toDepartmentResponse(*dept, nil),
+	})
+	utils.SendSuccessResponse(c, gin.H{"message": "部门删除成功", "moved_users": userCount})
+}
+
+// loadDepartment 按 ID 读取未删除的部门，失败时直接写响应
+func (h *UserHandler) loadDepartment(c *gin.Context, id string) (*models.Department, bool) {
+	if !isUUID(id) {
+		utils.SendBadRequest(c, "部门ID格式错误")
+		return nil, false
+	}
+	var dept models.Department
+	if err := h.db.Where("id = ?", id).First(&dept).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.SendNotFound(c, "部门不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
+		return nil, false
+	}
+	return &dept, true
+}
+
+// requireDepartmentScope 部门管理员只能调整自己管理范围内的节点；不带节点时要求不受限的管理员
+func (h *UserHandler) requireDepartmentScope(c *gin.Context, ids ...string) bool {
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return false
+	}
+	if !scope.restricted {
+		return true
+	}
+	allowed := make(map[string]bool, len(scope.departmentIDs))
+	for _, id := range scope.departmentIDs {
+		allowed[id] = true
+	}
+	if len(ids) == 0 {
+		utils.SendForbidden(c, "部门管理员不能创建根部门")
+		return false
+	}
+	for _, id := range ids {
+		if !allowed[id] {
+			utils.SendForbidden(c, "部门不在您的管理范围内")
+			return false
+		}
+	}
+	return true
+}
+
+// checkDepartmentUnique 同一父节点下名称唯一，代码全局唯一；excludeID 为正在修改的部门
+func (h *UserHandler) checkDepartmentUnique(c *gin.Context, excludeID, name string, parentID, code *string) bool {
+	query := h.db.Model(&models.Department{}).Where("name = ?", name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if excludeID != "" {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return false
+	}
+	if count > 0 {
+		utils.SendConflict(c, "同一上级部门下已存在同名部门")
+		return false
+	}
+
+	if code == nil {
+		return true
+	}
+	// 代码唯一约束同样作用于已删除的部门，删除时会清空代码
+	query = h.db.Unscoped().Model(&models.Department{}).Where("code = ?", *code)
+	if excludeID != "" {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return false
+	}
+	if count > 0 {
+		utils.SendConflict(c, "部门代码已存在")
+		return false
+	}
+	return true
+}
+
+func (h *UserHandler) departmentUserCounts() (map[string]int64, error) {
+	var rows []struct {
+		DepartmentID string
+		Count        int64
+	}
+	if err := h.db.Model(&models.User{}).
+		Select("department_id, COUNT(*) AS count").
+		Where("department_id IS NOT NULL").
+		Group("department_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.DepartmentID] = row.Count
+	}
+	return counts, nil
+}
+
+// departmentSubtree 返回节点及其所有下级节点的 ID
+func departmentSubtree(db *gorm.DB, id string) ([]string, error) {
+	var ids []string
+	err := db.Raw(subtreeSQL, []string{id}).Scan(&ids).Error
+	return ids, err
+}
+
+// inDepartmentSubtree 判断 id 是否为 root 自身或其下级部门
+func inDepartmentSubtree(db *gorm.DB, root, id string) (bool, error) {
+	subtree, err := departmentSubtree(db, root)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range subtree {
+		if item == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// departmentTreeLock 修改部门层级（移动、合并）时持有的事务级咨询锁
+const departmentTreeLock = 7340201
+
+var errDepartmentCycle = errors.New("department cycle")
+
+// lockDepartmentTree 在事务中获取部门树的咨询锁，同一时间只有一个事务调整层级；
+// 获取锁后重新检查 newParent 不在 root 的子树中，检查不通过时返回 errDepartmentCycle
+func lockDepartmentTree(tx *gorm.DB, root, newParent string) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", departmentTreeLock).Error; err != nil {
+		return err
+	}
+	cyclic, err := inDepartmentSubtree(tx, root, newParent)
+	if err != nil {
+		return err
+	}
+	if cyclic {
+		return errDepartmentCycle
+	}
+	return nil
+}
+
+// softDeleteDepartment 软删除部门并释放其代码，避免占用唯一约束
+func softDeleteDepartment(tx *gorm.DB, id string) error {
+	return tx.Exec(`UPDATE departments SET deleted_at = NOW(), updated_at = NOW(), code = NULL WHERE id = ?`, id).Error
+}
+
+func checkParentType(deptType string, parent *models.Department) error {
+	allowed := allowedParentTypes[deptType]
+	if parent == nil {
+		if len(allowed) == 0 || deptType == models.DeptTypeOthers {
+			return nil
+		}
+		return fmt.Errorf("%s 类型的部门必须指定上级部门", deptType)
+	}
+	for _, t := range allowed {
+		if parent.DeptType == t {
+			return nil
+		}
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("%s 类型的部门只能作为根节点", deptType)
+	}
+	return fmt.Errorf("%s 类型的部门只能挂在 %s 类型的部门下", deptType, strings.Join(allowed, " / "))
+}
+
+func normalizeCode(code *string) *string {
+	if code == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*code)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func toDepartmentResponse(dept models.Department, counts map[string]int64) *models.DepartmentResponse {
+	return &models.DepartmentResponse{
+		ID:         dept.ID,
+		Name:       dept.Name,
+		Code:       dept.Code,
+		DeptType:   dept.DeptType,
+		Level:      dept.Level,
+		ParentID:   dept.ParentID,
+		UserCount:  counts[dept.ID],
+		ArchivedAt: dept.ArchivedAt,
+		CreatedAt:  dept.CreatedAt,
+		UpdatedAt:  dept.UpdatedAt,
+	}
+}
+
+// departmentOptions 由部门表生成学部 / 专业 / 班级下拉选项，结构与 options.json 转换结果一致
+func departmentOptions(db *gorm.DB) (colleges []SelectOption, majors, classes map[string][]SelectOption, err error) {
+	var departments []models.Department
+	if err = db.Where("dept_type IN ? AND archived_at IS NULL", []string{models.DeptTypeCollege, models.DeptTypeMajor, models.DeptTypeClass}).
+		Order("level, name").
+		Find(&departments).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	byID := make(map[string]models.Department, len(departments))
+	for _, dept := range departments {
+		byID[dept.ID] = dept
+	}
+	colleges = []SelectOption{}
+	majors = make(map[string][]SelectOption)
+	classes = make(map[string][]SelectOption)
+	for _, dept := range departments {
+		option := SelectOption{Value: dept.Name, Label: dept.Name}
+		switch dept.DeptType {
+		case models.DeptTypeCollege:
+			colleges = append(colleges, option)
+		case models.DeptTypeMajor, models.DeptTypeClass:
+			if dept.ParentID == nil {
+				continue
+			}
+			parent, ok := byID[*dept.ParentID]
+			if !ok {
+				continue
+			}
+			if dept.DeptType == models.DeptTypeMajor {
+				majors[parent.Name] = append(majors[parent.Name], option)
+			} else {
+				classes[parent.Name] = append(classes[parent.Name], option)
+			}
+		}
+	}
+	return colleges, majors, classes, nil
+}
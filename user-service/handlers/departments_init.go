@@ -136,6 +136,12 @@ func InitDepartments(db *gorm.DB) error {
 		}
 	}
 
+	// 配置初始化时未写入 level，统一按树形结构重算
+	if err := tx.Exec(allLevelsSQL).Error; err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
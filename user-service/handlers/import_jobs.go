@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"credit-management/shared/audit"
+	"credit-management/shared/realtime"
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetImportJobs 查询当前用户提交的导入任务，可按 status 过滤
+func (h *UserHandler) GetImportJobs(c *gin.Context) {
+	validator := utils.NewValidator()
+	page, pageSize, err := validator.ValidatePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("page_size", "20"))
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
+	query := h.db.Model(&models.ImportJob{}).
+		Where("service = ? AND created_by = ?", importService, utils.GetCurrentUserID(c))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	var jobs []models.ImportJob
+	if err := query.Omit("rows").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&jobs).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendPaginatedResponse(c, jobs, total, page, pageSize)
+}
+
+// GetImportJob 查询导入任务的状态与进度
+func (h *UserHandler) GetImportJob(c *gin.Context) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+	utils.SendSuccessResponse(c, job)
+}
+
+// GetImportJobErrors 下载导入任务的错误报告。默认返回 CSV（行号、错误信息和原始数据，可修改后重新导入），
+// format=json 时返回 JSON 列表
+func (h *UserHandler) GetImportJobErrors(c *gin.Context) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+
+	var rows []models.ImportJobError
+	if err := h.db.Where("job_id = ?", job.ID).Order("row_number").Find(&rows).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	if c.Query("format") == "json" {
+		utils.SendSuccessResponse(c, rows)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=import_errors_%s.csv", job.ID))
+
+	// 写入 UTF-8 BOM，避免在 Excel 中出现中文乱码
+	if _, err := c.Writer.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	writer := csv.NewWriter(c.Writer)
+	defer writer.Flush()
+
+	if err := writer.Write(append([]string{"行号", "错误信息"}, job.Header...)); err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	for _, row := range rows {
+		if err := writer.Write(append([]string{strconv.Itoa(row.RowNumber), row.Message}, row.Record...)); err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+	}
+}
+
+// CancelImportJob 取消等待中或执行中的导入任务，已提交的批次不会回滚
+func (h *UserHandler) CancelImportJob(c *gin.Context) {
+	h.transitionImportJob(c, "users.import.cancel", models.ImportJobCancelled,
+		[]string{models.ImportJobPending, models.ImportJobRunning}, "只能取消等待中或执行中的任务")
+}
+
+// ResumeImportJob 让失败或已取消的导入任务从游标处继续执行
+func (h *UserHandler) ResumeImportJob(c *gin.Context) {
+	h.transitionImportJob(c, "users.import.resume", models.ImportJobPending,
+		[]string{models.ImportJobFailed, models.ImportJobCancelled}, "只能继续失败或已取消的任务")
+}
+
+func (h *UserHandler) transitionImportJob(c *gin.Context, action, status string, from []string, conflict string) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{"status": status}
+	if status == models.ImportJobPending {
+		updates["last_error"] = ""
+		updates["finished_at"] = nil
+	} else {
+		updates["finished_at"] = gorm.Expr("NOW()")
+	}
+	result := h.db.Model(&models.ImportJob{}).
+		Where("id = ? AND status IN ?", job.ID, from).
+		Updates(updates)
+	if result.Error != nil {
+		utils.SendInternalServerError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.SendConflict(c, conflict)
+		return
+	}
+
+	audit.Record(c, audit.Entry{
+		Action:       action,
+		ResourceType: "import_job",
+		ResourceID:   job.ID,
+		Before:       gin.H{"status": job.Status},
+		After:        gin.H{"status": status},
+	})
+
+	job, ok = h.loadImportJob(c)
+	if !ok {
+		return
+	}
+	realtime.NotifyImportProgress(*job)
+	utils.SendSuccessResponse(c, job)
+}
+
+// loadImportJob 读取路径参数中的导入任务，只能访问自己提交的任务
+func (h *UserHandler) loadImportJob(c *gin.Context) (*models.ImportJob, bool) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		utils.SendBadRequest(c, "任务ID格式错误")
+		return nil, false
+	}
+	var job models.ImportJob
+	err := h.db.Omit("rows").
+		Where("id = ? AND service = ? AND created_by = ?", id, importService, utils.GetCurrentUserID(c)).
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendNotFound(c, "导入任务不存在")
+		return nil, false
+	}
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return nil, false
+	}
+	return &job, true
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"strings"
+
+	"credit-management/shared/netguard"
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetNotificationChannels 获取当前用户的外部通知渠道设置
+func (h *UserHandler) GetNotificationChannels(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	responses, err := h.loadNotificationChannels(userID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, responses)
+}
+
+// UpdateNotificationChannels 整体替换当前用户的外部通知渠道设置
+func (h *UserHandler) UpdateNotificationChannels(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	if userID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+
+	var req models.NotificationChannelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, input := range req.Channels {
+		if seen[input.Channel] {
+			utils.SendBadRequest(c, "通知渠道重复: "+input.Channel)
+			return
+		}
+		seen[input.Channel] = true
+
+		target := strings.TrimSpace(input.Target)
+		switch input.Channel {
+		case models.ChannelWebhook, models.ChannelWeCom, models.ChannelDingTalk:
+			if !input.Enabled && target == "" {
+				continue
+			}
+			// 地址会由服务端请求，不允许指向内网；发送时还会按实际连接的 IP 再检查一次
+			if err := netguard.CheckURL(c.Request.Context(), target); err != nil {
+				utils.SendBadRequest(c, input.Channel+" 渠道需要提供有效的公网 webhook 地址: "+err.Error())
+				return
+			}
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.NotificationChannel
+		if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+			return err
+		}
+		secrets := make(map[string]string, len(existing))
+		for _, ch := range existing {
+			secrets[ch.Channel] = ch.Secret
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NotificationChannel{}).Error; err != nil {
+			return err
+		}
+
+		for _, input := range req.Channels {
+			secret := secrets[input.Channel]
+			if input.Secret != nil {
+				secret = strings.TrimSpace(*input.Secret)
+			}
+			channel := models.NotificationChannel{
+				UserID:  userID,
+				Channel: input.Channel,
+				Enabled: input.Enabled,
+				Target:  strings.TrimSpace(input.Target),
+				Secret:  secret,
+				Digest:  input.Digest,
+			}
+			if err := tx.Create(&channel).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	responses, err := h.loadNotificationChannels(userID)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, responses)
+}
+
+func (h *UserHandler) loadNotificationChannels(userID string) ([]models.NotificationChannelResponse, error) {
+	var channels []models.NotificationChannel
+	if err := h.db.Where("user_id = ?", userID).Order("channel").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.NotificationChannelResponse, 0, len(channels))
+	for _, ch := range channels {
+		responses = append(responses, models.NotificationChannelResponse{
+			Channel:   ch.Channel,
+			Enabled:   ch.Enabled,
+			Target:    ch.Target,
+			HasSecret: ch.Secret != "",
+			Digest:    ch.Digest,
+		})
+	}
+	return responses, nil
+}
+
+// GetDeliverableChannels 内部接口：按用户ID或用户类型查询已启用的外部通知渠道
+// GET /api/internal/notification-channels?user_ids=a,b&user_types=teacher,admin
+func (h *UserHandler) GetDeliverableChannels(c *gin.Context) {
+	userIDs := splitQueryList(c.Query("user_ids"))
+	userTypes := splitQueryList(c.Query("user_types"))
+	if len(userIDs) == 0 && len(userTypes) == 0 {
+		utils.SendBadRequest(c, "需要提供 user_ids 或 user_types")
+		return
+	}
+
+	var rows []struct {
+		models.NotificationChannel
+		Email    string
+		RealName string
+		UserType string
+	}
+	query := h.db.Table("user_notification_channels AS c").
+		Select("c.*, u.email, u.real_name, u.user_type").
+		Joins("JOIN users u ON u.uuid = c.user_id AND u.deleted_at IS NULL").
+		Where("c.enabled = ? AND u.status = ?", true, "active")
+	if len(userIDs) > 0 {
+		query = query.Where("c.user_id IN ?", userIDs)
+	}
+	if len(userTypes) > 0 {
+		query = query.Where("u.user_type IN ?", userTypes)
+	}
+	if err := query.Order("c.user_id").Scan(&rows).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	result := make([]models.UserChannels, 0)
+	index := make(map[string]int)
+	for _, row := range rows {
+		target := row.Target
+		if row.Channel == models.ChannelEmail && target == "" {
+			target = row.Email
+		}
+		if target == "" {
+			continue
+		}
+
+		i, ok := index[row.UserID]
+		if !ok {
+			result = append(result, models.UserChannels{
+				UserID:   row.UserID,
+				RealName: row.RealName,
+				UserType: row.UserType,
+			})
+			i = len(result) - 1
+			index[row.UserID] = i
+		}
+		result[i].Channels = append(result[i].Channels, models.ChannelTarget{
+			Channel: row.Channel,
+			Target:  target,
+			Secret:  row.Secret,
+			Digest:  row.Digest,
+		})
+	}
+
+	utils.SendSuccessResponse(c, result)
+}
+
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
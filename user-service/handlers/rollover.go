@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"credit-management/shared/audit"
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// archivableClassesSQL 可归档的班级：成员全部是该年级的学生；没有成员的班级按名称前缀判断年级
+const archivableClassesSQL = `
+	SELECT d.id, d.name, m.id AS major_id, m.name AS major, COUNT(u.uuid) AS students
+	FROM departments d
+	JOIN departments m ON m.id = d.parent_id
+	LEFT JOIN users u ON u.department_id = d.id AND u.deleted_at IS NULL
+	WHERE d.dept_type = 'class' AND d.archived_at IS NULL AND d.deleted_at IS NULL
+	GROUP BY d.id, d.name, m.id, m.name
+	HAVING COUNT(u.uuid) FILTER (WHERE u.user_type <> 'student' OR u.grade IS DISTINCT FROM @grade) = 0
+	   AND (COUNT(u.uuid) > 0 OR d.name LIKE @prefix)
+	ORDER BY m.name, d.name`
+
+// RolloverAcademicYear 学年结转：指定年级学生标记为 graduated（学分记录不受影响，仍可登录查看），
+// 可选归档该年级班级、生成新一届班级。先以 dry_run 预览，再带 confirm_token 正式执行
+func (h *UserHandler) RolloverAcademicYear(c *gin.Context) {
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if scope.restricted {
+		utils.SendForbidden(c, "学年结转只能由全校管理员执行")
+		return
+	}
+
+	var req models.RolloverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if plan := req.NewClasses; plan != nil {
+		if plan.FromGrade == "" && plan.Pattern == "" {
+			utils.SendBadRequest(c, "new_classes 需要指定 from_grade 或 pattern")
+			return
+		}
+		if plan.Pattern != "" && (!strings.Contains(plan.Pattern, "{n}") || plan.Count == 0 || len(plan.MajorIDs) == 0) {
+			utils.SendBadRequest(c, "pattern 必须包含 {n}，并指定 count 和 major_ids")
+			return
+		}
+	}
+
+	report, newClasses, err := buildRolloverReport(h.db, req)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	if req.DryRun {
+		utils.SendSuccessResponse(c, report)
+		return
+	}
+	if req.ConfirmToken == "" || req.ConfirmToken != report.ConfirmToken {
+		utils.SendConflict(c, "结转计划与预览不一致或未预览，请先以 dry_run 预览并确认")
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("user_type = ? AND grade = ? AND status <> ?", "student", req.GraduateGrade, "graduated").
+			Update("status", "graduated")
+		if result.Error != nil {
+			return result.Error
+		}
+		report.GraduatingStudents = result.RowsAffected
+
+		if len(report.ArchivedClasses) > 0 {
+			ids := make([]string, 0, len(report.ArchivedClasses))
+			for _, class := range report.ArchivedClasses {
+				ids = append(ids, class.ID)
+			}
+			if err := tx.Exec(`UPDATE departments SET archived_at = NOW(), updated_at = NOW() WHERE id IN ?`, ids).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range newClasses {
+			if err := tx.Create(&newClasses[i]).Error; err != nil {
+				return err
+			}
+			report.CreatedClasses[i].ID = newClasses[i].ID
+		}
+		return nil
+	})
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	notifyAllUsersChanged(h.db)
+
+	report.DryRun = false
+	audit.Record(c, audit.Entry{
+		Action:       "users.rollover",
+		ResourceType: "users",
+		ResourceID:   req.GraduateGrade,
+		After: gin.H{
+			"graduated_students": report.GraduatingStudents,
+			"archived_classes":   len(report.ArchivedClasses),
+			"created_classes":    len(report.CreatedClasses),
+		},
+	})
+	utils.SendSuccessResponse(c, report)
+}
+
+// buildRolloverReport 计算结转影响范围，返回报告和待创建的班级
+func buildRolloverReport(db *gorm.DB, req models.RolloverRequest) (*models.RolloverReport, []models.Department, error) {
+	report := &models.RolloverReport{
+		DryRun:          true,
+		GraduateGrade:   req.GraduateGrade,
+		ArchivedClasses: []models.RolloverClass{},
+		CreatedClasses:  []models.RolloverClass{},
+		SkippedClasses:  []models.RolloverClass{},
+	}
+
+	if err := db.Model(&models.User{}).
+		Where("user_type = ? AND grade = ? AND status <> ?", "student", req.GraduateGrade, "graduated").
+		Count(&report.GraduatingStudents).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if req.ArchiveClasses {
+		if err := db.Raw(archivableClassesSQL, map[string]interface{}{
+			"grade":  req.GraduateGrade,
+			"prefix": req.GraduateGrade + "%",
+		}).Scan(&report.ArchivedClasses).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var newClasses []models.Department
+	if req.NewClasses != nil {
+		candidates, err := plannedClasses(db, req.NewClasses)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, candidate := range candidates {
+			var exists int64
+			if err := db.Model(&models.Department{}).
+				Where("dept_type = ? AND parent_id = ? AND name = ?", models.DeptTypeClass, candidate.parent.ID, candidate.name).
+				Count(&exists).Error; err != nil {
+				return nil, nil, err
+			}
+			entry := models.RolloverClass{Name: candidate.name, MajorID: candidate.parent.ID, Major: candidate.parent.Name}
+			if exists > 0 {
+				report.SkippedClasses = append(report.SkippedClasses, entry)
+				continue
+			}
+			parentID := candidate.parent.ID
+			newClasses = append(newClasses, models.Department{
+				Name:     candidate.name,
+				DeptType: models.DeptTypeClass,
+				Level:    candidate.parent.Level + 1,
+				ParentID: &parentID,
+			})
+			report.CreatedClasses = append(report.CreatedClasses, entry)
+		}
+	}
+
+	report.ConfirmToken = rolloverToken(req, report)
+	return report, newClasses, nil
+}
+
+type plannedClass struct {
+	name   string
+	parent models.Department
+}
+
+// plannedClasses 按 from_grade 复制和按 pattern 生成新班级名称，同一专业下去重
+func plannedClasses(db *gorm.DB, plan *models.NewClassesPlan) ([]plannedClass, error) {
+	var result []plannedClass
+	seen := map[string]bool{}
+	add := func(name string, parent models.Department) {
+		key := parent.ID + "/" + name
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, plannedClass{name: name, parent: parent})
+		}
+	}
+
+	if plan.FromGrade != "" {
+		var rows []struct {
+			Name     string
+			ParentID string
+		}
+		if err := db.Model(&models.Department{}).
+			Select("name, parent_id").
+			Where("dept_type = ? AND name LIKE ? AND parent_id IS NOT NULL", models.DeptTypeClass, plan.FromGrade+"%").
+			Order("name").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		majors, err := loadMajors(db, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if major, ok := majors[row.ParentID]; ok {
+				add(plan.Grade+strings.TrimPrefix(row.Name, plan.FromGrade), major)
+			}
+		}
+	}
+
+	if plan.Pattern != "" {
+		majors, err := loadMajors(db, plan.MajorIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range plan.MajorIDs {
+			major, ok := majors[id]
+			if !ok {
+				return nil, fmt.Errorf("专业 %s 不存在", id)
+			}
+			for n := 1; n <= plan.Count; n++ {
+				name := strings.NewReplacer(
+					"{grade}", plan.Grade,
+					"{yy}", plan.Grade[2:],
+					"{n}", strconv.Itoa(n),
+				).Replace(plan.Pattern)
+				add(name, major)
+			}
+		}
+	}
+	return result, nil
+}
+
+// loadMajors 按 ID 读取专业节点；ids 为空时读取全部
+func loadMajors(db *gorm.DB, ids []string) (map[string]models.Department, error) {
+	query := db.Where("dept_type = ?", models.DeptTypeMajor)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	var majors []models.Department
+	if err := query.Find(&majors).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]models.Department, len(majors))
+	for _, major := range majors {
+		result[major.ID] = major
+	}
+	return result, nil
+}
+
+// rolloverToken 对结转计划和影响范围取摘要，预览与执行之间数据变化时摘要随之变化
+func rolloverToken(req models.RolloverRequest, report *models.RolloverReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%d|%t\n", req.GraduateGrade, report.GraduatingStudents, req.ArchiveClasses)
+	for _, class := range report.ArchivedClasses {
+		fmt.Fprintf(&b, "a:%s:%d\n", class.ID, class.Students)
+	}
+	for _, class := range report.CreatedClasses {
+		fmt.Fprintf(&b, "c:%s:%s\n", class.MajorID, class.Name)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])[:16]
+}
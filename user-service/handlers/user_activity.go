@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"credit-management/shared/servicetoken"
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单页合并时最多回溯的条目数，与学分活动服务内部接口的上限一致
+const maxTimelineDepth = 2000
+
+// auditTimelineRule 审计日志动作到时间线条目的映射；byActor 为 true 时按操作人匹配，否则按被操作用户匹配
+type auditTimelineRule struct {
+	itemType string
+	title    string
+	byActor  bool
+}
+
+var auditTimelineRules = map[string]auditTimelineRule{
+	"auth.login":                      {models.TimelineLogin, "登录系统", true},
+	"auth.logout":                     {models.TimelineLogout, "退出登录", true},
+	"POST /api/users/change_password": {models.TimelinePasswordChange, "修改密码", true},
+	"users.password_reset":            {models.TimelinePasswordChange, "密码被管理员重置", false},
+	"users.update":                    {models.TimelineProfileUpdate, "个人资料变更", false},
+	"users.status_change":             {models.TimelineProfileUpdate, "账户状态变更", false},
+	"POST /api/users/avatar":          {models.TimelineProfileUpdate, "上传头像", true},
+	"DELETE /api/users/avatar":        {models.TimelineProfileUpdate, "删除头像", true},
+}
+
+var timelineClient = &http.Client{Timeout: 5 * time.Second}
+
+// GetUserActivity 获取用户动态时间线：登录、资料变更来自审计日志，
+// 活动创建 / 提交、审核操作、获得学分来自学分活动服务，合并后按时间倒序分页。
+// 学生只能查看自己的动态，教师和管理员可以查看数据范围内的指定用户。
+// 学分活动服务不可用时仍返回本服务的数据，并以 partial 标记。
+func (h *UserHandler) GetUserActivity(c *gin.Context) {
+	currentUserID := utils.GetCurrentUserID(c)
+	if currentUserID == "" {
+		utils.SendUnauthorized(c)
+		return
+	}
+	userID := c.Param("id")
+	if userID == "" {
+		userID = currentUserID
+	} else if userID != currentUserID && !utils.IsTeacherOrAdmin(utils.GetCurrentUserRole(c)) {
+		utils.SendForbidden(c, "无权查看其他用户的动态")
+		return
+	}
+
+	validator := utils.NewValidator()
+	if err := validator.ValidateUUID(userID); err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+	if !h.requireUserInScope(c, userID) {
+		return
+	}
+	page, pageSize, _ := validator.ValidatePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("page_size", "10"))
+
+	// 按时间倒序合并多个来源时，第 page 页需要每个来源的前 page*page_size 条
+	depth := page * pageSize
+	if depth > maxTimelineDepth {
+		utils.SendBadRequest(c, fmt.Sprintf("最多可查看最近 %d 条动态", maxTimelineDepth))
+		return
+	}
+
+	types := map[string]bool{}
+	for _, t := range strings.Split(c.Query("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+
+	localItems, localTotal, err := h.auditTimeline(userID, types, depth)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	partial := false
+	remoteItems, remoteTotal, err := fetchCreditTimeline(c.Request.Context(), userID, c.Query("types"), depth)
+	if err != nil {
+		log.Printf("获取学分活动动态失败: %v", err)
+		partial = true
+	}
+
+	items := append(localItems, remoteItems...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OccurredAt.After(items[j].OccurredAt)
+	})
+	start := (page - 1) * pageSize
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+
+	total := localTotal + remoteTotal
+	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
+
+	utils.SendSuccessResponse(c, gin.H{
+		"id":          userID,
+		"activities":  items[start:end],
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": totalPages,
+		"partial":     partial,
+	})
+}
+
+// auditTimeline 从审计日志中读取用户的登录与资料变更记录
+func (h *UserHandler) auditTimeline(userID string, types map[string]bool, limit int) ([]models.TimelineItem, int64, error) {
+	var actorActions, resourceActions []string
+	for action, rule := range auditTimelineRules {
+		if len(types) > 0 && !types[rule.itemType] {
+			continue
+		}
+		if rule.byActor {
+			actorActions = append(actorActions, action)
+		} else {
+			resourceActions = append(resourceActions, action)
+		}
+	}
+	if len(actorActions) == 0 && len(resourceActions) == 0 {
+		return nil, 0, nil
+	}
+
+	query := h.db.Model(&models.AuditLog{})
+	switch {
+	case len(actorActions) > 0 && len(resourceActions) > 0:
+		query = query.Where("(actor_id = ? AND action IN ?) OR (resource_type = 'users' AND resource_id = ? AND action IN ?)",
+			userID, actorActions, userID, resourceActions)
+	case len(actorActions) > 0:
+		query = query.Where("actor_id = ? AND action IN ?", userID, actorActions)
+	default:
+		query = query.Where("resource_type = 'users' AND resource_id = ? AND action IN ?", userID, resourceActions)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]models.TimelineItem, 0, len(logs))
+	for _, entry := range logs {
+		rule := auditTimelineRules[entry.Action]
+		details := map[string]interface{}{"ip": entry.IP}
+		if entry.ActorID != "" && entry.ActorID != userID {
+			details["operator_id"] = entry.ActorID
+		}
+		if len(entry.Changes) > 0 {
+			details["changes"] = entry.Changes
+		}
+		items = append(items, models.TimelineItem{
+			Type:         rule.itemType,
+			Title:        rule.title,
+			OccurredAt:   entry.CreatedAt,
+			Source:       entry.Service,
+			ResourceType: "users",
+			ResourceID:   userID,
+			Details:      details,
+		})
+	}
+	return items, total, nil
+}
+
+// fetchCreditTimeline 调用学分活动服务的内部接口获取活动相关动态
+func fetchCreditTimeline(ctx context.Context, userID, types string, limit int) ([]models.TimelineItem, int64, error) {
+	baseURL := strings.TrimRight(utils.GetEnv("CREDIT_ACTIVITY_SERVICE_URL", "http://credit-activity-service:8083"), "/")
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if types != "" {
+		query.Set("types", types)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/internal/users/"+url.PathEscape(userID)+"/timeline?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := utils.ServiceAuth(servicetoken.CreditActivityService)(req); err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := timelineClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("credit-activity-service responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Items []models.TimelineItem `json:"items"`
+			Total int64                 `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, 0, err
+	}
+	if body.Code != 0 {
+		return nil, 0, fmt.Errorf("credit-activity-service error: %s", body.Message)
+	}
+	return body.Data.Items, body.Data.Total, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"log"
+
+	"credit-management/user-service/models"
+	"credit-management/user-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserChangedChannel 用户信息变更的 PostgreSQL 通知频道，负载为用户 UUID 或学号 / 工号
+// （新建用户时通知学号，使其他服务缓存的"不存在"结果失效）；
+// 部门结构变化会影响大量用户的学部 / 专业 / 班级，此时负载为 "*"
+const UserChangedChannel = "user_changed"
+
+// userProfileSQL 用户基本信息及其所在部门链（学生：班级 -> 专业 -> 学部）
+const userProfileSQL = `
+	SELECT u.uuid, u.username, u.real_name, u.user_type, u.status, u.student_id, u.teacher_id,
+	       u.avatar, u.grade, u.title,
+	       u.department_id, d.name AS department,
+	       CASE WHEN d.dept_type = 'class' THEN g.name WHEN d.dept_type = 'major' THEN p.name
+	            WHEN d.dept_type = 'college' THEN d.name END AS college,
+	       CASE WHEN d.dept_type = 'class' THEN p.name WHEN d.dept_type = 'major' THEN d.name END AS major,
+	       CASE WHEN d.dept_type = 'class' THEN d.name END AS class,
+	       u.deleted_at IS NOT NULL AS deleted,
+	       GREATEST(u.updated_at, COALESCE(u.deleted_at, u.updated_at)) AS changed_at
+	FROM users u
+	LEFT JOIN departments d ON d.id = u.department_id
+	LEFT JOIN departments p ON p.id = d.parent_id
+	LEFT JOIN departments g ON g.id = p.parent_id`
+
+const userBatchSQL = userProfileSQL + `
+	WHERE u.deleted_at IS NULL
+	  AND (u.uuid::text IN @uuids OR u.student_id IN @ids OR u.teacher_id IN @ids)`
+
+// userChangesSQL 按 (changed_at, uuid) 顺序翻页，包含已删除的用户
+const userChangesSQL = `
+	SELECT * FROM (` + userProfileSQL + `) AS profile
+	WHERE (changed_at, uuid::text) > (@since, @after)
+	ORDER BY changed_at, uuid::text
+	LIMIT @limit`
+
+// BatchGetUsers 内部接口：按 UUID 或学号 / 工号批量查询用户，供其他服务补全用户信息
+func (h *UserHandler) BatchGetUsers(c *gin.Context) {
+	var req models.UserBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if len(req.UUIDs) == 0 && len(req.StudentIDs) == 0 {
+		utils.SendBadRequest(c, "需要提供 uuids 或 student_ids")
+		return
+	}
+
+	// IN 空列表会生成非法 SQL，用不会匹配的占位值代替
+	uuids, ids := req.UUIDs, req.StudentIDs
+	if len(uuids) == 0 {
+		uuids = []string{""}
+	}
+	if len(ids) == 0 {
+		ids = []string{""}
+	}
+
+	users := make([]models.UserProfile, 0)
+	if err := h.db.Raw(userBatchSQL, map[string]interface{}{"uuids": uuids, "ids": ids}).Scan(&users).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	found := make(map[string]bool, len(users)*2)
+	for _, user := range users {
+		found[user.UUID] = true
+		if user.StudentID != nil {
+			found[*user.StudentID] = true
+		}
+		if user.TeacherID != nil {
+			found[*user.TeacherID] = true
+		}
+	}
+	missing := make([]string, 0)
+	for _, list := range [][]string{req.UUIDs, req.StudentIDs} {
+		for _, id := range list {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+	}
+
+	utils.SendSuccessResponse(c, models.UserBatchResponse{Users: users, Missing: missing})
+}
+
+// GetUserChanges 内部接口：返回 since / after 之后变更（含删除）的用户，供其他服务同步本地用户快照。
+// 调用方以最后一条的 changed_at 和 uuid 作为下一页的 since / after，直到 has_more 为 false
+func (h *UserHandler) GetUserChanges(c *gin.Context) {
+	var req models.UserChangesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.SendBadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 500
+	}
+
+	users := make([]models.UserProfile, 0)
+	if err := h.db.Raw(userChangesSQL, map[string]interface{}{
+		"since": req.Since,
+		"after": req.After,
+		"limit": req.Limit,
+	}).Scan(&users).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	// 附带教师 / 管理员展开后的数据范围，其他服务据此在本地按部门过滤，不再读取本服务的部门表
+	for i := range users {
+		if users[i].Deleted || (users[i].UserType != "teacher" && users[i].UserType != "admin") {
+			continue
+		}
+		scope, err := resolveDataScope(h.db, users[i].UUID, users[i].UserType)
+		if err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+		users[i].ScopeRestricted = scope.restricted
+		users[i].ScopeDepartmentIDs = scope.departmentIDs
+	}
+
+	utils.SendSuccessResponse(c, models.UserChangesResponse{Users: users, HasMore: len(users) == req.Limit})
+}
+
+// userCacheKeys 返回其他服务可能用于缓存该用户的键
+func userCacheKeys(user models.User) []string {
+	keys := []string{user.UUID}
+	if user.StudentID != nil && *user.StudentID != "" {
+		keys = append(keys, *user.StudentID)
+	}
+	if user.TeacherID != nil && *user.TeacherID != "" {
+		keys = append(keys, *user.TeacherID)
+	}
+	return keys
+}
+
+// notifyUsersChanged 通知其他服务丢弃这些用户的缓存。在事务中调用时通知随事务提交发出，回滚则不发出；
+// 通知失败只记录日志，缓存仍会按 TTL 过期
+func notifyUsersChanged(db *gorm.DB, keys ...string) {
+	for _, key := range keys {
+		if err := db.Exec("SELECT pg_notify(?, ?)", UserChangedChannel, key).Error; err != nil {
+			log.Printf("发送用户变更通知失败: %v", err)
+			return
+		}
+	}
+}
+
+// notifyAllUsersChanged 部门结构变化时通知其他服务清空全部用户缓存
+func notifyAllUsersChanged(db *gorm.DB) {
+	notifyUsersChanged(db, "*")
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"credit-management/shared/audit"
+	"credit-management/shared/imports"
+	"credit-management/shared/realtime"
 	"credit-management/user-service/models"
 	"credit-management/user-service/utils"
 	"encoding/csv"
@@ -9,10 +12,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xuri/excelize/v2"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 func (h *UserHandler) Register(c *gin.Context) {
@@ -163,6 +169,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, userCacheKeys(user)...)
 
 	userResponse := h.convertToUserResponse(user)
 	utils.SendCreatedResponse(c, "学生注册成功", gin.H{
@@ -278,6 +285,7 @@ func (h *UserHandler) CreateTeacher(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, userCacheKeys(user)...)
 
 	userResponse := h.convertToUserResponse(user)
 	utils.SendCreatedResponse(c, "教师创建成功", gin.H{
@@ -496,6 +504,7 @@ func (h *UserHandler) CreateStudent(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, userCacheKeys(user)...)
 
 	userResponse := h.convertToUserResponse(user)
 	utils.SendCreatedResponse(c, "学生创建成功", gin.H{
@@ -704,41 +713,45 @@ func (h *UserHandler) parseExcelFile(file *multipart.FileHeader) ([][]string, er
 	return records, nil
 }
 
+// importService 导入任务表中本服务任务的 service 标识
+const importService = "user-service"
+
+// importRequiredColumns 以当前导出的表头为准：
+// 学生：学号, 姓名, 邮箱, 手机号, 学部, 专业, 班级, 年级, 状态
+// 教师：工号, 姓名, 邮箱, 手机号, 学部, 专业, 班级, 职称, 状态
+func importRequiredColumns(userType string) []string {
+	switch userType {
+	case "student":
+		return []string{"学号", "姓名", "邮箱", "学部", "专业", "班级", "年级"}
+	case "teacher":
+		return []string{"工号", "姓名", "邮箱", "学部", "专业", "班级", "职称"}
+	}
+	return nil
+}
+
+// processImportData 校验标题行后创建导入任务并立即返回任务 ID，数据行由后台任务分批处理
 func (h *UserHandler) processImportData(c *gin.Context, records [][]string, userType string, fileName string) {
 	if len(records) < 2 {
 		utils.SendBadRequest(c, "文件至少需要包含标题行和一行数据")
 		return
 	}
 
-	validator := utils.NewValidator()
-
-	headers := records[0]
-	headerMap := make(map[string]int)
-	for i, header := range headers {
-		key := strings.TrimSpace(header)
-		if key != "" {
-			headerMap[key] = i
-		}
-	}
-
-	// 以当前导出的表头为准：
-	// 学生：学号, 姓名, 邮箱, 手机号, 学部, 专业, 班级, 年级, 状态
-	// 教师：工号, 姓名, 邮箱, 手机号, 学部, 专业, 班级, 职称, 状态
-	var requiredColumns []string
-	switch userType {
-	case "student":
-		requiredColumns = []string{"学号", "姓名", "邮箱", "学部", "专业", "班级", "年级"}
-	case "teacher":
-		requiredColumns = []string{"工号", "姓名", "邮箱", "学部", "专业", "班级", "职称"}
-	default:
+	requiredColumns := importRequiredColumns(userType)
+	if requiredColumns == nil {
 		utils.SendBadRequestWithData(c, "仅支持导入学生或教师数据", gin.H{
 			"errors": []string{"仅支持导入学生或教师数据"},
 		})
 		return
 	}
 
+	headerMap := make(map[string]bool)
+	for _, header := range records[0] {
+		if key := strings.TrimSpace(header); key != "" {
+			headerMap[key] = true
+		}
+	}
 	for _, col := range requiredColumns {
-		if _, exists := headerMap[col]; !exists {
+		if !headerMap[col] {
 			msg := fmt.Sprintf("缺少必需的列: %s", col)
 			utils.SendBadRequestWithData(c, "数据验证失败", gin.H{
 				"errors": []string{msg},
@@ -747,325 +760,253 @@ func (h *UserHandler) processImportData(c *gin.Context, records [][]string, user
 		}
 	}
 
-	var users []models.UserRequest
-	var errors []string
-
-	for i, record := range records[1:] {
-		rowNum := i + 2 // 从第2行开始计算
-
-		// 检查记录长度
-		if len(record) < len(headers) {
-			errors = append(errors, fmt.Sprintf("第%d行: 列数不匹配", rowNum))
-			continue
-		}
-
-		getVal := func(col string) string {
-			idx, ok := headerMap[col]
-			if !ok || idx >= len(record) {
-				return ""
-			}
-			return strings.TrimSpace(record[idx])
-		}
+	job := models.ImportJob{
+		Service:   importService,
+		Kind:      "users." + userType,
+		CreatedBy: utils.GetCurrentUserID(c),
+		FileName:  fileName,
+		Options:   datatypes.JSONMap{"user_type": userType, "file_type": filepath.Ext(fileName)},
+	}
+	if err := imports.Submit(h.db, &job, records); err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
 
-		var user models.UserRequest
-		user.UserType = userType
-		user.Email = getVal("邮箱")
-		user.Phone = getVal("手机号")
-		user.RealName = getVal("姓名")
-
-		if userType == "student" {
-			studentID := getVal("学号")
-			collegeName := getVal("学部")
-			majorName := getVal("专业")
-			className := getVal("班级")
-			grade := getVal("年级")
-
-			if studentID == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 学生必须提供学号", rowNum))
-				continue
-			}
-			if collegeName == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 学生必须提供学部名称", rowNum))
-				continue
-			}
-			if majorName == "" || className == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 学生必须提供专业和班级名称", rowNum))
-				continue
-			}
+	realtime.NotifyImportProgress(job)
+	audit.Record(c, audit.Entry{
+		Action:       "users.import",
+		ResourceType: "import_job",
+		ResourceID:   job.ID,
+		After:        gin.H{"user_type": userType, "file_name": fileName, "total_rows": job.TotalRows},
+	})
+	utils.SendAcceptedResponse(c, "导入任务已创建", job)
+}
 
-			// 使用学号作为用户名，密码使用默认密码
-			defaultPassword := utils.GenerateDefaultPassword()
-			user.StudentID = studentID
-			user.Username = studentID
-			user.Password = defaultPassword
-			user.Grade = grade
+// NewImportRunner 创建处理用户导入任务的后台执行器，每批提交后向发起人推送进度
+func (h *UserHandler) NewImportRunner(interval time.Duration, batchSize int) *imports.Runner {
+	runner := imports.NewRunner(h.db, importService, interval, batchSize)
+	runner.Register("users.student", importUserRow)
+	runner.Register("users.teacher", importUserRow)
+	runner.OnProgress = realtime.NotifyImportProgress
+	return runner
+}
 
-			// 验证学部 -> 专业 -> 班级的层级关系
-			type deptRow struct {
-				ID string
-			}
+// importUserRow 校验并创建导入文件中的一个用户，校验规则与注册 / 创建接口一致。
+// 唯一性检查在任务事务内进行，同一文件中重复的学号、邮箱等也能被发现
+func importUserRow(tx *gorm.DB, job *models.ImportJob, headerMap map[string]int, record []string) error {
+	userType, _ := job.Options["user_type"].(string)
+	h := &UserHandler{db: tx}
+	validator := utils.NewValidator()
 
-			// 首先验证学部是否存在
-			var collegeDept deptRow
-			if err := h.db.Raw(`
-				SELECT id
-				FROM departments
-				WHERE dept_type = 'college' AND name = ?
-				LIMIT 1
-			`, collegeName).Scan(&collegeDept).Error; err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: 查询学部失败: %v", rowNum, err))
-				continue
-			}
-			if collegeDept.ID == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 未找到对应的学部（学部=%s）", rowNum, collegeName))
-				continue
-			}
+	if len(record) < len(job.Header) {
+		return fmt.Errorf("列数不匹配")
+	}
 
-			// 验证专业是否属于该学部
-			var majorDept deptRow
-			if err := h.db.Raw(`
-				SELECT id
-				FROM departments
-				WHERE dept_type = 'major' AND name = ? AND parent_id = ?
-				LIMIT 1
-			`, majorName, collegeDept.ID).Scan(&majorDept).Error; err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: 查询专业失败: %v", rowNum, err))
-				continue
-			}
-			if majorDept.ID == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 未找到对应的专业，或专业不属于该学部（学部=%s, 专业=%s）", rowNum, collegeName, majorName))
-				continue
-			}
+	getVal := func(col string) string {
+		idx, ok := headerMap[col]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
 
-			// 验证班级是否属于该专业
-			var classDept deptRow
-			if err := h.db.Raw(`
-				SELECT id
-				FROM departments
-				WHERE dept_type = 'class' AND name = ? AND parent_id = ?
-				LIMIT 1
-			`, className, majorDept.ID).Scan(&classDept).Error; err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: 查询班级失败: %v", rowNum, err))
-				continue
-			}
-			if classDept.ID == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 未找到对应的班级，或班级不属于该专业（专业=%s, 班级=%s）", rowNum, majorName, className))
-				continue
-			}
-			user.DepartmentID = classDept.ID
-		} else if userType == "teacher" {
-			teacherID := getVal("工号")
-			collegeName := getVal("学部")
-			majorName := getVal("专业") // 当前未用于反查，仅保留
-			className := getVal("班级") // 当前未用于反查
-			title := getVal("职称")
-
-			if teacherID == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 教师必须提供工号", rowNum))
-				continue
-			}
-			if collegeName == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 教师必须提供学部名称", rowNum))
-				continue
-			}
+	var user models.UserRequest
+	user.UserType = userType
+	user.Email = getVal("邮箱")
+	user.Phone = getVal("手机号")
+	user.RealName = getVal("姓名")
 
-			user.TeacherID = teacherID
-			user.Username = teacherID
-			user.Password = utils.GenerateDefaultPassword()
-			user.Title = title
+	type deptRow struct {
+		ID string
+	}
 
-			type deptRow struct {
-				ID string
-			}
-			var collegeDept deptRow
-			if err := h.db.Raw(`
-				SELECT id
-				FROM departments
-				WHERE dept_type = 'college' AND name = ?
-				LIMIT 1
-			`, collegeName).Scan(&collegeDept).Error; err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: 查询学部失败: %v", rowNum, err))
-				continue
-			}
-			if collegeDept.ID == "" {
-				errors = append(errors, fmt.Sprintf("第%d行: 未找到对应的学部（学部=%s）", rowNum, collegeName))
-				continue
-			}
-			user.DepartmentID = collegeDept.ID
-			_ = majorName
-			_ = className
+	if userType == "student" {
+		studentID := getVal("学号")
+		collegeName := getVal("学部")
+		majorName := getVal("专业")
+		className := getVal("班级")
+		grade := getVal("年级")
+
+		if studentID == "" {
+			return fmt.Errorf("学生必须提供学号")
 		}
-
-		// 处理状态列：允许为空（默认 active），否则按导出的英文枚举校验
-		statusVal := getVal("状态")
-		if statusVal == "" {
-			statusVal = "active"
-		} else {
-			if err := validator.ValidateStatus(statusVal); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
+		if collegeName == "" {
+			return fmt.Errorf("学生必须提供学部名称")
 		}
-		user.Status = statusVal
+		if majorName == "" || className == "" {
+			return fmt.Errorf("学生必须提供专业和班级名称")
+		}
+
+		// 使用学号作为用户名，密码使用默认密码
+		user.StudentID = studentID
+		user.Username = studentID
+		user.Password = utils.GenerateDefaultPassword()
+		user.Grade = grade
 
-		// 与注册/更新保持一致的格式校验
-		if err := validator.ValidateEmail(user.Email); err != nil {
-			errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-			continue
+		// 验证学部 -> 专业 -> 班级的层级关系
+		var collegeDept deptRow
+		if err := tx.Raw(`
+			SELECT id
+			FROM departments
+			WHERE dept_type = 'college' AND name = ?
+			LIMIT 1
+		`, collegeName).Scan(&collegeDept).Error; err != nil {
+			return fmt.Errorf("查询学部失败: %v", err)
 		}
-		if user.Phone != "" {
-			if err := validator.ValidatePhone(user.Phone); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
+		if collegeDept.ID == "" {
+			return fmt.Errorf("未找到对应的学部（学部=%s）", collegeName)
 		}
-		if err := validator.ValidateUsername(user.Username); err != nil {
-			errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-			continue
+
+		var majorDept deptRow
+		if err := tx.Raw(`
+			SELECT id
+			FROM departments
+			WHERE dept_type = 'major' AND name = ? AND parent_id = ?
+			LIMIT 1
+		`, majorName, collegeDept.ID).Scan(&majorDept).Error; err != nil {
+			return fmt.Errorf("查询专业失败: %v", err)
 		}
-		if err := validator.ValidatePassword(user.Password); err != nil {
-			// 密码格式错误也视为普通业务错误：记录但不中断后续行检查
-			errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-			continue
+		if majorDept.ID == "" {
+			return fmt.Errorf("未找到对应的专业，或专业不属于该学部（学部=%s, 专业=%s）", collegeName, majorName)
 		}
 
-		// 学生/教师特定字段校验
-		if userType == "student" {
-			if err := validator.ValidateStudentID(user.StudentID); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
-			if err := validator.ValidateGrade(user.Grade); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
-		} else if userType == "teacher" {
-			if err := validator.ValidateTeacherID(user.TeacherID); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
+		var classDept deptRow
+		if err := tx.Raw(`
+			SELECT id
+			FROM departments
+			WHERE dept_type = 'class' AND name = ? AND parent_id = ?
+			LIMIT 1
+		`, className, majorDept.ID).Scan(&classDept).Error; err != nil {
+			return fmt.Errorf("查询班级失败: %v", err)
 		}
+		if classDept.ID == "" {
+			return fmt.Errorf("未找到对应的班级，或班级不属于该专业（专业=%s, 班级=%s）", majorName, className)
+		}
+		user.DepartmentID = classDept.ID
+	} else if userType == "teacher" {
+		teacherID := getVal("工号")
+		collegeName := getVal("学部")
 
-		// 与注册/更新保持一致的唯一性检查
-		if err := h.checkUsernameUniqueness(user.Username); err != nil {
-			errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-			continue
+		if teacherID == "" {
+			return fmt.Errorf("教师必须提供工号")
 		}
-		if err := h.checkEmailUniqueness(user.Email); err != nil {
-			errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-			continue
+		if collegeName == "" {
+			return fmt.Errorf("教师必须提供学部名称")
 		}
-		if user.Phone != "" {
-			if err := h.checkPhoneUniqueness(user.Phone); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
+
+		user.TeacherID = teacherID
+		user.Username = teacherID
+		user.Password = utils.GenerateDefaultPassword()
+		user.Title = getVal("职称")
+
+		var collegeDept deptRow
+		if err := tx.Raw(`
+			SELECT id
+			FROM departments
+			WHERE dept_type = 'college' AND name = ?
+			LIMIT 1
+		`, collegeName).Scan(&collegeDept).Error; err != nil {
+			return fmt.Errorf("查询学部失败: %v", err)
 		}
-		if userType == "student" {
-			if err := h.checkStudentIDUniqueness(user.StudentID); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
-		} else if userType == "teacher" {
-			if err := h.checkTeacherIDUniqueness(user.TeacherID); err != nil {
-				errors = append(errors, fmt.Sprintf("第%d行: %s", rowNum, err.Error()))
-				continue
-			}
+		if collegeDept.ID == "" {
+			return fmt.Errorf("未找到对应的学部（学部=%s）", collegeName)
 		}
-
-		users = append(users, user)
+		user.DepartmentID = collegeDept.ID
+	} else {
+		return fmt.Errorf("仅支持导入学生或教师数据")
 	}
 
-	if len(errors) > 0 {
-		utils.SendBadRequestWithData(c, "数据验证失败", gin.H{
-			"errors":       errors,
-			"total_rows":   len(records) - 1,
-			"valid_rows":   len(users),
-			"invalid_rows": len(errors),
-		})
-		return
+	// 处理状态列：允许为空（默认 active），否则按导出的英文枚举校验
+	statusVal := getVal("状态")
+	if statusVal == "" {
+		statusVal = "active"
+	} else if err := validator.ValidateStatus(statusVal); err != nil {
+		return err
 	}
+	user.Status = statusVal
 
-	var createdUsers []models.UserResponse
-	var createErrors []string
-
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	for i, userReq := range users {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userReq.Password), bcrypt.DefaultCost)
-		if err != nil {
-			createErrors = append(createErrors, fmt.Sprintf("第%d个用户: 密码加密失败", i+1))
-			continue
-		}
-
-		user := models.User{
-			Username: userReq.Username,
-			Password: string(hashedPassword),
-			Email:    userReq.Email,
-			RealName: userReq.RealName,
-			UserType: userReq.UserType,
-			Status:   userReq.Status,
+	// 与注册/更新保持一致的格式校验
+	if err := validator.ValidateEmail(user.Email); err != nil {
+		return err
+	}
+	if user.Phone != "" {
+		if err := validator.ValidatePhone(user.Phone); err != nil {
+			return err
 		}
-		if userReq.Phone != "" {
-			user.Phone = &userReq.Phone
+	}
+	if err := validator.ValidateUsername(user.Username); err != nil {
+		return err
+	}
+	if err := validator.ValidatePassword(user.Password); err != nil {
+		return err
+	}
+	if userType == "student" {
+		if err := validator.ValidateStudentID(user.StudentID); err != nil {
+			return err
 		}
-		if userReq.DepartmentID != "" {
-			user.DepartmentID = &userReq.DepartmentID
+		if err := validator.ValidateGrade(user.Grade); err != nil {
+			return err
 		}
+	} else if err := validator.ValidateTeacherID(user.TeacherID); err != nil {
+		return err
+	}
 
-		if userType == "student" {
-			studentID := userReq.StudentID
-			user.StudentID = &studentID
-			if userReq.Grade != "" {
-				user.Grade = &userReq.Grade
-			}
-		} else {
-			teacherID := userReq.TeacherID
-			user.TeacherID = &teacherID
-			if userReq.Title != "" {
-				user.Title = &userReq.Title
-			}
+	// 与注册/更新保持一致的唯一性检查
+	if err := h.checkUsernameUniqueness(user.Username); err != nil {
+		return err
+	}
+	if err := h.checkEmailUniqueness(user.Email); err != nil {
+		return err
+	}
+	if user.Phone != "" {
+		if err := h.checkPhoneUniqueness(user.Phone); err != nil {
+			return err
 		}
-
-		if err := tx.Create(&user).Error; err != nil {
-			createErrors = append(createErrors, fmt.Sprintf("第%d个用户创建失败: %s", i+1, err.Error()))
-			continue
+	}
+	if userType == "student" {
+		if err := h.checkStudentIDUniqueness(user.StudentID); err != nil {
+			return err
 		}
-
-		response := h.convertToUserResponse(user)
-		createdUsers = append(createdUsers, response)
+	} else if err := h.checkTeacherIDUniqueness(user.TeacherID); err != nil {
+		return err
 	}
 
-	// 如果有创建错误，回滚事务
-	if len(createErrors) > 0 {
-		tx.Rollback()
-		utils.SendBadRequestWithData(c, "批量创建用户失败", gin.H{
-			"errors":        createErrors,
-			"created_count": 0,
-			"total_count":   len(users),
-			"created_users": []models.UserResponse{},
-		})
-		return
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("密码加密失败")
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		utils.SendInternalServerError(c, err)
-		return
+	created := models.User{
+		Username: user.Username,
+		Password: string(hashedPassword),
+		Email:    user.Email,
+		RealName: user.RealName,
+		UserType: user.UserType,
+		Status:   user.Status,
+	}
+	if user.Phone != "" {
+		created.Phone = &user.Phone
+	}
+	if user.DepartmentID != "" {
+		created.DepartmentID = &user.DepartmentID
+	}
+	if userType == "student" {
+		created.StudentID = &user.StudentID
+		if user.Grade != "" {
+			created.Grade = &user.Grade
+		}
+	} else {
+		created.TeacherID = &user.TeacherID
+		if user.Title != "" {
+			created.Title = &user.Title
+		}
 	}
 
-	utils.SendCreatedResponse(c, "批量导入成功", gin.H{
-		"created_count": len(createdUsers),
-		"total_count":   len(users),
-		"created_users": createdUsers,
-		"user_type":     userType,
-		"file_name":     fileName,
-		"file_type":     filepath.Ext(fileName),
-	})
+	if err := tx.Create(&created).Error; err != nil {
+		return fmt.Errorf("创建用户失败: %s", err.Error())
+	}
+	notifyUsersChanged(tx, userCacheKeys(created)...)
+	return nil
 }
 
 func (h *UserHandler) GetUserCSVTemplate(c *gin.Context) {
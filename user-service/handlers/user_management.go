@@ -1,14 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"net/http"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
+	"credit-management/shared/audit"
+	"credit-management/shared/storage"
 	"credit-management/user-service/models"
 	"credit-management/user-service/utils"
 
@@ -30,12 +32,14 @@ func isNumeric(s string) bool {
 }
 
 type UserHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	files storage.Storage // 头像文件
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
+func NewUserHandler(db *gorm.DB, files storage.Storage) *UserHandler {
 	return &UserHandler{
-		db: db,
+		db:    db,
+		files: files,
 	}
 }
 
@@ -52,6 +56,9 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		utils.SendNotFound(c, "用户不存在")
 		return
 	}
+	if !h.requireUserInScope(c, userID) {
+		return
+	}
 
 	if userID == currentUserID {
 		var result map[string]interface{}
@@ -125,6 +132,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		}
 		return
 	}
+	before := h.convertToUserResponse(user)
 
 	// 验证邮箱唯一性
 	if req.Email != "" {
@@ -309,8 +317,16 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, userCacheKeys(user)...)
 
 	userResponse := h.convertToUserResponse(user)
+	audit.Record(c, audit.Entry{
+		Action:       "users.update",
+		ResourceType: "users",
+		ResourceID:   user.UUID,
+		Before:       before,
+		After:        userResponse,
+	})
 	utils.SendSuccessResponse(c, userResponse)
 }
 
@@ -335,7 +351,14 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, userCacheKeys(user)...)
 
+	audit.Record(c, audit.Entry{
+		Action:       "users.delete",
+		ResourceType: "users",
+		ResourceID:   user.UUID,
+		Before:       h.convertToUserResponse(user),
+	})
 	utils.SendSuccessResponse(c, gin.H{"message": "用户删除成功"})
 }
 
@@ -364,6 +387,18 @@ func (h *UserHandler) BatchDeleteUsers(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	for _, user := range users {
+		notifyUsersChanged(h.db, userCacheKeys(user)...)
+	}
+
+	for _, user := range users {
+		audit.Record(c, audit.Entry{
+			Action:       "users.batch_delete",
+			ResourceType: "users",
+			ResourceID:   user.UUID,
+			Before:       h.convertToUserResponse(user),
+		})
+	}
 
 	utils.SendSuccessResponse(c, gin.H{"deleted_count": len(users)})
 }
@@ -379,10 +414,30 @@ func (h *UserHandler) BatchUpdateUserStatus(c *gin.Context) {
 		return
 	}
 
+	var users []models.User
+	if err := h.db.Select("uuid", "status").Where("uuid IN ?", req.UUIDs).Find(&users).Error; err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
 	if err := h.db.Model(&models.User{}).Where("uuid IN ?", req.UUIDs).Update("status", req.Status).Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, req.UUIDs...)
+
+	for _, user := range users {
+		if user.Status == req.Status {
+			continue
+		}
+		audit.Record(c, audit.Entry{
+			Action:       "users.status_change",
+			ResourceType: "users",
+			ResourceID:   user.UUID,
+			Before:       gin.H{"status": user.Status},
+			After:        gin.H{"status": req.Status},
+		})
+	}
 
 	utils.SendSuccessResponse(c, gin.H{"updated_count": len(req.UUIDs), "status": req.Status})
 }
@@ -425,6 +480,11 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	audit.Record(c, audit.Entry{
+		Action:       "users.password_reset",
+		ResourceType: "users",
+		ResourceID:   user.UUID,
+	})
 	utils.SendSuccessResponse(c, gin.H{"message": "密码重置成功"})
 }
 
@@ -480,44 +540,17 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	utils.SendSuccessResponse(c, gin.H{"message": "密码修改成功"})
 }
 
-func (h *UserHandler) GetUserActivity(c *gin.Context) {
-	userID := c.Param("id")
-	if userID == "" {
-		userID = utils.GetCurrentUserID(c)
-		if userID == "" {
-			utils.SendUnauthorized(c)
-			return
-		}
-	}
-
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-
-	validator := utils.NewValidator()
-	page, pageSize, _ = validator.ValidatePagination(strconv.Itoa(page), strconv.Itoa(pageSize))
-
-	// 这里可以添加用户活动记录的查询逻辑
-	// 例如：登录记录、操作日志等
-	// 目前返回空结果，后续可以扩展
-
-	response := gin.H{
-		"id":          userID,
-		"activities":  []interface{}{},
-		"total":       0,
-		"page":        page,
-		"page_size":   pageSize,
-		"total_pages": 0,
-	}
-
-	utils.SendSuccessResponse(c, response)
-}
-
 func (h *UserHandler) ExportUsers(c *gin.Context) {
 	format := c.DefaultQuery("format", "xlsx")
 	userType := c.Query("user_type")
 	status := c.Query("status")
 
-	query := h.db.Model(&models.User{})
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	query := scope.users(h.db.Model(&models.User{}), "uuid")
 
 	if userType != "" {
 		query = query.Where("user_type = ?", userType)
@@ -721,6 +754,14 @@ func (h *UserHandler) convertToUserResponse(user models.User) models.UserRespons
 	}
 }
 
+// avatarURLPrefix 头像地址前缀，用户记录中保存的是经网关访问的地址
+const avatarURLPrefix = "/api/uploads/avatars/"
+
+// avatarKey 头像文件在存储中的 key
+func avatarKey(filename string) string {
+	return storage.Key("avatars", filename)
+}
+
 // UploadAvatar handles avatar file upload
 func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
@@ -763,46 +804,31 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 		return
 	}
 
-	// Create uploads directory if not exists
-	uploadsDir := "./uploads/avatars"
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		utils.SendInternalServerError(c, fmt.Errorf("创建上传目录失败: %v", err))
-		return
-	}
-
 	// Generate unique filename
 	newFilename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
-	filePath := filepath.Join(uploadsDir, newFilename)
+	key := avatarKey(newFilename)
 
 	// Save file
-	out, err := os.Create(filePath)
-	if err != nil {
-		utils.SendInternalServerError(c, fmt.Errorf("创建文件失败: %v", err))
-		return
-	}
-	defer out.Close()
-
-	if _, err := io.Copy(out, file); err != nil {
+	if err := h.files.Put(c.Request.Context(), key, file, header.Size, storage.ContentTypeFor(ext)); err != nil {
 		utils.SendInternalServerError(c, fmt.Errorf("保存文件失败: %v", err))
 		return
 	}
 
 	// Delete old avatar if exists
 	if user.Avatar != nil && *user.Avatar != "" {
-		oldPath := strings.TrimPrefix(*user.Avatar, "/api/uploads/avatars/")
-		oldFilePath := filepath.Join(uploadsDir, oldPath)
-		os.Remove(oldFilePath) // Ignore error if file doesn't exist
+		h.files.Delete(c.Request.Context(), avatarKey(strings.TrimPrefix(*user.Avatar, avatarURLPrefix))) // Ignore error if file doesn't exist
 	}
 
 	// Update user avatar in database
-	avatarURL := "/api/uploads/avatars/" + newFilename
+	avatarURL := avatarURLPrefix + newFilename
 	user.Avatar = &avatarURL
 	if err := h.db.Save(&user).Error; err != nil {
 		// Clean up uploaded file on error
-		os.Remove(filePath)
+		h.files.Delete(c.Request.Context(), key)
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, user.UUID)
 
 	utils.SendSuccessResponse(c, gin.H{
 		"message": "头像上传成功",
@@ -818,18 +844,26 @@ func (h *UserHandler) GetAvatar(c *gin.Context) {
 		return
 	}
 
-	// Prevent directory traversal
-	filename = filepath.Base(filename)
-	filePath := filepath.Join("./uploads/avatars", filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		utils.SendNotFound(c, "头像文件不存在")
+	// Prevent directory traversal: avatarKey keeps only the base name
+	reader, info, err := h.files.Open(c.Request.Context(), avatarKey(filename))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			utils.SendNotFound(c, "头像文件不存在")
+		} else {
+			utils.SendInternalServerError(c, err)
+		}
 		return
 	}
+	defer reader.Close()
 
-	// Serve the file
-	c.File(filePath)
+	// Serve the file: local files support Range requests, object storage content is streamed
+	contentType := storage.ContentTypeFor(filepath.Ext(filename))
+	c.Header("Content-Type", contentType)
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime, seeker)
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, reader, nil)
 }
 
 // DeleteAvatar removes the user's avatar
@@ -852,9 +886,7 @@ func (h *UserHandler) DeleteAvatar(c *gin.Context) {
 
 	// Delete old avatar file if exists
 	if user.Avatar != nil && *user.Avatar != "" {
-		oldPath := strings.TrimPrefix(*user.Avatar, "/api/uploads/avatars/")
-		oldFilePath := filepath.Join("./uploads/avatars", oldPath)
-		os.Remove(oldFilePath) // Ignore error if file doesn't exist
+		h.files.Delete(c.Request.Context(), avatarKey(strings.TrimPrefix(*user.Avatar, avatarURLPrefix))) // Ignore error if file doesn't exist
 	}
 
 	// Clear avatar in database
@@ -863,6 +895,7 @@ func (h *UserHandler) DeleteAvatar(c *gin.Context) {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	notifyUsersChanged(h.db, user.UUID)
 
 	utils.SendSuccessResponse(c, gin.H{
 		"message": "头像删除成功",
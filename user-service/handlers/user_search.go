@@ -1,29 +1,127 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"credit-management/user-service/models"
 	"credit-management/user-service/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// SearchUsersRequest 用户搜索参数。多值过滤可以重复参数或用逗号分隔（如 class=1班,2班）
 type SearchUsersRequest struct {
-	Page       int    `form:"page" binding:"required,min=1"`
-	PageSize   int    `form:"page_size" binding:"required,min=1,max=100"`
-	Query      string `form:"query"`
-	UserType   string `form:"user_type" binding:"required,oneof=student teacher"`
-	College    string `form:"college"`
-	Major      string `form:"major"`
-	Class      string `form:"class"`
-	Grade      string `form:"grade"`
-	Department string `form:"department"`
-	Title      string `form:"title"`
-	Status     string `form:"status"`
+	Page       int      `form:"page" binding:"omitempty,min=1"`
+	PageSize   int      `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Query      string   `form:"query"`
+	UserType   string   `form:"user_type" binding:"required,oneof=student teacher"`
+	College    []string `form:"college"`
+	Major      []string `form:"major"`
+	Class      []string `form:"class"`
+	Grade      []string `form:"grade"`
+	Department []string `form:"department"`
+	Title      []string `form:"title"`
+	Status     []string `form:"status"`
+
+	// DepartmentID 部门节点，匹配节点整棵子树内的用户
+	DepartmentID []string `form:"department_id"`
+
+	// 学号前缀或闭区间（仅学生）
+	StudentIDPrefix string `form:"student_id_prefix"`
+	StudentIDFrom   string `form:"student_id_from"`
+	StudentIDTo     string `form:"student_id_to"`
+
+	// 时间范围（YYYY-MM-DD，含首尾两天）
+	LastLoginFrom string `form:"last_login_from"`
+	LastLoginTo   string `form:"last_login_to"`
+	CreatedFrom   string `form:"created_from"`
+	CreatedTo     string `form:"created_to"`
+
+	SortBy    string `form:"sort_by"`
+	SortOrder string `form:"sort_order" binding:"omitempty,oneof=asc desc"`
+}
+
+// userSortField 用户搜索的排序字段。可为空的列按 COALESCE 后的值排序，保证游标比较有意义
+type userSortField struct {
+	column    string
+	nullSQL   string
+	nullValue interface{}
+}
+
+func (f userSortField) expr() string {
+	if f.nullSQL == "" {
+		return f.column
+	}
+	return fmt.Sprintf("COALESCE(%s, %s)", f.column, f.nullSQL)
+}
+
+// value 返回记录的排序值，用于生成游标
+func (f userSortField) value(user map[string]interface{}) interface{} {
+	if v := user[f.column]; v != nil {
+		return v
+	}
+	return f.nullValue
+}
+
+var (
+	neverLoggedIn = userSortField{column: "last_login_at", nullSQL: "'epoch'::timestamptz", nullValue: time.Unix(0, 0).UTC()}
+
+	// studentSortFields / teacherSortFields 各视图可用的排序字段
+	studentSortFields = map[string]userSortField{
+		"created_at":    {column: "created_at"},
+		"updated_at":    {column: "updated_at"},
+		"last_login_at": neverLoggedIn,
+		"username":      {column: "username"},
+		"real_name":     {column: "real_name"},
+		"student_id":    {column: "student_id"},
+		"grade":         {column: "grade", nullSQL: "''", nullValue: ""},
+		"college":       {column: "college"},
+		"major":         {column: "major"},
+		"class":         {column: "class"},
+	}
+	teacherSortFields = map[string]userSortField{
+		"created_at":    {column: "created_at"},
+		"updated_at":    {column: "updated_at"},
+		"last_login_at": neverLoggedIn,
+		"username":      {column: "username"},
+		"real_name":     {column: "real_name"},
+		"teacher_id":    {column: "teacher_id"},
+		"department":    {column: "department", nullSQL: "''", nullValue: ""},
+		"title":         {column: "title", nullSQL: "''", nullValue: ""},
+	}
+)
+
+// multiValues 展开多值参数：支持重复参数和逗号分隔，忽略空值
+func multiValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		result = append(result, splitQueryList(value)...)
+	}
+	return result
+}
+
+// whereIn 多值过滤，未指定值时不过滤
+func whereIn(query *gorm.DB, column string, values []string) *gorm.DB {
+	values = multiValues(values)
+	switch len(values) {
+	case 0:
+		return query
+	case 1:
+		return query.Where(column+" = ?", values[0])
+	default:
+		return query.Where(column+" IN ?", values)
+	}
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 func isUUID(str string) bool {
@@ -50,6 +148,12 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 	req.Page = page
 	req.PageSize = pageSize
 
+	pager, err := utils.NewPager(c, page, pageSize)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
+
 	currentUserRole := utils.GetCurrentUserRole(c)
 	if currentUserRole == "" {
 		utils.SendUnauthorized(c)
@@ -76,8 +180,13 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 		return
 	}
 
-	// 构建查询
-	query := h.db.Table(viewName)
+	// 构建查询，教师和部门管理员只能看到自己管理的部门子树内的用户
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+	query := scope.users(h.db.Table(viewName), "uuid")
 
 	// 搜索条件
 	if req.Query != "" {
@@ -90,60 +199,131 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 	}
 
 	// 根据用户类型添加特定过滤条件
+	var sortFields map[string]userSortField
 	switch req.UserType {
 	case "student":
-		if req.College != "" {
-			query = query.Where("college = ?", req.College)
+		sortFields = studentSortFields
+		query = whereIn(query, "college", req.College)
+		query = whereIn(query, "major", req.Major)
+		query = whereIn(query, "class", req.Class)
+		query = whereIn(query, "grade", req.Grade)
+		if utils.IsTeacherOrAdmin(currentUserRole) {
+			query = whereIn(query, "status", req.Status)
 		}
-		if req.Major != "" {
-			query = query.Where("major = ?", req.Major)
+		if req.StudentIDPrefix != "" {
+			query = query.Where("student_id LIKE ?", escapeLike(req.StudentIDPrefix)+"%")
 		}
-		if req.Class != "" {
-			query = query.Where("class = ?", req.Class)
+		if req.StudentIDFrom != "" {
+			query = query.Where("student_id >= ?", req.StudentIDFrom)
 		}
-		if req.Grade != "" {
-			query = query.Where("grade = ?", req.Grade)
-		}
-		if req.Status != "" && utils.IsTeacherOrAdmin(currentUserRole) {
-			query = query.Where("status = ?", req.Status)
+		if req.StudentIDTo != "" {
+			query = query.Where("student_id <= ?", req.StudentIDTo)
 		}
 	case "teacher":
-		if req.Department != "" {
-			query = query.Where("department = ?", req.Department)
+		sortFields = teacherSortFields
+		query = whereIn(query, "department", req.Department)
+		query = whereIn(query, "title", req.Title)
+		if utils.IsAdmin(currentUserRole) {
+			query = whereIn(query, "status", req.Status)
 		}
-		if req.Title != "" {
-			query = query.Where("title = ?", req.Title)
+	}
+
+	// 部门节点：视图只有部门名称，按用户表的 department_id 匹配子树
+	if departmentIDs := multiValues(req.DepartmentID); len(departmentIDs) > 0 {
+		for _, id := range departmentIDs {
+			if !isUUID(id) {
+				utils.SendBadRequest(c, "无效的部门ID: "+id)
+				return
+			}
 		}
-		if req.Status != "" && utils.IsAdmin(currentUserRole) {
-			query = query.Where("status = ?", req.Status)
+		var subtree []string
+		if err := h.db.Raw(subtreeSQL, departmentIDs).Scan(&subtree).Error; err != nil {
+			utils.SendInternalServerError(c, err)
+			return
+		}
+		query = query.Where("uuid IN (SELECT uuid FROM users WHERE department_id IN ?)", subtree)
+	}
+
+	// 时间范围
+	for _, r := range []struct {
+		column, from, to, name string
+	}{
+		{"last_login_at", req.LastLoginFrom, req.LastLoginTo, "last_login"},
+		{"created_at", req.CreatedFrom, req.CreatedTo, "created"},
+	} {
+		if r.from != "" {
+			from, err := time.ParseInLocation("2006-01-02", r.from, time.Local)
+			if err != nil {
+				utils.SendBadRequest(c, r.name+"_from 日期格式错误，应为 YYYY-MM-DD")
+				return
+			}
+			query = query.Where(r.column+" >= ?", from)
+		}
+		if r.to != "" {
+			to, err := time.ParseInLocation("2006-01-02", r.to, time.Local)
+			if err != nil {
+				utils.SendBadRequest(c, r.name+"_to 日期格式错误，应为 YYYY-MM-DD")
+				return
+			}
+			query = query.Where(r.column+" < ?", to.AddDate(0, 0, 1))
 		}
 	}
 
-	var total int64
-	query.Count(&total)
+	// 排序字段，默认按创建时间倒序
+	if req.SortBy == "" {
+		req.SortBy = "created_at"
+	}
+	sortField, ok := sortFields[req.SortBy]
+	if !ok {
+		utils.SendBadRequest(c, "不支持的排序字段: "+req.SortBy)
+		return
+	}
 
-	// 获取用户列表
-	offset := (req.Page - 1) * req.PageSize
+	total, err := pager.Count(query)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	// 获取用户列表，排序值相同时按 uuid 排序保证翻页稳定
+	key := utils.SortKey{Column: sortField.expr(), IDColumn: "uuid", Desc: req.SortOrder != "asc"}
+	query, err = pager.Apply(query, key)
+	if err != nil {
+		utils.SendBadRequest(c, err.Error())
+		return
+	}
 	var users []map[string]interface{}
-	if err := query.Offset(offset).Limit(req.PageSize).Find(&users).Error; err != nil {
+	if err := query.Find(&users).Error; err != nil {
 		utils.SendInternalServerError(c, err)
 		return
 	}
+	users = utils.PageRows(pager, users, key, func(user map[string]interface{}) (interface{}, string) {
+		return sortField.value(user), fmt.Sprint(user[key.IDColumn])
+	})
 
 	// 移除列表中每个用户记录的敏感字段（如密码哈希）
 	for _, u := range users {
 		sanitizeUserResult(u)
 	}
 
-	totalPages := (total + int64(req.PageSize) - 1) / int64(req.PageSize)
+	var totalPages int64
+	if total > 0 {
+		totalPages = (total + int64(req.PageSize) - 1) / int64(req.PageSize)
+	}
 
 	response := models.ViewBasedSearchResponse{
-		Users:      users,
-		Total:      total,
-		Page:       req.Page,
-		PageSize:   req.PageSize,
-		TotalPages: int(totalPages),
-		ViewType:   viewName,
+		Users:          users,
+		Total:          total,
+		Page:           req.Page,
+		PageSize:       req.PageSize,
+		TotalPages:     int(totalPages),
+		ViewType:       viewName,
+		TotalEstimated: pager.Estimated(),
+	}
+	if pager.CursorMode() {
+		hasMore := pager.HasMore()
+		response.NextCursor = pager.NextCursor()
+		response.HasMore = &hasMore
 	}
 
 	utils.SendSuccessResponse(c, response)
@@ -152,26 +332,32 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 func (h *UserHandler) GetUserStats(c *gin.Context) {
 	var stats models.UserStats
 
-	h.db.Model(&models.User{}).Count(&stats.TotalUsers)
-	h.db.Model(&models.User{}).Where("status = ?", "active").Count(&stats.ActiveUsers)
-	h.db.Model(&models.User{}).Where("status = ?", "suspended").Count(&stats.SuspendedUsers)
-	h.db.Model(&models.User{}).Where("user_type = ?", "student").Count(&stats.StudentUsers)
-	h.db.Model(&models.User{}).Where("user_type = ?", "teacher").Count(&stats.TeacherUsers)
-	h.db.Model(&models.User{}).Where("user_type = ?", "admin").Count(&stats.AdminUsers)
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	scope.users(h.db.Model(&models.User{}), "uuid").Count(&stats.TotalUsers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("status = ?", "active").Count(&stats.ActiveUsers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("status = ?", "suspended").Count(&stats.SuspendedUsers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ?", "student").Count(&stats.StudentUsers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ?", "teacher").Count(&stats.TeacherUsers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ?", "admin").Count(&stats.AdminUsers)
 
 	today := time.Now().Truncate(24 * time.Hour)
-	h.db.Model(&models.User{}).Where("created_at >= ?", today).Count(&stats.NewUsersToday)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("created_at >= ?", today).Count(&stats.NewUsersToday)
 
 	weekStart := time.Now().Truncate(24*time.Hour).AddDate(0, 0, -int(time.Now().Weekday()))
-	h.db.Model(&models.User{}).Where("created_at >= ?", weekStart).Count(&stats.NewUsersWeek)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("created_at >= ?", weekStart).Count(&stats.NewUsersWeek)
 
 	monthStart := time.Now().Truncate(24*time.Hour).AddDate(0, 0, -time.Now().Day()+1)
-	h.db.Model(&models.User{}).Where("created_at >= ?", monthStart).Count(&stats.NewUsersMonth)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("created_at >= ?", monthStart).Count(&stats.NewUsersMonth)
 
 	// 计算上个月的新用户数（用于计算增长率）
 	lastMonthStart := monthStart.AddDate(0, -1, 0)
 	lastMonthEnd := monthStart
-	h.db.Model(&models.User{}).Where("created_at >= ? AND created_at < ?", lastMonthStart, lastMonthEnd).Count(&stats.NewUsersLastMonth)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("created_at >= ? AND created_at < ?", lastMonthStart, lastMonthEnd).Count(&stats.NewUsersLastMonth)
 
 	utils.SendSuccessResponse(c, stats)
 }
@@ -179,16 +365,22 @@ func (h *UserHandler) GetUserStats(c *gin.Context) {
 func (h *UserHandler) GetStudentStats(c *gin.Context) {
 	var stats models.StudentStats
 
-	h.db.Model(&models.User{}).Where("user_type = ?", "student").Count(&stats.TotalStudents)
-	h.db.Model(&models.User{}).Where("user_type = ? AND status = ?", "student", "active").Count(&stats.ActiveStudents)
-	h.db.Model(&models.User{}).Where("user_type = ? AND status = ?", "student", "inactive").Count(&stats.GraduatedStudents)
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ?", "student").Count(&stats.TotalStudents)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ? AND status = ?", "student", "active").Count(&stats.ActiveStudents)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ? AND status = ?", "student", "graduated").Count(&stats.GraduatedStudents)
 
 	stats.StudentsByCollege = make(map[string]int64)
 	var collegeStats []struct {
 		College string
 		Count   int64
 	}
-	h.db.Table("student_complete_info").
+	scope.users(h.db.Table("student_complete_info"), "uuid").
 		Select("college, count(*) as count").
 		Group("college").
 		Find(&collegeStats)
@@ -202,7 +394,7 @@ func (h *UserHandler) GetStudentStats(c *gin.Context) {
 		Major string
 		Count int64
 	}
-	h.db.Table("student_complete_info").
+	scope.users(h.db.Table("student_complete_info"), "uuid").
 		Select("major, count(*) as count").
 		Group("major").
 		Find(&majorStats)
@@ -216,7 +408,7 @@ func (h *UserHandler) GetStudentStats(c *gin.Context) {
 		Grade string
 		Count int64
 	}
-	h.db.Model(&models.User{}).
+	scope.users(h.db.Model(&models.User{}), "uuid").
 		Select("grade, count(*) as count").
 		Where("user_type = ? AND grade IS NOT NULL", "student").
 		Group("grade").
@@ -232,18 +424,24 @@ func (h *UserHandler) GetStudentStats(c *gin.Context) {
 func (h *UserHandler) GetTeacherStats(c *gin.Context) {
 	var stats models.TeacherStats
 
-	h.db.Model(&models.User{}).Where("user_type = ?", "teacher").Count(&stats.TotalTeachers)
+	scope, err := h.currentDataScope(c)
+	if err != nil {
+		utils.SendInternalServerError(c, err)
+		return
+	}
+
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ?", "teacher").Count(&stats.TotalTeachers)
 
-	h.db.Model(&models.User{}).Where("user_type = ? AND status = ?", "teacher", "active").Count(&stats.ActiveTeachers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ? AND status = ?", "teacher", "active").Count(&stats.ActiveTeachers)
 
-	h.db.Model(&models.User{}).Where("user_type = ? AND status = ?", "teacher", "inactive").Count(&stats.RetiredTeachers)
+	scope.users(h.db.Model(&models.User{}), "uuid").Where("user_type = ? AND status = ?", "teacher", "inactive").Count(&stats.RetiredTeachers)
 
 	stats.TeachersByDepartment = make(map[string]int64)
 	var deptStats []struct {
 		Department string
 		Count      int64
 	}
-	h.db.Table("teacher_complete_info").
+	scope.users(h.db.Table("teacher_complete_info"), "uuid").
 		Select("department, count(*) as count").
 		Group("department").
 		Find(&deptStats)
@@ -257,7 +455,7 @@ func (h *UserHandler) GetTeacherStats(c *gin.Context) {
 		Title string
 		Count int64
 	}
-	h.db.Model(&models.User{}).
+	scope.users(h.db.Model(&models.User{}), "uuid").
 		Select("title, count(*) as count").
 		Where("user_type = ? AND title IS NOT NULL", "teacher").
 		Group("title").
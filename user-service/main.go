@@ -1,19 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	_ "github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"credit-management/shared/audit"
+	"credit-management/shared/imports"
+	"credit-management/shared/realtime"
 	"credit-management/user-service/handlers"
+	"credit-management/user-service/migrations"
 	// "credit-management/user-service/middleware"
+	"credit-management/shared/storage"
 	"credit-management/user-service/routers"
 )
 
@@ -59,20 +67,66 @@ func main() {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
 
+	// storage 子命令：把本地目录中的头像复制到配置的对象存储后退出（./main storage migrate [--dry-run] [--delete-source]）
+	if len(os.Args) > 1 && os.Args[1] == "storage" {
+		if err := storage.Command(context.Background(), storageConfig(), "avatars/", os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("头像迁移失败: ", err)
+		}
+		return
+	}
+
 	log.Println("正在连接数据库...")
 	db, err := connectDatabase(dsn)
 	if err != nil {
 		log.Fatal("数据库连接失败:", err)
 	}
 
+	// migrate 子命令：执行数据库迁移后退出（./main migrate up | down [N] | status）
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.Command(db, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("数据库迁移失败: ", err)
+		}
+		return
+	}
+
+	// 数据库结构版本与程序要求不一致时拒绝启动
+	if err := migrations.CheckVersion(db); err != nil {
+		log.Fatal("数据库结构版本检查失败: ", err)
+	}
+
 	// 根据配置初始化 departments（学部 / 专业 / 班级）数据
 	if err := handlers.InitDepartments(db); err != nil {
 		log.Printf("初始化部门数据失败: %v", err)
 	}
 
-	userHandler := handlers.NewUserHandler(db)
+	// 初始化默认管理员（users 表由用户服务负责，认证服务不再直接写入）
+	if err := handlers.InitializeAdminUser(db); err != nil {
+		log.Fatal("初始化管理员用户失败:", err)
+	}
+	if err := handlers.InitializeDemoUsers(db); err != nil {
+		log.Fatal("初始化演示账号失败:", err)
+	}
+
+	// 头像存储：默认本地目录；多副本部署时使用 S3 兼容的对象存储
+	files, err := storage.New(storageConfig())
+	if err != nil {
+		log.Fatal("初始化头像存储失败: ", err)
+	}
+
+	userHandler := handlers.NewUserHandler(db, files)
+
+	// 实时推送：导入进度通过 Redis 发布给网关的 /api/events/stream
+	if getEnv("REALTIME_PUSH_ENABLED", "false") == "true" {
+		realtime.SetPublisher(realtime.NewRedisPublisher(redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", getEnv("REDIS_HOST", "localhost"), getEnv("REDIS_PORT", "6379")),
+			Password: getEnv("REDIS_PASSWORD", ""),
+		})))
+	}
+
+	// 异步导入任务：服务重启后从已提交的进度继续
+	startImportRunner(userHandler)
 
-	r := routers.RegisterRouters(userHandler)
+	r := routers.RegisterRouters(userHandler, audit.NewLogger(db, "user-service"))
 
 	port := getEnv("PORT", "8084")
 	log.Printf("用户服务启动，监听端口：%s", port)
@@ -81,6 +135,36 @@ func main() {
 	}
 }
 
+// startImportRunner 启动导入任务执行协程
+func startImportRunner(userHandler *handlers.UserHandler) {
+	interval, err := strconv.Atoi(getEnv("IMPORT_POLL_SECONDS", "3"))
+	if err != nil || interval <= 0 {
+		interval = 3
+	}
+	batchSize, err := strconv.Atoi(getEnv("IMPORT_BATCH_SIZE", strconv.Itoa(imports.DefaultBatchSize)))
+	if err != nil || batchSize <= 0 {
+		batchSize = imports.DefaultBatchSize
+	}
+	userHandler.NewImportRunner(time.Duration(interval)*time.Second, batchSize).Start(context.Background())
+}
+
+// storageConfig 头像存储配置：STORAGE_BACKEND=local 时保存在 STORAGE_LOCAL_DIR，s3 时保存在 S3 兼容的对象存储
+func storageConfig() storage.Config {
+	return storage.Config{
+		Backend:  getEnv("STORAGE_BACKEND", storage.BackendLocal),
+		LocalDir: getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		S3: storage.S3Config{
+			Endpoint:       getEnv("S3_ENDPOINT", ""),
+			PublicEndpoint: getEnv("S3_PUBLIC_ENDPOINT", ""),
+			Region:         getEnv("S3_REGION", "us-east-1"),
+			Bucket:         getEnv("S3_BUCKET", ""),
+			AccessKey:      getEnv("S3_ACCESS_KEY", ""),
+			SecretKey:      getEnv("S3_SECRET_KEY", ""),
+			Prefix:         getEnv("S3_PREFIX", ""),
+		},
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -1,33 +1,70 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 
+	"credit-management/shared/servicetoken"
+	"credit-management/user-service/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-type HeaderAuthMiddleware struct{}
+// HeaderAuthMiddleware 基于服务令牌的认证中间件：请求必须携带认证服务签发的 X-Service-Token，
+// 只有网关的请求才采信其传递的 X-User-* 用户信息，其他内部服务以系统身份调用
+type HeaderAuthMiddleware struct {
+	verifier *servicetoken.Verifier
+}
 
 func NewHeaderAuthMiddleware() *HeaderAuthMiddleware {
-	return &HeaderAuthMiddleware{}
+	verifier, err := utils.DefaultServiceVerifier()
+	if err != nil {
+		log.Fatal("服务令牌校验器初始化失败: ", err)
+	}
+	return &HeaderAuthMiddleware{verifier: verifier}
+}
+
+// verifyService 校验服务令牌，返回调用方服务名；失败时已写入响应
+func (m *HeaderAuthMiddleware) verifyService(c *gin.Context) (string, bool) {
+	token := c.GetHeader(utils.ServiceTokenHeader)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "缺少服务凭证", "data": nil})
+		c.Abort()
+		return "", false
+	}
+	service, err := m.verifier.Verify(c.Request.Context(), token)
+	if err != nil {
+		log.Printf("服务令牌校验失败: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "服务凭证无效", "data": nil})
+		c.Abort()
+		return "", false
+	}
+	c.Set("service", service)
+	return service, true
 }
 
-// AuthRequired 认证中间件（从请求头获取用户信息）
+// setSystemIdentity 内部服务调用以系统管理员身份执行
+func setSystemIdentity(c *gin.Context) {
+	c.Set("id", "system")
+	c.Set("username", "system")
+	c.Set("user_type", "admin")
+	c.Set("claims", jwt.MapClaims{
+		"id":        "system",
+		"username":  "system",
+		"user_type": "admin",
+	})
+}
+
+// AuthRequired 认证中间件（用户信息来自网关传递的请求头，网关身份由服务令牌证明）
 func (m *HeaderAuthMiddleware) AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 检查是否为内部服务通信
-		internalService := c.GetHeader("X-Internal-Service")
-		if internalService != "" {
-			// 内部服务通信，设置系统用户信息
-			c.Set("id", "system")
-			c.Set("username", "system")
-			c.Set("user_type", "admin")
-			c.Set("claims", jwt.MapClaims{
-				"id":        "system",
-				"username":  "system",
-				"user_type": "admin",
-			})
+		service, ok := m.verifyService(c)
+		if !ok {
+			return
+		}
+		if service != utils.GatewayService {
+			setSystemIdentity(c)
 			c.Next()
 			return
 		}
@@ -60,6 +97,17 @@ func (m *HeaderAuthMiddleware) AuthRequired() gin.HandlerFunc {
 	}
 }
 
+// InternalOnly 仅允许内部服务调用（网关不转发 /api/internal，且须持有有效的服务令牌）
+func (m *HeaderAuthMiddleware) InternalOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := m.verifyService(c); !ok {
+			return
+		}
+		setSystemIdentity(c)
+		c.Next()
+	}
+}
+
 type PermissionMiddleware struct{}
 
 func NewPermissionMiddleware() *PermissionMiddleware {
@@ -0,0 +1,15 @@
+package middleware
+
+import "strings"
+
+// TrustedProxies 受信任的反向代理（TRUSTED_PROXIES，逗号分隔的 IP 或 CIDR）。
+// 只有来自这些地址的请求才采信 X-Forwarded-For / X-Real-IP，未配置时不采信任何代理
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,17 @@
+DROP VIEW IF EXISTS teacher_complete_info;
+DROP VIEW IF EXISTS teacher_detail_info;
+DROP VIEW IF EXISTS teacher_basic_info;
+DROP VIEW IF EXISTS student_complete_info;
+DROP VIEW IF EXISTS student_detail_info;
+DROP VIEW IF EXISTS student_basic_info;
+
+DROP TABLE IF EXISTS import_job_errors;
+DROP TABLE IF EXISTS import_jobs;
+DROP TABLE IF EXISTS user_notification_channels;
+DROP TABLE IF EXISTS department_scopes;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS departments;
+
+DROP TYPE IF EXISTS dept_type_enum;
+DROP TYPE IF EXISTS user_status_enum;
+DROP TYPE IF EXISTS user_type_enum;
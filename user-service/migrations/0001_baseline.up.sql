@@ -0,0 +1,329 @@
+-- 用户服务基线结构：部门、用户、数据范围、外部通知渠道、导入任务（各服务共用）及学生 / 教师信息视图。
+-- 与原 database/init.sql 一致，全部使用 IF NOT EXISTS，由旧版 init.sql 创建的数据库执行本迁移即可纳入版本管理
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- 枚举：用户身份
+DO
+$$
+    BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'user_type_enum') THEN
+            CREATE TYPE user_type_enum AS ENUM ('student', 'teacher', 'admin');
+        END IF;
+    END
+$$;
+-- 枚举：账号状态
+DO
+$$
+    BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'user_status_enum') THEN
+            CREATE TYPE user_status_enum AS ENUM ('active', 'inactive', 'suspended', 'graduated');
+        END IF;
+    END
+$$;
+-- 旧版 init.sql 创建的枚举缺少 graduated（学年结转后的毕业状态）
+ALTER TYPE user_status_enum ADD VALUE IF NOT EXISTS 'graduated';
+-- 枚举：部门类型
+DO
+$$
+    BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'dept_type_enum') THEN
+            CREATE TYPE dept_type_enum AS ENUM ('school','faculty', 'college', 'major', 'class', 'office', 'others');
+        END IF;
+    END
+$$;
+
+-- 创建部门表
+CREATE TABLE IF NOT EXISTS departments
+(
+    id         UUID PRIMARY KEY        DEFAULT gen_random_uuid(),
+    name       VARCHAR(100)   NOT NULL,
+    code       VARCHAR(20) UNIQUE,
+    dept_type  dept_type_enum NOT NULL DEFAULT 'others',
+    level      INT            NOT NULL DEFAULT 0,
+    parent_id  UUID           REFERENCES departments (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    archived_at TIMESTAMPTZ,                -- 班级随学年结转归档的时间
+    created_at TIMESTAMPTZ    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
+);
+-- 旧版 init.sql 创建的部门表缺少归档时间
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+-- 创建用户表（统一用户、学生、教师信息）
+CREATE TABLE IF NOT EXISTS users
+(
+    uuid         UUID PRIMARY KEY             DEFAULT gen_random_uuid(),
+    student_id   VARCHAR(18) UNIQUE,
+    teacher_id   VARCHAR(18) UNIQUE,
+    username     VARCHAR(20) UNIQUE           NOT NULL,
+    password     TEXT                         NOT NULL,
+    email        VARCHAR(100) UNIQUE          NOT NULL CHECK (email ~ '^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$'),
+    phone        VARCHAR(11) UNIQUE CHECK (phone IS NULL OR phone ~ '^1[3-9]\d{9}$'),
+    real_name    VARCHAR(50)                  NOT NULL,
+    user_type    user_type_enum               NOT NULL DEFAULT 'student',
+    status       user_status_enum             NOT NULL DEFAULT 'active',
+    avatar       TEXT,
+    department_id UUID                        REFERENCES departments (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    last_login_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ                  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMPTZ                  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at   TIMESTAMPTZ,
+    grade        VARCHAR(4),
+    title        VARCHAR(50),
+    CONSTRAINT ck_user_identity_consistency CHECK (
+            (user_type = 'student' AND student_id IS NOT NULL AND teacher_id IS NULL)
+        OR  (user_type = 'teacher' AND teacher_id IS NOT NULL AND student_id IS NULL)
+        OR  (user_type = 'admin'   AND student_id IS NULL     AND teacher_id IS NULL)
+    )
+);
+
+-- 创建数据范围绑定表（教师 / 部门管理员管理的部门节点，可见范围为这些节点的整棵子树）
+CREATE TABLE IF NOT EXISTS department_scopes
+(
+    user_id       UUID        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    department_id UUID        NOT NULL REFERENCES departments (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_by    UUID,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, department_id)
+);
+
+-- 创建用户外部通知渠道表（邮件 / webhook / 企业微信 / 钉钉，每个用户每个渠道一条）
+CREATE TABLE IF NOT EXISTS user_notification_channels
+(
+    user_id    UUID        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    channel    VARCHAR(20) NOT NULL CHECK (channel IN ('email', 'webhook', 'wecom', 'dingtalk')),
+    enabled    BOOLEAN     NOT NULL DEFAULT TRUE,
+    target     VARCHAR(500),          -- 邮箱或机器人 webhook 地址，邮件渠道为空时使用账号邮箱
+    secret     VARCHAR(200),          -- 钉钉加签密钥等
+    digest     BOOLEAN     NOT NULL DEFAULT FALSE, -- 待审核提醒改为每日汇总
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, channel)
+);
+
+-- 创建异步导入任务表（各服务共用，按 service 区分；rows 保存上传时解析的数据行，processed_rows 为处理游标）
+CREATE TABLE IF NOT EXISTS import_jobs
+(
+    id             UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    service        VARCHAR(50) NOT NULL,
+    kind           VARCHAR(50) NOT NULL,
+    created_by     VARCHAR(64) NOT NULL,
+    file_name      VARCHAR(255),
+    options        JSONB,
+    header         JSONB,
+    rows           JSONB,
+    status         VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
+    total_rows     INTEGER     NOT NULL DEFAULT 0,
+    processed_rows INTEGER     NOT NULL DEFAULT 0,
+    succeeded_rows INTEGER     NOT NULL DEFAULT 0,
+    failed_rows    INTEGER     NOT NULL DEFAULT 0,
+    last_error     TEXT,
+    heartbeat_at   TIMESTAMPTZ,
+    started_at     TIMESTAMPTZ,
+    finished_at    TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建导入失败行表（错误报告）
+CREATE TABLE IF NOT EXISTS import_job_errors
+(
+    id         BIGSERIAL PRIMARY KEY,
+    job_id     UUID        NOT NULL REFERENCES import_jobs (id) ON DELETE CASCADE,
+    row_number INTEGER     NOT NULL,
+    message    TEXT        NOT NULL,
+    record     JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- =========================
+-- 用户表索引（users）
+-- =========================
+-- 单列
+CREATE INDEX IF NOT EXISTS idx_users_username ON users (username); -- 按用户名登录/查询
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email); -- 邮箱登录
+CREATE INDEX IF NOT EXISTS idx_users_user_type ON users (user_type); -- 按身份过滤（student/teacher/admin）
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status); -- 按账号状态过滤（active/inactive/suspended/graduated）
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at); -- 软删除过滤
+CREATE INDEX IF NOT EXISTS idx_users_type_status ON users (user_type, status);
+-- 身份+状态复合过滤
+
+-- 复合/专用
+CREATE INDEX IF NOT EXISTS idx_users_status_type_username ON users (status, user_type, username); -- 状态+身份+用户名（后台列表/搜索）
+CREATE INDEX IF NOT EXISTS idx_users_student_id ON users (student_id); -- 学号精确查找
+CREATE INDEX IF NOT EXISTS idx_users_student_id_pattern ON users (student_id varchar_pattern_ops); -- 学号前缀搜索（LIKE '2023%'）
+CREATE INDEX IF NOT EXISTS idx_users_teacher_id ON users (teacher_id); -- 工号精确查找
+CREATE INDEX IF NOT EXISTS idx_users_phone ON users (phone); -- 手机号登录
+CREATE INDEX IF NOT EXISTS idx_users_department_id ON users (department_id); -- 按学部/专业/班级查人
+CREATE INDEX IF NOT EXISTS idx_users_grade ON users (grade) WHERE grade IS NOT NULL;-- 按年级查学生
+CREATE INDEX IF NOT EXISTS idx_users_last_login_at ON users (last_login_at DESC); -- 最近登录排序
+CREATE INDEX IF NOT EXISTS idx_users_active ON users (deleted_at) WHERE deleted_at IS NULL;
+-- 仅活跃用户
+
+-- 部门表索引
+-- 按 parent_id 找子部门（树形）：
+CREATE INDEX IF NOT EXISTS idx_departments_parent_id ON departments (parent_id);
+-- 按 code 精确查（如学部代码）：
+CREATE INDEX IF NOT EXISTS idx_departments_code ON departments (code);
+-- 按 dept_type 过滤（查所有学部/所有班级）：
+CREATE INDEX IF NOT EXISTS idx_departments_type ON departments (dept_type);
+-- 按层级排序：
+CREATE INDEX IF NOT EXISTS idx_departments_level ON departments (level);
+-- 软删除过滤：
+CREATE INDEX IF NOT EXISTS idx_departments_deleted_at ON departments (deleted_at)
+    WHERE deleted_at IS NULL;
+
+-- 数据范围绑定表：按部门反查绑定的用户
+CREATE INDEX IF NOT EXISTS idx_department_scopes_department_id ON department_scopes (department_id);
+
+-- 导入任务表索引
+CREATE INDEX IF NOT EXISTS idx_import_jobs_service ON import_jobs (service);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_created_by ON import_jobs (created_by);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs (status);
+CREATE INDEX IF NOT EXISTS idx_import_job_errors_job_id ON import_job_errors (job_id);
+
+-- 学生基本信息视图
+CREATE OR REPLACE VIEW student_basic_info AS
+SELECT u.uuid,
+       u.username,
+       u.real_name,
+       u.student_id     AS student_id,
+       college.name      AS college, -- 学部
+       major.name        AS major,   -- 专业
+       class.name        AS class,   -- 班级
+       u.grade,
+       u.avatar
+FROM users u
+-- 1. 先拿到班级
+         JOIN departments class
+              ON class.id = u.department_id
+                  AND class.dept_type = 'class'
+-- 2. 再拿专业
+         JOIN departments major
+              ON major.id = class.parent_id
+                  AND major.dept_type = 'major'
+-- 3. 最后拿学部
+         JOIN departments college
+              ON college.id = major.parent_id
+                  AND college.dept_type = 'college'
+WHERE u.user_type = 'student'
+  AND u.deleted_at IS NULL;
+
+-- 学生详细信息视图
+CREATE OR REPLACE VIEW student_detail_info AS
+SELECT u.uuid,
+       u.username,
+       u.real_name,
+       u.email,
+       u.phone,
+       u.student_id     AS student_id, -- 学号
+       college.name      AS college,    -- 学部
+       major.name        AS major,      -- 专业
+       class.name        AS class,      -- 班级
+       u.grade,
+       u.status,
+       u.avatar,
+       u.last_login_at
+FROM users AS u
+-- 1. 班级
+         JOIN departments AS class
+              ON class.id = u.department_id
+                  AND class.dept_type = 'class'
+-- 2. 专业
+         JOIN departments AS major
+              ON major.id = class.parent_id
+                  AND major.dept_type = 'major'
+-- 3. 学部
+         JOIN departments AS college
+              ON college.id = major.parent_id
+                  AND college.dept_type = 'college'
+WHERE u.user_type = 'student'
+  AND u.deleted_at IS NULL;
+
+-- 学生完整信息视图
+CREATE OR REPLACE VIEW student_complete_info AS
+SELECT u.uuid,
+       u.username,
+       u.email,
+       u.phone,
+       u.real_name,
+       u.user_type,
+       u.status,
+       u.avatar,
+       u.last_login_at,
+       u.created_at,
+       u.updated_at,
+       u.student_id     AS student_id, -- 学号
+       college.name      AS college,    -- 学部
+       major.name        AS major,      -- 专业
+       class.name        AS class,      -- 班级
+       u.grade
+FROM users AS u
+-- 1. 班级
+         JOIN departments AS class
+              ON class.id = u.department_id
+                  AND class.dept_type = 'class'
+-- 2. 专业
+         JOIN departments AS major
+              ON major.id = class.parent_id
+                  AND major.dept_type = 'major'
+-- 3. 学部
+         JOIN departments AS college
+              ON college.id = major.parent_id
+                  AND college.dept_type = 'college'
+WHERE u.user_type = 'student'
+  AND u.deleted_at IS NULL;
+
+-- 教师基本信息视图
+CREATE OR REPLACE VIEW teacher_basic_info AS
+SELECT u.uuid,
+       u.teacher_id     AS teacher_id,
+       u.username,
+       u.real_name,
+       d.name            AS department,
+       u.title,
+       u.avatar
+FROM users u
+         LEFT JOIN departments d ON d.id = u.department_id
+WHERE u.user_type = 'teacher'
+  AND u.deleted_at IS NULL;
+
+-- 教师详细信息视图
+CREATE OR REPLACE VIEW teacher_detail_info AS
+SELECT u.uuid,
+       u.teacher_id     AS teacher_id,
+       u.username,
+       u.real_name,
+       u.email,
+       u.phone,
+       d.name            AS department,
+       u.title,
+       u.status,
+       u.avatar,
+       u.last_login_at
+FROM users u
+         LEFT JOIN departments d ON d.id = u.department_id
+WHERE u.user_type = 'teacher'
+  AND u.deleted_at IS NULL;
+
+-- 教师完整信息视图
+CREATE OR REPLACE VIEW teacher_complete_info AS
+SELECT u.uuid,
+       u.teacher_id     AS teacher_id,
+       u.username,
+       u.email,
+       u.phone,
+       u.real_name,
+       u.user_type,
+       u.status,
+       u.avatar,
+       u.last_login_at,
+       u.created_at,
+       u.updated_at,
+       d.name            AS department,
+       u.title
+FROM users u
+         LEFT JOIN departments d ON d.id = u.department_id
+WHERE u.user_type = 'teacher'
+  AND u.deleted_at IS NULL;
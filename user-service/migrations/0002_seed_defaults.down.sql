@@ -0,0 +1,5 @@
+-- 删除初始组织结构；已被其他数据引用的部门不会删除
+DELETE FROM departments d
+WHERE d.code IN ('2024222', 'SE', 'CS', 'SUEP')
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.department_id = d.id)
+  AND NOT EXISTS (SELECT 1 FROM departments c WHERE c.parent_id = d.id AND c.code NOT IN ('2024222', 'SE', 'CS', 'SUEP'));
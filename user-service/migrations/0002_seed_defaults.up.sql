@@ -0,0 +1,41 @@
+-- 初始组织结构，已存在同名记录时跳过。
+-- 迁移不创建账号：管理员由服务启动时创建（密码取 ADMIN_DEFAULT_PASSWORD，未设置时随机生成），
+-- 演示用的教师 / 学生账号只在开发环境（SEED_DEMO_USERS=true）创建，见 handlers.InitializeDemoUsers
+
+-- 上海电力大学
+INSERT INTO departments (id, name, code, dept_type, parent_id)
+VALUES (gen_random_uuid(), '上海电力大学', 'SUEP', 'school', NULL)
+ON CONFLICT DO NOTHING;
+
+-- 计算机科学与技术学部（挂在 上海电力大学 下）
+WITH parent AS (SELECT id
+                FROM departments
+                WHERE name = '上海电力大学'
+                  AND dept_type = 'school')
+INSERT
+INTO departments (id, name, code, dept_type, parent_id)
+SELECT gen_random_uuid(), '计算机科学与技术学部', 'CS', 'college', parent.id
+FROM parent
+ON CONFLICT DO NOTHING;
+
+-- 软件工程专业（挂在 计算机科学与技术学部 下）
+WITH parent AS (SELECT id
+                FROM departments
+                WHERE name = '计算机科学与技术学部'
+                  AND dept_type = 'college')
+INSERT
+INTO departments (id, name, code, dept_type, parent_id)
+SELECT gen_random_uuid(), '软件工程', 'SE', 'major', parent.id
+FROM parent
+ON CONFLICT DO NOTHING;
+
+-- 2024222（挂在 软件工程 下）
+WITH parent AS (SELECT id
+                FROM departments
+                WHERE name = '软件工程'
+                  AND dept_type = 'major')
+INSERT
+INTO departments (id, name, code, dept_type, parent_id)
+SELECT gen_random_uuid(), '2024222', '2024222', 'class', parent.id
+FROM parent
+ON CONFLICT DO NOTHING;
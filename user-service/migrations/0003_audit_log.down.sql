@@ -0,0 +1,2 @@
+-- 审计日志只追加，回滚时保留该表和其中的记录（DROP 会绕过禁止删除的触发器，抹掉各服务的审计记录）
+SELECT 1;
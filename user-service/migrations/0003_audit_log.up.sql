@@ -0,0 +1,44 @@
+-- 审计日志表（认证服务、用户服务、学分活动服务共用，只由本迁移创建）。
+-- 全部使用 IF NOT EXISTS，已由旧版 init.sql 创建该表的数据库执行本迁移即可纳入版本管理
+
+-- 创建审计日志表（各服务共用，只追加；actor_id 可能是 system 等非用户标识，因此不设外键）
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id            BIGSERIAL PRIMARY KEY,
+    service       VARCHAR(50)  NOT NULL,
+    actor_id      VARCHAR(64),
+    actor_type    VARCHAR(20),
+    action        VARCHAR(200) NOT NULL,
+    resource_type VARCHAR(50),
+    resource_id   VARCHAR(64),
+    method        VARCHAR(10),
+    path          VARCHAR(500),
+    status_code   INTEGER,
+    before        JSONB,
+    after         JSONB,
+    changes       JSONB,
+    ip            VARCHAR(64),
+    request_id    VARCHAR(64),
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 审计日志禁止修改和删除
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_log_append_only ON audit_log;
+CREATE TRIGGER trg_audit_log_append_only
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log (resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_request_id ON audit_log (request_id);
@@ -0,0 +1,32 @@
+// Package migrations 用户服务负责的数据库结构的版本化迁移。
+//
+// 迁移脚本按 <版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql 命名，编译时嵌入二进制，
+// 由共享模块的 migrate 包执行（版本检查、加锁、schema_migrations 记录等逻辑各服务一致）。
+package migrations
+
+import (
+	"embed"
+	"io"
+
+	"credit-management/shared/migrate"
+
+	"gorm.io/gorm"
+)
+
+// Service schema_migrations 中本服务的标识
+const Service = "user-service"
+
+//go:embed *.sql
+var scripts embed.FS
+
+var runner = migrate.New(Service, scripts)
+
+// CheckVersion 服务启动时检查数据库结构版本与程序要求的版本一致，不一致时拒绝启动
+func CheckVersion(db *gorm.DB) error {
+	return runner.CheckVersion(db)
+}
+
+// Command 执行 migrate 子命令：up 执行全部未执行的迁移，down 回滚最近 N 个（默认 1 个），status 查看状态
+func Command(db *gorm.DB, args []string, out io.Writer) error {
+	return runner.Command(db, args, out)
+}
@@ -0,0 +1,6 @@
+package models
+
+import "credit-management/shared/audit"
+
+// AuditLog 审计日志（只追加，数据库触发器禁止修改和删除），各服务共用同一张表，定义在共享模块中
+type AuditLog = audit.Log
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// CredentialsRequest 登录凭据校验请求（认证服务调用），用户名、学号、工号三选一
+type CredentialsRequest struct {
+	Username  string `json:"username"`
+	StudentID string `json:"student_id"`
+	TeacherID string `json:"teacher_id"`
+	Password  string `json:"password" binding:"required"`
+}
+
+// CredentialsResponse 凭据校验结果。用户不存在时 User 为空；密码错误时 Valid 为 false 但仍返回用户，供认证服务记录审计
+type CredentialsResponse struct {
+	Valid bool          `json:"valid"`
+	User  *UserIdentity `json:"user"`
+}
+
+// UserIdentity 认证服务签发和校验令牌所需的用户信息，不含密码
+type UserIdentity struct {
+	UUID        string     `json:"uuid"`
+	StudentID   *string    `json:"student_id,omitempty"`
+	TeacherID   *string    `json:"teacher_id,omitempty"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	Phone       *string    `json:"phone"`
+	RealName    string     `json:"real_name"`
+	UserType    string     `json:"user_type"`
+	Status      string     `json:"status"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// NewUserIdentity 从用户记录构造认证用的用户信息
+func NewUserIdentity(user User) *UserIdentity {
+	return &UserIdentity{
+		UUID:        user.UUID,
+		StudentID:   user.StudentID,
+		TeacherID:   user.TeacherID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Phone:       user.Phone,
+		RealName:    user.RealName,
+		UserType:    user.UserType,
+		Status:      user.Status,
+		LastLoginAt: user.LastLoginAt,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 部门类型，对应数据库 dept_type_enum
+const (
+	DeptTypeSchool  = "school"
+	DeptTypeFaculty = "faculty"
+	DeptTypeCollege = "college"
+	DeptTypeMajor   = "major"
+	DeptTypeClass   = "class"
+	DeptTypeOffice  = "office"
+	DeptTypeOthers  = "others"
+)
+
+// Department 部门树节点（学校 / 学部 / 专业 / 班级等），level 为到根节点的深度
+type Department struct {
+	ID         string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name       string         `json:"name" gorm:"not null;size:100"`
+	Code       *string        `json:"code,omitempty" gorm:"size:20"`
+	DeptType   string         `json:"dept_type" gorm:"column:dept_type;type:dept_type_enum;not null"`
+	Level      int            `json:"level" gorm:"not null;default:0"`
+	ParentID   *string        `json:"parent_id,omitempty" gorm:"type:uuid"`
+	ArchivedAt *time.Time     `json:"archived_at,omitempty"` // 班级随学年结转归档后不再出现在下拉选项中
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Department) TableName() string {
+	return "departments"
+}
+
+// DepartmentCreateRequest 创建部门请求
+type DepartmentCreateRequest struct {
+	Name     string  `json:"name" binding:"required,max=100"`
+	Code     *string `json:"code" binding:"omitempty,max=20"`
+	DeptType string  `json:"dept_type" binding:"required,oneof=school faculty college major class office others"`
+	ParentID *string `json:"parent_id" binding:"omitempty,uuid"`
+}
+
+// DepartmentUpdateRequest 更新部门名称 / 代码（移动节点使用单独的接口）
+type DepartmentUpdateRequest struct {
+	Name *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Code *string `json:"code" binding:"omitempty,max=20"`
+}
+
+// DepartmentMoveRequest 将部门移动到新的父节点下
+type DepartmentMoveRequest struct {
+	ParentID string `json:"parent_id" binding:"required,uuid"`
+}
+
+// DepartmentMergeRequest 将当前部门合并到目标部门：子部门、用户和数据范围绑定全部转移，当前部门删除
+type DepartmentMergeRequest struct {
+	TargetID string `json:"target_id" binding:"required,uuid"`
+}
+
+// DepartmentResponse 部门信息，树形查询时带子节点
+type DepartmentResponse struct {
+	ID         string                `json:"id"`
+	Name       string                `json:"name"`
+	Code       *string               `json:"code,omitempty"`
+	DeptType   string                `json:"dept_type"`
+	Level      int                   `json:"level"`
+	ParentID   *string               `json:"parent_id,omitempty"`
+	UserCount  int64                 `json:"user_count"`
+	ArchivedAt *time.Time            `json:"archived_at,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Children   []*DepartmentResponse `json:"children,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// DepartmentScope 教师 / 部门管理员绑定的部门节点，可见数据范围为节点的整棵子树
+type DepartmentScope struct {
+	UserID       string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	DepartmentID string    `json:"department_id" gorm:"primaryKey;type:uuid;index"`
+	CreatedBy    *string   `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (DepartmentScope) TableName() string {
+	return "department_scopes"
+}
+
+// DepartmentScopesRequest 设置用户管理的部门节点（整体替换）
+type DepartmentScopesRequest struct {
+	DepartmentIDs []string `json:"department_ids" binding:"omitempty,dive,uuid"`
+}
+
+// DepartmentScopeResponse 用户的数据范围
+type DepartmentScopeResponse struct {
+	UserID       string          `json:"user_id"`
+	Restricted   bool            `json:"restricted"`
+	Bindings     []DepartmentRef `json:"bindings"`
+	SubtreeCount int             `json:"subtree_count"`
+}
+
+// DepartmentRef 部门节点的简要信息
+type DepartmentRef struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	DeptType string `json:"dept_type"`
+}
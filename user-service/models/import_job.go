@@ -0,0 +1,18 @@
+package models
+
+import "credit-management/shared/imports"
+
+// 导入任务状态
+const (
+	ImportJobPending   = imports.StatusPending
+	ImportJobRunning   = imports.StatusRunning
+	ImportJobCompleted = imports.StatusCompleted
+	ImportJobFailed    = imports.StatusFailed
+	ImportJobCancelled = imports.StatusCancelled
+)
+
+// ImportJob 异步导入任务（各服务共用一张表，按 service 区分），定义在共享模块中
+type ImportJob = imports.Job
+
+// ImportJobError 导入失败的行，用于生成错误报告
+type ImportJobError = imports.JobError
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// 通知渠道
+const (
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+	ChannelWeCom    = "wecom"    // 企业微信群机器人
+	ChannelDingTalk = "dingtalk" // 钉钉群机器人
+)
+
+// NotificationChannel 用户的外部通知渠道偏好（每个用户每个渠道一条）
+type NotificationChannel struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	Channel   string    `json:"channel" gorm:"primaryKey;size:20"`
+	Enabled   bool      `json:"enabled" gorm:"not null;default:true"`
+	Target    string    `json:"target" gorm:"size:500"`               // 邮箱地址或机器人 webhook 地址，邮件渠道为空时使用账号邮箱
+	Secret    string    `json:"-" gorm:"size:200"`                    // 钉钉加签密钥等，不对外返回
+	Digest    bool      `json:"digest" gorm:"not null;default:false"` // 待审核提醒改为每日汇总发送
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (NotificationChannel) TableName() string {
+	return "user_notification_channels"
+}
+
+// NotificationChannelInput 单个渠道的设置，Secret 为空表示保留原值
+type NotificationChannelInput struct {
+	Channel string  `json:"channel" binding:"required,oneof=email webhook wecom dingtalk"`
+	Enabled bool    `json:"enabled"`
+	Target  string  `json:"target" binding:"omitempty,max=500"`
+	Secret  *string `json:"secret" binding:"omitempty,max=200"`
+	Digest  bool    `json:"digest"`
+}
+
+// NotificationChannelsRequest 整体替换当前用户的通知渠道设置
+type NotificationChannelsRequest struct {
+	Channels []NotificationChannelInput `json:"channels" binding:"dive"`
+}
+
+// NotificationChannelResponse 通知渠道设置响应
+type NotificationChannelResponse struct {
+	Channel   string `json:"channel"`
+	Enabled   bool   `json:"enabled"`
+	Target    string `json:"target"`
+	HasSecret bool   `json:"has_secret"`
+	Digest    bool   `json:"digest"`
+}
+
+// ChannelTarget 内部接口返回的可投递渠道（包含密钥）
+type ChannelTarget struct {
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+	Secret  string `json:"secret,omitempty"`
+	Digest  bool   `json:"digest"`
+}
+
+// UserChannels 内部接口返回的单个用户的可投递渠道
+type UserChannels struct {
+	UserID   string          `json:"user_id"`
+	RealName string          `json:"real_name"`
+	UserType string          `json:"user_type"`
+	Channels []ChannelTarget `json:"channels"`
+}
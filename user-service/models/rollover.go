@@ -0,0 +1,43 @@
+package models
+
+// RolloverRequest 学年结转：指定年级的学生毕业、班级归档，并可按规则创建新一届班级。
+// DryRun 为 true 时只返回预览报告；正式执行时须带上预览报告中的 ConfirmToken，
+// 预览之后数据发生变化会导致校验失败，需要重新预览
+type RolloverRequest struct {
+	GraduateGrade  string          `json:"graduate_grade" binding:"required,len=4,numeric"`
+	ArchiveClasses bool            `json:"archive_classes"`
+	NewClasses     *NewClassesPlan `json:"new_classes"`
+	DryRun         bool            `json:"dry_run"`
+	ConfirmToken   string          `json:"confirm_token"`
+}
+
+// NewClassesPlan 新一届班级的生成规则，两种方式可同时使用：
+// FromGrade：复制该年级的班级设置，班级名称中的年级前缀替换为 Grade（如 2025051 -> 2026051）；
+// Pattern：为 MajorIDs 中的每个专业按模板生成 Count 个班级，模板支持 {grade}、{yy}、{n} 占位符
+type NewClassesPlan struct {
+	Grade     string   `json:"grade" binding:"required,len=4,numeric"`
+	FromGrade string   `json:"from_grade" binding:"omitempty,len=4,numeric"`
+	Pattern   string   `json:"pattern" binding:"omitempty,max=50"`
+	Count     int      `json:"count" binding:"omitempty,min=1,max=50"`
+	MajorIDs  []string `json:"major_ids" binding:"omitempty,dive,uuid"`
+}
+
+// RolloverClass 报告中的班级条目
+type RolloverClass struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	MajorID  string `json:"major_id"`
+	Major    string `json:"major"`
+	Students int64  `json:"students"`
+}
+
+// RolloverReport 学年结转报告（预览与执行结果共用）
+type RolloverReport struct {
+	DryRun             bool            `json:"dry_run"`
+	GraduateGrade      string          `json:"graduate_grade"`
+	GraduatingStudents int64           `json:"graduating_students"`
+	ArchivedClasses    []RolloverClass `json:"archived_classes"`
+	CreatedClasses     []RolloverClass `json:"created_classes"`
+	SkippedClasses     []RolloverClass `json:"skipped_classes"` // 已存在同名班级，不重复创建
+	ConfirmToken       string          `json:"confirm_token"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 用户动态时间线条目类型（user-service 与 auth-service 产生的部分）
+const (
+	TimelineLogin          = "login"
+	TimelineLogout         = "logout"
+	TimelineProfileUpdate  = "profile_update"
+	TimelinePasswordChange = "password_change"
+)
+
+// TimelineItem 用户动态时间线条目，学分活动服务返回的条目使用相同结构
+type TimelineItem struct {
+	Type         string                 `json:"type"`
+	Title        string                 `json:"title"`
+	OccurredAt   time.Time              `json:"occurred_at"`
+	Source       string                 `json:"source"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   string                 `json:"resource_id"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+}
@@ -223,3 +223,16 @@ func ValidateGradeFormat(grade string) bool {
 	}
 	return true
 }
+
+// UserBatchRequest 内部批量查询用户请求，按 UUID 或学号 / 工号查询，两者至少提供一项
+type UserBatchRequest struct {
+	UUIDs      []string `json:"uuids" binding:"omitempty,max=500,dive,uuid"`
+	StudentIDs []string `json:"student_ids" binding:"omitempty,max=500,dive,min=1,max=18"` // 学号或工号
+}
+
+// UserChangesRequest 内部增量同步请求，since / after 为上一页最后一条的 changed_at 和 uuid
+type UserChangesRequest struct {
+	Since time.Time `form:"since" time_format:"2006-01-02T15:04:05.999999999Z07:00"`
+	After string    `form:"after" binding:"omitempty,uuid"`
+	Limit int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // ViewBasedSearchResponse 基于视图的搜索响应
 type ViewBasedSearchResponse struct {
 	Users      []map[string]interface{} `json:"users"`
@@ -8,6 +10,10 @@ type ViewBasedSearchResponse struct {
 	PageSize   int                      `json:"page_size"`
 	TotalPages int                      `json:"total_pages"`
 	ViewType   string                   `json:"view_type"`
+	// 游标分页（请求带 cursor 参数）时返回；total=estimate 时 total 为估算值，total=none 时为 -1
+	NextCursor     string `json:"next_cursor,omitempty"`
+	HasMore        *bool  `json:"has_more,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
 }
 
 // UserStats 用户统计信息
@@ -42,3 +48,41 @@ type TeacherStats struct {
 	TeachersByDepartment map[string]int64 `json:"teachers_by_department"`
 	TeachersByTitle      map[string]int64 `json:"teachers_by_title"`
 }
+
+// UserProfile 供其他服务使用的用户信息（学生的学部 / 专业 / 班级由所在班级向上推导）。
+// ChangedAt 为最后修改或删除的时间，用于增量同步
+type UserProfile struct {
+	UUID       string    `json:"uuid"`
+	Username   string    `json:"username"`
+	RealName   string    `json:"real_name"`
+	UserType   string    `json:"user_type"`
+	Status     string    `json:"status"`
+	StudentID  *string   `json:"student_id,omitempty"`
+	TeacherID  *string   `json:"teacher_id,omitempty"`
+	Avatar     *string   `json:"avatar,omitempty"`
+	Grade      *string   `json:"grade,omitempty"`
+	Title      *string   `json:"title,omitempty"`
+	Department string    `json:"department,omitempty"`
+	College    string    `json:"college,omitempty"`
+	Major      string    `json:"major,omitempty"`
+	Class      string    `json:"class,omitempty"`
+	Deleted    bool      `json:"deleted,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+
+	// 所在部门节点；增量同步接口还返回教师 / 管理员展开后的数据范围（受限时可见的部门节点）
+	DepartmentID       *string  `json:"department_id,omitempty"`
+	ScopeRestricted    bool     `json:"scope_restricted,omitempty" gorm:"-"`
+	ScopeDepartmentIDs []string `json:"scope_department_ids,omitempty" gorm:"-"`
+}
+
+// UserBatchResponse 批量查询结果，missing 为未找到的 UUID / 学号
+type UserBatchResponse struct {
+	Users   []UserProfile `json:"users"`
+	Missing []string      `json:"missing"`
+}
+
+// UserChangesResponse 用户增量变更
+type UserChangesResponse struct {
+	Users   []UserProfile `json:"users"`
+	HasMore bool          `json:"has_more"`
+}
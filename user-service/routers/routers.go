@@ -1,24 +1,33 @@
 package routers
 
 import (
+	"log"
+
+	"credit-management/shared/audit"
 	"credit-management/user-service/handlers"
 	"credit-management/user-service/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRouters(userHandler *handlers.UserHandler) *gin.Engine {
+func RegisterRouters(userHandler *handlers.UserHandler, auditLogger *audit.Logger) *gin.Engine {
 	authMiddleware := middleware.NewHeaderAuthMiddleware()
 	permissionMiddleware := middleware.NewPermissionMiddleware()
 
 	r := gin.Default()
+	// 只采信网关（TRUSTED_PROXIES）在 X-Real-IP 中转发的客户端地址，审计日志记录的是真实的客户端 IP
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := r.SetTrustedProxies(middleware.TrustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置错误: ", err)
+	}
 
 	r.Use(middleware.CORSMiddleware())
+	r.Use(auditLogger.Middleware())
 
 	api := r.Group("/api")
 	{
 		// 公共配置选项（无需认证）
-		api.GET("/config/options", handlers.GetOptions)
+		api.GET("/config/options", userHandler.GetOptions)
 
 		// 静态文件服务（头像）- 无需认证
 		api.GET("/uploads/avatars/:filename", userHandler.GetAvatar)
@@ -52,9 +61,16 @@ func RegisterRouters(userHandler *handlers.UserHandler) *gin.Engine {
 				allUsers.POST("/avatar", userHandler.UploadAvatar)   // 上传头像
 				allUsers.DELETE("/avatar", userHandler.DeleteAvatar) // 删除头像
 
-				// 获取用户活动记录（预留）
+				// 外部通知渠道（邮件 / webhook / 企业微信 / 钉钉）
+				allUsers.GET("/notification-channels", userHandler.GetNotificationChannels)
+				allUsers.PUT("/notification-channels", userHandler.UpdateNotificationChannels)
+
+				// 用户动态时间线（登录、资料变更、活动与学分）
 				allUsers.GET("/activity", userHandler.GetUserActivity)     // 当前用户活动
 				allUsers.GET("/:id/activity", userHandler.GetUserActivity) // 指定用户活动（管理员/教师）
+
+				// 数据范围
+				allUsers.GET("/data-scope", userHandler.GetMyDataScope) // 当前用户可见的部门范围
 			}
 
 				// 管理员路由
@@ -78,6 +94,20 @@ func RegisterRouters(userHandler *handlers.UserHandler) *gin.Engine {
 					admin.POST("/import", userHandler.ImportUsers)                 // 通用导入接口（支持Excel和CSV）
 					admin.GET("/excel-template", userHandler.GetUserExcelTemplate) // 获取Excel模板
 
+					// 异步导入任务：进度、错误报告、取消与继续
+					admin.GET("/import-jobs", userHandler.GetImportJobs)
+					admin.GET("/import-jobs/:id", userHandler.GetImportJob)
+					admin.GET("/import-jobs/:id/errors", userHandler.GetImportJobErrors)
+					admin.POST("/import-jobs/:id/cancel", userHandler.CancelImportJob)
+					admin.POST("/import-jobs/:id/resume", userHandler.ResumeImportJob)
+
+					// 学年结转（毕业、班级归档、新生班级）
+					admin.POST("/rollover", userHandler.RolloverAcademicYear)
+
+					// 部门数据范围绑定
+					admin.GET("/:id/department-scopes", userHandler.GetDepartmentScopes)
+					admin.PUT("/:id/department-scopes", userHandler.UpdateDepartmentScopes)
+
 					// 学生、教师和管理员可以访问的路由（基于角色的权限控制）
 					studentTeacherOrAdmin := auth.Group("")
 					studentTeacherOrAdmin.Use(permissionMiddleware.StudentTeacherOrAdmin())
@@ -128,6 +158,45 @@ func RegisterRouters(userHandler *handlers.UserHandler) *gin.Engine {
 			}
 		}
 
+		// 部门管理（学校 / 学部 / 专业 / 班级树）
+		departments := api.Group("/departments")
+		departments.Use(authMiddleware.AuthRequired())
+		{
+			departments.GET("", permissionMiddleware.AllUsers(), userHandler.ListDepartments)
+			departments.GET("/tree", permissionMiddleware.AllUsers(), userHandler.GetDepartmentTree)
+			departments.GET("/:id", permissionMiddleware.AllUsers(), userHandler.GetDepartment)
+
+			admin := departments.Group("")
+			admin.Use(permissionMiddleware.AdminOnly())
+			{
+				admin.POST("", userHandler.CreateDepartment)
+				admin.PUT("/:id", userHandler.UpdateDepartment)
+				admin.DELETE("/:id", userHandler.DeleteDepartment)
+				admin.POST("/:id/move", userHandler.MoveDepartment)   // 移动到新的上级部门
+				admin.POST("/:id/merge", userHandler.MergeDepartment) // 合并到同类型部门
+			}
+		}
+
+		// 审计日志查询（仅管理员）
+		auditLogs := api.Group("/audit-logs")
+		auditLogs.Use(authMiddleware.AuthRequired(), permissionMiddleware.AdminOnly())
+		{
+			auditLogs.GET("", userHandler.GetAuditLogs)
+		}
+
+		// 内部服务接口（不经网关暴露）
+		internal := api.Group("/internal")
+		internal.Use(authMiddleware.InternalOnly())
+		{
+			internal.GET("/notification-channels", userHandler.GetDeliverableChannels)
+			internal.POST("/users/batch", userHandler.BatchGetUsers)
+			internal.GET("/users/changes", userHandler.GetUserChanges)
+			// 认证服务登录与令牌校验：users 表只由用户服务读写
+			internal.POST("/credentials/verify", userHandler.VerifyCredentials)
+			internal.GET("/users/:id/identity", userHandler.GetUserIdentity)
+			internal.POST("/users/:id/login", userHandler.RecordLogin)
+		}
+
 		// 搜索相关路由
 		search := api.Group("/search")
 		{